@@ -0,0 +1,44 @@
+module github.com/jaime/go-sqlite/cmd/go-sqlite-learn
+
+go 1.24
+
+require (
+	github.com/jaime/go-sqlite/00-setup-validation v0.0.0
+	github.com/jaime/go-sqlite/01-foundation v0.0.0
+	github.com/jaime/go-sqlite/02-bm25-fundamentals v0.0.0
+	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/spf13/cobra v1.8.1
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.7.0 // indirect
+	github.com/guptarohit/asciigraph v0.7.3 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/jaime/go-sqlite/shared v0.0.0 // indirect
+	github.com/magiconair/properties v1.8.7 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.2 // indirect
+	github.com/sagikazarmark/locafero v0.4.0 // indirect
+	github.com/sagikazarmark/slog-shim v0.1.0 // indirect
+	github.com/sourcegraph/conc v0.3.0 // indirect
+	github.com/spf13/afero v1.11.0 // indirect
+	github.com/spf13/cast v1.6.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/viper v1.19.0 // indirect
+	github.com/subosito/gotenv v1.6.0 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
+	go.uber.org/multierr v1.9.0 // indirect
+	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/ini.v1 v1.67.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/jaime/go-sqlite/00-setup-validation => ../../src/00-setup-validation
+	github.com/jaime/go-sqlite/01-foundation => ../../src/01-foundation
+	github.com/jaime/go-sqlite/02-bm25-fundamentals => ../../src/02-bm25-fundamentals
+	github.com/jaime/go-sqlite/shared => ../../src/shared
+)
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	validation "github.com/jaime/go-sqlite/00-setup-validation/setup-validation/commands"
+	validationerrors "github.com/jaime/go-sqlite/00-setup-validation/setup-validation/errors"
+	foundation "github.com/jaime/go-sqlite/01-foundation/fts5-foundation/commands"
+	bm25 "github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/commands"
+	bm25errors "github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/spf13/cobra"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// rootCmd mounts each learning phase's root command as a subcommand
+var rootCmd = &cobra.Command{
+	Use:   "go-sqlite-learn",
+	Short: "SQLite FTS5 & BM25 learning tools in a single binary",
+	Long: `Runs every learning phase from one binary. Each phase keeps its own
+flags, configuration, and database connection:
+
+  validate     Phase 0: SQLite FTS5 setup validation
+  foundation   Phase 1: FTS5 foundation
+  bm25         Phase 2: BM25 fundamentals`,
+	SilenceUsage: true,
+}
+
+func main() {
+	validation.Root.Init()
+	foundation.Root.Init()
+	bm25.Root.Init()
+
+	// Mount each phase under a short name; the phases bind their flags to separate
+	// viper instances, so "--database" and "--verbose" do not collide
+	mount(validation.Root.Command, "validate")
+	mount(foundation.Root.Command, "foundation")
+	mount(bm25.Root.Command, "bm25")
+
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+
+	// Preserve each phase's exit behavior from its standalone binary
+	switch {
+	case isPhase(cmd, validation.Root.Command):
+		validationerrors.DisplayError(err)
+	case errors.Is(err, bm25errors.ErrCancelled):
+		os.Exit(130) // Conventional exit status for termination by SIGINT
+	}
+	os.Exit(1)
+}
+
+// isPhase reports whether cmd is phase or one of its descendants
+func isPhase(cmd, phase *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// mount renames a phase's root command and adds it under the launcher
+func mount(cmd *cobra.Command, name string) {
+	cmd.Use = name
+	rootCmd.AddCommand(cmd)
+}
@@ -6,14 +6,19 @@ import (
 
 	"github.com/jaime/go-sqlite/00-setup-validation/setup-validation/config"
 	"github.com/jaime/go-sqlite/00-setup-validation/setup-validation/database"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
+// skipDatabaseAnnotation marks commands that must run without the shared database connection
+const skipDatabaseAnnotation = "skip-database"
+
 var (
 	cfgFile string
 	verbose bool
 	format  string
+
+	minSQLiteVersion string
 )
 
 // rootCmd stores the root command for flag registration
@@ -33,11 +38,16 @@ Phase 0 establishes a solid foundation for subsequent learning phases.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// Initialize configuration after flags are parsed
 		config.App = &config.Config{}
-		config.App.Init()
+		config.App.Init(config.Viper)
 		
+		if cmd.Annotations[skipDatabaseAnnotation] == "true" {
+			return
+		}
+
 		// Initialize database connection (in-memory for validation)
 		if err := database.Init(":memory:"); err != nil {
 			fmt.Fprintf(os.Stderr, "Database initialization error: %v\n", err)
+			fmt.Fprintln(os.Stderr, "💡 Tip: Run 'setup-validation validation build' to diagnose the build configuration")
 			os.Exit(1)
 		}
 		
@@ -56,9 +66,9 @@ Phase 0 establishes a solid foundation for subsequent learning phases.`,
 }
 
 // Root represents the root command group with all child groups initialized
-var Root = &CommandGroup{
+var Root = &cli.CommandGroup{
 	Command:     rootCmd,
-	ChildGroups: []*CommandGroup{
+	ChildGroups: []*cli.CommandGroup{
 		Validation,
 	},
 	FlagSetup: setupGlobalFlags,
@@ -69,8 +79,10 @@ func setupGlobalFlags() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.setup-validation.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output with detailed explanations")
 	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "output format (text, json)")
+	rootCmd.PersistentFlags().StringVar(&minSQLiteVersion, "min-sqlite-version", config.DefaultMinSQLiteVersion, "minimum SQLite version required by the version check")
 
-	// Bind flags to viper
-	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
-	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	// Bind flags to this phase's viper instance
+	config.Viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	config.Viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	config.Viper.BindPFlag("min_sqlite_version", rootCmd.PersistentFlags().Lookup("min-sqlite-version"))
 }
\ No newline at end of file
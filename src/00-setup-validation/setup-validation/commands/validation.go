@@ -2,6 +2,7 @@ package commands
 
 import (
 	"github.com/jaime/go-sqlite/00-setup-validation/setup-validation/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
 )
 
@@ -9,7 +10,7 @@ import (
 var Validation = newValidationGroup()
 
 // newValidationGroup creates the validation command group with all its subcommands
-func newValidationGroup() *CommandGroup {
+func newValidationGroup() *cli.CommandGroup {
 	// validateCmd represents the comprehensive validation command
 	validateCmd := &cobra.Command{
 		Use:   "validate",
@@ -17,16 +18,39 @@ func newValidationGroup() *CommandGroup {
 		Long: `Runs a comprehensive suite of validation checks for the learning environment.
 
 This command tests:
+- Build configuration (cgo, go-sqlite3 driver, fts5 build tag)
 - SQLite database connection
+- Minimum SQLite version (--min-sqlite-version, default 3.34)
 - FTS5 virtual table support
 - Sample data generation and insertion  
 - BM25 scoring functionality
+- Weighted bm25() and ORDER BY rank ordering
 - Utility function accessibility
 
-All checks must pass for the environment to be considered ready for FTS5 learning.`,
+All checks must pass for the environment to be considered ready for FTS5 learning.
+Use --format json for a machine-readable report including system information.
+
+Each check recreates its test table, and a final step drops them all so
+repeated runs leave nothing behind. Use --keep-artifacts to keep the tables
+for debugging.`,
 		RunE: handlers.Validation.HandleValidateAll,
 	}
 
+	// buildCmd checks the build configuration
+	buildCmd := &cobra.Command{
+		Use:   "build",
+		Short: "Check cgo, driver, and fts5 build tag configuration",
+		Long: `Validates how this binary was built: cgo must be enabled for go-sqlite3, the
+driver must open and execute on an in-memory database, and SQLite must report
+ENABLE_FTS5 (set by the fts5 build tag). Failures print the detected settings and
+the exact build command to fix them.
+
+This check runs without the shared database connection, so it works even when
+the other commands fail to start.`,
+		Annotations: map[string]string{skipDatabaseAnnotation: "true"},
+		RunE:        handlers.Validation.HandleBuild,
+	}
+
 	// connectCmd tests SQLite connection
 	connectCmd := &cobra.Command{
 		Use:   "connect",
@@ -35,6 +59,16 @@ All checks must pass for the environment to be considered ready for FTS5 learnin
 		RunE:  handlers.Validation.HandleConnect,
 	}
 
+	// versionCmd checks the minimum SQLite version
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Check the minimum SQLite version",
+		Long: `Validates that sqlite_version() is at least --min-sqlite-version (default 3.34).
+Older releases lack FTS5 features used in later phases, such as the trigram tokenizer;
+failures name the features that may be unavailable.`,
+		RunE: handlers.Validation.HandleVersion,
+	}
+
 	// fts5Cmd tests FTS5 functionality
 	fts5Cmd := &cobra.Command{
 		Use:   "fts5",
@@ -59,14 +93,24 @@ All checks must pass for the environment to be considered ready for FTS5 learnin
 		RunE:  handlers.Validation.HandleBM25,
 	}
 
+	// rankingCmd tests weighted BM25 and rank ordering
+	rankingCmd := &cobra.Command{
+		Use:   "ranking",
+		Short: "Test weighted BM25 and ORDER BY rank",
+		Long: `Validates that bm25() column weights change result ordering on the sample documents
+and that ORDER BY rank matches ORDER BY bm25(). Later phases rely on both; failures
+report the SQLite version and the observed orderings.`,
+		RunE: handlers.Validation.HandleRanking,
+	}
+
 	// setupFlags configures flags for validation commands
 	setupFlags := func() {
-		// No specific flags needed for validation commands
-		// They inherit global flags (verbose, format) from root
+		// Validation commands inherit global flags (verbose, format) from root
+		validateCmd.Flags().BoolP("keep-artifacts", "", false, "keep the validation test tables instead of dropping them")
 	}
 
 	// Return the command group
-	return &CommandGroup{
+	return &cli.CommandGroup{
 		Command: &cobra.Command{
 			Use:   "validation",
 			Short: "Validation commands",
@@ -74,10 +118,13 @@ All checks must pass for the environment to be considered ready for FTS5 learnin
 		},
 		SubCommands: []*cobra.Command{
 			validateCmd,
+			buildCmd,
 			connectCmd,
+			versionCmd,
 			fts5Cmd,
 			testDataCmd,
 			bm25Cmd,
+			rankingCmd,
 		},
 		FlagSetup: setupFlags,
 	}
@@ -6,24 +6,38 @@ import (
 
 // Config holds application configuration
 type Config struct {
-	Verbose bool   `mapstructure:"verbose"`
-	Format  string `mapstructure:"format"`
+	Verbose          bool   `mapstructure:"verbose"`
+	Format           string `mapstructure:"format"`
+	MinSQLiteVersion string `mapstructure:"min_sqlite_version"`
 }
 
+// DefaultMinSQLiteVersion is the oldest SQLite release the learning phases support
+const DefaultMinSQLiteVersion = "3.34"
+
 // App is the global configuration instance
 var App *Config
 
-// Init initializes the global configuration from viper
-func (c *Config) Init() error {
+// Viper holds this phase's settings and flag bindings. The phase uses its own instance
+// rather than the viper singleton so that phases mounted in one binary keep their
+// "database", "verbose", and "format" bindings separate.
+var Viper = viper.New()
+
+
+// Init initializes the global configuration from v, which holds the flag bindings
+func (c *Config) Init(v *viper.Viper) error {
 	App = &Config{
-		Verbose: viper.GetBool("verbose"),
-		Format:  viper.GetString("format"),
+		Verbose:          v.GetBool("verbose"),
+		Format:           v.GetString("format"),
+		MinSQLiteVersion: v.GetString("min_sqlite_version"),
 	}
 	
 	// Apply defaults
 	if App.Format == "" {
 		App.Format = "text"
 	}
+	if App.MinSQLiteVersion == "" {
+		App.MinSQLiteVersion = DefaultMinSQLiteVersion
+	}
 	
 	return nil
 }
@@ -33,6 +47,11 @@ func (c *Config) GetFormat() string {
 	return c.Format
 }
 
+// GetMinSQLiteVersion returns the minimum SQLite version the version check accepts
+func (c *Config) GetMinSQLiteVersion() string {
+	return c.MinSQLiteVersion
+}
+
 // IsVerbose returns whether verbose mode is enabled
 func (c *Config) IsVerbose() bool {
 	return c.Verbose
@@ -67,8 +67,13 @@ func (d *Database) VerifyFTS5Support(ctx context.Context) error {
 	return nil
 }
 
-// CreateTestTable creates a test FTS5 table for validation
+// CreateTestTable creates a test FTS5 table for validation, replacing any table
+// of the same name left by an earlier run so repeated runs start empty
 func (d *Database) CreateTestTable(ctx context.Context, tableName string) error {
+	if err := d.DropTestTable(ctx, tableName); err != nil {
+		return err
+	}
+
 	err := utilities.CreateBasicDocumentTable(d.db, tableName)
 	if err != nil {
 		return errors.FTS5f("failed to create test table: %w", err)
@@ -76,6 +81,14 @@ func (d *Database) CreateTestTable(ctx context.Context, tableName string) error
 	return nil
 }
 
+// DropTestTable removes a validation test table if it exists
+func (d *Database) DropTestTable(ctx context.Context, tableName string) error {
+	if err := utilities.DropTable(d.db, tableName); err != nil {
+		return errors.Databasef("failed to drop test table: %w", err)
+	}
+	return nil
+}
+
 // InsertTestData inserts sample documents for validation
 func (d *Database) InsertTestData(ctx context.Context, tableName string) error {
 	err := utilities.InsertSampleDocuments(d.db, tableName)
@@ -94,6 +107,15 @@ func (d *Database) QueryWithBM25(ctx context.Context, tableName, searchTerm stri
 	return rows, nil
 }
 
+// QueryOrdering returns the ids matching searchTerm in the order given by orderBy
+func (d *Database) QueryOrdering(ctx context.Context, tableName, searchTerm, orderBy string) ([]int, error) {
+	ids, err := utilities.QueryFTS5IDs(d.db, tableName, searchTerm, orderBy)
+	if err != nil {
+		return nil, errors.FTS5f("failed to execute ordering query: %w", err)
+	}
+	return ids, nil
+}
+
 // CountDocuments returns the number of documents in a table
 func (d *Database) CountDocuments(ctx context.Context, tableName string) (int, error) {
 	var count int
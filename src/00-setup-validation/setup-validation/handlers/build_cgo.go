@@ -0,0 +1,6 @@
+//go:build cgo
+
+package handlers
+
+// cgoEnabled reports whether this binary was built with cgo, which go-sqlite3 requires
+const cgoEnabled = true
@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/jaime/go-sqlite/00-setup-validation/setup-validation/config"
@@ -19,9 +24,22 @@ var Validation ValidationHandler
 // ValidationHandler manages validation operations (stateless - accesses global instances)
 type ValidationHandler struct{}
 
+// validationTables lists the tables the validation checks create
+var validationTables = []string{
+	"fts5_validation_test",
+	"test_data_validation",
+	"bm25_validation_test",
+	"ranking_validation_test",
+}
+
 // HandleValidateAll handles the comprehensive validation command
 func (h *ValidationHandler) HandleValidateAll(cmd *cobra.Command, args []string) error {
-	fmt.Println("🔍 Running setup validation checks...")
+	jsonOutput := config.App.GetFormat() == "json"
+	keepArtifacts, _ := cmd.Flags().GetBool("keep-artifacts")
+
+	if !jsonOutput {
+		fmt.Println("🔍 Running setup validation checks...")
+	}
 
 	suite := &models.ValidationSuite{
 		Name:        "Setup Validation Suite",
@@ -36,24 +54,47 @@ func (h *ValidationHandler) HandleValidateAll(cmd *cobra.Command, args []string)
 		description string
 		fn          func() error
 	}{
+		{"Build Configuration", "Verify cgo, the SQLite driver, and the fts5 build tag", h.validateBuildConfiguration},
 		{"SQLite Connection", "Test basic SQLite database connection", h.validateSQLiteConnection},
+		{"SQLite Version", "Check SQLite meets the minimum supported version", h.validateSQLiteVersion},
 		{"FTS5 Support", "Verify FTS5 virtual table support", h.validateFTS5Support},
 		{"Test Data Generation", "Validate sample data insertion", h.validateTestData},
 		{"BM25 Scoring", "Test BM25 scoring functionality", h.validateBM25Scoring},
+		{"Ranking Behavior", "Verify weighted bm25() and ORDER BY rank behavior", h.validateRankingBehavior},
 		{"Shared Utilities", "Verify utility functions work correctly", h.validateUtilities},
 	}
 
+	// Drop the test tables last, unless they are kept for inspection
+	if !keepArtifacts {
+		checks = append(checks, struct {
+			name        string
+			description string
+			fn          func() error
+		}{"Artifact Cleanup", "Drop the tables created by the validation checks", h.cleanupArtifacts})
+	}
+
 	for _, check := range checks {
 		result := h.runValidationCheck(check.name, check.description, check.fn)
 		suite.AddResult(result)
-		h.displayResult(result)
+		if !jsonOutput {
+			h.displayResult(result)
+		}
 	}
 
 	suite.EndTime = time.Now()
 	suite.Duration = suite.EndTime.Sub(suite.StartTime)
+	suite.System = h.collectSystemInfo()
 
 	// Display summary
-	h.displaySummary(suite)
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(suite); err != nil {
+			return errors.Validationf("failed to encode validation report: %w", err)
+		}
+	} else {
+		h.displaySummary(suite)
+	}
 
 	if !suite.IsSuccessful() {
 		return errors.Validationf("validation suite failed with %d errors", suite.Failed)
@@ -74,6 +115,35 @@ func (h *ValidationHandler) HandleConnect(cmd *cobra.Command, args []string) err
 	return nil
 }
 
+// HandleBuild handles the build configuration check
+func (h *ValidationHandler) HandleBuild(cmd *cobra.Command, args []string) error {
+	fmt.Println("🛠️  Checking build configuration...")
+
+	err := h.validateBuildConfiguration()
+	if err != nil && !config.App.IsVerbose() {
+		// Always show what was detected when the check fails
+		h.displayBuildInfo(h.collectBuildInfo())
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Build configuration correct")
+	return nil
+}
+
+// HandleVersion handles the minimum SQLite version check
+func (h *ValidationHandler) HandleVersion(cmd *cobra.Command, args []string) error {
+	fmt.Printf("🏷️  Checking SQLite version (minimum %s)...\n", config.App.GetMinSQLiteVersion())
+
+	if err := h.validateSQLiteVersion(); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ SQLite version supported")
+	return nil
+}
+
 // HandleFTS5 handles the FTS5 functionality test
 func (h *ValidationHandler) HandleFTS5(cmd *cobra.Command, args []string) error {
 	fmt.Println("🔍 Testing FTS5 functionality...")
@@ -110,6 +180,18 @@ func (h *ValidationHandler) HandleBM25(cmd *cobra.Command, args []string) error
 	return nil
 }
 
+// HandleRanking handles the weighted bm25() and ORDER BY rank validation
+func (h *ValidationHandler) HandleRanking(cmd *cobra.Command, args []string) error {
+	fmt.Println("📐 Testing weighted BM25 and rank ordering...")
+
+	if err := h.validateRankingBehavior(); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Weighted BM25 and rank ordering behave as expected")
+	return nil
+}
+
 // Validation check implementations
 
 func (h *ValidationHandler) validateSQLiteConnection() error {
@@ -128,6 +210,136 @@ func (h *ValidationHandler) validateSQLiteConnection() error {
 	return nil
 }
 
+// buildCommand is the command that produces a correctly configured binary
+const buildCommand = "CGO_ENABLED=1 go build -tags fts5 -o setup-validator ./setup-validation"
+
+func (h *ValidationHandler) validateBuildConfiguration() error {
+	info := h.collectBuildInfo()
+
+	if config.App.IsVerbose() {
+		h.displayBuildInfo(info)
+	}
+
+	if !info.CGOEnabled {
+		return errors.Connectionf("built with CGO_ENABLED=0 but go-sqlite3 requires cgo; install a C compiler and rebuild using %s", buildCommand)
+	}
+
+	if !info.DriverFunctional {
+		return errors.Connectionf("go-sqlite3 driver failed to open an in-memory database; rebuild using %s", buildCommand)
+	}
+
+	if !info.FTS5Compiled {
+		return errors.FTS5f("SQLite was built without FTS5 (build tags %s); rebuild using %s",
+			formatTags(info.BuildTags), buildCommand)
+	}
+
+	return nil
+}
+
+// collectBuildInfo inspects the binary's build settings and probes the SQLite driver
+// directly, independent of the global database instance
+func (h *ValidationHandler) collectBuildInfo() *models.BuildInfo {
+	info := &models.BuildInfo{
+		CGOEnabled: cgoEnabled,
+		BuildTags:  []string{},
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			if setting.Key == "-tags" && setting.Value != "" {
+				info.BuildTags = strings.Split(setting.Value, ",")
+			}
+		}
+	}
+
+	for _, tag := range info.BuildTags {
+		if tag == "fts5" || tag == "sqlite_fts5" {
+			info.FTS5Tag = true
+		}
+	}
+
+	if err := utilities.ProbeDriver(); err != nil {
+		info.DriverError = err.Error()
+		return info
+	}
+	info.DriverFunctional = true
+
+	if enabled, err := utilities.FTS5CompileOption(); err == nil {
+		info.FTS5Compiled = enabled
+	}
+
+	return info
+}
+
+// displayBuildInfo prints the detected build configuration
+func (h *ValidationHandler) displayBuildInfo(info *models.BuildInfo) {
+	fmt.Printf("  📍 Go %s on %s/%s, cgo enabled: %t\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, info.CGOEnabled)
+	fmt.Printf("  📍 Build tags: %s (fts5 tag: %t)\n", formatTags(info.BuildTags), info.FTS5Tag)
+	fmt.Printf("  📍 Driver functional: %t\n", info.DriverFunctional)
+	if info.DriverError != "" {
+		fmt.Printf("  📍 Driver error: %s\n", info.DriverError)
+	}
+	fmt.Printf("  📍 ENABLE_FTS5 compile option: %t\n", info.FTS5Compiled)
+}
+
+// formatTags renders build tags for display
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "none"
+	}
+	return strings.Join(tags, ",")
+}
+
+// versionedFeatures lists FTS5 features with the SQLite release that introduced them
+var versionedFeatures = []struct {
+	name    string
+	version models.SQLiteVersion
+}{
+	{"trigram tokenizer", models.SQLiteVersion{Major: 3, Minor: 34}},
+	{"secure-delete option", models.SQLiteVersion{Major: 3, Minor: 42}},
+	{"contentless_delete tables", models.SQLiteVersion{Major: 3, Minor: 43}},
+}
+
+func (h *ValidationHandler) validateSQLiteVersion() error {
+	ctx := context.Background()
+
+	minimum, err := models.ParseSQLiteVersion(config.App.GetMinSQLiteVersion())
+	if err != nil {
+		return errors.Validationf("invalid --min-sqlite-version: %w", err)
+	}
+
+	raw, err := database.Instance.GetSQLiteVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	version, err := models.ParseSQLiteVersion(raw)
+	if err != nil {
+		return errors.Validationf("unrecognized sqlite_version() result: %w", err)
+	}
+
+	if !version.AtLeast(minimum) {
+		var missing []string
+		for _, feature := range versionedFeatures {
+			if !version.AtLeast(feature.version) {
+				missing = append(missing, fmt.Sprintf("%s (%s)", feature.name, feature.version))
+			}
+		}
+
+		message := fmt.Sprintf("SQLite %s is older than the required minimum %s", version, minimum)
+		if len(missing) > 0 {
+			message += "; features that may be unavailable: " + strings.Join(missing, ", ")
+		}
+		return errors.Validationf("%s", message)
+	}
+
+	if config.App.IsVerbose() {
+		fmt.Printf("  📍 SQLite %s meets minimum %s\n", version, minimum)
+	}
+
+	return nil
+}
+
 func (h *ValidationHandler) validateFTS5Support() error {
 	ctx := context.Background()
 
@@ -240,6 +452,89 @@ func (h *ValidationHandler) validateBM25Scoring() error {
 	return nil
 }
 
+// rankingQuery matches "BM25 Algorithm" by title and "Information Retrieval" by content,
+// so a strong title weight reverses their unweighted order
+const rankingQuery = "algorithm OR relevant"
+
+// titleWeightedBM25 weights the sample table columns (id, title, content) toward title
+const titleWeightedBM25 = "bm25(%s, 0.0, 10.0, 1.0)"
+
+func (h *ValidationHandler) validateRankingBehavior() error {
+	ctx := context.Background()
+	tableName := "ranking_validation_test"
+
+	// Setup test data
+	if err := database.Instance.CreateTestTable(ctx, tableName); err != nil {
+		return err
+	}
+
+	if err := database.Instance.InsertTestData(ctx, tableName); err != nil {
+		return err
+	}
+
+	version, err := database.Instance.GetSQLiteVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	unweighted, err := database.Instance.QueryOrdering(ctx, tableName, rankingQuery, fmt.Sprintf("bm25(%s)", tableName))
+	if err != nil {
+		return err
+	}
+
+	weighted, err := database.Instance.QueryOrdering(ctx, tableName, rankingQuery, fmt.Sprintf(titleWeightedBM25, tableName))
+	if err != nil {
+		return err
+	}
+
+	ranked, err := database.Instance.QueryOrdering(ctx, tableName, rankingQuery, "rank")
+	if err != nil {
+		return err
+	}
+
+	if len(unweighted) < 2 {
+		return errors.Validationf("SQLite %s: expected at least 2 results for %q, got ordering %v",
+			version, rankingQuery, unweighted)
+	}
+
+	// Later phases rely on column weights changing the ranking
+	if sameOrdering(unweighted, weighted) {
+		return errors.Validationf("SQLite %s: title-weighted bm25() did not change the ordering for %q (bm25: %v, weighted: %v)",
+			version, rankingQuery, unweighted, weighted)
+	}
+
+	// ...and on ORDER BY rank being equivalent to ORDER BY bm25() by default
+	if !sameOrdering(unweighted, ranked) {
+		return errors.Validationf("SQLite %s: ORDER BY rank differs from ORDER BY bm25() for %q (bm25: %v, rank: %v)",
+			version, rankingQuery, unweighted, ranked)
+	}
+
+	if config.App.IsVerbose() {
+		fmt.Printf("  📍 Query: %s\n", rankingQuery)
+		fmt.Printf("  📍 ORDER BY bm25():          %v\n", unweighted)
+		fmt.Printf("  📍 ORDER BY weighted bm25(): %v (title weight 10.0)\n", weighted)
+		fmt.Printf("  📍 ORDER BY rank:            %v (matches bm25())\n", ranked)
+	}
+
+	return nil
+}
+
+func (h *ValidationHandler) cleanupArtifacts() error {
+	ctx := context.Background()
+
+	for _, tableName := range validationTables {
+		if err := database.Instance.DropTestTable(ctx, tableName); err != nil {
+			return err
+		}
+	}
+
+	if config.App.IsVerbose() {
+		fmt.Printf("  📍 Dropped %d validation tables\n", len(validationTables))
+	}
+
+	return nil
+}
+
 func (h *ValidationHandler) validateUtilities() error {
 	// This validation is implicit in the other tests
 	if config.App.IsVerbose() {
@@ -250,6 +545,19 @@ func (h *ValidationHandler) validateUtilities() error {
 
 // Helper methods
 
+// sameOrdering reports whether two result orderings list the same ids in the same order
+func sameOrdering(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (h *ValidationHandler) runValidationCheck(name, description string, fn func() error) models.ValidationResult {
 	start := time.Now()
 	err := fn()
@@ -262,10 +570,35 @@ func (h *ValidationHandler) runValidationCheck(name, description string, fn func
 		Error:       err,
 		Duration:    duration,
 	}
+	if err != nil {
+		result.Message = err.Error()
+	}
 
 	return result
 }
 
+// collectSystemInfo gathers the environment details included in the validation report
+func (h *ValidationHandler) collectSystemInfo() *models.SystemInfo {
+	ctx := context.Background()
+
+	info := &models.SystemInfo{
+		MinVersion:    config.App.GetMinSQLiteVersion(),
+		FTS5Available: database.Instance.VerifyFTS5Support(ctx) == nil,
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		Build:         h.collectBuildInfo(),
+	}
+
+	if version, err := database.Instance.GetSQLiteVersion(ctx); err == nil {
+		info.SQLiteVersion = version
+		if parsed, err := models.ParseSQLiteVersion(version); err == nil {
+			info.ParsedVersion = &parsed
+		}
+	}
+
+	return info
+}
+
 func (h *ValidationHandler) displayResult(result models.ValidationResult) {
 	status := "✅"
 	if !result.Passed {
@@ -7,7 +7,8 @@ type ValidationResult struct {
 	Name        string        `json:"name"`
 	Description string        `json:"description"`
 	Passed      bool          `json:"passed"`
-	Error       error         `json:"error,omitempty"`
+	Error       error         `json:"-"`
+	Message     string        `json:"error,omitempty"` // Error text for reports
 	Duration    time.Duration `json:"duration"`
 	Details     string        `json:"details,omitempty"`
 }
@@ -23,17 +24,31 @@ type ValidationSuite struct {
 	Passed      int                `json:"passed"`
 	Failed      int                `json:"failed"`
 	Total       int                `json:"total"`
+	System      *SystemInfo        `json:"system,omitempty"`
 }
 
 // SystemInfo holds information about the system environment
 type SystemInfo struct {
 	SQLiteVersion string            `json:"sqlite_version"`
+	ParsedVersion *SQLiteVersion    `json:"sqlite_version_parsed,omitempty"`
+	MinVersion    string            `json:"minimum_sqlite_version"`
 	FTS5Available bool              `json:"fts5_available"`
 	GoVersion     string            `json:"go_version"`
 	Platform      string            `json:"platform"`
+	Build         *BuildInfo        `json:"build,omitempty"`
 	Environment   map[string]string `json:"environment,omitempty"`
 }
 
+// BuildInfo describes how the binary and its SQLite driver were built
+type BuildInfo struct {
+	CGOEnabled       bool     `json:"cgo_enabled"`
+	BuildTags        []string `json:"build_tags"`
+	FTS5Tag          bool     `json:"fts5_tag"`          // fts5 or sqlite_fts5 passed to -tags
+	DriverFunctional bool     `json:"driver_functional"` // go-sqlite3 could open and execute
+	FTS5Compiled     bool     `json:"fts5_compiled"`     // ENABLE_FTS5 in pragma compile_options
+	DriverError      string   `json:"driver_error,omitempty"`
+}
+
 // BM25TestResult represents results from BM25 scoring validation
 type BM25TestResult struct {
 	Query       string  `json:"query"`
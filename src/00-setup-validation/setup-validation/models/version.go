@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SQLiteVersion is a parsed SQLite version number
+type SQLiteVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// ParseSQLiteVersion parses versions such as "3.45.1" or "3.34" as returned by
+// sqlite_version() or given in configuration. Missing components default to zero
+// and components beyond the patch level are ignored.
+func ParseSQLiteVersion(s string) (SQLiteVersion, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) < 2 {
+		return SQLiteVersion{}, fmt.Errorf("invalid SQLite version %q: expected major.minor[.patch]", s)
+	}
+
+	numbers := make([]int, 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return SQLiteVersion{}, fmt.Errorf("invalid SQLite version %q: component %q is not a number", s, parts[i])
+		}
+		numbers[i] = n
+	}
+
+	return SQLiteVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// Compare returns -1, 0, or 1 as v is older than, equal to, or newer than other
+func (v SQLiteVersion) Compare(other SQLiteVersion) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInts(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInts(v.Minor, other.Minor)
+	default:
+		return compareInts(v.Patch, other.Patch)
+	}
+}
+
+// AtLeast returns true if v is the same as or newer than minimum
+func (v SQLiteVersion) AtLeast(minimum SQLiteVersion) bool {
+	return v.Compare(minimum) >= 0
+}
+
+// String formats the version as major.minor.patch
+func (v SQLiteVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// compareInts returns -1, 0, or 1 as a is less than, equal to, or greater than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,41 @@
+package utilities
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ProbeDriver opens a fresh in-memory database and executes a trivial statement,
+// confirming the go-sqlite3 driver is linked and functional. Builds without cgo
+// link a stub driver that fails here.
+func ProbeDriver() error {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		return fmt.Errorf("failed to execute on in-memory database: %w", err)
+	}
+
+	return nil
+}
+
+// FTS5CompileOption reports whether SQLite lists ENABLE_FTS5 among its compile options,
+// which go-sqlite3 sets when built with the fts5 tag
+func FTS5CompileOption() (bool, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return false, fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+	defer db.Close()
+
+	var enabled bool
+	err = db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_compile_options WHERE compile_options = 'ENABLE_FTS5'`).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to read compile options: %w", err)
+	}
+
+	return enabled, nil
+}
@@ -1,26 +1,29 @@
 package utilities
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/jaime/go-sqlite/shared/fts5"
 )
 
 // CreateFTS5Table creates a basic FTS5 virtual table
 func CreateFTS5Table(db *sql.DB, tableName string, columns []string) error {
-	columnList := ""
-	for i, col := range columns {
-		if i > 0 {
-			columnList += ", "
-		}
-		columnList += col
-	}
-	
-	query := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s)", tableName, columnList)
-	_, err := db.Exec(query)
+	err := fts5.CreateFTS5Table(context.Background(), db, tableName, fts5.TableSpec{Columns: columns})
 	if err != nil {
 		return fmt.Errorf("failed to create FTS5 table %s: %w", tableName, err)
 	}
-	
+
+	return nil
+}
+
+// DropTable drops tableName if it exists
+func DropTable(db *sql.DB, tableName string) error {
+	_, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+	if err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+	}
 	return nil
 }
 
@@ -39,6 +42,27 @@ func QueryFTS5(db *sql.DB, tableName, searchTerm string) (*sql.Rows, error) {
 	return rows, nil
 }
 
+// QueryFTS5IDs returns the ids of matching documents in the order given by orderBy,
+// an SQL ordering expression such as "rank" or "bm25(docs, 0.0, 10.0, 1.0)"
+func QueryFTS5IDs(db *sql.DB, tableName, searchTerm, orderBy string) ([]int, error) {
+	query := fmt.Sprintf("SELECT id FROM %s WHERE %s MATCH ? ORDER BY %s", tableName, tableName, orderBy)
+	rows, err := db.Query(query, searchTerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query FTS5 table ordered by %s: %w", orderBy, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan document id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // QueryFTS5WithBM25 performs an FTS5 query with BM25 scoring
 func QueryFTS5WithBM25(db *sql.DB, tableName, searchTerm string) (*sql.Rows, error) {
 	query := fmt.Sprintf("SELECT id, title, content, bm25(%s) as score FROM %s WHERE %s MATCH ? ORDER BY bm25(%s)", 
@@ -1,12 +1,15 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
+	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/config"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/errors"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/handlers"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/models"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
 )
 
@@ -21,7 +24,7 @@ and performing CRUD operations on the document collection.`,
 }
 
 // documentGroup represents the document command group with all sub-commands
-var documentGroup = &CommandGroup{
+var documentGroup = &cli.CommandGroup{
 	Command: documentCmd,
 	SubCommands: []*cobra.Command{
 		createTableCmd,
@@ -33,6 +36,9 @@ var documentGroup = &CommandGroup{
 		listCmd,
 		updateCmd,
 		deleteCmd,
+		internalsCmd,
+		setRankCmd,
+		showRankCmd,
 	},
 	FlagSetup: setupDocumentFlags,
 }
@@ -55,7 +61,10 @@ international text handling.`,
 			errors.DisplayError(err)
 			os.Exit(1)
 		}
-		fmt.Println("✓ FTS5 documents table created successfully")
+
+		if !config.App.IsQuiet() {
+			fmt.Printf("✓ FTS5 %s table created successfully\n", config.App.TableName)
+		}
 	},
 }
 
@@ -67,30 +76,70 @@ var insertCmd = &cobra.Command{
 
 This demonstrates basic FTS5 document insertion patterns and automatic indexing.
 
+With --stdin, documents are read as newline-delimited JSON objects with title,
+content, and category fields and inserted in a single transaction. Every line
+is validated first; any invalid line is reported by line number and nothing is
+inserted. Use --continue-on-error to skip invalid lines and insert the rest.
+
+With --unique-title (or unique_title: true in the config file), the insert
+fails if a document with the same title exists, ignoring case. Add --replace
+to update that document instead.
+
 Example usage:
-  fts5-foundation document insert --title "My Document" --content "Document content here" --category "example"`,
+  fts5-foundation document insert --title "My Document" --content "Document content here" --category "example"
+  cat docs.ndjson | fts5-foundation document insert --stdin
+  cat docs.ndjson | fts5-foundation document insert --stdin --continue-on-error
+  fts5-foundation document insert --unique-title --replace --title "My Document" --content "Revised" --category "example"`,
 	Run: func(cmd *cobra.Command, args []string) {
+		fromStdin, _ := cmd.Flags().GetBool("stdin")
+		if fromStdin {
+			insertFromStdin(cmd)
+			return
+		}
+
 		title, _ := cmd.Flags().GetString("title")
 		content, _ := cmd.Flags().GetString("content")
 		category, _ := cmd.Flags().GetString("category")
 
 		// Validate required fields
 		if title == "" || content == "" || category == "" {
-			fmt.Println("Error: title, content, and category are all required")
-			fmt.Println("Usage:")
-			fmt.Println("  --title \"Document Title\"")
-			fmt.Println("  --content \"Document content...\"")
-			fmt.Println("  --category \"document-category\"")
+			fmt.Fprintln(os.Stderr, "Error: title, content, and category are all required")
+			fmt.Fprintln(os.Stderr, "Usage:")
+			fmt.Fprintln(os.Stderr, "  --title \"Document Title\"")
+			fmt.Fprintln(os.Stderr, "  --content \"Document content...\"")
+			fmt.Fprintln(os.Stderr, "  --category \"document-category\"")
 			os.Exit(1)
 		}
 
-		// Insert the document
-		if err := handlers.InsertDocument(title, content, category); err != nil {
+		replace, _ := cmd.Flags().GetBool("replace")
+		uniqueTitle := config.App.RequireUniqueTitle()
+		if cmd.Flags().Changed("unique-title") {
+			uniqueTitle, _ = cmd.Flags().GetBool("unique-title")
+		}
+
+		// Insert the document, guarding against duplicate titles when requested
+		var rowID int64
+		var replaced bool
+		var err error
+		if uniqueTitle || replace {
+			rowID, replaced, err = handlers.InsertUniqueDocument(title, content, category, replace)
+		} else {
+			rowID, err = handlers.InsertDocument(title, content, category)
+		}
+		if err != nil {
 			errors.DisplayError(err)
 			os.Exit(1)
 		}
 
-		fmt.Println("✓ Document inserted successfully")
+		if config.App.IsQuiet() {
+			fmt.Println(rowID)
+			return
+		}
+		if replaced {
+			fmt.Printf("✓ Existing document %d replaced successfully\n", rowID)
+			return
+		}
+		fmt.Printf("✓ Document inserted successfully (rowid %d)\n", rowID)
 	},
 }
 
@@ -127,15 +176,22 @@ The example documents cover various topics to showcase FTS5 search capabilities.
 			},
 		}
 
-		fmt.Printf("Inserting %d example documents...\n", len(documents))
+		if !config.App.IsQuiet() {
+			fmt.Printf("Inserting %d example documents...\n", len(documents))
+		}
 
 		// Perform batch insertion
-		if err := handlers.BatchInsertDocuments(documents); err != nil {
+		rowIDs, err := handlers.BatchInsertDocuments(documents)
+		if err != nil {
 			errors.DisplayError(err)
 			os.Exit(1)
 		}
 
-		fmt.Printf("✓ Successfully inserted %d documents\n", len(documents))
+		if config.App.IsQuiet() {
+			printRowIDs(rowIDs)
+			return
+		}
+		fmt.Printf("✓ Successfully inserted %d documents\n", len(rowIDs))
 	},
 }
 
@@ -147,25 +203,59 @@ var searchCmd = &cobra.Command{
 
 This demonstrates basic FTS5 MATCH queries and BM25 relevance ranking.
 SQLite FTS5 returns negative BM25 scores where lower values indicate better matches.
+With --scores, each result also shows its rank and a 0-100 relevance score
+normalized over the result set (100 = best match in this result set), and labels
+the raw score excellent, good, fair, or poor against the search.relevance
+thresholds in the config file (-4, -2, and -1 by default). With --format json,
+results are printed as a JSON array that always includes the label.
+
+With --use-rank, results are ordered by FTS5's built-in rank column instead of
+an explicit bm25(documents) call. rank uses bm25() with default weights unless
+the table's rank option is reconfigured; --verbose shows whether the two
+orderings agree.
+
+A MATCH without a column filter searches title, content, and category together,
+and the score does not say which of them matched. With --match-columns, each
+result also lists the columns that contain a query match.
 
 Example usage:
   fts5-foundation document search "golang programming"
   fts5-foundation document search "database" --limit 5
-  fts5-foundation document search "sqlite" --scores`,
+  fts5-foundation document search "sqlite" --scores
+  fts5-foundation document search "sqlite" --use-rank --verbose
+  fts5-foundation document search "bm25" --match-columns`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		query := args[0]
 		limit, _ := cmd.Flags().GetInt("limit")
 		showScores, _ := cmd.Flags().GetBool("scores")
+		useRank, _ := cmd.Flags().GetBool("use-rank")
+		matchColumns, _ := cmd.Flags().GetBool("match-columns")
 
 		// Perform the search
-		results, err := handlers.SearchDocuments(query, limit)
+		search := handlers.SearchDocuments
+		if useRank {
+			search = handlers.SearchDocumentsByRank
+		}
+		results, err := search(query, limit)
 		if err != nil {
 			errors.DisplayError(err)
 			os.Exit(1)
 		}
 
+		if matchColumns {
+			if err := handlers.AddMatchedColumns(query, results); err != nil {
+				errors.DisplayError(err)
+				os.Exit(1)
+			}
+		}
+
 		// Display results
+		if config.App.GetFormat() == "json" {
+			printSearchResultsJSON(results)
+			return
+		}
+
 		if len(results) == 0 {
 			fmt.Printf("No documents found matching: %s\n", query)
 			return
@@ -202,6 +292,11 @@ Example usage:
 		}
 
 		// Display results
+		if config.App.GetFormat() == "json" {
+			printSearchResultsJSON(results)
+			return
+		}
+
 		if len(results) == 0 {
 			fmt.Printf("No documents found matching '%s' in category '%s'\n", query, category)
 			return
@@ -240,6 +335,11 @@ Example usage:
 		}
 
 		// Display results
+		if config.App.GetFormat() == "json" {
+			printSearchResultsJSON(results)
+			return
+		}
+
 		if len(results) == 0 {
 			fmt.Printf("No documents found matching '%s' in field '%s'\n", query, field)
 			return
@@ -287,28 +387,54 @@ and/or category. Only the fields you specify will be changed.
 
 The FTS5 index will be automatically updated when the document is modified.
 
+With --diff, the command shows each field before and after the update: a
+changed title or category with its old and new value, and content line by line
+so only the changed lines are marked. Removed text is red and added text green
+on a terminal (set NO_COLOR to turn colors off). With --format json, the diff is
+written as the document's before and after values.
+
 Example usage:
   fts5-foundation document update 1 --title "New Title"
-  fts5-foundation document update 2 --content "New content here" --category "updated"`,
+  fts5-foundation document update 2 --content "New content here" --category "updated"
+  fts5-foundation document update 2 --category "databases" --diff`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Parse row ID
 		var rowID int64
 		if _, err := fmt.Sscanf(args[0], "%d", &rowID); err != nil {
-			fmt.Printf("Invalid row ID '%s': must be a number\n", args[0])
+			fmt.Fprintf(os.Stderr, "Invalid row ID '%s': must be a number\n", args[0])
 			os.Exit(1)
 		}
 
 		title, _ := cmd.Flags().GetString("title")
 		content, _ := cmd.Flags().GetString("content")
 		category, _ := cmd.Flags().GetString("category")
+		showDiff, _ := cmd.Flags().GetBool("diff")
 
 		// Update the document
-		if err := handlers.UpdateDocument(rowID, title, content, category); err != nil {
+		change, err := handlers.UpdateDocument(rowID, title, content, category)
+		if err != nil {
 			errors.DisplayError(err)
 			os.Exit(1)
 		}
 
+		if config.App.IsQuiet() {
+			fmt.Println(rowID)
+			return
+		}
+
+		if showDiff {
+			if config.App.GetFormat() == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(change); err != nil {
+					errors.DisplayError(err)
+					os.Exit(1)
+				}
+				return
+			}
+			fmt.Print(handlers.FormatDocumentDiff(change, colorOutput()))
+		}
 		fmt.Printf("✓ Document %d updated successfully\n", rowID)
 	},
 }
@@ -329,7 +455,7 @@ Example usage:
 		// Parse row ID
 		var rowID int64
 		if _, err := fmt.Sscanf(args[0], "%d", &rowID); err != nil {
-			fmt.Printf("Invalid row ID '%s': must be a number\n", args[0])
+			fmt.Fprintf(os.Stderr, "Invalid row ID '%s': must be a number\n", args[0])
 			os.Exit(1)
 		}
 
@@ -339,10 +465,176 @@ Example usage:
 			os.Exit(1)
 		}
 
+		if config.App.IsQuiet() {
+			fmt.Println(rowID)
+			return
+		}
 		fmt.Printf("✓ Document %d deleted successfully\n", rowID)
 	},
 }
 
+// insertFromStdin inserts newline-delimited JSON documents read from the command's input
+func insertFromStdin(cmd *cobra.Command) {
+	if cmd.Flags().Changed("title") || cmd.Flags().Changed("content") || cmd.Flags().Changed("category") {
+		errors.DisplayError(errors.Validationf("--stdin cannot be combined with --title, --content, or --category"))
+		os.Exit(1)
+	}
+	if cmd.Flags().Changed("unique-title") || cmd.Flags().Changed("replace") {
+		errors.DisplayError(errors.Validationf("--unique-title and --replace apply to single-document inserts only"))
+		os.Exit(1)
+	}
+
+	continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+
+	summary, err := handlers.InsertDocumentStream(cmd.InOrStdin(), continueOnError)
+	if summary != nil {
+		for _, failure := range summary.Failures {
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", failure.Line, failure.Message)
+		}
+	}
+	if err != nil {
+		errors.DisplayError(err)
+		os.Exit(1)
+	}
+
+	if config.App.IsQuiet() {
+		printRowIDs(summary.RowIDs)
+	} else {
+		fmt.Printf("✓ Inserted %d of %d document(s) from stdin", len(summary.RowIDs), summary.Documents)
+		if len(summary.Failures) > 0 {
+			fmt.Printf(" (%d failed)", len(summary.Failures))
+		}
+		fmt.Println()
+	}
+
+	if len(summary.Failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// internalsCmd represents the internals command
+var internalsCmd = &cobra.Command{
+	Use:   "internals",
+	Short: "Show the shadow tables that store the FTS5 index",
+	Long: `Show the regular tables FTS5 creates behind the 'documents' virtual table,
+with row counts and payload sizes, to see what the virtual table actually stores:
+
+  documents_data     the inverted index, stored as segment b-tree pages
+  documents_idx      an index of the first term on each leaf page
+  documents_content  the original column values
+  documents_docsize  per-document column token counts used by BM25
+  documents_config   table settings such as the format version
+
+Payload bytes total the stored values and exclude SQLite page overhead.
+
+Example usage:
+  fts5-foundation document internals
+  fts5-foundation document internals --show-config
+  fts5-foundation document internals --format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		showConfig, _ := cmd.Flags().GetBool("show-config")
+
+		internals, err := handlers.GetFTS5Internals(showConfig)
+		if err != nil {
+			errors.DisplayError(err)
+			os.Exit(1)
+		}
+
+		if config.App.GetFormat() == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(internals); err != nil {
+				errors.DisplayError(err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		fmt.Print(handlers.FormatInternals(internals))
+	},
+}
+
+// setRankCmd represents the set-rank command
+var setRankCmd = &cobra.Command{
+	Use:   "set-rank",
+	Short: "Persist a custom rank function on the FTS5 table",
+	Long: `Configure the function FTS5 evaluates for the rank column by issuing
+INSERT INTO documents(documents, rank) VALUES('rank', ...). The setting is
+stored in the documents_config shadow table and used by 'search --use-rank'.
+
+Column weights follow the column order: title, content, category. The table
+argument is optional; bm25(documents, 10.0, 1.0, 1.0) is stored as
+bm25(10.0, 1.0, 1.0). Use bm25() to restore the default.
+
+Example usage:
+  fts5-foundation document set-rank --expr "bm25(documents, 10.0, 1.0, 1.0)"
+  fts5-foundation document search "sqlite" --use-rank --scores`,
+	Run: func(cmd *cobra.Command, args []string) {
+		expr, _ := cmd.Flags().GetString("expr")
+
+		rank, err := handlers.SetRank(expr)
+		if err != nil {
+			errors.DisplayError(err)
+			os.Exit(1)
+		}
+
+		if !config.App.IsQuiet() {
+			fmt.Printf("✓ Rank set to %s\n", rank)
+		}
+	},
+}
+
+// showRankCmd represents the show-rank command
+var showRankCmd = &cobra.Command{
+	Use:   "show-rank",
+	Short: "Show the rank function configured on the FTS5 table",
+	Long: `Show the function FTS5 evaluates for the rank column. Without a setting made
+by 'document set-rank', FTS5 uses bm25() with every column weighted 1.0.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rank, configured, err := handlers.GetRank()
+		if err != nil {
+			errors.DisplayError(err)
+			os.Exit(1)
+		}
+
+		if configured {
+			fmt.Printf("rank: %s\n", rank)
+		} else {
+			fmt.Printf("rank: %s (default)\n", rank)
+		}
+	},
+}
+
+// printRowIDs prints one rowid per line for quiet mode
+func printRowIDs(rowIDs []int64) {
+	for _, rowID := range rowIDs {
+		fmt.Println(rowID)
+	}
+}
+
+// printSearchResultsJSON prints search results as a JSON array, empty when nothing matched
+func printSearchResultsJSON(results []models.SearchResult) {
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		errors.DisplayError(err)
+		os.Exit(1)
+	}
+}
+
+// colorOutput reports whether text output may use terminal escape codes: stdout is a
+// terminal and NO_COLOR is unset
+func colorOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // Flag setup function
 
 func setupDocumentFlags() {
@@ -350,24 +642,38 @@ func setupDocumentFlags() {
 	insertCmd.Flags().StringP("title", "t", "", "Document title")
 	insertCmd.Flags().StringP("content", "c", "", "Document content")
 	insertCmd.Flags().StringP("category", "g", "", "Document category")
+	insertCmd.Flags().Bool("stdin", false, "Read newline-delimited JSON documents from stdin")
+	insertCmd.Flags().Bool("continue-on-error", false, "With --stdin, skip invalid lines and insert the rest individually")
+	insertCmd.Flags().Bool("unique-title", false, "Fail if a document with the same title exists (case-insensitive; default from unique_title config)")
+	insertCmd.Flags().Bool("replace", false, "Update the document with the same title instead of failing (implies --unique-title)")
 
 	// Search command flags
-	searchCmd.Flags().IntP("limit", "l", 10, "Maximum number of results to return")
-	searchCmd.Flags().BoolP("scores", "s", false, "Show BM25 scores and ranking information")
+	searchCmd.Flags().IntP("limit", "l", 0, "Maximum number of results to return (0 = search.default_limit from the config file, 10 unless set)")
+	searchCmd.Flags().BoolP("scores", "s", false, "Show rank, normalized relevance (0-100), and raw BM25 scores")
+	searchCmd.Flags().Bool("use-rank", false, "Order by the FTS5 rank column instead of calling bm25() explicitly")
+	searchCmd.Flags().Bool("match-columns", false, "List the columns (title, content, category) each result matched in")
 
 	// Search-category command flags
-	searchCategoryCmd.Flags().IntP("limit", "l", 10, "Maximum number of results to return")
-	searchCategoryCmd.Flags().BoolP("scores", "s", false, "Show BM25 scores and ranking information")
+	searchCategoryCmd.Flags().IntP("limit", "l", 0, "Maximum number of results to return (0 = search.default_limit from the config file, 10 unless set)")
+	searchCategoryCmd.Flags().BoolP("scores", "s", false, "Show rank, normalized relevance (0-100), and raw BM25 scores")
 
 	// Search-field command flags
-	searchFieldCmd.Flags().IntP("limit", "l", 10, "Maximum number of results to return")
-	searchFieldCmd.Flags().BoolP("scores", "s", false, "Show BM25 scores and ranking information")
+	searchFieldCmd.Flags().IntP("limit", "l", 0, "Maximum number of results to return (0 = search.default_limit from the config file, 10 unless set)")
+	searchFieldCmd.Flags().BoolP("scores", "s", false, "Show rank, normalized relevance (0-100), and raw BM25 scores")
 
 	// List command flags
-	listCmd.Flags().IntP("limit", "l", 50, "Maximum number of documents to list")
+	listCmd.Flags().IntP("limit", "l", 0, "Maximum number of documents to list (0 = list.default_limit from the config file, 50 unless set)")
+
+	// Internals command flags
+	internalsCmd.Flags().Bool("show-config", false, "Also dump the rows of the table's config shadow table (documents_config by default)")
+
+	// Set-rank command flags
+	setRankCmd.Flags().StringP("expr", "e", "", "Rank function call, e.g. \"bm25(10.0, 1.0, 1.0)\" (required)")
+	setRankCmd.MarkFlagRequired("expr")
 
 	// Update command flags
 	updateCmd.Flags().StringP("title", "t", "", "New document title")
 	updateCmd.Flags().StringP("content", "c", "", "New document content")
 	updateCmd.Flags().StringP("category", "g", "", "New document category")
+	updateCmd.Flags().Bool("diff", false, "Show each field before and after the update, highlighting what changed")
 }
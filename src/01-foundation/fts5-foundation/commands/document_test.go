@@ -0,0 +1,184 @@
+//go:build fts5
+
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/models"
+)
+
+// TestMain runs the command line in place of the tests when runCLI is set,
+// so a test can check exactly what an invocation writes to stdout
+func TestMain(m *testing.M) {
+	if os.Getenv(runCLI) != "" {
+		Root.Init()
+		rootCmd.SetArgs(os.Args[1:])
+		if err := rootCmd.Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI is the environment variable that makes the test binary act as the CLI
+const runCLI = "FTS5_FOUNDATION_RUN_CLI"
+
+// runCommand runs the CLI with args and stdin against the database in dir,
+// with dir as its home so no user config file is read, and returns its stdout
+func runCommand(t *testing.T, dir, stdin string, args ...string) string {
+	t.Helper()
+
+	args = append([]string{"--database", filepath.Join(dir, "documents.db")}, args...)
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runCLI+"=1", "HOME="+dir)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s%s", strings.Join(args, " "), err, output, stderr.String())
+	}
+	return string(output)
+}
+
+// newDocumentsDatabase creates a database with the documents table in a
+// temporary directory and returns the directory
+func newDocumentsDatabase(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	runCommand(t, dir, "", "--quiet", "document", "create-table")
+	return dir
+}
+
+func TestQuietInsert(t *testing.T) {
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  []int64 // Rowids the insert prints, one per line
+	}{
+		{
+			name: "single document",
+			args: []string{"document", "insert", "--title", "Go", "--content", "compiled language", "--category", "programming"},
+			want: []int64{1},
+		},
+		{
+			name: "batch insert",
+			args: []string{"document", "batch-insert"},
+			want: []int64{1, 2, 3, 4},
+		},
+		{
+			name:  "documents from stdin",
+			stdin: `{"title":"One","content":"first","category":"a"}` + "\n\n" + `{"title":"Two","content":"second","category":"b"}` + "\n",
+			args:  []string{"document", "insert", "--stdin"},
+			want:  []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newDocumentsDatabase(t)
+			output := runCommand(t, dir, tt.stdin, append([]string{"--quiet"}, tt.args...)...)
+
+			var got []int64
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+				rowID, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					t.Fatalf("quiet output line %q is not a rowid:\n%s", line, output)
+				}
+				got = append(got, rowID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quiet insert printed rowids %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietInsertReplacePrintsExistingRowID(t *testing.T) {
+	dir := newDocumentsDatabase(t)
+	runCommand(t, dir, "", "document", "batch-insert")
+
+	output := runCommand(t, dir, "", "--quiet", "document", "insert", "--replace",
+		"--title", "database indexing fundamentals", "--content", "revised", "--category", "database")
+	if output != "4\n" {
+		t.Errorf("quiet replace printed %q, want the replaced rowid \"4\\n\"", output)
+	}
+}
+
+func TestSearchJSONOutput(t *testing.T) {
+	dir := newDocumentsDatabase(t)
+	runCommand(t, dir, "", "document", "batch-insert")
+
+	tests := []struct {
+		name    string
+		args    []string
+		rowIDs  []int64
+		columns bool // Whether results list their matched columns
+	}{
+		{"matches", []string{"document", "search", "database"}, []int64{4, 2}, false},
+		{"ranked by the rank column", []string{"document", "search", "database", "--use-rank"}, []int64{4, 2}, false},
+		{"matched columns", []string{"document", "search", "database", "--match-columns"}, []int64{4, 2}, true},
+		{"category filter", []string{"document", "search-category", "sqlite", "database"}, []int64{2}, false},
+		{"field filter", []string{"document", "search-field", "database", "title"}, []int64{4}, false},
+		{"no matches", []string{"document", "search", "nonexistent"}, []int64{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := runCommand(t, dir, "", append([]string{"--format", "json"}, tt.args...)...)
+
+			// Decode into raw fields first, so a missing or renamed key fails
+			var raw []map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(output), &raw); err != nil {
+				t.Fatalf("output is not a JSON array: %v\n%s", err, output)
+			}
+			if raw == nil {
+				t.Fatalf("output %q is null, want an array", output)
+			}
+			for _, fields := range raw {
+				for _, key := range []string{"rowid", "title", "content", "category", "score", "relevance"} {
+					if _, ok := fields[key]; !ok {
+						t.Errorf("result %s lacks %q", fields["rowid"], key)
+					}
+				}
+				if _, ok := fields["matched_columns"]; ok != tt.columns {
+					t.Errorf("result %s has matched_columns = %v, want %v", fields["rowid"], ok, tt.columns)
+				}
+			}
+
+			var results []models.SearchResult
+			if err := json.Unmarshal([]byte(output), &results); err != nil {
+				t.Fatal(err)
+			}
+			rowIDs := []int64{}
+			for i, result := range results {
+				rowIDs = append(rowIDs, result.RowID)
+				if result.Score >= 0 {
+					t.Errorf("result %d has score %.4f, want a negative BM25 score", result.RowID, result.Score)
+				}
+				if i > 0 && result.Score < results[i-1].Score {
+					t.Errorf("result %d scores %.4f, better than the result before it (%.4f)", result.RowID, result.Score, results[i-1].Score)
+				}
+				if result.Relevance == "" {
+					t.Errorf("result %d has no relevance label", result.RowID)
+				}
+			}
+			if !reflect.DeepEqual(rowIDs, tt.rowIDs) {
+				t.Errorf("results %v, want %v", rowIDs, tt.rowIDs)
+			}
+		})
+	}
+}
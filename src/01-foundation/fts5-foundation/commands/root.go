@@ -6,15 +6,17 @@ import (
 
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/config"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/database"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	dbPath  string
-	format  string
+	cfgFile    string
+	verbose    bool
+	dbPath     string
+	format     string
+	quiet      bool
+	autoCreate bool
 )
 
 // rootCmd stores the root command for flag registration
@@ -32,7 +34,10 @@ This educational tool demonstrates:
 Phase 1 focuses on establishing the foundation concepts of FTS5.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// Initialize configuration after flags are parsed
-		config.App.Init()
+		if err := config.App.Init(config.Viper); err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+			os.Exit(1)
+		}
 		
 		// Initialize database connection
 		if err := database.Init(config.App.GetDatabasePath()); err != nil {
@@ -55,9 +60,9 @@ Phase 1 focuses on establishing the foundation concepts of FTS5.`,
 }
 
 // Root represents the root command group with all child groups initialized
-var Root = &CommandGroup{
+var Root = &cli.CommandGroup{
 	Command:     rootCmd,
-	ChildGroups: []*CommandGroup{documentGroup},
+	ChildGroups: []*cli.CommandGroup{documentGroup},
 	FlagSetup:   setupGlobalFlags,
 }
 
@@ -67,9 +72,14 @@ func setupGlobalFlags() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().StringVarP(&dbPath, "database", "d", ":memory:", "database path (default: in-memory)")
 	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "output format (text, json)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress success messages; print only affected rowids")
+	rootCmd.PersistentFlags().BoolVar(&autoCreate, "auto-create", false, "create the documents table on insert when it is missing instead of failing")
 
-	// Bind flags to viper
-	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
-	viper.BindPFlag("database", rootCmd.PersistentFlags().Lookup("database"))
-	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	// Bind flags to this phase's viper instance
+	config.Viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	config.Viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	config.Viper.BindPFlag("database", rootCmd.PersistentFlags().Lookup("database"))
+	config.Viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	config.Viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+	config.Viper.BindPFlag("auto_create", rootCmd.PersistentFlags().Lookup("auto-create"))
 }
\ No newline at end of file
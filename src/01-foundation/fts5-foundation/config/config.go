@@ -1,6 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jaime/go-sqlite/shared/relevance"
 	"github.com/spf13/viper"
 )
 
@@ -9,25 +16,106 @@ type Config struct {
 	DatabasePath string `mapstructure:"database"`
 	Verbose      bool   `mapstructure:"verbose"`
 	Format       string `mapstructure:"format"`
+	Quiet        bool   `mapstructure:"quiet"`
+	UniqueTitle  bool   `mapstructure:"unique_title"`
+	AutoCreate   bool   `mapstructure:"auto_create"`
+
+	// TableName is the FTS5 table every command reads and writes
+	TableName string `mapstructure:"table_name"`
+
+	// Search configuration
+	Search SearchConfig `mapstructure:"search"`
+
+	// List configuration
+	List ListConfig `mapstructure:"list"`
+
+	// viper is the settings source injected by Init
+	viper *viper.Viper
+
+	// VerboseOutput receives verbose diagnostics so stdout stays clean for results
+	VerboseOutput io.Writer `mapstructure:"-"`
 }
 
+// SearchConfig holds search settings
+type SearchConfig struct {
+	// DefaultLimit caps search results when --limit is not given
+	DefaultLimit int `mapstructure:"default_limit"`
+
+	// Relevance sets the score thresholds behind each result's relevance label
+	Relevance relevance.Thresholds `mapstructure:"relevance"`
+}
+
+// ListConfig holds document listing settings
+type ListConfig struct {
+	// DefaultLimit caps listed documents when --limit is not given
+	DefaultLimit int `mapstructure:"default_limit"`
+}
+
+// tableNamePattern matches the plain SQL identifiers accepted as table names, which
+// are written into statements unquoted
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // NewConfig creates a new config instance with defaults
 func NewConfig() *Config {
 	return &Config{
 		DatabasePath: ":memory:",
 		Verbose:      false,
 		Format:       "text",
+		Quiet:        false,
+		UniqueTitle:  false,
+		AutoCreate:   false,
+		TableName:    "documents",
+		Search: SearchConfig{
+			DefaultLimit: 10,
+			Relevance:    relevance.DefaultThresholds(),
+		},
+		List: ListConfig{
+			DefaultLimit: 50,
+		},
+
+		VerboseOutput: os.Stderr,
 	}
 }
 
 // App is the global configuration instance
 var App = NewConfig()
 
-// Init initializes the global configuration from viper
-func (c *Config) Init() error {
-	c.DatabasePath = viper.GetString("database")
-	c.Verbose = viper.GetBool("verbose")
-	c.Format = viper.GetString("format")
+// Viper holds this phase's settings and flag bindings. The phase uses its own instance
+// rather than the viper singleton so that phases mounted in one binary keep their
+// "database", "verbose", and "format" bindings separate.
+var Viper = viper.New()
+
+
+// SetDefaults applies default values to viper, so settings missing from the
+// config file keep the values NewConfig gives them
+func (c *Config) SetDefaults() {
+	c.viper.SetDefault("table_name", c.TableName)
+	c.viper.SetDefault("search.default_limit", c.Search.DefaultLimit)
+	c.viper.SetDefault("search.relevance.excellent", c.Search.Relevance.Excellent)
+	c.viper.SetDefault("search.relevance.good", c.Search.Relevance.Good)
+	c.viper.SetDefault("search.relevance.fair", c.Search.Relevance.Fair)
+	c.viper.SetDefault("list.default_limit", c.List.DefaultLimit)
+}
+
+// Init initializes the configuration from v, which holds the flag bindings, and
+// validates the result
+func (c *Config) Init(v *viper.Viper) error {
+	c.viper = v
+	c.SetDefaults()
+	c.readConfigFile()
+
+	c.DatabasePath = v.GetString("database")
+	c.Verbose = v.GetBool("verbose")
+	c.Format = v.GetString("format")
+	c.Quiet = v.GetBool("quiet")
+	c.UniqueTitle = v.GetBool("unique_title")
+	c.AutoCreate = v.GetBool("auto_create")
+	c.TableName = v.GetString("table_name")
+	c.Search.DefaultLimit = v.GetInt("search.default_limit")
+	c.Search.Relevance.Excellent = v.GetFloat64("search.relevance.excellent")
+	c.Search.Relevance.Good = v.GetFloat64("search.relevance.good")
+	c.Search.Relevance.Fair = v.GetFloat64("search.relevance.fair")
+	c.List.DefaultLimit = v.GetInt("list.default_limit")
 	
 	// Apply defaults if empty
 	if c.DatabasePath == "" {
@@ -36,10 +124,54 @@ func (c *Config) Init() error {
 	if c.Format == "" {
 		c.Format = "text"
 	}
+	if c.VerboseOutput == nil {
+		c.VerboseOutput = os.Stderr
+	}
 	
+	return c.Validate()
+}
+
+// Validate checks the settings loaded from flags and the config file
+func (c *Config) Validate() error {
+	if !tableNamePattern.MatchString(c.TableName) {
+		return fmt.Errorf("table_name %q must start with a letter or underscore and contain only letters, digits, and underscores", c.TableName)
+	}
+	if strings.HasPrefix(strings.ToLower(c.TableName), "sqlite_") {
+		return fmt.Errorf("table_name %q uses the sqlite_ prefix SQLite reserves for its own tables", c.TableName)
+	}
+	if c.Search.DefaultLimit < 1 {
+		return fmt.Errorf("search.default_limit must be at least 1, got %d", c.Search.DefaultLimit)
+	}
+	if err := c.Search.Relevance.Validate(); err != nil {
+		return fmt.Errorf("search.relevance: %w", err)
+	}
+	if c.List.DefaultLimit < 1 {
+		return fmt.Errorf("list.default_limit must be at least 1, got %d", c.List.DefaultLimit)
+	}
 	return nil
 }
 
+// readConfigFile loads the --config file, or .fts5-foundation.yaml from the home or
+// current directory when present, so file settings back the command-line flags
+func (c *Config) readConfigFile() {
+	if cfgFile := c.viper.GetString("config"); cfgFile != "" {
+		c.viper.SetConfigFile(cfgFile)
+	} else {
+		if home, err := os.UserHomeDir(); err == nil {
+			c.viper.AddConfigPath(home)
+		}
+		c.viper.AddConfigPath(".")
+		c.viper.SetConfigType("yaml")
+		c.viper.SetConfigName(".fts5-foundation")
+	}
+
+	c.viper.AutomaticEnv()
+
+	if err := c.viper.ReadInConfig(); err == nil && c.viper.GetBool("verbose") {
+		fmt.Fprintln(os.Stderr, "Using config file:", c.viper.ConfigFileUsed())
+	}
+}
+
 // GetDatabasePath returns the configured database path
 func (c *Config) GetDatabasePath() string {
 	return c.DatabasePath
@@ -53,4 +185,19 @@ func (c *Config) GetFormat() string {
 // IsVerbose returns whether verbose mode is enabled
 func (c *Config) IsVerbose() bool {
 	return c.Verbose
+}
+
+// IsQuiet returns whether quiet mode is enabled
+func (c *Config) IsQuiet() bool {
+	return c.Quiet
+}
+
+// RequireUniqueTitle returns whether inserts reject duplicate titles by default
+func (c *Config) RequireUniqueTitle() bool {
+	return c.UniqueTitle
+}
+
+// AutoCreateTable returns whether inserts create the documents table when it is missing
+func (c *Config) AutoCreateTable() bool {
+	return c.AutoCreate
 }
\ No newline at end of file
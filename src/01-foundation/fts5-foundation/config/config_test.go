@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTableName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tableName string
+		want      string // Substring of the error; empty when the name is accepted
+	}{
+		{"default", "documents", ""},
+		{"leading underscore", "_documents", ""},
+		{"mixed case and digits", "Notes2024", ""},
+		{"sqlite without the underscore", "sqlitedocs", ""},
+		{"sqlite later in the name", "my_sqlite_docs", ""},
+		{"empty", "", "must start with a letter or underscore"},
+		{"leading digit", "2024notes", "must start with a letter or underscore"},
+		{"hyphen", "my-docs", "must start with a letter or underscore"},
+		{"space", "my docs", "must start with a letter or underscore"},
+		{"quoted", `"documents"`, "must start with a letter or underscore"},
+		{"statement", "docs; DROP TABLE docs", "must start with a letter or underscore"},
+		{"non-ASCII letter", "dokumente_ä", "must start with a letter or underscore"},
+		{"reserved prefix", "sqlite_docs", "sqlite_ prefix"},
+		{"reserved prefix in upper case", "SQLITE_docs", "sqlite_ prefix"},
+		{"reserved prefix in mixed case", "SQLite_stat1", "sqlite_ prefix"},
+		{"reserved prefix alone", "sqlite_", "sqlite_ prefix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			c.TableName = tt.tableName
+
+			err := c.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate() with table_name %q = %v, want no error", tt.tableName, err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate() with table_name %q = %v, want an error containing %q", tt.tableName, err, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,13 @@ package database
 import (
 	"context"
 	"database/sql"
+	stderrors "errors"
 	"fmt"
 
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/errors"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/jaime/go-sqlite/shared/fts5"
+	"github.com/jaime/go-sqlite/shared/txn"
+	"github.com/mattn/go-sqlite3"
 )
 
 // Instance is the global database instance
@@ -25,8 +28,15 @@ func Init(dataSourceName string) error {
 // Database wraps the SQL database connection with FTS5-specific operations
 type Database struct {
 	db *sql.DB
+
+	// busyRetries is how many times WithTx retries a transaction on a locked database
+	busyRetries int
 }
 
+// DefaultBusyRetries is the number of times WithTx retries a transaction that
+// found the database locked by another connection
+const DefaultBusyRetries = 3
+
 // NewDatabase creates a new database connection
 func NewDatabase(dataSourceName string) (*Database, error) {
 	db, err := sql.Open("sqlite3", dataSourceName)
@@ -40,7 +50,7 @@ func NewDatabase(dataSourceName string) (*Database, error) {
 		return nil, err
 	}
 
-	return &Database{db: db}, nil
+	return &Database{db: db, busyRetries: DefaultBusyRetries}, nil
 }
 
 // configureSQLite applies optimal settings for FTS5 operations
@@ -90,36 +100,52 @@ func (d *Database) VerifyFTS5Support(ctx context.Context) error {
 	return nil
 }
 
-// InitSchema creates the FTS5 tables and indexes
-func (d *Database) InitSchema(ctx context.Context) error {
+// IndexedColumns names the documents table columns in schema order, which is the order
+// auxiliary functions such as highlight() number them in
+var IndexedColumns = []string{"title", "content", "category"}
+
+// documentsFTS is the schema of the documents table: title for headline searches, content
+// for the main body, and category for filtering
+var documentsFTS = fts5.TableSpec{
+	Columns:     IndexedColumns,
+	Tokenizer:   "unicode61 remove_diacritics 1",
+	IfNotExists: true,
+}
+
+// InitSchema creates the FTS5 documents table under the given name
+func (d *Database) InitSchema(ctx context.Context, table string) error {
 	// Check FTS5 support first
 	if err := d.VerifyFTS5Support(ctx); err != nil {
 		return err
 	}
 
 	// Create the FTS5 virtual table
-	createTableSQL := `
-		CREATE VIRTUAL TABLE IF NOT EXISTS documents USING fts5(
-			title,           -- Document title for headline searches  
-			content,         -- Main document body content
-			category,        -- Document classification for filtering
-			tokenize='unicode61 remove_diacritics 1'
-		)`
-
-	if _, err := d.db.ExecContext(ctx, createTableSQL); err != nil {
-		return errors.FTS5f("failed to create FTS5 table: %w", err)
+	return fts5.CreateFTS5Table(ctx, d.db, table, documentsFTS)
+}
+
+// IsMissingTable reports whether err is SQLite's "no such table" error for
+// table. SQLite reports it with the generic SQLITE_ERROR code, so the code
+// narrows the error to a failed statement and the message names the table.
+func IsMissingTable(err error, table string) bool {
+	var sqliteErr sqlite3.Error
+	if !stderrors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrError {
+		return false
 	}
+	return sqliteErr.Error() == "no such table: "+table
+}
 
-	return nil
+// WithTx runs fn in a transaction that commits when fn returns nil and rolls
+// back when it returns an error or panics. A transaction that finds the
+// database locked is retried from the start, so fn must not carry state over
+// from an earlier attempt.
+func (d *Database) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return txn.Run(ctx, d.db, txn.Options{BusyRetries: d.busyRetries}, fn)
 }
 
-// Begin starts a new transaction
-func (d *Database) Begin(ctx context.Context) (*sql.Tx, error) {
-	tx, err := d.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, errors.Transactionf("failed to begin transaction: %w", err)
-	}
-	return tx, nil
+// SetBusyRetries sets how many times WithTx retries a transaction that found
+// the database locked; zero disables retrying
+func (d *Database) SetBusyRetries(retries int) {
+	d.busyRetries = retries
 }
 
 // DB returns the underlying sql.DB for direct queries when needed
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/models"
+	"github.com/jaime/go-sqlite/shared/scan"
+)
+
+// SearchResultColumns are the columns ScanSearchResults reads, in order
+var SearchResultColumns = []string{"rowid", "title", "content", "category", "score"}
+
+// ScanSearchResults reads every row of a query selecting SearchResultColumns.
+// FTS5 columns are untyped, so a NULL title, content, or category reads as the
+// empty string.
+func ScanSearchResults(rows scan.Rows) ([]models.SearchResult, error) {
+	if err := scan.Columns(rows, SearchResultColumns...); err != nil {
+		return nil, err
+	}
+
+	return scan.All(rows, "search result", func(row scan.Rows) (models.SearchResult, error) {
+		var result models.SearchResult
+		err := row.Scan(
+			&result.RowID,
+			scan.Text(&result.Title),
+			scan.Text(&result.Content),
+			scan.Text(&result.Category),
+			&result.Score,
+		)
+		return result, err
+	})
+}
@@ -2,54 +2,54 @@ package errors
 
 import (
 	"errors"
-	"fmt"
 
-	"github.com/spf13/viper"
+	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/config"
+	shared "github.com/jaime/go-sqlite/shared/errors"
 )
 
 // Sentinel errors for common cases
 var (
 	// ErrNotFound indicates a requested resource was not found
-	ErrNotFound = errors.New("not found")
-	
+	ErrNotFound = shared.ErrNotFound
+
 	// ErrValidation indicates input validation failed
-	ErrValidation = errors.New("validation failed")
-	
+	ErrValidation = shared.ErrValidation
+
 	// ErrDatabase indicates a database operation failed
-	ErrDatabase = errors.New("database operation failed")
-	
+	ErrDatabase = shared.ErrDatabase
+
 	// ErrFTS5 indicates an FTS5-specific operation failed
-	ErrFTS5 = errors.New("FTS5 operation failed")
-	
+	ErrFTS5 = shared.ErrFTS5
+
 	// ErrTransaction indicates a transaction failed
-	ErrTransaction = errors.New("transaction failed")
+	ErrTransaction = shared.ErrTransaction
 )
 
 // Helper functions for creating contextual errors
 
 // NotFoundf creates a formatted not found error
 func NotFoundf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrNotFound}, args...)...)
+	return shared.NotFoundf(format, args...)
 }
 
 // Validationf creates a formatted validation error
 func Validationf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrValidation}, args...)...)
+	return shared.Validationf(format, args...)
 }
 
 // Databasef creates a formatted database error
 func Databasef(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrDatabase}, args...)...)
+	return shared.Databasef(format, args...)
 }
 
 // FTS5f creates a formatted FTS5 error
 func FTS5f(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrFTS5}, args...)...)
+	return shared.FTS5f(format, args...)
 }
 
 // Transactionf creates a formatted transaction error
 func Transactionf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrTransaction}, args...)...)
+	return shared.Transactionf(format, args...)
 }
 
 // Type checking functions
@@ -81,36 +81,22 @@ func IsTransaction(err error) bool {
 
 // Display functions for standardized error output
 
-// DisplayError displays an error with appropriate formatting based on its type
-// Automatically checks verbose flag and displays full error chain if enabled
-func DisplayError(err error) {
-	if viper.GetBool("verbose") {
-		displayVerbose(err)
-	} else {
-		displaySimple(err)
-	}
+// display shows the full error message after each label and appends the
+// error chain in verbose mode
+var display = &shared.Display{
+	Registry: shared.NewRegistry(
+		shared.Category{Sentinel: ErrValidation, Label: "Validation Error"},
+		shared.Category{Sentinel: ErrDatabase, Label: "Database Error"},
+		shared.Category{Sentinel: ErrFTS5, Label: "FTS5 Error", Hint: "Ensure SQLite is compiled with FTS5 support (go build -tags fts5)"},
+		shared.Category{Sentinel: ErrNotFound, Label: "Not Found"},
+		shared.Category{Sentinel: ErrTransaction, Label: "Transaction Error"},
+	),
+	HintPrefix: "Hint: ",
+	Verbose:    shared.VerboseFullChain,
 }
 
-// displayVerbose displays error with full error chain information
-func displayVerbose(err error) {
-	displaySimple(err)
-	fmt.Printf("\nFull error chain: %+v\n", err)
+// DisplayError displays an error on stderr with appropriate formatting based on its type
+// Automatically checks verbose flag and displays full error chain if enabled
+func DisplayError(err error) {
+	display.Show(err, config.Viper.GetBool("verbose"))
 }
-
-// displaySimple displays error with appropriate formatting based on its type
-func displaySimple(err error) {
-	if IsValidation(err) {
-		fmt.Printf("Validation Error: %v\n", err)
-	} else if IsDatabase(err) {
-		fmt.Printf("Database Error: %v\n", err)
-	} else if IsFTS5(err) {
-		fmt.Printf("FTS5 Error: %v\n", err)
-		fmt.Println("Hint: Ensure SQLite is compiled with FTS5 support (go build -tags fts5)")
-	} else if IsNotFound(err) {
-		fmt.Printf("Not Found: %v\n", err)
-	} else if IsTransaction(err) {
-		fmt.Printf("Transaction Error: %v\n", err)
-	} else {
-		fmt.Printf("Error: %v\n", err)
-	}
-}
\ No newline at end of file
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/models"
+)
+
+// Terminal escape codes for removed and added text in a document diff
+const (
+	colorRemoved = "\033[31m"
+	colorAdded   = "\033[32m"
+	colorReset   = "\033[0m"
+)
+
+// diffLine is one line of a content diff: ' ' for a line kept, '-' for a line
+// removed, '+' for a line added
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// FormatDocumentDiff formats a field-by-field comparison of a document before and after
+// an update. A changed title or category shows its old and new value; content is
+// compared line by line, so only the lines that changed are marked. With color set,
+// removed text is red and added text green.
+func FormatDocumentDiff(change *models.DocumentChange, color bool) string {
+	var output strings.Builder
+
+	paint := func(op byte, text string) string {
+		line := fmt.Sprintf("    %c %s", op, text)
+		if !color || op == ' ' {
+			return line + "\n"
+		}
+		code := colorAdded
+		if op == '-' {
+			code = colorRemoved
+		}
+		return code + line + colorReset + "\n"
+	}
+
+	output.WriteString(fmt.Sprintf("Document %d:\n", change.RowID))
+
+	changedFields := 0
+	for _, field := range []struct {
+		name          string
+		before, after string
+	}{
+		{"title", change.Before.Title, change.After.Title},
+		{"category", change.Before.Category, change.After.Category},
+	} {
+		if field.before == field.after {
+			output.WriteString(fmt.Sprintf("  %s (unchanged): %s\n", field.name, field.after))
+			continue
+		}
+		changedFields++
+		output.WriteString(fmt.Sprintf("  %s (changed):\n", field.name))
+		output.WriteString(paint('-', field.before))
+		output.WriteString(paint('+', field.after))
+	}
+
+	if change.Before.Content == change.After.Content {
+		output.WriteString(fmt.Sprintf("  content (unchanged): %d characters\n", len(change.After.Content)))
+	} else {
+		changedFields++
+		lines := diffLines(strings.Split(change.Before.Content, "\n"), strings.Split(change.After.Content, "\n"))
+		removed, added := 0, 0
+		for _, line := range lines {
+			switch line.op {
+			case '-':
+				removed++
+			case '+':
+				added++
+			}
+		}
+		output.WriteString(fmt.Sprintf("  content (changed: %d line(s) removed, %d added):\n", removed, added))
+		for _, line := range lines {
+			output.WriteString(paint(line.op, line.text))
+		}
+	}
+
+	// FTS5 updates a row by deleting its old tokens and indexing the new ones, so every
+	// column is reindexed even when only one changed
+	output.WriteString(fmt.Sprintf("%d of 3 field(s) changed; FTS5 reindexed all 3 columns of the row\n", changedFields))
+
+	return output.String()
+}
+
+// diffLines compares two texts line by line, keeping the longest common subsequence of
+// lines and marking the rest as removed or added. Removed lines come before the lines
+// added in their place.
+func diffLines(before, after []string) []diffLine {
+	// common[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			lines = append(lines, diffLine{' ', before[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, diffLine{'-', before[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', after[j]})
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		lines = append(lines, diffLine{'-', before[i]})
+	}
+	for ; j < len(after); j++ {
+		lines = append(lines, diffLine{'+', after[j]})
+	}
+
+	return lines
+}
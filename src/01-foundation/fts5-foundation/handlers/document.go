@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"regexp"
 	"strings"
 
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/config"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/database"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/errors"
 	"github.com/jaime/go-sqlite/01-foundation/fts5-foundation/models"
+	"github.com/jaime/go-sqlite/shared/scan"
 )
 
 // CreateDocumentsTable creates the FTS5 virtual table for document storage
@@ -16,132 +23,327 @@ func CreateDocumentsTable() error {
 	ctx := context.Background()
 	
 	// Initialize schema using global database instance
-	if err := database.Instance.InitSchema(ctx); err != nil {
+	if err := database.Instance.InitSchema(ctx, config.App.TableName); err != nil {
 		return err
 	}
 
 	if config.App.IsVerbose() {
-		fmt.Println("✓ FTS5 documents table created successfully")
-		fmt.Println("Schema: title, content, category with unicode61 tokenizer")
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "✓ FTS5 %s table created successfully\n", config.App.TableName)
+		fmt.Fprintln(out, "Schema: title, content, category with unicode61 tokenizer")
 	}
 
 	return nil
 }
 
+// withDocumentsTable runs fn, a statement against the documents table. When fn fails
+// because the table does not exist yet, the table is created and fn run again if
+// auto_create is set (--auto-create); otherwise the failure becomes a validation error
+// pointing at create-table. fn must be safe to run a second time.
+func withDocumentsTable(fn func() error) error {
+	err := fn()
+	if !database.IsMissingTable(err, config.App.TableName) {
+		return err
+	}
+
+	if !config.App.AutoCreateTable() {
+		return errors.Validationf("%s table not found — run 'document create-table' first, or pass --auto-create", config.App.TableName)
+	}
+
+	if err := CreateDocumentsTable(); err != nil {
+		return err
+	}
+	return fn()
+}
+
 // VerifyFTS5Support checks if SQLite was compiled with FTS5 support
 func VerifyFTS5Support() error {
 	ctx := context.Background()
 	return database.Instance.VerifyFTS5Support(ctx)
 }
 
-// InsertDocument inserts a single document into the FTS5 table
-func InsertDocument(title, content, category string) error {
+// InsertDocument inserts a single document into the FTS5 table and returns its rowid
+func InsertDocument(title, content, category string) (int64, error) {
 	// Input validation
 	if strings.TrimSpace(title) == "" {
-		return errors.Validationf("title cannot be empty")
+		return 0, errors.Validationf("title cannot be empty")
 	}
 	if strings.TrimSpace(content) == "" {
-		return errors.Validationf("content cannot be empty")
+		return 0, errors.Validationf("content cannot be empty")
 	}
 	if strings.TrimSpace(category) == "" {
-		return errors.Validationf("category cannot be empty")
+		return 0, errors.Validationf("category cannot be empty")
 	}
 
 	ctx := context.Background()
 	db := database.Instance.DB()
 
 	// Insert the document
-	insertSQL := `INSERT INTO documents (title, content, category) VALUES (?, ?, ?)`
-	result, err := db.ExecContext(ctx, insertSQL, title, content, category)
+	insertSQL := fmt.Sprintf(`INSERT INTO %s (title, content, category) VALUES (?, ?, ?)`, config.App.TableName)
+	var result sql.Result
+	err := withDocumentsTable(func() error {
+		var err error
+		result, err = db.ExecContext(ctx, insertSQL, title, content, category)
+		if err != nil {
+			return errors.Databasef("failed to insert document: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Databasef("failed to insert document: %w", err)
+		return 0, err
 	}
 
 	// Get the row ID for confirmation
 	rowID, err := result.LastInsertId()
 	if err != nil {
-		return errors.Databasef("failed to get last insert ID: %w", err)
+		return 0, errors.Databasef("failed to get last insert ID: %w", err)
 	}
 
 	if config.App.IsVerbose() {
-		fmt.Printf("Successfully inserted document with ID: %d\n", rowID)
-		fmt.Printf("Title: %s\n", title)
-		fmt.Printf("Category: %s\n", category)
-		fmt.Printf("Content length: %d characters\n", len(content))
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Successfully inserted document with ID: %d\n", rowID)
+		fmt.Fprintf(out, "Title: %s\n", title)
+		fmt.Fprintf(out, "Category: %s\n", category)
+		fmt.Fprintf(out, "Content length: %d characters\n", len(content))
 	}
 
-	return nil
+	return rowID, nil
+}
+
+// InsertUniqueDocument inserts a document unless one with the same title already exists,
+// comparing titles case-insensitively. A conflict is a validation error naming the
+// existing rowid; with replace, the existing document is updated instead. It returns the
+// affected rowid and whether an existing document was replaced.
+func InsertUniqueDocument(title, content, category string, replace bool) (int64, bool, error) {
+	if strings.TrimSpace(title) == "" {
+		return 0, false, errors.Validationf("title cannot be empty")
+	}
+
+	existingID, found, err := FindDocumentByTitle(title)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !found {
+		rowID, err := InsertDocument(title, content, category)
+		return rowID, false, err
+	}
+
+	if !replace {
+		return 0, false, errors.Validationf("a document titled %q already exists with rowid %d (use --replace to update it)",
+			title, existingID)
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return 0, false, errors.Validationf("content cannot be empty")
+	}
+	if strings.TrimSpace(category) == "" {
+		return 0, false, errors.Validationf("category cannot be empty")
+	}
+
+	if _, err := UpdateDocument(existingID, title, content, category); err != nil {
+		return 0, false, err
+	}
+
+	return existingID, true, nil
 }
 
-// BatchInsertDocuments inserts multiple documents in a single transaction
-func BatchInsertDocuments(documents []models.Document) error {
+// FindDocumentByTitle looks up the lowest rowid whose title matches case-insensitively
+func FindDocumentByTitle(title string) (int64, bool, error) {
+	ctx := context.Background()
+	db := database.Instance.DB()
+
+	// FTS5 columns cannot be indexed for equality, so this scans the table
+	findSQL := fmt.Sprintf(`SELECT rowid FROM %s WHERE lower(title) = lower(?) ORDER BY rowid LIMIT 1`, config.App.TableName)
+
+	var rowID int64
+	found := true
+	err := withDocumentsTable(func() error {
+		err := db.QueryRowContext(ctx, findSQL, strings.TrimSpace(title)).Scan(&rowID)
+		if err == sql.ErrNoRows {
+			found = false
+			return nil
+		}
+		if err != nil {
+			return errors.Databasef("failed to check for existing title: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return rowID, found, nil
+}
+
+// BatchInsertDocuments inserts multiple documents in a single transaction and
+// returns their rowids in input order
+func BatchInsertDocuments(documents []models.Document) ([]int64, error) {
 	if len(documents) == 0 {
-		return errors.Validationf("no documents provided for batch insertion")
+		return nil, errors.Validationf("no documents provided for batch insertion")
 	}
 
 	// Validate all documents before starting transaction
 	for i, doc := range documents {
-		if strings.TrimSpace(doc.Title) == "" {
-			return errors.Validationf("document %d: title cannot be empty", i+1)
-		}
-		if strings.TrimSpace(doc.Content) == "" {
-			return errors.Validationf("document %d: content cannot be empty", i+1)
-		}
-		if strings.TrimSpace(doc.Category) == "" {
-			return errors.Validationf("document %d: category cannot be empty", i+1)
+		if err := validateDocument(doc); err != nil {
+			return nil, errors.Validationf("document %d: %s", i+1, err)
 		}
 	}
 
 	ctx := context.Background()
 
-	// Begin transaction for batch operations
-	tx, err := database.Instance.Begin(ctx)
+	// Insert all documents in one transaction; any failure rolls back the batch
+	var rowIDs []int64
+	err := withDocumentsTable(func() error {
+		return database.Instance.WithTx(ctx, func(tx *sql.Tx) error {
+			insertSQL := fmt.Sprintf("INSERT INTO %s (title, content, category) VALUES (?, ?, ?)", config.App.TableName)
+			stmt, err := tx.PrepareContext(ctx, insertSQL)
+			if err != nil {
+				return errors.Databasef("failed to prepare batch insert statement: %w", err)
+			}
+			defer stmt.Close()
+
+			rowIDs = make([]int64, 0, len(documents))
+			for i, doc := range documents {
+				result, err := stmt.ExecContext(ctx, doc.Title, doc.Content, doc.Category)
+				if err != nil {
+					return errors.Databasef("failed to insert document %d: %w", i+1, err)
+				}
+				rowID, err := result.LastInsertId()
+				if err != nil {
+					return errors.Databasef("failed to get rowid of document %d: %w", i+1, err)
+				}
+				rowIDs = append(rowIDs, rowID)
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
+
+	if config.App.IsVerbose() {
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Successfully inserted %d documents in batch operation\n", len(rowIDs))
+
+		// Count documents by category for summary, listing categories in the
+		// order they first appear so the output is stable
+		categoryCount := make(map[string]int)
+		var categories []string
+		for _, doc := range documents {
+			if categoryCount[doc.Category] == 0 {
+				categories = append(categories, doc.Category)
+			}
+			categoryCount[doc.Category]++
 		}
-	}()
 
-	// Prepare statement within transaction
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO documents (title, content, category) VALUES (?, ?, ?)")
-	if err != nil {
-		return errors.Databasef("failed to prepare batch insert statement: %w", err)
+		fmt.Fprintf(out, "Documents by category:\n")
+		for _, category := range categories {
+			fmt.Fprintf(out, "  %s: %d documents\n", category, categoryCount[category])
+		}
 	}
-	defer stmt.Close()
 
-	// Insert all documents
-	insertedCount := 0
-	for i, doc := range documents {
-		_, err := stmt.ExecContext(ctx, doc.Title, doc.Content, doc.Category)
+	return rowIDs, nil
+}
+
+// InsertDocumentStream reads newline-delimited JSON documents from r and inserts them.
+// By default every line is validated first and the documents are inserted in a single
+// transaction, so any invalid line inserts nothing. With continueOnError, invalid lines
+// are skipped and the remaining documents are inserted one at a time; failures are
+// reported by line number in the summary.
+func InsertDocumentStream(r io.Reader, continueOnError bool) (*models.StreamInsertSummary, error) {
+	summary := &models.StreamInsertSummary{}
+
+	var documents []models.Document
+	var lines []int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		summary.Documents++
+
+		doc, err := parseDocumentLine(line)
 		if err != nil {
-			return errors.Databasef("failed to insert document %d: %w", i+1, err)
+			summary.Failures = append(summary.Failures, models.LineFailure{Line: lineNumber, Message: err.Error()})
+			continue
 		}
-		insertedCount++
+
+		documents = append(documents, doc)
+		lines = append(lines, lineNumber)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return errors.Transactionf("failed to commit batch insert transaction: %w", err)
+	if err := scanner.Err(); err != nil {
+		return summary, errors.Validationf("failed to read documents at line %d: %w", lineNumber+1, err)
 	}
 
-	if config.App.IsVerbose() {
-		fmt.Printf("Successfully inserted %d documents in batch operation\n", insertedCount)
+	if summary.Documents == 0 {
+		return summary, errors.Validationf("no documents provided on input")
+	}
 
-		// Count documents by category for summary
-		categoryCount := make(map[string]int)
-		for _, doc := range documents {
-			categoryCount[doc.Category]++
+	if !continueOnError {
+		if len(summary.Failures) > 0 {
+			return summary, errors.Validationf("%d of %d line(s) are invalid; no documents were inserted",
+				len(summary.Failures), summary.Documents)
 		}
 
-		fmt.Printf("Documents by category:\n")
-		for category, count := range categoryCount {
-			fmt.Printf("  %s: %d documents\n", category, count)
+		rowIDs, err := BatchInsertDocuments(documents)
+		if err != nil {
+			return summary, err
 		}
+		summary.RowIDs = rowIDs
+		return summary, nil
 	}
 
+	// Best-effort: insert each valid document on its own
+	for i, doc := range documents {
+		rowID, err := InsertDocument(doc.Title, doc.Content, doc.Category)
+		if err != nil {
+			summary.Failures = append(summary.Failures, models.LineFailure{Line: lines[i], Message: err.Error()})
+			continue
+		}
+		summary.RowIDs = append(summary.RowIDs, rowID)
+	}
+
+	return summary, nil
+}
+
+// parseDocumentLine decodes and validates a single JSON document
+func parseDocumentLine(line string) (models.Document, error) {
+	var doc models.Document
+
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return doc, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if decoder.More() {
+		return doc, fmt.Errorf("invalid JSON: unexpected data after document")
+	}
+
+	if err := validateDocument(doc); err != nil {
+		return doc, err
+	}
+	return doc, nil
+}
+
+// validateDocument checks that every document field is present
+func validateDocument(doc models.Document) error {
+	if strings.TrimSpace(doc.Title) == "" {
+		return fmt.Errorf("title cannot be empty")
+	}
+	if strings.TrimSpace(doc.Content) == "" {
+		return fmt.Errorf("content cannot be empty")
+	}
+	if strings.TrimSpace(doc.Category) == "" {
+		return fmt.Errorf("category cannot be empty")
+	}
 	return nil
 }
 
@@ -153,24 +355,165 @@ func SearchDocuments(query string, limit int) ([]models.SearchResult, error) {
 	}
 
 	if limit <= 0 {
-		limit = 10 // Default limit
+		limit = config.App.Search.DefaultLimit
+	}
+
+	// Score each match by calling the BM25 auxiliary function explicitly
+	results, err := runSearch(query, limit, bm25Expr())
+	if err != nil {
+		return nil, err
+	}
+
+	if config.App.IsVerbose() {
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Search query: %s\n", query)
+		fmt.Fprintf(out, "Found %d results (limit: %d)\n", len(results), limit)
+		if len(results) > 0 {
+			fmt.Fprintf(out, "Best match score: %.4f (lower is better in SQLite FTS5)\n", results[0].Score)
+		}
+	}
+
+	return results, nil
+}
+
+// SearchDocumentsByRank performs a full-text search ordered by the FTS5 rank column.
+// rank evaluates the table's configured ranking function, which is bm25() with default
+// weights unless the table's rank option has been changed. In verbose mode the ordering
+// is compared against an explicit bm25(documents) search.
+func SearchDocumentsByRank(query string, limit int) ([]models.SearchResult, error) {
+	// Input validation
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.Validationf("search query cannot be empty")
+	}
+
+	if limit <= 0 {
+		limit = config.App.Search.DefaultLimit
+	}
+
+	results, err := runSearch(query, limit, "rank")
+	if err != nil {
+		return nil, err
+	}
+
+	if config.App.IsVerbose() {
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Search query: %s (ORDER BY rank)\n", query)
+		fmt.Fprintf(out, "Found %d results (limit: %d)\n", len(results), limit)
+
+		if rank, configured, err := GetRank(); err == nil {
+			source := "default"
+			if configured {
+				source = "configured with 'document set-rank'"
+			}
+			fmt.Fprintf(out, "Rank function: %s (%s)\n", rank, source)
+		}
+
+		bm25Results, err := runSearch(query, limit, bm25Expr())
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(out, compareOrderings(results, bm25Results))
+	}
+
+	return results, nil
+}
+
+// rankExprPattern matches a rank function call: a function name and its argument list
+var rankExprPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*\((.*)\)\s*$`)
+
+// rankArgPattern matches a numeric SQL literal, the only argument form accepted here
+var rankArgPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+
+// SetRank persists the rank function of the documents table, which ORDER BY rank and
+// the rank column then use. FTS5 expects the function without its table argument, e.g.
+// "bm25(10.0, 1.0, 1.0)"; a leading "documents" argument is accepted and removed so the
+// familiar bm25(documents, ...) form also works. It returns the stored expression.
+func SetRank(expr string) (string, error) {
+	normalized, err := normalizeRankExpr(expr)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	db := database.Instance.DB()
+
+	rankSQL := fmt.Sprintf(`INSERT INTO %[1]s(%[1]s, rank) VALUES('rank', ?)`, config.App.TableName)
+	if _, err := db.ExecContext(ctx, rankSQL, normalized); err != nil {
+		return "", errors.FTS5f("failed to set rank to %s: %w", normalized, err)
+	}
+
+	if config.App.IsVerbose() {
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Stored rank configuration: %s\n", normalized)
+		fmt.Fprintf(out, "ORDER BY rank now evaluates %s for every match\n", normalized)
+	}
+
+	return normalized, nil
+}
+
+// GetRank returns the rank function configured on the documents table, or the FTS5
+// default "bm25()" with configured false when none has been set
+func GetRank() (string, bool, error) {
+	ctx := context.Background()
+	db := database.Instance.DB()
+
+	var rank string
+	configSQL := fmt.Sprintf(`SELECT v FROM %s_config WHERE k = 'rank'`, config.App.TableName)
+	err := db.QueryRowContext(ctx, configSQL).Scan(&rank)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "bm25()", false, nil
+		}
+		return "", false, errors.FTS5f("failed to read rank configuration (run 'document create-table' first): %w", err)
+	}
+
+	return rank, true, nil
+}
+
+// normalizeRankExpr checks that expr is a function call with numeric literal arguments
+// and returns it in canonical form without a table-name argument
+func normalizeRankExpr(expr string) (string, error) {
+	match := rankExprPattern.FindStringSubmatch(expr)
+	if match == nil {
+		return "", errors.Validationf("invalid rank expression %q: expected a function call such as bm25(10.0, 1.0, 1.0)", expr)
+	}
+
+	function, argList := match[1], strings.TrimSpace(match[2])
+
+	var args []string
+	if argList != "" {
+		for i, arg := range strings.Split(argList, ",") {
+			arg = strings.TrimSpace(arg)
+			if i == 0 && arg == config.App.TableName {
+				continue
+			}
+			if !rankArgPattern.MatchString(arg) {
+				return "", errors.Validationf("invalid rank argument %q: rank arguments must be numeric literals", arg)
+			}
+			args = append(args, arg)
+		}
 	}
 
+	return fmt.Sprintf("%s(%s)", function, strings.Join(args, ", ")), nil
+}
+
+// runSearch executes a MATCH query, scoring and ordering results by scoreExpr
+func runSearch(query string, limit int, scoreExpr string) ([]models.SearchResult, error) {
 	ctx := context.Background()
 	db := database.Instance.DB()
 
-	// Prepare the FTS5 search query with BM25 scoring
-	searchSQL := `
+	// Prepare the FTS5 search query; scoreExpr is one of a fixed set of expressions
+	searchSQL := fmt.Sprintf(`
 		SELECT 
 			rowid,
 			title,
 			content,
 			category,
-			bm25(documents) as score
-		FROM documents 
-		WHERE documents MATCH ? 
+			%s as score
+		FROM %[2]s 
+		WHERE %[2]s MATCH ? 
 		ORDER BY score 
-		LIMIT ?`
+		LIMIT ?`, scoreExpr, config.App.TableName)
 
 	rows, err := db.QueryContext(ctx, searchSQL, query, limit)
 	if err != nil {
@@ -178,35 +521,130 @@ func SearchDocuments(query string, limit int) ([]models.SearchResult, error) {
 	}
 	defer rows.Close()
 
-	var results []models.SearchResult
-	for rows.Next() {
-		var result models.SearchResult
-		err := rows.Scan(
-			&result.RowID,
-			&result.Title,
-			&result.Content,
-			&result.Category,
-			&result.Score,
-		)
-		if err != nil {
-			return nil, errors.Databasef("failed to scan search result: %w", err)
+	results, err := database.ScanSearchResults(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Relevance = ClassifyRelevance(results[i].Score)
+	}
+	return results, nil
+}
+
+// AddMatchedColumns sets MatchedColumns on each result to the columns where query
+// matched. A plain MATCH searches every column at once and bm25() folds them into one
+// score, so this asks highlight() for each column: a column whose highlighted text
+// contains the start marker holds at least one matched phrase. Because highlight()
+// follows the query's own matching, column filters, phrases, and prefixes are honoured.
+func AddMatchedColumns(query string, results []models.SearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	db := database.Instance.DB()
+	table := config.App.TableName
+
+	// char(1) never appears in indexed text, so its presence marks a match
+	checks := make([]string, len(database.IndexedColumns))
+	for i := range database.IndexedColumns {
+		checks[i] = fmt.Sprintf("COALESCE(instr(highlight(%s, %d, char(1), char(2)), char(1)) > 0, 0)", table, i)
+	}
+
+	args := []interface{}{query}
+	placeholders := make([]string, len(results))
+	for i, result := range results {
+		placeholders[i] = "?"
+		args = append(args, result.RowID)
+	}
+
+	matchSQL := fmt.Sprintf(`SELECT rowid, %s FROM %[2]s WHERE %[2]s MATCH ? AND rowid IN (%s)`,
+		strings.Join(checks, ", "), table, strings.Join(placeholders, ", "))
+
+	rows, err := db.QueryContext(ctx, matchSQL, args...)
+	if err != nil {
+		return errors.Databasef("failed to check matched columns: %w", err)
+	}
+	defer rows.Close()
+
+	matched := make(map[int64][]string, len(results))
+	_, err = scan.Each(rows, "matched columns", func(row scan.Rows) error {
+		var rowID int64
+		found := make([]bool, len(database.IndexedColumns))
+		dest := []any{&rowID}
+		for i := range found {
+			dest = append(dest, &found[i])
+		}
+		if err := row.Scan(dest...); err != nil {
+			return err
+		}
+
+		for i, column := range database.IndexedColumns {
+			if found[i] {
+				matched[rowID] = append(matched[rowID], column)
+			}
 		}
-		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, errors.Databasef("error iterating search results: %w", err)
+	for i := range results {
+		results[i].MatchedColumns = matched[results[i].RowID]
 	}
+	return nil
+}
 
-	if config.App.IsVerbose() {
-		fmt.Printf("Search query: %s\n", query)
-		fmt.Printf("Found %d results (limit: %d)\n", len(results), limit)
-		if len(results) > 0 {
-			fmt.Printf("Best match score: %.4f (lower is better in SQLite FTS5)\n", results[0].Score)
+// bm25Expr calls bm25() on the configured table with its default weights
+func bm25Expr() string {
+	return fmt.Sprintf("bm25(%s)", config.App.TableName)
+}
+
+// compareOrderings describes whether ORDER BY rank and ORDER BY bm25(documents) agree,
+// listing the positions where they differ
+func compareOrderings(rankResults, bm25Results []models.SearchResult) string {
+	var output strings.Builder
+
+	same, sameScores := len(rankResults) == len(bm25Results), true
+	for i := 0; same && i < len(rankResults); i++ {
+		same = rankResults[i].RowID == bm25Results[i].RowID
+		sameScores = sameScores && math.Abs(rankResults[i].Score-bm25Results[i].Score) < 1e-9
+	}
+
+	if same && sameScores {
+		output.WriteString(fmt.Sprintf("ORDER BY rank matches ORDER BY %s: rank uses the default bm25() configuration\n", bm25Expr()))
+		return output.String()
+	}
+	if same {
+		output.WriteString(fmt.Sprintf("ORDER BY rank keeps the %s ordering, but its scores differ: the table's rank option has been reconfigured\n", bm25Expr()))
+		return output.String()
+	}
+
+	output.WriteString(fmt.Sprintf("ORDER BY rank differs from ORDER BY %s: the table's rank option has been reconfigured\n", bm25Expr()))
+	output.WriteString(fmt.Sprintf("  %-6s %-12s %-12s\n", "Rank", "rank rowid", "bm25 rowid"))
+
+	rows := len(rankResults)
+	if len(bm25Results) > rows {
+		rows = len(bm25Results)
+	}
+	for i := 0; i < rows; i++ {
+		rankID, bm25ID := "-", "-"
+		if i < len(rankResults) {
+			rankID = fmt.Sprint(rankResults[i].RowID)
+		}
+		if i < len(bm25Results) {
+			bm25ID = fmt.Sprint(bm25Results[i].RowID)
 		}
+		marker := ""
+		if rankID != bm25ID {
+			marker = " *"
+		}
+		output.WriteString(fmt.Sprintf("  %-6d %-12s %-12s%s\n", i+1, rankID, bm25ID, marker))
 	}
 
-	return results, nil
+	return output.String()
 }
 
 // SearchByCategory performs a category-filtered search
@@ -220,7 +658,7 @@ func SearchByCategory(query, category string, limit int) ([]models.SearchResult,
 	}
 
 	if limit <= 0 {
-		limit = 10
+		limit = config.App.Search.DefaultLimit
 	}
 
 	// Construct FTS5 query with category filter
@@ -251,7 +689,7 @@ func SearchByField(query, field string, limit int) ([]models.SearchResult, error
 	}
 
 	if limit <= 0 {
-		limit = 10
+		limit = config.App.Search.DefaultLimit
 	}
 
 	// Construct FTS5 field-specific query
@@ -264,22 +702,22 @@ func SearchByField(query, field string, limit int) ([]models.SearchResult, error
 // ListDocuments retrieves all documents from the FTS5 table
 func ListDocuments(limit int) ([]models.DocumentInfo, error) {
 	if limit <= 0 {
-		limit = 50 // Default limit for listings
+		limit = config.App.List.DefaultLimit
 	}
 
 	ctx := context.Background()
 	db := database.Instance.DB()
 
 	// Query all documents
-	listSQL := `
+	listSQL := fmt.Sprintf(`
 		SELECT 
 			rowid,
 			title,
 			content,
 			category
-		FROM documents 
+		FROM %s 
 		ORDER BY rowid 
-		LIMIT ?`
+		LIMIT ?`, config.App.TableName)
 
 	rows, err := db.QueryContext(ctx, listSQL, limit)
 	if err != nil {
@@ -287,19 +725,22 @@ func ListDocuments(limit int) ([]models.DocumentInfo, error) {
 	}
 	defer rows.Close()
 
-	var documents []models.DocumentInfo
-	for rows.Next() {
+	if err := scan.Columns(rows, "rowid", "title", "content", "category"); err != nil {
+		return nil, err
+	}
+
+	documents, err := scan.All(rows, "document", func(row scan.Rows) (models.DocumentInfo, error) {
 		var doc models.DocumentInfo
 		var fullContent string
 
-		err := rows.Scan(
+		err := row.Scan(
 			&doc.RowID,
-			&doc.Title,
-			&fullContent,
-			&doc.Category,
+			scan.Text(&doc.Title),
+			scan.Text(&fullContent),
+			scan.Text(&doc.Category),
 		)
 		if err != nil {
-			return nil, errors.Databasef("failed to scan document: %w", err)
+			return doc, err
 		}
 
 		// Create preview (first 100 characters)
@@ -308,31 +749,31 @@ func ListDocuments(limit int) ([]models.DocumentInfo, error) {
 		} else {
 			doc.Preview = fullContent
 		}
-
-		documents = append(documents, doc)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, errors.Databasef("error iterating documents: %w", err)
+		return doc, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	if config.App.IsVerbose() {
-		fmt.Printf("Found %d documents (limit: %d)\n", len(documents), limit)
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Found %d documents (limit: %d)\n", len(documents), limit)
 	}
 
 	return documents, nil
 }
 
-// UpdateDocument updates an existing document in the FTS5 table
-func UpdateDocument(rowID int64, title, content, category string) error {
+// UpdateDocument updates an existing document in the FTS5 table and returns its
+// fields before and after the update
+func UpdateDocument(rowID int64, title, content, category string) (*models.DocumentChange, error) {
 	// Input validation
 	if rowID <= 0 {
-		return errors.Validationf("invalid rowid: %d", rowID)
+		return nil, errors.Validationf("invalid rowid: %d", rowID)
 	}
 
 	// At least one field must be provided for update
 	if title == "" && content == "" && category == "" {
-		return errors.Validationf("at least one field (title, content, category) must be provided for update")
+		return nil, errors.Validationf("at least one field (title, content, category) must be provided for update")
 	}
 
 	ctx := context.Background()
@@ -340,13 +781,13 @@ func UpdateDocument(rowID int64, title, content, category string) error {
 
 	// First, check if the document exists and get current values
 	var currentTitle, currentContent, currentCategory string
-	checkSQL := `SELECT title, content, category FROM documents WHERE rowid = ?`
+	checkSQL := fmt.Sprintf(`SELECT title, content, category FROM %s WHERE rowid = ?`, config.App.TableName)
 	err := db.QueryRowContext(ctx, checkSQL, rowID).Scan(&currentTitle, &currentContent, &currentCategory)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return errors.NotFoundf("document with rowid %d", rowID)
+			return nil, errors.NotFoundf("document with rowid %d", rowID)
 		}
-		return errors.Databasef("failed to check existing document: %w", err)
+		return nil, errors.Databasef("failed to check existing document: %w", err)
 	}
 
 	// Use current values for fields not being updated
@@ -365,32 +806,37 @@ func UpdateDocument(rowID int64, title, content, category string) error {
 	}
 
 	// Update the document (FTS5 will automatically update the index)
-	updateSQL := `UPDATE documents SET title = ?, content = ?, category = ? WHERE rowid = ?`
+	updateSQL := fmt.Sprintf(`UPDATE %s SET title = ?, content = ?, category = ? WHERE rowid = ?`, config.App.TableName)
 	result, err := db.ExecContext(ctx, updateSQL, newTitle, newContent, newCategory, rowID)
 	if err != nil {
-		return errors.Databasef("failed to update document: %w", err)
+		return nil, errors.Databasef("failed to update document: %w", err)
 	}
 
 	// Verify the update was successful
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return errors.Databasef("failed to get rows affected: %w", err)
+		return nil, errors.Databasef("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return errors.NotFoundf("no document was updated (rowid %d may not exist)", rowID)
+		return nil, errors.NotFoundf("no document was updated (rowid %d may not exist)", rowID)
 	}
 
 	if config.App.IsVerbose() {
-		fmt.Printf("Successfully updated document %d\n", rowID)
-		fmt.Printf("New values:\n")
-		fmt.Printf("  Title: %s\n", newTitle)
-		fmt.Printf("  Category: %s\n", newCategory)
-		fmt.Printf("  Content length: %d characters\n", len(newContent))
-		fmt.Printf("FTS5 index automatically updated\n")
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Successfully updated document %d\n", rowID)
+		fmt.Fprintf(out, "New values:\n")
+		fmt.Fprintf(out, "  Title: %s\n", newTitle)
+		fmt.Fprintf(out, "  Category: %s\n", newCategory)
+		fmt.Fprintf(out, "  Content length: %d characters\n", len(newContent))
+		fmt.Fprintf(out, "FTS5 index automatically updated\n")
 	}
 
-	return nil
+	return &models.DocumentChange{
+		RowID:  rowID,
+		Before: models.Document{Title: currentTitle, Content: currentContent, Category: currentCategory},
+		After:  models.Document{Title: newTitle, Content: newContent, Category: newCategory},
+	}, nil
 }
 
 // DeleteDocument removes a document from the FTS5 table
@@ -405,7 +851,7 @@ func DeleteDocument(rowID int64) error {
 
 	// First, get document info for confirmation (optional but helpful)
 	var title, category string
-	checkSQL := `SELECT title, category FROM documents WHERE rowid = ?`
+	checkSQL := fmt.Sprintf(`SELECT title, category FROM %s WHERE rowid = ?`, config.App.TableName)
 	err := db.QueryRowContext(ctx, checkSQL, rowID).Scan(&title, &category)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -415,7 +861,7 @@ func DeleteDocument(rowID int64) error {
 	}
 
 	// Delete the document (FTS5 will automatically update the index)
-	deleteSQL := `DELETE FROM documents WHERE rowid = ?`
+	deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE rowid = ?`, config.App.TableName)
 	result, err := db.ExecContext(ctx, deleteSQL, rowID)
 	if err != nil {
 		return errors.Databasef("failed to delete document: %w", err)
@@ -432,14 +878,140 @@ func DeleteDocument(rowID int64) error {
 	}
 
 	if config.App.IsVerbose() {
-		fmt.Printf("Successfully deleted document %d\n", rowID)
-		fmt.Printf("Deleted document: %s (category: %s)\n", title, category)
-		fmt.Printf("FTS5 index automatically updated\n")
+		out := config.App.VerboseOutput
+		fmt.Fprintf(out, "Successfully deleted document %d\n", rowID)
+		fmt.Fprintf(out, "Deleted document: %s (category: %s)\n", title, category)
+		fmt.Fprintf(out, "FTS5 index automatically updated\n")
 	}
 
 	return nil
 }
 
+// shadowTables lists the tables FTS5 maintains for the documents virtual table, with the
+// expression that totals each table's stored payload
+var shadowTables = []struct {
+	suffix   string
+	purpose  string
+	payload  string
+	optional bool
+}{
+	{"data", "Inverted index: segment leaf pages and structure records", "length(block)", false},
+	{"idx", "Segment index: first term on each leaf page", "length(term)", false},
+	{"content", "Original column values returned by SELECT", "length(c0) + length(c1) + length(c2)", true},
+	{"docsize", "Token count of each column per document, used by BM25", "length(sz)", false},
+	{"config", "Persistent table settings such as the format version", "length(k) + length(v)", false},
+}
+
+// GetFTS5Internals reports row counts and payload sizes for the shadow tables behind the
+// documents virtual table, optionally including the rows of its config table
+func GetFTS5Internals(includeConfig bool) (*models.FTS5Internals, error) {
+	ctx := context.Background()
+	db := database.Instance.DB()
+
+	name := config.App.TableName
+	shadowPattern := strings.ReplaceAll(name, "_", `\_`) + `\_%`
+
+	existing := make(map[string]bool)
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE name = ? OR name LIKE ? ESCAPE '\'`, name, shadowPattern)
+	if err != nil {
+		return nil, errors.Databasef("failed to read schema: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, errors.Databasef("failed to scan table name: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.Databasef("error iterating schema: %w", err)
+	}
+
+	if !existing[name] {
+		return nil, errors.NotFoundf("FTS5 table '%s' (run 'document create-table' first)", name)
+	}
+
+	internals := &models.FTS5Internals{Table: name}
+
+	if err := db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, name)).Scan(&internals.Documents); err != nil {
+		return nil, errors.FTS5f("failed to count documents: %w", err)
+	}
+
+	for _, shadow := range shadowTables {
+		table := models.ShadowTable{
+			Name:    name + "_" + shadow.suffix,
+			Purpose: shadow.purpose,
+			Present: existing[name+"_"+shadow.suffix],
+		}
+
+		if !table.Present {
+			if !shadow.optional {
+				return nil, errors.FTS5f("shadow table %s is missing; the %s table may be corrupt", table.Name, name)
+			}
+			internals.ShadowTables = append(internals.ShadowTables, table)
+			continue
+		}
+
+		countSQL := fmt.Sprintf(`SELECT COUNT(*), COALESCE(SUM(%s), 0) FROM %s`, shadow.payload, table.Name)
+		if err := db.QueryRowContext(ctx, countSQL).Scan(&table.Rows, &table.PayloadBytes); err != nil {
+			return nil, errors.Databasef("failed to inspect %s: %w", table.Name, err)
+		}
+
+		internals.ShadowTables = append(internals.ShadowTables, table)
+	}
+
+	if includeConfig {
+		configRows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT k, v FROM %s_config ORDER BY k`, name))
+		if err != nil {
+			return nil, errors.Databasef("failed to read %s_config: %w", name, err)
+		}
+		defer configRows.Close()
+
+		for configRows.Next() {
+			var entry models.ConfigEntry
+			var value interface{}
+			if err := configRows.Scan(&entry.Key, &value); err != nil {
+				return nil, errors.Databasef("failed to scan config row: %w", err)
+			}
+			entry.Value = fmt.Sprint(value)
+			internals.Config = append(internals.Config, entry)
+		}
+
+		if err := configRows.Err(); err != nil {
+			return nil, errors.Databasef("error iterating config rows: %w", err)
+		}
+	}
+
+	return internals, nil
+}
+
+// FormatInternals formats the shadow table summary for display
+func FormatInternals(internals *models.FTS5Internals) string {
+	var output strings.Builder
+
+	output.WriteString(fmt.Sprintf("FTS5 table '%s': %d document(s)\n\n", internals.Table, internals.Documents))
+	output.WriteString(fmt.Sprintf("%-20s %8s %14s  %s\n", "Shadow Table", "Rows", "Payload Bytes", "Purpose"))
+
+	for _, table := range internals.ShadowTables {
+		if !table.Present {
+			output.WriteString(fmt.Sprintf("%-20s %8s %14s  %s\n", table.Name, "-", "-", table.Purpose+" (not present)"))
+			continue
+		}
+		output.WriteString(fmt.Sprintf("%-20s %8d %14d  %s\n", table.Name, table.Rows, table.PayloadBytes, table.Purpose))
+	}
+
+	if len(internals.Config) > 0 {
+		output.WriteString(fmt.Sprintf("\nConfig (%s_config):\n", internals.Table))
+		for _, entry := range internals.Config {
+			output.WriteString(fmt.Sprintf("  %s = %s\n", entry.Key, entry.Value))
+		}
+	}
+
+	return output.String()
+}
+
 // FormatSearchResults formats search results for display
 func FormatSearchResults(results []models.SearchResult, showScores bool) string {
 	if len(results) == 0 {
@@ -448,6 +1020,11 @@ func FormatSearchResults(results []models.SearchResult, showScores bool) string
 
 	var output strings.Builder
 
+	var normalized []float64
+	if showScores {
+		normalized = NormalizeScores(results)
+	}
+
 	for i, result := range results {
 		output.WriteString(fmt.Sprintf("\n--- Result #%d ---\n", i+1))
 		output.WriteString(fmt.Sprintf("Title: %s\n", result.Title))
@@ -460,14 +1037,75 @@ func FormatSearchResults(results []models.SearchResult, showScores bool) string
 		}
 		output.WriteString(fmt.Sprintf("Content: %s\n", content))
 
+		if len(result.MatchedColumns) > 0 {
+			output.WriteString(fmt.Sprintf("Matched in: %s\n", strings.Join(result.MatchedColumns, ", ")))
+		}
+
 		if showScores {
-			output.WriteString(fmt.Sprintf("BM25 Score: %.4f (lower is better)\n", result.Score))
+			output.WriteString(fmt.Sprintf("Rank: %d of %d\n", i+1, len(results)))
+			output.WriteString(fmt.Sprintf("Relevance: %5.1f/100 %s\n", normalized[i], scoreBar(normalized[i])))
+			output.WriteString(fmt.Sprintf("BM25 Score: %.4f (%s relevance; lower is better)\n", result.Score, result.Relevance))
 		}
 	}
 
 	return output.String()
 }
 
+// ClassifyRelevance labels a BM25 score using the search.relevance thresholds. FTS5
+// scores are negative and more negative is a better match, so each label covers the
+// scores at or below its threshold. The label depends on the score alone, unlike the
+// normalized relevance, which compares a result with the rest of its result set.
+func ClassifyRelevance(score float64) string {
+	return config.App.Search.Relevance.Classify(score)
+}
+
+// scoreBarWidth is the number of cells in the relevance bar
+const scoreBarWidth = 20
+
+// NormalizeScores maps BM25 scores onto a 0-100 scale using min-max normalization
+// over the result set. The best match (most negative score) becomes 100 and the
+// weakest becomes 0. When every score is equal, including a single result, there
+// is no spread to normalize against and every result is reported as 100.
+func NormalizeScores(results []models.SearchResult) []float64 {
+	normalized := make([]float64, len(results))
+	if len(results) == 0 {
+		return normalized
+	}
+
+	best, worst := results[0].Score, results[0].Score
+	for _, result := range results {
+		if result.Score < best {
+			best = result.Score
+		}
+		if result.Score > worst {
+			worst = result.Score
+		}
+	}
+
+	spread := worst - best
+	for i, result := range results {
+		if spread == 0 {
+			normalized[i] = 100
+			continue
+		}
+		normalized[i] = (worst - result.Score) / spread * 100
+	}
+
+	return normalized
+}
+
+// scoreBar renders a normalized score as a fixed-width bar
+func scoreBar(normalized float64) string {
+	filled := int(normalized/100*scoreBarWidth + 0.5)
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > scoreBarWidth {
+		filled = scoreBarWidth
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", scoreBarWidth-filled) + "]"
+}
+
 // FormatDocumentList formats document listing for display
 func FormatDocumentList(documents []models.DocumentInfo) string {
 	if len(documents) == 0 {
@@ -2,18 +2,26 @@ package models
 
 // Document represents a document to be inserted into the FTS5 table
 type Document struct {
-	Title    string
-	Content  string
-	Category string
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Category string `json:"category"`
 }
 
 // SearchResult represents a search result from the FTS5 table
 type SearchResult struct {
-	RowID    int64
-	Title    string
-	Content  string
-	Category string
-	Score    float64
+	RowID    int64   `json:"rowid"`
+	Title    string  `json:"title"`
+	Content  string  `json:"content"`
+	Category string  `json:"category"`
+	Score    float64 `json:"score"`
+
+	// Relevance labels Score against the search.relevance thresholds: "excellent",
+	// "good", "fair", or "poor"
+	Relevance string `json:"relevance"`
+
+	// MatchedColumns lists the columns holding a query match, in schema order; it is
+	// only set when requested, as with 'document search --match-columns'
+	MatchedColumns []string `json:"matched_columns,omitempty"`
 }
 
 // DocumentInfo represents basic document information for listing
@@ -22,4 +30,47 @@ type DocumentInfo struct {
 	Title    string
 	Category string
 	Preview  string // First 100 chars of content
+}
+
+// DocumentChange records a document's fields before and after an update
+type DocumentChange struct {
+	RowID  int64    `json:"rowid"`
+	Before Document `json:"before"`
+	After  Document `json:"after"`
+}
+
+// LineFailure records a document line that could not be inserted
+type LineFailure struct {
+	Line    int
+	Message string
+}
+
+// StreamInsertSummary reports the outcome of inserting newline-delimited documents
+type StreamInsertSummary struct {
+	Documents int           // Non-blank lines read
+	RowIDs    []int64       // Rowids of inserted documents, in input order
+	Failures  []LineFailure // Lines rejected by validation or insertion
+}
+
+// ShadowTable describes one of the regular tables FTS5 creates to back a virtual table
+type ShadowTable struct {
+	Name         string `json:"name"`
+	Purpose      string `json:"purpose"`
+	Present      bool   `json:"present"`
+	Rows         int64  `json:"rows"`
+	PayloadBytes int64  `json:"payload_bytes"` // Sum of stored value lengths, excluding b-tree overhead
+}
+
+// ConfigEntry is a key/value row from the FTS5 config shadow table
+type ConfigEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// FTS5Internals summarizes the storage behind an FTS5 virtual table
+type FTS5Internals struct {
+	Table        string        `json:"table"`
+	Documents    int64         `json:"documents"`
+	ShadowTables []ShadowTable `json:"shadow_tables"`
+	Config       []ConfigEntry `json:"config,omitempty"`
 }
\ No newline at end of file
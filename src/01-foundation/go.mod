@@ -3,6 +3,7 @@ module github.com/jaime/go-sqlite/01-foundation
 go 1.24
 
 require (
+	github.com/jaime/go-sqlite/shared v0.0.0
 	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/spf13/cobra v1.8.1
 	github.com/spf13/viper v1.19.0
@@ -30,3 +31,5 @@ require (
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/jaime/go-sqlite/shared => ../shared
@@ -0,0 +1,72 @@
+// Package collation normalizes category names as they are stored and orders
+// them for display. SQLite compares text byte by byte, which puts "Zoology"
+// before "algorithms" and "Économie" after both; a locale-aware collator
+// sorts them the way a reader expects.
+package collation
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize returns the stored form of a category name: surrounding space
+// trimmed, composed to NFC when nfc is set, and lowercased when lower is set.
+// NFC makes a name typed with a precomposed "é" equal to one with "e" and a
+// combining accent, so both land in the same category.
+func Normalize(name string, nfc, lower bool) string {
+	name = strings.TrimSpace(name)
+	if nfc {
+		name = norm.NFC.String(name)
+	}
+	if lower {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// Order compares names for display. The zero Order (no locale) compares
+// bytes, matching SQLite's default BINARY collation.
+type Order struct {
+	collator *collate.Collator
+}
+
+// New returns the order for locale, a BCP 47 tag such as "en" or "fr-CA".
+// An empty locale gives byte order.
+func New(locale string) (*Order, error) {
+	if locale == "" {
+		return &Order{}, nil
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, err
+	}
+	return &Order{collator: collate.New(tag)}, nil
+}
+
+// Less reports whether a sorts before b
+func (o *Order) Less(a, b string) bool {
+	return o.Compare(a, b) < 0
+}
+
+// Compare returns -1, 0, or 1 as a sorts before, with, or after b. Names the
+// collator considers equal fall back to byte order so the result is stable.
+func (o *Order) Compare(a, b string) int {
+	if o != nil && o.collator != nil {
+		if c := o.collator.CompareString(a, b); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// Sort sorts names in place
+func (o *Order) Sort(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		return o.Less(names[i], names[j])
+	})
+}
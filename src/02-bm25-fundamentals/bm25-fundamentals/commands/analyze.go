@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/completion"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
+	"github.com/spf13/cobra"
+)
+
+// Analyze is the public analyze command group instance
+var Analyze = newAnalyzeGroup()
+
+// newAnalyzeGroup creates the analyze command group with all its subcommands
+func newAnalyzeGroup() *cli.CommandGroup {
+	// analyzeCmd represents the analyze command group
+	analyzeCmd := &cobra.Command{
+		Use:   "analyze",
+		Short: "Analyze the corpus statistics behind BM25 scores",
+		Long: `The analyze command group reads the statistics FTS5 keeps about the indexed
+corpus: how often each term occurs, how many documents contain it, and the
+inverse document frequency (IDF) bm25() derives from that, as well as the
+length of each document and the normalization bm25() applies to it.`,
+	}
+
+	// termsCmd lists the most widespread terms in the index
+	termsCmd := &cobra.Command{
+		Use:   "terms",
+		Short: "List the terms found in the most documents",
+		Long: `List indexed terms by document frequency, most widespread first, read from an
+fts5vocab table over the index. Terms are shown as the tokenizer indexed them,
+case-folded and stemmed, and are counted across the title, content, and
+category columns.
+
+For each term:
+- Documents: how many documents contain it (n in the IDF)
+- Occurrences: how many times it appears in those documents
+- IDF: ln((N - n + 0.5) / (n + 0.5)), the weight bm25() gives a match on it
+
+Terms in more than half the documents have an IDF at or below zero, which
+SQLite raises to 0.000001, so they contribute almost nothing to a score.
+
+With --category, documents and occurrences are counted within those
+categories; the IDF stays the corpus-wide value, since that is what bm25()
+scores with when a search is filtered by category.
+
+Examples:
+  # The 10 most widespread terms (search.term_freq_limit in the config)
+  bm25-fundamentals analyze terms
+
+  # The top 25 terms in the programming category
+  bm25-fundamentals analyze terms --category programming --limit 25
+
+  # Terms in at least 100 documents, as CSV
+  bm25-fundamentals analyze terms --min-doc-count 100 --limit 50 --format csv`,
+		RunE: handlers.Analyze.HandleTerms,
+	}
+
+	// documentCmd measures one document the way bm25() sees it
+	documentCmd := &cobra.Command{
+		Use:   "document",
+		Short: "Show a document's length statistics and BM25 length normalization",
+		Long: `Measure one document as FTS5 indexed it and show how its length shapes its
+BM25 scores. Token counts come from the index's docsize records and terms from
+an fts5vocab instance table, so both match what bm25() uses.
+
+The report includes:
+- Token count, unique terms, and average term length (of the indexed, stemmed forms)
+- Tokens and unique terms in each field, against the corpus average for that field
+- The length normalization K = k1 × ((1 - b) + b × |d| / avgdl) at the
+  default k1 = 1.2 and b = 0.75
+
+A document longer than average gets a K above k1, which lowers the score of
+every match in it; a shorter one gets a K below k1.
+
+Examples:
+  # Analyze document 42
+  bm25-fundamentals analyze document --id 42
+
+  # The same analysis as JSON
+  bm25-fundamentals analyze document --id 42 --format json`,
+		RunE: handlers.Analyze.HandleDocument,
+	}
+
+	// lengthImpactCmd relates a query's scores to document length
+	lengthImpactCmd := &cobra.Command{
+		Use:   "length-impact",
+		Short: "Show how document length affects a query's BM25 scores",
+		Long: `Run a search and group its results by document length to isolate the effect of
+BM25 length normalization. Results are split into length quantiles (quintiles
+by default) of the stored length column, and each bucket reports the mean and
+median score of its documents. The Pearson correlation between length and score
+summarizes the trend across all results.
+
+Scores are negative and closer to zero is worse, so a positive correlation
+means longer documents score worse: normalization is penalizing them more than
+their extra term occurrences help. Up to 1000 results are analyzed unless --all
+is given.
+
+Examples:
+  # Length quintiles for a query
+  bm25-fundamentals analyze length-impact --query "database"
+
+  # Ten buckets over every match, as JSON
+  bm25-fundamentals analyze length-impact --query "algorithm" --buckets 10 --all --format json`,
+		RunE: handlers.Analyze.HandleLengthImpact,
+	}
+
+	// setupFlags configures flags for analyze commands
+	setupFlags := func() {
+		// Terms command flags
+		termsCmd.Flags().IntP("limit", "l", 0, "number of terms to list (default: search.term_freq_limit)")
+		termsCmd.Flags().StringSliceP("category", "c", nil, "count only documents in these categories; repeat or separate with commas")
+		termsCmd.Flags().Int("min-doc-count", 1, "list only terms found in at least this many documents")
+		termsCmd.RegisterFlagCompletionFunc("category", completion.CategoryList)
+		termsCmd.RegisterFlagCompletionFunc("limit", cobra.NoFileCompletions)
+		termsCmd.RegisterFlagCompletionFunc("min-doc-count", cobra.NoFileCompletions)
+
+		// Document command flags
+		documentCmd.Flags().Int64("id", 0, "document ID to analyze (required)")
+		documentCmd.MarkFlagRequired("id")
+		documentCmd.RegisterFlagCompletionFunc("id", completion.DocumentIDs)
+
+		// Length-impact command flags
+		flagutil.RegisterSearchFlags(lengthImpactCmd)
+		flagutil.RegisterDateFlags(lengthImpactCmd)
+		flagutil.RegisterAllFlag(lengthImpactCmd)
+		lengthImpactCmd.Flags().IntP("buckets", "b", 5, "number of length buckets (quantiles of document length)")
+		lengthImpactCmd.RegisterFlagCompletionFunc("buckets", cobra.NoFileCompletions)
+	}
+
+	// Return the command group
+	return &cli.CommandGroup{
+		Command: analyzeCmd,
+		SubCommands: []*cobra.Command{
+			termsCmd,
+			documentCmd,
+			lengthImpactCmd,
+		},
+		FlagSetup: setupFlags,
+	}
+}
@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/completion"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
 )
 
@@ -9,7 +11,7 @@ import (
 var Corpus = newCorpusGroup()
 
 // newCorpusGroup creates the corpus command group with all its subcommands
-func newCorpusGroup() *CommandGroup {
+func newCorpusGroup() *cli.CommandGroup {
 	// corpusCmd represents the corpus command group
 	corpusCmd := &cobra.Command{
 		Use:   "corpus",
@@ -44,10 +46,117 @@ Examples:
   bm25-fundamentals corpus generate --size 500
   
   # Generate with custom categories
-  bm25-fundamentals corpus generate --categories "tech,science,business"`,
+  bm25-fundamentals corpus generate --categories "tech,science,business"
+  
+  # Generate readable template sentences with "optimization" in ~20% of documents
+  bm25-fundamentals corpus generate --style sentences --term-rates "optimization:0.2"
+  
+  # Plant ground truth for 'search evaluate --from-manifest'; a phrase the
+  # generator also produces on its own is rejected, so the set stays exact
+  bm25-fundamentals corpus generate --inject "database optimization:20" --manifest manifest.json
+  
+  # Inject 5% exact and 5% near duplicates, recording them in a manifest
+  bm25-fundamentals corpus generate --duplicate-rate 0.05 --near-duplicate-rate 0.05 --manifest manifest.json`,
 		RunE: handlers.Corpus.HandleGenerate,
 	}
 
+	// importCmd loads documents from a JSON or JSONL file
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import documents from a JSON or JSONL file",
+		Long: `Load real documents into the corpus from a file, so BM25 experiments can
+run on text other than the synthetic corpus.
+
+The file holds either one JSON document per line (JSONL) or a single JSON
+array of documents; a .csv file is read as CSV with a header row naming the
+fields. Each document has these fields:
+
+  title     required
+  content   required
+  category  optional, defaults to "general"
+  created   optional RFC 3339 timestamp, defaults to the time of import
+
+Records missing a title or content are skipped and listed after the import;
+other fields are ignored. Documents are inserted in batches of
+corpus.batch_size, each batch in its own transaction. As with generate, an
+existing corpus is cleared first after a prompt, or without one given --confirm.
+
+For large files, --defer-index drops the FTS5 sync triggers, inserts every
+document, then indexes them all with one INSERT ... SELECT and optimizes the
+index, restoring the triggers before the commit. The whole import is then a
+single transaction: a failure or interrupt leaves nothing imported.
+
+Examples:
+  # Import one document per line
+  bm25-fundamentals corpus import --file articles.jsonl
+
+  # Check a large file without writing anything
+  bm25-fundamentals corpus import --file articles.json --dry-run
+
+  # Replace the current corpus without prompting
+  bm25-fundamentals corpus import --file articles.jsonl --confirm
+
+  # Load a large file with one indexing pass, then confirm the index is complete
+  bm25-fundamentals corpus import --file articles.jsonl --defer-index -d big.db
+  bm25-fundamentals db check-sync -d big.db`,
+		RunE: handlers.Corpus.HandleImport,
+	}
+
+	// importFoundationCmd converts a phase 1 database into this corpus
+	importFoundationCmd := &cobra.Command{
+		Use:   "import-foundation",
+		Short: "Import the documents of a phase 1 (fts5-foundation) database",
+		Long: `Copy documents built with fts5-foundation into this corpus.
+
+Phase 1 stores documents in a standalone FTS5 table (title, content,
+category), while this phase keeps them in a documents table indexed by an
+external-content FTS5 table with a length and creation time per document. The
+phase 1 database is attached and only read: its documents are read in rowid
+order, and each is inserted here with its length counted by the tokenizer and
+the time of the import as its created time. Documents get new ids.
+
+Documents are inserted in batches of corpus.batch_size, each in its own
+transaction, with a progress line per batch. Documents without a title or
+content are skipped and listed. As with import, an existing corpus is cleared
+first after a prompt, or without one given --confirm.
+
+Examples:
+  bm25-fundamentals corpus import-foundation --from ../../01-foundation/fts5-foundation/phase1.db -d corpus.db`,
+		RunE: handlers.Corpus.HandleImportFoundation,
+	}
+
+	// exportCmd writes the corpus to a JSONL, JSON, or CSV file
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the corpus to a JSONL, JSON, or CSV file",
+		Long: `Write every document (id, title, content, category, length, created) to a
+file in id order, so a generated corpus can be kept or shared and loaded again
+with 'corpus import'. Rows are streamed, so large corpora are not held in memory.
+
+The file format is set with --format (jsonl, json, or csv), which on this
+command names a file format rather than an output style. Without it, the format
+follows the output file extension (.json, .csv), defaulting to JSONL. CSV files
+have a header row and are quoted with encoding/csv, so quotes, commas, and
+newlines in content survive the round trip; the CSV reader does turn a CRLF
+line break inside content into LF, so use JSONL for a byte-exact copy. Import
+recomputes id and length.
+
+Without --output the documents are written to stdout.
+
+Examples:
+  # Snapshot a seeded corpus and load it into another database
+  bm25-fundamentals corpus generate --size 500 --seed 42 -d seeded.db
+  bm25-fundamentals corpus export --output corpus.jsonl -d seeded.db
+  bm25-fundamentals corpus import --file corpus.jsonl -d copy.db
+
+  # Export as CSV
+  bm25-fundamentals corpus export --output corpus.csv -d seeded.db
+
+  # Choose the format explicitly, here JSONL on stdout
+  bm25-fundamentals corpus export --format jsonl -d seeded.db`,
+		RunE: handlers.Corpus.HandleExport,
+	}
+
 	// statsCmd shows corpus statistics
 	statsCmd := &cobra.Command{
 		Use:   "stats",
@@ -63,6 +172,48 @@ These statistics help understand how BM25 scoring will behave with your corpus.`
 		RunE: handlers.Corpus.HandleStats,
 	}
 
+	// categoriesCmd lists the categories in the corpus
+	categoriesCmd := &cobra.Command{
+		Use:   "categories",
+		Short: "List categories with document counts",
+		Long: `List each distinct category in the corpus with its number of documents,
+average document length in tokens, and the range of its creation times, most
+documents first.
+
+The same listing backs --category completion, which shows each category's
+document count, and the check the search and visualize commands make before
+searching: a --category the corpus does not have fails with the closest names.
+
+Examples:
+  bm25-fundamentals corpus categories -d corpus.db
+
+  # Machine-readable, e.g. to build facets
+  bm25-fundamentals corpus categories -d corpus.db --format csv`,
+		RunE: handlers.Corpus.HandleCategories,
+	}
+
+	// fingerprintCmd hashes the corpus for experiment provenance
+	fingerprintCmd := &cobra.Command{
+		Use:   "fingerprint",
+		Short: "Print a SHA-256 fingerprint of the corpus",
+		Long: `Compute a deterministic SHA-256 over the corpus schema (the documents and
+documents_fts CREATE statements, which include the tokenizer) and every
+document's id, title, content, and category, read in id order.
+
+Two databases with the same fingerprint index and score identically, so the
+fingerprint ties results to the exact corpus they came from. It is also
+recorded in 'search query' and 'search evaluate' JSON output and in
+experiment reports. Document length and creation time are not hashed, so
+'corpus recount' leaves the fingerprint unchanged.
+
+Examples:
+  bm25-fundamentals corpus fingerprint -d corpus.db
+
+  # Compare a copy against the original
+  bm25-fundamentals corpus fingerprint -d copy.db --format json`,
+		RunE: handlers.Corpus.HandleFingerprint,
+	}
+
 	// clearCmd removes all documents
 	clearCmd := &cobra.Command{
 		Use:   "clear",
@@ -75,10 +226,33 @@ This operation:
 - Resets document ID sequence
 - Optimizes index storage
 
+With --hard, the documents table, FTS5 index, triggers, and indexes are
+dropped and recreated instead. This is faster on large corpora and also
+discards FTS5 configuration set on the index (such as a rank override or
+automerge setting), giving the same state as a new database.
+
 Use this to start fresh with new corpus experiments.`,
 		RunE: handlers.Corpus.HandleClear,
 	}
 
+	// recountCmd backfills document lengths from the FTS5 index
+	recountCmd := &cobra.Command{
+		Use:   "recount",
+		Short: "Recompute document lengths from the FTS5 index",
+		Long: `Set each document's stored length to the token count FTS5 recorded for it.
+
+BM25 normalizes scores by the number of tokens the FTS5 tokenizer produced for
+the whole row, which is kept in the documents_fts_docsize shadow table. Older
+corpora stored whitespace-separated word counts of the title and content, which
+disagree with the tokenizer wherever punctuation splits words or the category
+adds tokens. Run this once on such a corpus so stats and explain output match
+the scores.
+
+Examples:
+  bm25-fundamentals corpus recount -d corpus.db`,
+		RunE: handlers.Corpus.HandleRecount,
+	}
+
 	// setupFlags configures flags for corpus commands
 	setupFlags := func() {
 		// Generate command flags
@@ -89,17 +263,56 @@ Use this to start fresh with new corpus experiments.`,
 		generateCmd.Flags().IntP("title-min-tokens", "", 0, "minimum title length in tokens")
 		generateCmd.Flags().IntP("title-max-tokens", "", 0, "maximum title length in tokens")
 		generateCmd.Flags().Int64P("seed", "", 0, "random seed for reproducible generation (0 = use current time)")
+		generateCmd.Flags().StringP("style", "", "", "content style: words or sentences (default words)")
+		generateCmd.Flags().StringP("term-rates", "", "", "inject terms into a fraction of documents (format: term:rate,term:rate)")
+		generateCmd.Flags().StringArrayP("inject", "", nil, "inject a phrase into exactly N documents (format: \"phrase:N\", repeatable)")
+		generateCmd.Flags().Float64P("duplicate-rate", "", 0, "fraction of documents that are exact copies of earlier ones")
+		generateCmd.Flags().Float64P("near-duplicate-rate", "", 0, "fraction of documents that are copies with a few substituted words")
+		generateCmd.Flags().StringP("manifest", "", "", "write the generation manifest (options, seed, injected duplicates) to a JSON file")
+		generateCmd.Flags().IntP("workers", "", 0, "number of concurrent document generators (0 = one per CPU); output is identical for any value")
+		generateCmd.Flags().BoolP("defer-index", "", false, "index all documents in one pass after inserting them instead of row by row")
+		generateCmd.Flags().BoolP("confirm", "y", false, "clear an existing corpus without prompt")
+		generateCmd.RegisterFlagCompletionFunc("manifest", completion.Files("json"))
+
+		// Import command flags
+		importCmd.Flags().StringP("file", "", "", "JSON array, JSONL, or CSV file of documents to import")
+		importCmd.Flags().BoolP("confirm", "y", false, "clear an existing corpus without prompt")
+		importCmd.Flags().BoolP("dry-run", "", false, "validate and count the documents without writing them")
+		importCmd.Flags().BoolP("defer-index", "", false, "import in one transaction and index all documents in one pass at the end")
+		importCmd.MarkFlagRequired("file")
+		importCmd.RegisterFlagCompletionFunc("file", completion.Files("json", "jsonl", "ndjson", "csv"))
+
+		// Import-foundation command flags
+		importFoundationCmd.Flags().StringP("from", "", "", "phase 1 (fts5-foundation) database to convert")
+		importFoundationCmd.Flags().BoolP("confirm", "y", false, "clear an existing corpus without prompt")
+		importFoundationCmd.MarkFlagRequired("from")
+		importFoundationCmd.RegisterFlagCompletionFunc("from", completion.Files("db", "sqlite", "sqlite3"))
+
+		// Export command flags
+		exportCmd.Flags().StringP("output", "o", "", "file to write the documents to (default: stdout)")
+		// --format shadows the global output format, whose values do not include jsonl
+		exportCmd.Flags().StringP("format", "f", "", "file format: jsonl, json, or csv (default: from the file extension, else jsonl)")
+		exportCmd.RegisterFlagCompletionFunc("output", completion.Files("jsonl", "json", "csv"))
+		exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+			[]string{"jsonl", "json", "csv"}, cobra.ShellCompDirectiveNoFileComp))
 
 		// Clear command flags
 		clearCmd.Flags().BoolP("confirm", "y", false, "confirm corpus deletion without prompt")
+		clearCmd.Flags().BoolP("hard", "", false, "drop and recreate the corpus schema instead of deleting rows")
 	}
 
 	// Return the command group
-	return &CommandGroup{
+	return &cli.CommandGroup{
 		Command: corpusCmd,
 		SubCommands: []*cobra.Command{
 			generateCmd,
+			importCmd,
+			importFoundationCmd,
+			exportCmd,
 			statsCmd,
+			categoriesCmd,
+			fingerprintCmd,
+			recountCmd,
 			clearCmd,
 		},
 		FlagSetup: setupFlags,
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
+	"github.com/spf13/cobra"
+)
+
+// Database is the public database maintenance command group instance
+var Database = newDatabaseGroup()
+
+// newDatabaseGroup creates the db command group with all its subcommands
+func newDatabaseGroup() *cli.CommandGroup {
+	// dbCmd represents the db command group
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Maintain the FTS5 index behind the document corpus",
+		Long: `The db command group provides maintenance tools for the external content
+FTS5 index. The index is kept in sync with the documents table by triggers;
+rows written with triggers disabled, or a partially restored backup, leave
+documents that search silently misses.`,
+	}
+
+	// checkSyncCmd compares the documents table with the FTS5 index
+	checkSyncCmd := &cobra.Command{
+		Use:   "check-sync",
+		Short: "Check that the FTS5 index matches the documents table",
+		Long: `Compare the documents table with the FTS5 index and run the FTS5 integrity-check.
+
+Reports:
+- Row counts for the documents table and the index
+- Documents missing from the index (bounded list of IDs)
+- Index rows whose document no longer exists
+- Integrity-check result against the content table
+
+Exits with an error when the index is out of sync.
+
+Examples:
+  bm25-fundamentals db check-sync -d corpus.db
+  bm25-fundamentals db check-sync --limit 100 -f json -d corpus.db`,
+		RunE: handlers.Database.HandleCheckSync,
+	}
+
+	// initCmd creates the corpus schema
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the documents table and FTS5 index",
+		Long: `Create the documents table, the FTS5 index, and the triggers that keep them
+in sync. Existing tables are left as they are, so this is safe to run on a
+database that already holds a corpus.
+
+Search and visualize commands report a missing schema instead of failing
+inside a query; run this first, or pass --auto-init (or set auto_init in the
+config file) to have them create it.
+
+Examples:
+  bm25-fundamentals db init -d corpus.db`,
+		RunE: handlers.Database.HandleInit,
+	}
+
+	// resyncCmd rebuilds the FTS5 index
+	resyncCmd := &cobra.Command{
+		Use:   "resync",
+		Short: "Rebuild the FTS5 index from the documents table",
+		Long: `Rebuild the FTS5 index from the documents table using the FTS5 'rebuild'
+command, then verify that the index is back in sync.
+
+Examples:
+  bm25-fundamentals db resync -d corpus.db`,
+		RunE: handlers.Database.HandleResync,
+	}
+
+	// setupFlags configures flags for db commands
+	setupFlags := func() {
+		// Check-sync command flags
+		checkSyncCmd.Flags().IntP("limit", "l", 20, "maximum number of missing or orphaned IDs to list")
+	}
+
+	// Return the command group
+	return &cli.CommandGroup{
+		Command: dbCmd,
+		SubCommands: []*cobra.Command{
+			initCmd,
+			checkSyncCmd,
+			resyncCmd,
+		},
+		FlagSetup: setupFlags,
+	}
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/completion"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
+	"github.com/spf13/cobra"
+)
+
+// Experiment is the public experiment command group instance
+var Experiment = newExperimentGroup()
+
+// newExperimentGroup creates the experiment command group with all its subcommands
+func newExperimentGroup() *cli.CommandGroup {
+	// experimentCmd represents the experiment command group
+	experimentCmd := &cobra.Command{
+		Use:   "experiment",
+		Short: "Run reproducible BM25 experiments from a declarative file",
+		Long: `The experiment command group ties corpus generation, search, comparison, and
+evaluation together. An experiment file declares a corpus, a set of queries,
+and the strategies to run them under; the runner reports how each strategy
+performs and how far it departs from the first (baseline) strategy.`,
+	}
+
+	// runCmd executes an experiment file
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run an experiment described by a YAML file",
+		Long: `Build the experiment corpus in a temporary database, run every query under
+every strategy, and emit a combined report. The --database corpus is not used
+or modified.
+
+The report includes, per strategy:
+- Mean results retrieved, and mean overlap and Spearman correlation with the baseline
+- Precision, recall, MAP, and NDCG for queries with relevance judgments
+
+Queries are judged by explicit "judgments" (document ID to grade) or, for a
+generated corpus, by matching a phrase listed under corpus "inject".
+
+Example experiment file:
+  name: title-weighting
+  max_results: 10
+  corpus:
+    seed: 42
+    size: 200
+    style: sentences
+    inject:
+      - phrase: "database optimization"
+        count: 15
+  queries:
+    - query: "database optimization"
+    - query: "query tuning"
+  strategies:
+    - name: baseline
+    - name: title-heavy
+      weights: {title: 3.0}
+
+Examples:
+  # Print the report to the terminal
+  bm25-fundamentals experiment run --file experiment.yaml
+
+  # Write an HTML report
+  bm25-fundamentals experiment run --file experiment.yaml --report-format html --output report.html`,
+		RunE: handlers.Experiment.HandleRun,
+	}
+
+	// setupFlags configures flags for experiment commands
+	setupFlags := func() {
+		// Run command flags
+		runCmd.Flags().StringP("file", "F", "", "experiment YAML file (required)")
+		runCmd.Flags().StringP("output", "o", "", "write the report to a file instead of stdout")
+		runCmd.Flags().StringP("report-format", "", "", "report format: text, json, csv, or html (default: --format)")
+		runCmd.MarkFlagRequired("file")
+		runCmd.RegisterFlagCompletionFunc("file", completion.Files("yaml", "yml"))
+		runCmd.RegisterFlagCompletionFunc("report-format", cobra.FixedCompletions(
+			[]string{"text", "json", "csv", "html"}, cobra.ShellCompDirectiveNoFileComp))
+	}
+
+	// Return the command group
+	return &cli.CommandGroup{
+		Command: experimentCmd,
+		SubCommands: []*cobra.Command{
+			runCmd,
+		},
+		FlagSetup: setupFlags,
+	}
+}
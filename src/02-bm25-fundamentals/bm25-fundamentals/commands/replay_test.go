@@ -0,0 +1,78 @@
+//go:build fts5
+
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command line in place of the tests when runCLI is set,
+// so a test can check what a whole invocation does, including the database
+// setup in the root command
+func TestMain(m *testing.M) {
+	if os.Getenv(runCLI) != "" {
+		Root.Init()
+		rootCmd.SetArgs(os.Args[1:])
+		if err := rootCmd.Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI is the environment variable that makes the test binary act as the CLI
+const runCLI = "BM25_FUNDAMENTALS_RUN_CLI"
+
+// runCommand runs the CLI with args in dir, with dir as its home so no user
+// config file is read, and returns its combined output
+func runCommand(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runCLI+"=1", "HOME="+dir)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+func TestExplainReplayDoesNotOpenDatabase(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus.db")
+	saved := filepath.Join(dir, "explain.json")
+
+	steps := [][]string{
+		{"--database", corpus, "corpus", "generate", "--size", "40", "--seed", "9", "--categories", "technology", "--auto-init"},
+		{"--database", corpus, "search", "explain", "--query", "system", "--save-explain", saved},
+	}
+	for _, args := range steps {
+		if output, err := runCommand(t, dir, args...); err != nil {
+			t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing.db")
+	output, err := runCommand(t, dir, "--database", missing, "search", "explain", "--replay", saved)
+	if err != nil {
+		t.Fatalf("replay: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Replay of "+saved) {
+		t.Errorf("replay output lacks its header:\n%s", output)
+	}
+
+	for _, flag := range [][]string{{"--query", "system"}, {"--max-results", "3"}} {
+		args := append([]string{"--database", missing, "search", "explain", "--replay", saved}, flag...)
+		output, err := runCommand(t, dir, args...)
+		if err == nil || !strings.Contains(output, "cannot be combined with "+flag[0]) {
+			t.Errorf("replay with %s: err = %v, want a rejection:\n%s", flag[0], err, output)
+		}
+	}
+
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("replay created the database file %s (stat: %v)", missing, err)
+	}
+}
@@ -1,13 +1,21 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/output"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -15,8 +23,19 @@ var (
 	verbose bool
 	dbPath  string
 	format  string
+
+	queryTimeout time.Duration
+	autoInit     bool
+
+	// stopSignals releases the SIGINT/SIGTERM handler installed for the running command
+	stopSignals context.CancelFunc = func() {}
 )
 
+// NoDatabaseFlag is a command annotation naming a flag that, when set, lets
+// the command run without opening the database, so a missing database file is
+// not created
+const NoDatabaseFlag = "no-database-flag"
+
 // rootCmd stores the root command for flag registration
 var rootCmd = &cobra.Command{
 	Use:   "bm25-fundamentals",
@@ -31,16 +50,49 @@ This educational tool demonstrates:
 
 Phase 2 builds on FTS5 foundation to explore relevance scoring in depth.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Cancel the command context on SIGINT/SIGTERM so long-running handlers can
+		// roll back and report progress; a second signal terminates immediately
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		stopSignals = stop
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		cmd.SetContext(ctx)
+
 		// Initialize configuration after flags are parsed
-		config.App.Init()
+		config.App.Init(config.Viper)
+
+		// Golden output is scrubbed on its way to stdout
+		if config.App.Golden {
+			if err := output.Capture(goldenScrubber()); err != nil {
+				fmt.Fprintf(os.Stderr, "Output capture error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 		
+		// Commands that can run from a saved file skip the database entirely
+		if flag, ok := cmd.Annotations[NoDatabaseFlag]; ok && cmd.Flags().Changed(flag) {
+			return
+		}
+
 		// Initialize database connection
 		if err := database.Init(config.App.GetDatabasePath()); err != nil {
 			fmt.Fprintf(os.Stderr, "Database initialization error: %v\n", err)
 			os.Exit(1)
 		}
+		database.Instance.SetTimeout(config.App.QueryTimeout)
 		
-		// Handlers are stateless - no initialization needed
+		// Hand the open database to the handlers
+		handlers.Init(database.Instance)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		stopSignals()
+
+		// Statement timings differ on every run, so golden output leaves them out
+		if config.App.Verbose && !config.App.Golden && database.Instance != nil {
+			displayQueryStats(database.Instance.Stats())
+		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("BM25 Fundamentals Learning Tool")
@@ -55,25 +107,74 @@ Phase 2 builds on FTS5 foundation to explore relevance scoring in depth.`,
 }
 
 // Root represents the root command group with all child groups initialized
-var Root = &CommandGroup{
+var Root = &cli.CommandGroup{
 	Command:     rootCmd,
-	ChildGroups: []*CommandGroup{
+	ChildGroups: []*cli.CommandGroup{
 		Corpus,
 		Search,
 		Visualize,
+		Database,
+		Experiment,
+		Analyze,
 	},
 	FlagSetup: setupGlobalFlags,
 }
 
 // setupGlobalFlags registers global flags that are available to all commands
 func setupGlobalFlags() {
+	// Flush golden output once the command finishes, whether or not it failed
+	cobra.OnFinalize(output.Close)
+
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.bm25-fundamentals.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output with detailed explanations")
 	rootCmd.PersistentFlags().StringVarP(&dbPath, "database", "d", ":memory:", "database path (default: in-memory)")
-	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "output format (text, json, csv)")
+	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "output format (text, json, csv, markdown, golden)")
+	rootCmd.PersistentFlags().DurationVar(&queryTimeout, "query-timeout", 0, "limit for each database statement, e.g. 500ms (0 = no limit)")
+	rootCmd.PersistentFlags().BoolVar(&autoInit, "auto-init", false, "create the corpus schema when it is missing instead of failing")
+
+	// Bind flags to this phase's viper instance
+	config.Viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	config.Viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	config.Viper.BindPFlag("database", rootCmd.PersistentFlags().Lookup("database"))
+	config.Viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	config.Viper.BindPFlag("query_timeout", rootCmd.PersistentFlags().Lookup("query-timeout"))
+	config.Viper.BindPFlag("auto_init", rootCmd.PersistentFlags().Lookup("auto-init"))
+}
+
+// goldenScrubber replaces the database path, temporary directory, and home
+// directory in golden output, along with the timings and dates every
+// Scrubber handles
+func goldenScrubber() *output.Scrubber {
+	literals := map[string]string{
+		strings.TrimSuffix(os.TempDir(), string(filepath.Separator)): output.TempPlaceholder,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		literals[home] = output.HomePlaceholder
+	}
+	if path := config.App.GetDatabasePath(); path != ":memory:" {
+		literals[path] = output.DatabasePlaceholder
+		if abs, err := filepath.Abs(path); err == nil {
+			literals[abs] = output.DatabasePlaceholder
+		}
+	}
+	return output.NewScrubber(literals)
+}
+
+// displayQueryStats reports the statements timed by the database helpers on stderr
+func displayQueryStats(stats database.QueryStats) {
+	if stats.Statements == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\nDatabase: %d statement(s) in %v, slowest %v\n",
+		stats.Statements, stats.Total.Round(time.Microsecond), stats.Slowest.Round(time.Microsecond))
+	fmt.Fprintf(os.Stderr, "  Slowest: %s\n", truncateSQL(stats.SlowestSQL, 100))
+}
 
-	// Bind flags to viper
-	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
-	viper.BindPFlag("database", rootCmd.PersistentFlags().Lookup("database"))
-	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+// truncateSQL shortens a statement for one-line display
+func truncateSQL(query string, max int) string {
+	if len(query) <= max {
+		return query
+	}
+	return query[:max-3] + "..."
 }
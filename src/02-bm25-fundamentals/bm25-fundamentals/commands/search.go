@@ -1,7 +1,10 @@
 package commands
 
 import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/completion"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
 )
 
@@ -9,7 +12,7 @@ import (
 var Search = newSearchGroup()
 
 // newSearchGroup creates the search command group with all its subcommands
-func newSearchGroup() *CommandGroup {
+func newSearchGroup() *cli.CommandGroup {
 	// searchCmd represents the search command group
 	searchCmd := &cobra.Command{
 		Use:   "search",
@@ -26,8 +29,14 @@ Key Features:
 
 Understanding BM25 scores:
 - SQLite FTS5 returns NEGATIVE scores (lower = better match)
-- Scores around -1.0 indicate excellent relevance
-- Scores below -4.0 typically indicate poor relevance`,
+- Results are labeled excellent at or below -4.0, good at or below -2.0,
+  fair at or below -1.0, and poor closer to zero
+
+Column weights:
+- Set with --weights "title:2.0,content:1.0,category:0.5" on every search command
+- Omitted fields keep the FTS5 default weight of 1.0
+- --title-weight, --content-weight and --category-weight are deprecated aliases;
+  a positive value overrides the same field in --weights`,
 	}
 
 	// queryCmd performs basic BM25 search
@@ -41,13 +50,40 @@ Examples:
   bm25-fundamentals search query --query "database optimization"
   
   # Search with custom column weights (title=2x, content=1x, category=0.5x)
-  bm25-fundamentals search query --query "database" --title-weight 2.0 --content-weight 1.0 --category-weight 0.5
+  bm25-fundamentals search query --query "database" --weights "title:2.0,content:1.0,category:0.5"
   
   # Filter by category
   bm25-fundamentals search query --query "algorithm" --category "programming"
+
+  # Include several categories (or repeat --category)
+  bm25-fundamentals search query --query "algorithm" --category "programming,science"
   
   # Show detailed results with snippets
-  bm25-fundamentals search query --query "optimization" --max-results 10 --snippets`,
+  bm25-fundamentals search query --query "optimization" --max-results 10 --snippets
+
+  # Show the full content with every match marked
+  bm25-fundamentals search query --query "optimization" --max-results 3 --highlight
+
+  # Spot-check how relevance degrades: 5 results from best to worst, labeled with their percentile
+  bm25-fundamentals search query --query "optimization" --sample 5 --snippets
+
+  # Page through every match 20 at a time: results 21-40
+  bm25-fundamentals search query --query "optimization" --page 2 --page-size 20
+
+  # Match any of the words, without writing FTS5 OR syntax
+  bm25-fundamentals search query --query "framework database" --mode any
+
+  # Only documents where the terms appear within 5 tokens of each other
+  bm25-fundamentals search query --query "framework data" --near 5
+
+  # Only documents created in the first week of August (--until covers the whole day)
+  bm25-fundamentals search query --query "database" --since 2024-08-01 --until 2024-08-07
+
+  # Newest matches first, still showing each BM25 score
+  bm25-fundamentals search query --query "database" --sort created --desc
+
+  # Drop the weakly matching tail: keep scores of -3.5 and below (more negative is better)
+  bm25-fundamentals search query --query "database" --min-score -3.5`,
 		RunE: handlers.Search.HandleQuery,
 	}
 
@@ -68,7 +104,16 @@ Examples:
   bm25-fundamentals search stats --query "database optimization"
   
   # Export statistics as JSON
-  bm25-fundamentals search stats --query "algorithm" --format json`,
+  bm25-fundamentals search stats --query "algorithm" --format json
+
+  # Score statistics for documents created since a point in time
+  bm25-fundamentals search stats --query "algorithm" --since 2024-08-01T12:00:00Z
+
+  # Analyze every match instead of the best 1000
+  bm25-fundamentals search stats --query "data OR system" --all
+
+  # Statistics of the matches scoring -3.5 or lower (better), with the number excluded
+  bm25-fundamentals search stats --query "database" --min-score -3.5`,
 		RunE: handlers.Search.HandleStats,
 	}
 
@@ -76,16 +121,45 @@ Examples:
 	compareCmd := &cobra.Command{
 		Use:   "compare",
 		Short: "Compare BM25 scores across different search configurations",
-		Long: `Compare how different column weightings affect BM25 search results.
+		Long: `Compare how different column weightings or queries affect BM25 search results.
 
 This helps understand the impact of field weighting on relevance ranking:
 - Compare default vs custom column weights
-- Analyze ranking changes between configurations
+- Compare the result sets of two different queries (--query-b)
+- Compare the same terms with and without a NEAR constraint (--compare-near)
+- Explain one document's score under both strategies (--explain-id)
+- Summarize each strategy's score distribution and categories (--analyze)
+- Apply the same category filter and snippet options to both strategies
+- Compare several named weightings at once (--strategy)
+- Analyze ranking changes between configurations, summarized by Kendall's tau,
+  Spearman's rho, and the overlap of the top results (--top-k)
 - Identify optimal weighting strategies
 
 Examples:
   # Compare default vs title-weighted search
-  bm25-fundamentals search compare --query "database" --compare-weights "title:2.0,content:1.0"`,
+  bm25-fundamentals search compare --query "database" --compare-weights "title:2.0,content:1.0"
+  
+  # Compare a category-weighted baseline against a title-weighted configuration
+  bm25-fundamentals search compare --query "database" --weights "category:2.0" --compare-weights "title:2.0"
+  
+  # Compare weightings within one category, with snippets in the JSON output
+  bm25-fundamentals search compare --query "database" --category technology --compare-weights "title:2.0" --snippets --format json
+  
+  # Compare the results of two related queries
+  bm25-fundamentals search compare --query "database optimization" --query-b "query tuning"
+  
+  # Show how title weighting changes the score breakdown of document 42
+  bm25-fundamentals search compare --query "database" --compare-weights "title:3.0" --explain-id 42
+  
+  # Include each strategy's score range, histogram, and category breakdown
+  bm25-fundamentals search compare --query "database" --compare-weights "title:3.0" --analyze --format json
+
+  # See which documents drop out when the terms must be within 5 tokens
+  bm25-fundamentals search compare --query "framework data" --compare-near 5
+
+  # Rank the same query under several named weightings at once
+  bm25-fundamentals search compare --query "database" \
+    --strategy "title-heavy=title:3.0,content:1.0" --strategy "content-only=content:1.0,title:0.0"`,
 		RunE: handlers.Search.HandleCompare,
 	}
 
@@ -106,55 +180,147 @@ Examples:
   bm25-fundamentals search explain --query "database optimization"
   
   # Explain with custom field weights
-  bm25-fundamentals search explain --query "database" --title-weight 2.0 --content-weight 1.0`,
+  bm25-fundamentals search explain --query "database" --weights "title:2.0,content:1.0"
+  
+  # Explain only the third-ranked result
+  bm25-fundamentals search explain --query "database" --rank 3
+  
+  # Follow the top result's score by hand, one equation at a time
+  bm25-fundamentals search explain --query "database" --rank 1 --teach
+
+  # Keep the explanations as an artifact, and show them again after the corpus changes
+  bm25-fundamentals search explain --query "database" --save-explain database-explain.json
+  bm25-fundamentals search explain --replay database-explain.json`,
 		RunE: handlers.Search.HandleExplain,
+		Annotations: map[string]string{
+			NoDatabaseFlag: "replay",
+		},
+	}
+
+	// replCmd runs queries read one per line, refining earlier result sets
+	replCmd := &cobra.Command{
+		Use:   "repl",
+		Short: "Search interactively and refine earlier result sets",
+		Long: `Read queries one per line and show the best matches of each.
+
+Each search remembers every document it matched, not only those shown.
+".refine <query>" runs the next query within that set, the way --within does
+with a saved result file; refinements chain, each narrowing the set further.
+Scores are the refining query's BM25 scores against the whole index.
+
+Commands:
+  <query>          search the whole corpus
+  .refine <query>  search only the documents the previous search matched
+  .help            list the commands
+  .quit            leave the REPL (or press Ctrl-D)
+
+Examples:
+  # Start the REPL, showing 5 results per search
+  bm25-fundamentals search repl --max-results 5 -d corpus.db
+
+  # Run a refinement from a script
+  printf 'database\n.refine optimization\n' | bm25-fundamentals search repl -d corpus.db`,
+		RunE: handlers.Search.HandleRepl,
+	}
+
+	// evaluateCmd measures retrieval quality against known relevance labels
+	evaluateCmd := &cobra.Command{
+		Use:   "evaluate",
+		Short: "Evaluate BM25 retrieval against ground-truth relevance labels",
+		Long: `Evaluate how well BM25 retrieves documents that are known to be relevant.
+
+Relevance labels come from the phrases injected by 'corpus generate --inject',
+recorded in the generation manifest. Each injected phrase is run as a quoted
+phrase query and compared to the documents that received it:
+- Precision and recall of the returned results
+- R-precision (precision at rank R, where R is the number of relevant documents)
+- Average precision (rewards ranking relevant documents first)
+
+Examples:
+  # Generate a corpus with planted ground truth, then evaluate
+  bm25-fundamentals corpus generate --inject "database optimization:20" --manifest manifest.json -d corpus.db
+  bm25-fundamentals search evaluate --from-manifest manifest.json -d corpus.db`,
+		RunE: handlers.Search.HandleEvaluate,
 	}
 
 	// setupFlags configures flags for search commands
 	setupFlags := func() {
 		// Query command flags
-		queryCmd.Flags().StringP("query", "q", "", "search query (required)")
+		flagutil.RegisterSearchFlags(queryCmd)
 		queryCmd.Flags().IntP("max-results", "n", 0, "maximum results to return (0 = use config default)")
-		queryCmd.Flags().StringP("category", "c", "", "filter by category")
-		queryCmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
-		queryCmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
-		queryCmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
-		queryCmd.Flags().BoolP("snippets", "s", false, "include content snippets")
-		queryCmd.Flags().IntP("snippet-length", "", 0, "snippet length in characters (0 = default)")
-		queryCmd.MarkFlagRequired("query")
+		flagutil.RegisterSnippetFlags(queryCmd)
+		flagutil.RegisterTruncateFlag(queryCmd)
+		flagutil.RegisterDateFlags(queryCmd)
+		flagutil.RegisterSortFlags(queryCmd)
+		flagutil.RegisterMinScoreFlag(queryCmd)
+		queryCmd.Flags().BoolP("explain-inline", "", false, "show each result's score split by field (reads index statistics per result)")
+		queryCmd.Flags().BoolP("highlight", "", false, "show each result's full content with matched terms marked")
+		queryCmd.Flags().IntP("sample", "", 0, "return N results spread across the whole ranking (best, worst, and evenly spaced between) instead of the top N")
+		queryCmd.MarkFlagsMutuallyExclusive("sample", "max-results")
+		queryCmd.Flags().IntP("page", "", 0, "show page N of the results, counting from 1 (0 = no paging)")
+		queryCmd.Flags().IntP("page-size", "", 0, "results per page; implies --page 1 (0 = use config default)")
+		queryCmd.MarkFlagsMutuallyExclusive("page-size", "max-results")
+		queryCmd.MarkFlagsMutuallyExclusive("sample", "page")
+		queryCmd.MarkFlagsMutuallyExclusive("sample", "page-size")
+		queryCmd.MarkFlagsMutuallyExclusive("sample", "sort")
+		queryCmd.MarkFlagsMutuallyExclusive("sample", "desc")
 
 		// Stats command flags
-		statsCmd.Flags().StringP("query", "q", "", "search query (required)")
-		statsCmd.Flags().StringP("category", "c", "", "filter by category")
-		statsCmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
-		statsCmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
-		statsCmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
-		statsCmd.MarkFlagRequired("query")
+		flagutil.RegisterSearchFlags(statsCmd)
+		flagutil.RegisterDateFlags(statsCmd)
+		flagutil.RegisterAllFlag(statsCmd)
+		flagutil.RegisterMinScoreFlag(statsCmd)
 
 		// Compare command flags
-		compareCmd.Flags().StringP("query", "q", "", "search query (required)")
+		flagutil.RegisterSearchFlags(compareCmd)
 		compareCmd.Flags().StringP("compare-weights", "", "", "weights to compare (format: field:weight,field:weight)")
+		compareCmd.Flags().StringP("query-b", "", "", "query for the comparison strategy (default: same as --query)")
+		compareCmd.Flags().IntP("compare-near", "", 0, "compare against the same terms as one NEAR group, within N tokens of each other (0 = off)")
+		compareCmd.Flags().Int64P("explain-id", "", 0, "explain this document's score under both strategies (0 = none)")
+		compareCmd.RegisterFlagCompletionFunc("explain-id", completion.DocumentIDs)
+		compareCmd.Flags().BoolP("analyze", "", false, "add a score analysis of each strategy's results")
+		compareCmd.Flags().IntP("top-k", "", 0, "compare the first K results of each strategy for the top-k overlap (0 = all returned results)")
+		compareCmd.Flags().StringArrayP("strategy", "", nil, "named weight strategy to compare against the baseline, repeatable (format: name=field:weight,field:weight)")
+		compareCmd.RegisterFlagCompletionFunc("strategy", cobra.NoFileCompletions)
+		compareCmd.MarkFlagsMutuallyExclusive("strategy", "compare-weights")
+		compareCmd.MarkFlagsMutuallyExclusive("strategy", "query-b")
+		compareCmd.MarkFlagsMutuallyExclusive("strategy", "compare-near")
+		compareCmd.RegisterFlagCompletionFunc("query-b", cobra.NoFileCompletions)
 		compareCmd.Flags().IntP("max-results", "n", 10, "maximum results for comparison")
-		compareCmd.MarkFlagRequired("query")
+		flagutil.RegisterSnippetFlags(compareCmd)
+		flagutil.RegisterTruncateFlag(compareCmd)
 
 		// Explain command flags
-		explainCmd.Flags().StringP("query", "q", "", "search query (required)")
-		explainCmd.Flags().StringP("category", "c", "", "filter by category")
-		explainCmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
-		explainCmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
-		explainCmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
+		flagutil.RegisterSearchFlags(explainCmd)
+		flagutil.RegisterTruncateFlag(explainCmd)
 		explainCmd.Flags().IntP("max-results", "n", 5, "maximum results to explain (default: 5)")
-		explainCmd.MarkFlagRequired("query")
+		explainCmd.Flags().IntP("rank", "r", 0, "explain only the result at this rank (1 = top result)")
+		explainCmd.Flags().BoolP("teach", "", false, "work through the BM25 arithmetic for the first explained result with the real index statistics")
+		explainCmd.Flags().StringP("save-explain", "", "", "save the explanations with a snapshot of the corpus statistics to a JSON file")
+		explainCmd.RegisterFlagCompletionFunc("save-explain", completion.Files("json"))
+		explainCmd.Flags().StringP("replay", "", "", "render explanations saved by --save-explain without reading the database")
+		explainCmd.RegisterFlagCompletionFunc("replay", completion.Files("json"))
+
+		// Evaluate command flags
+		evaluateCmd.Flags().StringP("from-manifest", "", "", "generation manifest providing injected relevance labels (required)")
+		evaluateCmd.RegisterFlagCompletionFunc("from-manifest", completion.Files("json"))
+		evaluateCmd.Flags().IntP("max-results", "n", 0, "maximum results to retrieve per query (0 = use config default)")
+		evaluateCmd.MarkFlagRequired("from-manifest")
+
+		// REPL command flags
+		replCmd.Flags().IntP("max-results", "n", 0, "maximum results shown per search (0 = use config default)")
 	}
 
 	// Return the command group
-	return &CommandGroup{
+	return &cli.CommandGroup{
 		Command: searchCmd,
 		SubCommands: []*cobra.Command{
 			queryCmd,
 			statsCmd,
 			compareCmd,
 			explainCmd,
+			evaluateCmd,
+			replCmd,
 		},
 		FlagSetup: setupFlags,
 	}
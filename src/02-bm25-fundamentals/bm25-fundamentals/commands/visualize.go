@@ -1,7 +1,10 @@
 package commands
 
 import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/completion"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/handlers"
+	"github.com/jaime/go-sqlite/shared/cli"
 	"github.com/spf13/cobra"
 )
 
@@ -9,7 +12,7 @@ import (
 var Visualize = newVisualizeGroup()
 
 // newVisualizeGroup creates the visualize command group with all its subcommands
-func newVisualizeGroup() *CommandGroup {
+func newVisualizeGroup() *cli.CommandGroup {
 	// visualizeCmd represents the visualize command group
 	visualizeCmd := &cobra.Command{
 		Use:   "visualize",
@@ -19,8 +22,10 @@ func newVisualizeGroup() *CommandGroup {
 Key Features:
 - Score distribution histograms
 - Category comparison charts  
+- Category heatmaps across query workloads
 - Percentile range visualizations
 - Ranking difference analysis
+- BM25 parameter and column weight sweeps
 
 All visualizations are designed for terminal display and provide educational
 insights into BM25 scoring patterns and search result distributions.`,
@@ -43,7 +48,10 @@ Examples:
   bm25-fundamentals visualize distribution --query "database optimization"
   
   # Distribution with custom weights
-  bm25-fundamentals visualize distribution --query "algorithm" --title-weight 2.0`,
+  bm25-fundamentals visualize distribution --query "algorithm" --weights "title:2.0"
+
+  # Distribution of every match, not just the best 100
+  bm25-fundamentals visualize distribution --query "framework" --all`,
 		RunE: handlers.Visualize.HandleDistribution,
 	}
 
@@ -90,44 +98,137 @@ Examples:
 		RunE: handlers.Visualize.HandleRange,
 	}
 
+	// k1SweepCmd plots scores across a range of BM25 parameter values
+	k1SweepCmd := &cobra.Command{
+		Use:   "k1-sweep",
+		Short: "Plot how BM25 scores change across a range of k1 and b values",
+		Long: `Plot a document's BM25 score, or the mean score of the result set, as the
+k1 parameter (and optionally b) sweeps across a range.
+
+SQLite's bm25() always uses k1 = 1.2 and b = 0.75. The sweep recomputes each
+score from the FTS5 index statistics with the other values, and checks the
+recomputed score at the defaults against bm25(). Each range is given as
+start:end:step; the parameter not being swept stays at its default.
+
+This visualization shows:
+- How k1 controls term frequency saturation
+- How b controls the penalty for longer-than-average documents
+- Which parameter values a document is most sensitive to
+
+Examples:
+  # Mean score of the top 20 results across k1
+  bm25-fundamentals visualize k1-sweep --query "database optimization"
+
+  # One document, sweeping both k1 and b
+  bm25-fundamentals visualize k1-sweep --query "database" --doc-id 42 --k1 0.5:2.0:0.25 --b 0:1:0.1`,
+		RunE: handlers.Visualize.HandleK1Sweep,
+	}
+
+	// weightSweepCmd plots ranking changes across a range of one column's weight
+	weightSweepCmd := &cobra.Command{
+		Use:   "weight-sweep",
+		Short: "Plot how rankings change as one column's weight varies",
+		Long: `Sweep one column's BM25 weight across a range while the other columns keep
+their weights (1.0 unless set with --weights), and chart how the ranking responds.
+
+At each weight this records:
+- The rank of the baseline's top document among all matches
+- The Kendall tau between the top results and the baseline ranking
+- How many of the baseline's top results are still in the top results
+
+The summary reports the weight beyond which the ranking stops changing, which
+shows where extra weighting no longer has any effect. The sweep data can be
+exported with --format json or --format csv.
+
+Examples:
+  # Sweep the title weight from 0 to 5
+  bm25-fundamentals visualize weight-sweep --query "database" --field title --range 0:5:0.5
+
+  # Export the sweep for a spreadsheet
+  bm25-fundamentals visualize weight-sweep --query "algorithm" --field content --format csv`,
+		RunE: handlers.Visualize.HandleWeightSweep,
+	}
+
+	// heatmapCmd shades mean category scores across a query workload
+	heatmapCmd := &cobra.Command{
+		Use:   "heatmap",
+		Short: "Shade mean category scores across several queries",
+		Long: `Run each query and shade the mean BM25 score of every category's results in a
+grid with one row per query and one column per category.
+
+This visualization helps spot:
+- Categories that match strongly across a whole query workload
+- Categories a query never reaches
+- Category bias that a single query would not show
+
+Categories are ordered by their total result count across all queries. The
+numeric matrix can be exported with --format csv or --format json.
+
+Examples:
+  # Compare three queries
+  bm25-fundamentals visualize heatmap --queries "database;algorithm;performance tuning"
+
+  # Export the matrix with title matches weighted up
+  bm25-fundamentals visualize heatmap --queries "database;network" --weights "title:2.0" --format csv`,
+		RunE: handlers.Visualize.HandleHeatmap,
+	}
+
 	// setupFlags configures flags for visualize commands
 	setupFlags := func() {
 		// Distribution command flags
-		distributionCmd.Flags().StringP("query", "q", "", "search query (required)")
-		distributionCmd.Flags().StringP("category", "c", "", "filter by category")
-		distributionCmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
-		distributionCmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
-		distributionCmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
+		flagutil.RegisterSearchFlags(distributionCmd)
 		distributionCmd.Flags().IntP("buckets", "b", 10, "number of histogram buckets")
 		distributionCmd.Flags().IntP("max-results", "n", 100, "maximum results to analyze")
-		distributionCmd.MarkFlagRequired("query")
+		flagutil.RegisterAllFlag(distributionCmd)
+		distributionCmd.MarkFlagsMutuallyExclusive("all", "max-results")
 
 		// Categories command flags
-		categoriesCmd.Flags().StringP("query", "q", "", "search query (required)")
+		flagutil.RegisterSearchFlags(categoriesCmd)
 		categoriesCmd.Flags().String("filter", "", "comma-separated list of categories to include")
-		categoriesCmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
-		categoriesCmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
-		categoriesCmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
+		categoriesCmd.RegisterFlagCompletionFunc("filter", completion.CategoryList)
 		categoriesCmd.Flags().IntP("max-results", "n", 100, "maximum results to analyze")
-		categoriesCmd.MarkFlagRequired("query")
+		flagutil.RegisterAllFlag(categoriesCmd)
+		categoriesCmd.MarkFlagsMutuallyExclusive("all", "max-results")
 
 		// Range command flags
-		rangeCmd.Flags().StringP("query", "q", "", "search query (required)")
-		rangeCmd.Flags().StringP("category", "c", "", "filter by category")
-		rangeCmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
-		rangeCmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
-		rangeCmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
+		flagutil.RegisterSearchFlags(rangeCmd)
 		rangeCmd.Flags().IntP("max-results", "n", 100, "maximum results to analyze")
-		rangeCmd.MarkFlagRequired("query")
+		flagutil.RegisterAllFlag(rangeCmd)
+		rangeCmd.MarkFlagsMutuallyExclusive("all", "max-results")
+
+		// K1 sweep command flags
+		flagutil.RegisterSearchFlags(k1SweepCmd)
+		k1SweepCmd.Flags().String("k1", "0.5:2.0:0.25", "k1 range to sweep as start:end:step (empty to skip)")
+		k1SweepCmd.Flags().String("b", "", "b range to sweep as start:end:step, e.g. 0:1:0.1")
+		k1SweepCmd.Flags().Int64("doc-id", 0, "score this document instead of the result set mean (0 = mean)")
+		k1SweepCmd.RegisterFlagCompletionFunc("doc-id", completion.DocumentIDs)
+		k1SweepCmd.Flags().IntP("max-results", "n", 20, "number of top results to average")
+
+		// Weight sweep command flags
+		flagutil.RegisterSearchFlags(weightSweepCmd)
+		weightSweepCmd.Flags().String("field", "title", "column whose weight is swept (title, content, category)")
+		weightSweepCmd.RegisterFlagCompletionFunc("field", cobra.FixedCompletions([]string{"title", "content", "category"}, cobra.ShellCompDirectiveNoFileComp))
+		weightSweepCmd.Flags().String("range", "0:5:0.5", "weight range to sweep as start:end:step")
+		weightSweepCmd.Flags().IntP("max-results", "n", 20, "number of top results to compare at each weight")
+
+		// Heatmap command flags
+		heatmapCmd.Flags().String("queries", "", "semicolon-separated queries, one heatmap row each (required)")
+		heatmapCmd.MarkFlagRequired("queries")
+		heatmapCmd.RegisterFlagCompletionFunc("queries", cobra.NoFileCompletions)
+		heatmapCmd.Flags().StringP("weights", "w", "", "column weights (format: title:2.0,content:1.0,category:0.5)")
+		heatmapCmd.Flags().IntP("max-results", "n", 100, "maximum results per query (0 = use config default)")
 	}
 
 	// Return the command group
-	return &CommandGroup{
+	return &cli.CommandGroup{
 		Command: visualizeCmd,
 		SubCommands: []*cobra.Command{
 			distributionCmd,
 			categoriesCmd,
 			rangeCmd,
+			k1SweepCmd,
+			weightSweepCmd,
+			heatmapCmd,
 		},
 		FlagSetup: setupFlags,
 	}
@@ -0,0 +1,154 @@
+package completion
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// queryTimeout bounds each completion query so a slow or locked database never stalls the shell
+	queryTimeout = 300 * time.Millisecond
+
+	// documentLimit bounds the number of document IDs offered
+	documentLimit = 50
+)
+
+// Categories completes category names from the documents table, described by
+// their document counts, most documents first
+func Categories(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	queryRows(cmd, database.CategoriesQuery, func(rows *sql.Rows) error {
+		categories, err := database.ScanCategories(rows)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			if strings.HasPrefix(category.Name, toComplete) {
+				completions = append(completions, fmt.Sprintf("%s\t%d documents", category.Name, category.Documents))
+			}
+		}
+		return nil
+	})
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// CategoryList completes the last entry of a comma-separated category list
+func CategoryList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix, current := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, current = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	// Skip categories already in the list
+	chosen := make(map[string]bool)
+	for _, category := range strings.Split(prefix, ",") {
+		chosen[strings.TrimSpace(category)] = true
+	}
+
+	categories, directive := Categories(cmd, args, current)
+	completions := make([]string, 0, len(categories))
+	for _, category := range categories {
+		name, _, _ := strings.Cut(category, "\t")
+		if !chosen[name] {
+			completions = append(completions, prefix+category)
+		}
+	}
+	return completions, directive | cobra.ShellCompDirectiveNoSpace
+}
+
+// DocumentIDs completes the most recently added document IDs, described by their titles
+func DocumentIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	rows := queryStrings(cmd,
+		fmt.Sprintf(`SELECT id || char(9) || title FROM documents
+		 WHERE CAST(id AS TEXT) LIKE ? || '%%' ORDER BY id DESC LIMIT %d`, documentLimit),
+		toComplete)
+	return rows, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// Files completes file names with the given extensions (without the leading dot)
+func Files(extensions ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return extensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+}
+
+// queryStrings runs a single-column query against the database named by --database,
+// returning nil on any failure so completion degrades to no suggestions
+func queryStrings(cmd *cobra.Command, query string, args ...interface{}) []string {
+	var values []string
+	ok := queryRows(cmd, query, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		return rows.Err()
+	}, args...)
+	if !ok {
+		return nil
+	}
+	return values
+}
+
+// queryRows runs query against the database named by --database and passes the
+// rows to fn, reporting whether both succeeded. The database is opened
+// read-only and never created.
+func queryRows(cmd *cobra.Command, query string, fn func(rows *sql.Rows) error, args ...interface{}) bool {
+	path := databasePath(cmd)
+	if path == "" {
+		return false
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+
+	return fn(rows) == nil
+}
+
+// databasePath returns the --database path when it names an existing file. In-memory
+// databases have nothing to complete from.
+func databasePath(cmd *cobra.Command) string {
+	flag := cmd.Flag("database")
+	if flag == nil {
+		return ""
+	}
+
+	path := flag.Value.String()
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = home + path[1:]
+	}
+
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return ""
+	}
+	return path
+}
@@ -4,13 +4,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/collation"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/numfmt"
 	"github.com/spf13/viper"
 )
 
 // App represents the global configuration instance with defaults
 var App = NewConfig()
 
+// Viper holds this phase's settings and flag bindings. The phase uses its own instance
+// rather than the viper singleton so that phases mounted in one binary keep their
+// "database", "verbose", and "format" bindings separate.
+var Viper = viper.New()
+
 // Config represents the application configuration schema
 type Config struct {
 	// Global settings
@@ -18,6 +27,20 @@ type Config struct {
 	Verbose  bool   `mapstructure:"verbose"`
 	Format   string `mapstructure:"format"`
 
+	// Golden is set by --format golden, which renders text output scrubbed of
+	// timings, dates, and paths; Format reads "text" while it is set
+	Golden bool `mapstructure:"-"`
+
+	// QueryTimeout bounds each database statement; zero means no limit
+	QueryTimeout time.Duration `mapstructure:"query_timeout"`
+
+	// AutoInit creates the corpus schema when a command finds it missing,
+	// instead of reporting an error
+	AutoInit bool `mapstructure:"auto_init"`
+
+	// viper is the settings source injected by Init
+	viper *viper.Viper
+
 	// Corpus configuration
 	Corpus CorpusConfig `mapstructure:"corpus"`
 
@@ -32,12 +55,21 @@ type Config struct {
 
 	// Analysis configuration
 	Analysis AnalysisConfig `mapstructure:"analysis"`
+
+	// Limits on flag values
+	Limits LimitsConfig `mapstructure:"limits"`
 }
 
 // CorpusConfig holds corpus generation settings
 type CorpusConfig struct {
 	Size      int `mapstructure:"size"`
 	BatchSize int `mapstructure:"batch_size"`
+
+	// NormalizeCategories composes category names to NFC as they are stored
+	NormalizeCategories bool `mapstructure:"normalize_categories"`
+
+	// LowercaseCategories lowercases category names as they are stored
+	LowercaseCategories bool `mapstructure:"lowercase_categories"`
 }
 
 // SearchConfig holds search-related settings
@@ -49,6 +81,25 @@ type SearchConfig struct {
 // DisplayConfig holds display formatting settings
 type DisplayConfig struct {
 	ScorePrecision int `mapstructure:"score_precision"`
+
+	// Locale orders category listings and formats report numbers for a
+	// language (BCP 47, e.g. "en" or "de"); empty keeps SQLite's byte order
+	// and plain numbers
+	Locale string `mapstructure:"locale"`
+
+	// SnippetTokens sizes snippets in tokens (1-64); zero derives the size
+	// from the snippet length in characters
+	SnippetTokens int `mapstructure:"snippet_tokens"`
+
+	// HighlightStart and HighlightEnd mark matched terms in snippets and
+	// highlighted content. Text output on a terminal colors the marked terms
+	// instead.
+	HighlightStart string `mapstructure:"highlight_start"`
+	HighlightEnd   string `mapstructure:"highlight_end"`
+
+	// MaxFieldChars cuts long text fields, such as titles, snippets, and
+	// content, in text output to this many characters; zero never cuts
+	MaxFieldChars int `mapstructure:"max_field_chars"`
 }
 
 // VisualizationConfig holds visualization settings
@@ -64,6 +115,20 @@ type AnalysisConfig struct {
 	Percentiles     []int `mapstructure:"percentiles"`
 }
 
+// LimitsConfig caps flag values that size allocations or output, so that a
+// mistyped value fails with an error naming the limit instead of exhausting memory
+type LimitsConfig struct {
+	MaxResults    int `mapstructure:"max_results"`    // Largest --max-results
+	Buckets       int `mapstructure:"buckets"`        // Largest --buckets
+	SnippetLength int `mapstructure:"snippet_length"` // Largest --snippet-length, in characters
+}
+
+// Histogram dimensions beyond these no longer fit any terminal
+const (
+	maxHistogramWidth  = 500
+	maxHistogramHeight = 200
+)
+
 // NewConfig creates a new config with defaults
 func NewConfig() *Config {
 	return &Config{
@@ -71,8 +136,9 @@ func NewConfig() *Config {
 		Verbose:  false,
 		Format:   "text",
 		Corpus: CorpusConfig{
-			Size:      100,
-			BatchSize: 1000,
+			Size:                100,
+			BatchSize:           1000,
+			NormalizeCategories: true,
 		},
 		Search: SearchConfig{
 			MaxResults:    20,
@@ -80,6 +146,9 @@ func NewConfig() *Config {
 		},
 		Display: DisplayConfig{
 			ScorePrecision: 4,
+			HighlightStart: "<<",
+			HighlightEnd:   ">>",
+			MaxFieldChars:  2000,
 		},
 		Visualization: VisualizationConfig{
 			HistogramWidth:  50,
@@ -90,39 +159,61 @@ func NewConfig() *Config {
 			MinScoreBuckets: 10,
 			Percentiles:     []int{25, 50, 75, 90, 95, 99},
 		},
+		Limits: LimitsConfig{
+			MaxResults:    100000,
+			Buckets:       100,
+			SnippetLength: 10000,
+		},
 	}
 }
 
 // SetDefaults applies default values to viper
 func (c *Config) SetDefaults() {
-	viper.SetDefault("database", c.Database)
-	viper.SetDefault("verbose", c.Verbose)
-	viper.SetDefault("format", c.Format)
-
-	viper.SetDefault("corpus.size", c.Corpus.Size)
-	viper.SetDefault("corpus.batch_size", c.Corpus.BatchSize)
-
-	viper.SetDefault("search.max_results", c.Search.MaxResults)
-	viper.SetDefault("search.term_freq_limit", c.Search.TermFreqLimit)
-
-	viper.SetDefault("display.score_precision", c.Display.ScorePrecision)
-
-	viper.SetDefault("visualization.histogram_width", c.Visualization.HistogramWidth)
-	viper.SetDefault("visualization.histogram_height", c.Visualization.HistogramHeight)
-	viper.SetDefault("visualization.show_legend", c.Visualization.ShowLegend)
-
-	viper.SetDefault("analysis.min_score_buckets", c.Analysis.MinScoreBuckets)
-	viper.SetDefault("analysis.percentiles", c.Analysis.Percentiles)
+	c.viper.SetDefault("database", c.Database)
+	c.viper.SetDefault("verbose", c.Verbose)
+	c.viper.SetDefault("format", c.Format)
+	c.viper.SetDefault("query_timeout", c.QueryTimeout)
+	c.viper.SetDefault("auto_init", c.AutoInit)
+
+	c.viper.SetDefault("corpus.size", c.Corpus.Size)
+	c.viper.SetDefault("corpus.batch_size", c.Corpus.BatchSize)
+	c.viper.SetDefault("corpus.normalize_categories", c.Corpus.NormalizeCategories)
+	c.viper.SetDefault("corpus.lowercase_categories", c.Corpus.LowercaseCategories)
+
+	c.viper.SetDefault("search.max_results", c.Search.MaxResults)
+	c.viper.SetDefault("search.term_freq_limit", c.Search.TermFreqLimit)
+
+	c.viper.SetDefault("display.score_precision", c.Display.ScorePrecision)
+	c.viper.SetDefault("display.locale", c.Display.Locale)
+	c.viper.SetDefault("display.snippet_tokens", c.Display.SnippetTokens)
+	c.viper.SetDefault("display.highlight_start", c.Display.HighlightStart)
+	c.viper.SetDefault("display.highlight_end", c.Display.HighlightEnd)
+	c.viper.SetDefault("display.max_field_chars", c.Display.MaxFieldChars)
+
+	c.viper.SetDefault("visualization.histogram_width", c.Visualization.HistogramWidth)
+	c.viper.SetDefault("visualization.histogram_height", c.Visualization.HistogramHeight)
+	c.viper.SetDefault("visualization.show_legend", c.Visualization.ShowLegend)
+
+	c.viper.SetDefault("analysis.min_score_buckets", c.Analysis.MinScoreBuckets)
+	c.viper.SetDefault("analysis.percentiles", c.Analysis.Percentiles)
+
+	c.viper.SetDefault("limits.max_results", c.Limits.MaxResults)
+	c.viper.SetDefault("limits.buckets", c.Limits.Buckets)
+	c.viper.SetDefault("limits.snippet_length", c.Limits.SnippetLength)
 }
 
 // Validate checks the configuration for errors
 func (c *Config) Validate() error {
 	// Validate format
 	switch c.Format {
-	case "text", "json", "csv":
+	case "text", "json", "csv", "markdown", "golden":
 		// Valid formats
 	default:
-		return fmt.Errorf("invalid format: %s (must be text, json, or csv)", c.Format)
+		return fmt.Errorf("invalid format: %s (must be text, json, csv, markdown, or golden)", c.Format)
+	}
+
+	if c.QueryTimeout < 0 {
+		return fmt.Errorf("query timeout cannot be negative")
 	}
 
 	// Validate corpus settings
@@ -133,17 +224,42 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("corpus batch size must be at least 1")
 	}
 
+	// Validate display settings
+	if _, err := collation.New(c.Display.Locale); err != nil {
+		return fmt.Errorf("invalid display locale %q: %w", c.Display.Locale, err)
+	}
+	if c.Display.SnippetTokens < 0 || c.Display.SnippetTokens > models.MaxSnippetTokens {
+		return fmt.Errorf("display.snippet_tokens must be between 0 and %d", models.MaxSnippetTokens)
+	}
+	if c.Display.MaxFieldChars < 0 {
+		return fmt.Errorf("display.max_field_chars cannot be negative")
+	}
+
+	// Validate limits
+	if c.Limits.MaxResults < 1 {
+		return fmt.Errorf("limits.max_results must be at least 1")
+	}
+	if c.Limits.Buckets < 1 {
+		return fmt.Errorf("limits.buckets must be at least 1")
+	}
+	if c.Limits.SnippetLength < 1 {
+		return fmt.Errorf("limits.snippet_length must be at least 1")
+	}
+
 	// Validate search settings
 	if c.Search.MaxResults < 1 {
 		return fmt.Errorf("max results must be at least 1")
 	}
+	if c.Search.MaxResults > c.Limits.MaxResults {
+		return fmt.Errorf("max results %d exceeds limits.max_results (%d)", c.Search.MaxResults, c.Limits.MaxResults)
+	}
 
 	// Validate visualization settings
-	if c.Visualization.HistogramWidth < 10 {
-		return fmt.Errorf("histogram width must be at least 10")
+	if c.Visualization.HistogramWidth < 10 || c.Visualization.HistogramWidth > maxHistogramWidth {
+		return fmt.Errorf("histogram width must be between 10 and %d", maxHistogramWidth)
 	}
-	if c.Visualization.HistogramHeight < 3 {
-		return fmt.Errorf("histogram height must be at least 3")
+	if c.Visualization.HistogramHeight < 3 || c.Visualization.HistogramHeight > maxHistogramHeight {
+		return fmt.Errorf("histogram height must be between 3 and %d", maxHistogramHeight)
 	}
 
 	return nil
@@ -151,21 +267,58 @@ func (c *Config) Validate() error {
 
 // Load reads configuration from viper
 func (c *Config) Load() error {
-	if err := viper.Unmarshal(c); err != nil {
+	if err := c.viper.Unmarshal(c); err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	c.applyGolden()
 
 	return c.Validate()
 }
 
+// applyGolden turns the golden format into text output with Golden set, so
+// handlers render golden output exactly as they render text
+func (c *Config) applyGolden() {
+	c.Golden = c.Format == "golden"
+	if c.Golden {
+		c.Format = "text"
+	}
+}
+
 // RefreshFromFlags updates config from current flag values
 func (c *Config) RefreshFromFlags() error {
-	if err := viper.Unmarshal(c); err != nil {
+	if err := c.viper.Unmarshal(c); err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	c.applyGolden()
 	return nil
 }
 
+// NormalizeCategory returns the form a category name is stored and filtered in
+func (c *Config) NormalizeCategory(name string) string {
+	return collation.Normalize(name, c.Corpus.NormalizeCategories, c.Corpus.LowercaseCategories)
+}
+
+// CategoryOrder returns the order category listings are displayed in. The
+// locale is checked by Validate; an invalid one falls back to byte order.
+func (c *Config) CategoryOrder() *collation.Order {
+	order, err := collation.New(c.Display.Locale)
+	if err != nil {
+		return &collation.Order{}
+	}
+	return order
+}
+
+// Numbers returns the format for counts and scores in text, markdown, and HTML
+// reports. The locale is checked by Validate; an invalid one falls back to
+// plain numbers.
+func (c *Config) Numbers() *numfmt.Format {
+	format, err := numfmt.New(c.Display.Locale)
+	if err != nil {
+		return &numfmt.Format{}
+	}
+	return format
+}
+
 // GetDatabasePath returns the database path, expanding ~ to home directory
 func (c *Config) GetDatabasePath() string {
 	dbPath := c.Database
@@ -186,14 +339,16 @@ func (c *Config) GetDatabasePath() string {
 	return dbPath
 }
 
-// Init initializes the global configuration
-func (c *Config) Init() {
+// Init initializes the configuration from v, which holds the flag bindings
+func (c *Config) Init(v *viper.Viper) {
+	c.viper = v
+
 	// Apply defaults to viper
 	c.SetDefaults()
 
-	if cfgFile := viper.GetString("config"); cfgFile != "" {
+	if cfgFile := c.viper.GetString("config"); cfgFile != "" {
 		// Use config file from the flag
-		viper.SetConfigFile(cfgFile)
+		c.viper.SetConfigFile(cfgFile)
 	} else {
 		// Find home directory
 		home, err := os.UserHomeDir()
@@ -203,18 +358,18 @@ func (c *Config) Init() {
 		}
 
 		// Search config in home directory with name ".bm25-fundamentals" (without extension)
-		viper.AddConfigPath(home)
-		viper.AddConfigPath(".")
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".bm25-fundamentals")
+		c.viper.AddConfigPath(home)
+		c.viper.AddConfigPath(".")
+		c.viper.SetConfigType("yaml")
+		c.viper.SetConfigName(".bm25-fundamentals")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	c.viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in
-	if err := viper.ReadInConfig(); err == nil {
-		if viper.GetBool("verbose") {
-			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	if err := c.viper.ReadInConfig(); err == nil {
+		if c.viper.GetBool("verbose") {
+			fmt.Fprintln(os.Stderr, "Using config file:", c.viper.ConfigFileUsed())
 		}
 	}
 
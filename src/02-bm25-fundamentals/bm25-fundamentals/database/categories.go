@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/shared/scan"
+)
+
+// CategoriesQuery summarizes each distinct category in the documents table,
+// selecting CategoryColumns, most documents first. It reads only the
+// documents table, so completion can run it on its own read-only connection.
+const CategoriesQuery = `
+	SELECT category, COUNT(*) AS documents, AVG(length) AS average_length,
+		MIN(created) AS earliest, MAX(created) AS latest
+	FROM documents
+	GROUP BY category
+	ORDER BY COUNT(*) DESC, category`
+
+// CategoryColumns are the columns ScanCategories reads, in order
+var CategoryColumns = []string{"category", "documents", "average_length", "earliest", "latest"}
+
+// ScanCategories reads every row of CategoriesQuery. A NULL category reads
+// as models.DefaultCategory; MIN and MAX skip NULL timestamps, and one that
+// does not parse leaves the created range unset.
+func ScanCategories(rows scan.Rows) ([]models.CategorySummary, error) {
+	if err := scan.Columns(rows, CategoryColumns...); err != nil {
+		return nil, err
+	}
+
+	return scan.All(rows, "category", func(row scan.Rows) (models.CategorySummary, error) {
+		var summary models.CategorySummary
+		var earliest, latest sql.NullString
+		if err := row.Scan(scan.TextOr(&summary.Name, models.DefaultCategory), &summary.Documents,
+			&summary.AverageLength, &earliest, &latest); err != nil {
+			return summary, err
+		}
+
+		if t, ok := ParseTime(earliest.String); earliest.Valid && ok {
+			summary.CreatedRange.Start = t
+		}
+		if t, ok := ParseTime(latest.String); latest.Valid && ok {
+			summary.CreatedRange.End = t
+		}
+		return summary, nil
+	})
+}
+
+// Categories summarizes the documents in each category
+func (d *Database) Categories(ctx context.Context) ([]models.CategorySummary, error) {
+	rows, err := d.QueryContext(ctx, CategoriesQuery)
+	if err != nil {
+		return nil, errors.Databasef("failed to list categories: %w", err)
+	}
+	defer rows.Close()
+
+	return ScanCategories(rows)
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/shared/fts5"
+	"github.com/jaime/go-sqlite/shared/txn"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -25,8 +29,25 @@ func Init(dataSourceName string) error {
 // Database wraps the SQL database connection with FTS5-specific operations
 type Database struct {
 	db *sql.DB
+
+	// timeout is the default statement timeout applied by the Exec and Query helpers
+	timeout time.Duration
+
+	// recorder collects timings for statements run through the helpers
+	recorder queryRecorder
+
+	// busyRetries is how many times WithTx retries a transaction on a locked database
+	busyRetries int
+
+	// schemaReady records that the corpus schema is known to exist, so HasSchema
+	// only queries sqlite_master until it first finds the tables
+	schemaReady bool
 }
 
+// DefaultBusyRetries is the number of times WithTx retries a transaction that
+// found the database locked by another connection
+const DefaultBusyRetries = 3
+
 // NewDatabase creates a new database connection
 func NewDatabase(dataSourceName string) (*Database, error) {
 	db, err := sql.Open("sqlite3", dataSourceName)
@@ -40,7 +61,7 @@ func NewDatabase(dataSourceName string) (*Database, error) {
 		return nil, err
 	}
 
-	return &Database{db: db}, nil
+	return &Database{db: db, busyRetries: DefaultBusyRetries}, nil
 }
 
 // configureSQLite applies optimal settings for FTS5 operations
@@ -97,81 +118,207 @@ func (d *Database) InitSchema(ctx context.Context) error {
 		return err
 	}
 
-	schemas := []string{
-		// Documents table
-		`CREATE TABLE IF NOT EXISTS documents (
-			id INTEGER PRIMARY KEY,
-			title TEXT NOT NULL,
-			content TEXT NOT NULL,
-			category TEXT NOT NULL DEFAULT 'general',
-			length INTEGER NOT NULL DEFAULT 0,
-			created DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// FTS5 virtual table for full-text search
-		`CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
-			title, 
-			content, 
-			category,
-			content='documents',
-			content_rowid='id',
-			tokenize='porter unicode61 remove_diacritics 1'
-		)`,
-
-		// Triggers to keep FTS5 index in sync
-		`CREATE TRIGGER IF NOT EXISTS documents_after_insert 
-		 AFTER INSERT ON documents BEGIN
-			INSERT INTO documents_fts(rowid, title, content, category) 
-			VALUES (new.id, new.title, new.content, new.category);
-		 END`,
-
-		`CREATE TRIGGER IF NOT EXISTS documents_after_update 
-		 AFTER UPDATE ON documents BEGIN
-			INSERT INTO documents_fts(documents_fts, rowid, title, content, category) 
-			VALUES('delete', old.id, old.title, old.content, old.category);
-			INSERT INTO documents_fts(rowid, title, content, category) 
-			VALUES (new.id, new.title, new.content, new.category);
-		 END`,
-
-		`CREATE TRIGGER IF NOT EXISTS documents_after_delete 
-		 AFTER DELETE ON documents BEGIN
-			INSERT INTO documents_fts(documents_fts, rowid, title, content, category) 
-			VALUES('delete', old.id, old.title, old.content, old.category);
-		 END`,
-
-		// Index for efficient category filtering
-		`CREATE INDEX IF NOT EXISTS idx_documents_category ON documents(category)`,
-		
-		// Index for creation time queries
-		`CREATE INDEX IF NOT EXISTS idx_documents_created ON documents(created)`,
-	}
-
-	tx, err := d.db.BeginTx(ctx, nil)
+	err := d.WithTx(ctx, func(tx *sql.Tx) error {
+		return createSchema(ctx, tx)
+	})
+	d.schemaReady = err == nil
+	return err
+}
+
+// HasSchema reports whether the documents table and its FTS5 index exist. A
+// positive answer is cached, since nothing in the tool drops the schema
+// without recreating it.
+func (d *Database) HasSchema(ctx context.Context) (bool, error) {
+	if d.schemaReady {
+		return true, nil
+	}
+
+	var tables int
+	err := d.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('documents', 'documents_fts')").Scan(&tables)
 	if err != nil {
-		return errors.Transactionf("failed to begin transaction: %w", err)
+		return false, errors.Databasef("failed to check corpus schema: %w", err)
 	}
-	defer tx.Rollback()
 
-	for _, schema := range schemas {
-		if _, err := tx.ExecContext(ctx, schema); err != nil {
-			return errors.Databasef("failed to create schema: %w", err)
+	d.schemaReady = tables == 2
+	return d.schemaReady, nil
+}
+
+// ResetSchema drops the documents table, the FTS5 index, and their triggers
+// and indexes, then creates them again, all in one transaction. Unlike
+// deleting rows, this discards any FTS5 configuration set on the index and
+// the rowid sequence, leaving the same state as a new database.
+func (d *Database) ResetSchema(ctx context.Context) error {
+	if err := d.VerifyFTS5Support(ctx); err != nil {
+		return err
+	}
+
+	err := d.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, drop := range dropStatements {
+			if _, err := tx.ExecContext(ctx, drop); err != nil {
+				return errors.Databasef("failed to drop schema: %w", err)
+			}
 		}
+		return createSchema(ctx, tx)
+	})
+	d.schemaReady = err == nil
+	return err
+}
+
+// WithDeferredIndex runs fn in one transaction with the documents_fts triggers
+// dropped, so the rows fn inserts into documents are not indexed one at a time.
+// Before the commit the new rows are indexed with a single INSERT ... SELECT,
+// the index is optimized, and the triggers are recreated; on failure the
+// rollback restores the triggers along with everything else. fn may only
+// insert documents: an update or delete would leave the index out of step.
+// A non-nil check runs last, once the new rows are searchable, and can still
+// roll the transaction back.
+func (d *Database) WithDeferredIndex(ctx context.Context, fn, check func(tx *sql.Tx) error) error {
+	triggers, err := fts5.TriggerSQL("documents_fts", documentsFTS)
+	if err != nil {
+		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return errors.Transactionf("failed to commit schema creation: %w", err)
+	return d.WithTx(ctx, func(tx *sql.Tx) error {
+		// Rows inserted by fn get ids above the current largest
+		var lastID int64
+		if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM documents").Scan(&lastID); err != nil {
+			return errors.Databasef("failed to read last document id: %w", err)
+		}
+
+		for _, drop := range dropTriggers {
+			if _, err := tx.ExecContext(ctx, drop); err != nil {
+				return errors.FTS5f("failed to drop sync trigger: %w", err)
+			}
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO documents_fts(rowid, title, content, category)
+			SELECT id, title, content, category FROM documents WHERE id > ?`, lastID)
+		if err != nil {
+			return errors.FTS5f("failed to index inserted documents: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO documents_fts(documents_fts) VALUES('optimize')"); err != nil {
+			return errors.FTS5f("failed to optimize index: %w", err)
+		}
+
+		for _, trigger := range triggers {
+			if _, err := tx.ExecContext(ctx, trigger); err != nil {
+				return errors.FTS5f("failed to restore sync trigger: %w", err)
+			}
+		}
+
+		if check != nil {
+			return check(tx)
+		}
+		return nil
+	})
+}
+
+// dropTriggers remove the triggers that keep documents_fts in step with documents
+var dropTriggers = []string{
+	`DROP TRIGGER IF EXISTS documents_after_insert`,
+	`DROP TRIGGER IF EXISTS documents_after_update`,
+	`DROP TRIGGER IF EXISTS documents_after_delete`,
+}
+
+// dropStatements remove everything createSchema creates, dependents first
+var dropStatements = append(slices.Clone(dropTriggers),
+	`DROP INDEX IF EXISTS idx_documents_category`,
+	`DROP INDEX IF EXISTS idx_documents_created`,
+	`DROP TABLE IF EXISTS documents_fts_vocab`,
+	`DROP TABLE IF EXISTS documents_fts`,
+	`DROP TABLE IF EXISTS documents`,
+)
+
+// createSchema creates the documents table, its FTS5 index with the triggers
+// that keep the two in sync, the vocabulary table over the index, and the
+// supporting indexes in tx
+func createSchema(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, documentsTable); err != nil {
+		return errors.Databasef("failed to create schema: %w", err)
+	}
+
+	if err := fts5.CreateFTS5TableWithTriggers(ctx, tx, "documents_fts", documentsFTS); err != nil {
+		return err
 	}
 
+	if _, err := tx.ExecContext(ctx, documentsVocab); err != nil {
+		return errors.FTS5f("failed to create vocabulary table: %w", err)
+	}
+
+	for _, index := range indexStatements {
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return errors.Databasef("failed to create schema: %w", err)
+		}
+	}
 	return nil
 }
 
-// Begin starts a new transaction
-func (d *Database) Begin(ctx context.Context) (*sql.Tx, error) {
-	tx, err := d.db.BeginTx(ctx, nil)
+// documentsTable holds the documents the FTS5 index is built from
+const documentsTable = `CREATE TABLE IF NOT EXISTS documents (
+	id INTEGER PRIMARY KEY,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	category TEXT NOT NULL DEFAULT 'general',
+	length INTEGER NOT NULL DEFAULT 0,
+	created DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+// documentsFTS is the FTS5 index over documents. Its triggers are named
+// documents_after_insert, documents_after_update, and documents_after_delete.
+var documentsFTS = fts5.TableSpec{
+	Columns:      []string{"title", "content", "category"},
+	Tokenizer:    "porter unicode61 remove_diacritics 1",
+	Content:      "documents",
+	ContentRowID: "id",
+	IfNotExists:  true,
+}
+
+// indexStatements create the indexes for category filtering and creation
+// time queries
+var indexStatements = []string{
+	`CREATE INDEX IF NOT EXISTS idx_documents_category ON documents(category)`,
+	`CREATE INDEX IF NOT EXISTS idx_documents_created ON documents(created)`,
+}
+
+// WithTx runs fn in a transaction that commits when fn returns nil and rolls
+// back when it returns an error or panics. A transaction that finds the
+// database locked is retried from the start, so fn must not carry state over
+// from an earlier attempt.
+func (d *Database) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return txn.Run(ctx, d.db, txn.Options{BusyRetries: d.busyRetries}, fn)
+}
+
+// WithAttached runs fn on one connection with the database file at path
+// attached as schema, detaching it when fn returns. ATTACH applies only to the
+// connection it runs on and cannot run inside a transaction, so fn is given
+// the pinned connection and begins its own transactions on it.
+func (d *Database) WithAttached(ctx context.Context, path, schema string, fn func(conn *sql.Conn) error) error {
+	conn, err := d.db.Conn(ctx)
 	if err != nil {
-		return nil, errors.Transactionf("failed to begin transaction: %w", err)
+		return errors.Databasef("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("ATTACH DATABASE ? AS %q", schema), path); err != nil {
+		return errors.Databasef("failed to attach %s: %w", path, err)
 	}
-	return tx, nil
+	// Detach even after cancellation, so the pooled connection comes back clean
+	defer conn.ExecContext(context.WithoutCancel(ctx), fmt.Sprintf("DETACH DATABASE %q", schema))
+
+	return fn(conn)
+}
+
+// SetBusyRetries sets how many times WithTx retries a transaction that found
+// the database locked; zero disables retrying
+func (d *Database) SetBusyRetries(retries int) {
+	d.busyRetries = retries
 }
 
 // DB returns the underlying sql.DB for direct queries when needed
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+// Document reads one document by id, or returns nil when there is none.
+// NULLs read as in ScanDocuments.
+func (d *Database) Document(ctx context.Context, id int64) (*models.Document, error) {
+	rows, err := d.QueryContext(ctx,
+		"SELECT "+strings.Join(DocumentColumns, ", ")+" FROM documents WHERE id = ?", id)
+	if err != nil {
+		return nil, errors.Databasef("failed to read document %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	docs, err := ScanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return docs[0], nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/shared/scan"
+)
+
+// fingerprintVersion starts every fingerprint, so a change to what is hashed
+// or how it is encoded never collides with an older fingerprint
+const fingerprintVersion = "bm25-corpus-fingerprint/1"
+
+// fingerprintTables are the tables whose CREATE statements are hashed. The
+// documents_fts statement carries the tokenizer and column list, which decide
+// how the same rows are indexed and scored.
+var fingerprintTables = []string{"documents", "documents_fts"}
+
+// Fingerprint computes a SHA-256 over the corpus schema and every document's
+// id, title, content, and category, streamed in id order. Each value is
+// length-prefixed, so no two corpora share an encoding; length and created are
+// left out, since recount and import rewrite them without changing what is
+// indexed.
+func (d *Database) Fingerprint(ctx context.Context) (*models.CorpusFingerprint, error) {
+	h := sha256.New()
+	writeField(h, fingerprintVersion)
+
+	for _, table := range fingerprintTables {
+		var schema string
+		err := d.QueryRowContext(ctx,
+			"SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(scan.Text(&schema))
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFoundf("table %s not found; run 'corpus generate' first", table)
+		}
+		if err != nil {
+			return nil, errors.Databasef("failed to read %s schema: %w", table, err)
+		}
+		writeField(h, schema)
+	}
+
+	rows, err := d.QueryContext(ctx, "SELECT id, title, content, category FROM documents ORDER BY id")
+	if err != nil {
+		return nil, errors.Databasef("failed to read documents: %w", err)
+	}
+	defer rows.Close()
+
+	var id [8]byte
+	count, err := scan.Each(rows, "document", func(row scan.Rows) error {
+		var docID int64
+		var title, content, category string
+		if err := row.Scan(&docID, scan.Text(&title), scan.Text(&content),
+			scan.TextOr(&category, models.DefaultCategory)); err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint64(id[:], uint64(docID))
+		h.Write(id[:])
+		writeField(h, title)
+		writeField(h, content)
+		writeField(h, category)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CorpusFingerprint{
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+		Documents: count,
+	}, nil
+}
+
+// writeField writes value to h behind its uvarint byte length
+func writeField(h hash.Hash, value string) {
+	var length [binary.MaxVarintLen64]byte
+	h.Write(length[:binary.PutUvarint(length[:], uint64(len(value)))])
+	h.Write([]byte(value))
+}
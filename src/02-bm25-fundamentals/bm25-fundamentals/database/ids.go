@@ -0,0 +1,39 @@
+package database
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// MaxParameters is the number of bound parameters a statement may use. SQLite
+// builds before 3.32 default SQLITE_MAX_VARIABLE_NUMBER to 999, and builds may
+// still set it that low, so ID lists are bound in chunks that fit under it
+// rather than relying on the higher limit of the bundled SQLite.
+const MaxParameters = 999
+
+// ChunkIDs splits ids into consecutive chunks small enough to bind in one
+// statement that also binds reserved other parameters. Callers run the
+// statement once per chunk and combine the results. It panics if reserved
+// leaves no room for an ID.
+func ChunkIDs(ids []int64, reserved int) iter.Seq[[]int64] {
+	size := MaxParameters - reserved
+	if size < 1 {
+		panic(fmt.Sprintf("database: %d reserved parameters leave no room for IDs", reserved))
+	}
+	return slices.Chunk(ids, size)
+}
+
+// InClause returns "column IN (?, ?, ...)" for values, such as document IDs or
+// category names, and the arguments it binds. values must be non-empty and,
+// with the statement's other parameters, within MaxParameters; split longer
+// ID lists with ChunkIDs.
+func InClause[T any](column string, values []T) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return fmt.Sprintf("%s IN (%s)", column, placeholders), args
+}
@@ -0,0 +1,87 @@
+//go:build fts5
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// TestChunkIDsUnderVariableLimit binds ID lists on a connection limited to
+// MaxParameters variables, as older SQLite builds are: chunked lists return
+// every row, while a list past the limit in one statement fails
+func TestChunkIDsUnderVariableLimit(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "ids.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.InitSchema(ctx); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	// Limits apply per connection, so keep the pool to the one that is limited
+	db.db.SetMaxOpenConns(1)
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("get connection: %v", err)
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		driverConn.(*sqlite3.SQLiteConn).SetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER, MaxParameters)
+		return nil
+	})
+	conn.Close()
+	if err != nil {
+		t.Fatalf("set variable limit: %v", err)
+	}
+
+	ids := sequentialIDs(10_000)
+	err = db.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO documents (id, title, content) VALUES (?, ?, ?)", id, fmt.Sprint("doc ", id), "text"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("insert documents: %v", err)
+	}
+
+	const reserved = 1 // the category below
+	for _, n := range []int{998, 999, 1000, 10_000} {
+		t.Run(fmt.Sprintf("%d IDs", n), func(t *testing.T) {
+			found, statements := 0, 0
+			for chunk := range ChunkIDs(ids[:n], reserved) {
+				clause, args := InClause("id", chunk)
+				var count int
+				query := "SELECT COUNT(*) FROM documents WHERE category = ? AND " + clause
+				if err := db.QueryRowContext(ctx, query, append([]interface{}{"general"}, args...)...).Scan(&count); err != nil {
+					t.Fatalf("chunk of %d IDs: %v", len(chunk), err)
+				}
+				found += count
+				statements++
+			}
+			if found != n {
+				t.Errorf("chunked queries found %d documents, want %d", found, n)
+			}
+			if want := (n + MaxParameters - reserved - 1) / (MaxParameters - reserved); statements != want {
+				t.Errorf("ran %d statements, want %d", statements, want)
+			}
+		})
+	}
+
+	clause, args := InClause("id", ids[:MaxParameters+1])
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents WHERE "+clause, args...).Scan(&count)
+	if err == nil || !strings.Contains(err.Error(), "too many SQL variables") {
+		t.Errorf("binding %d IDs at once: err = %v, want too many SQL variables", MaxParameters+1, err)
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// sequentialIDs returns the IDs 1 to n
+func sequentialIDs(n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	return ids
+}
+
+func TestChunkIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      int
+		reserved int
+		sizes    []int
+	}{
+		{"none", 0, 0, nil},
+		{"one", 1, 5, []int{1}},
+		{"fills the limit", 999, 0, []int{999}},
+		{"one over the limit", 1000, 0, []int{999, 1}},
+		{"998 with two reserved", 998, 2, []int{997, 1}},
+		{"999 with two reserved", 999, 2, []int{997, 2}},
+		{"1000 with two reserved", 1000, 2, []int{997, 3}},
+		{"10k with six reserved", 10_000, 6, []int{993, 993, 993, 993, 993, 993, 993, 993, 993, 993, 70}},
+		{"one ID per chunk", 3, 998, []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := sequentialIDs(tt.ids)
+
+			var sizes []int
+			var joined []int64
+			for chunk := range ChunkIDs(ids, tt.reserved) {
+				if len(chunk)+tt.reserved > MaxParameters {
+					t.Errorf("chunk of %d with %d reserved exceeds %d parameters", len(chunk), tt.reserved, MaxParameters)
+				}
+				sizes = append(sizes, len(chunk))
+				joined = append(joined, chunk...)
+			}
+
+			if !reflect.DeepEqual(sizes, tt.sizes) {
+				t.Errorf("chunk sizes = %v, want %v", sizes, tt.sizes)
+			}
+			if !slices.Equal(joined, ids) {
+				t.Errorf("chunks rejoin to %d IDs, want the %d given in order", len(joined), len(ids))
+			}
+		})
+	}
+}
+
+func TestChunkIDsPanicsWithoutRoom(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("ChunkIDs with every parameter reserved did not panic")
+		}
+	}()
+	ChunkIDs(sequentialIDs(3), MaxParameters)
+}
+
+func TestInClause(t *testing.T) {
+	clause, args := InClause("d.id", []int64{4, 9, 16})
+	if clause != "d.id IN (?, ?, ?)" {
+		t.Errorf("clause = %q, want %q", clause, "d.id IN (?, ?, ?)")
+	}
+	if want := []interface{}{int64(4), int64(9), int64(16)}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+
+	clause, args = InClause("d.category", []string{"science"})
+	if clause != "d.category IN (?)" || !reflect.DeepEqual(args, []interface{}{"science"}) {
+		t.Errorf("InClause(science) = %q, %#v", clause, args)
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+)
+
+// MatchExpression returns the FTS5 MATCH expression for query in mode, or
+// for its terms as a NEAR group when near is positive. The empty mode with no
+// NEAR distance returns query unchanged, as FTS5 query syntax. Otherwise
+// query is split into tokens the way documents_fts does and each one is
+// quoted, so operators and punctuation in the query are searched as plain
+// words:
+//
+//	any     "a" OR "b"
+//	all     "a" AND "b"
+//	phrase  "a b"
+//	prefix  "a"* AND "b"*
+//	near    NEAR("a" "b", N)
+//
+// A NEAR group needs at least two terms and cannot be combined with a mode.
+func MatchExpression(query, mode string, near int) (string, error) {
+	if near > 0 {
+		return nearExpression(query, mode, near)
+	}
+	if mode == "" {
+		return query, nil
+	}
+
+	words := tokens.Split(query)
+	if len(words) == 0 {
+		return "", errors.Validationf("query %q has no terms to search in %s mode", query, mode)
+	}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = quoteMatchString(word)
+	}
+
+	switch mode {
+	case models.QueryModeAny:
+		return strings.Join(quoted, " OR "), nil
+	case models.QueryModeAll:
+		return strings.Join(quoted, " AND "), nil
+	case models.QueryModePhrase:
+		return quoteMatchString(strings.Join(words, " ")), nil
+	case models.QueryModePrefix:
+		for i := range quoted {
+			quoted[i] += "*"
+		}
+		return strings.Join(quoted, " AND "), nil
+	default:
+		return "", errors.Validationf("invalid query mode: %s (must be %s)", mode, strings.Join(models.QueryModes, ", "))
+	}
+}
+
+// nearExpression groups the quoted terms of query in a NEAR with distance near
+func nearExpression(query, mode string, near int) (string, error) {
+	if mode != "" {
+		return "", errors.Validationf("NEAR cannot be combined with %s mode", mode)
+	}
+
+	words := tokens.Split(query)
+	if len(words) < 2 {
+		return "", errors.Validationf("NEAR needs at least two query terms, %q has %d", query, len(words))
+	}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = quoteMatchString(word)
+	}
+	return fmt.Sprintf("NEAR(%s, %d)", strings.Join(quoted, " "), near), nil
+}
+
+// quoteMatchString quotes text as an FTS5 string, doubling embedded quotes
+func quoteMatchString(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
@@ -0,0 +1,97 @@
+//go:build fts5
+
+package database
+
+import (
+	"context"
+	stderrors "errors"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+)
+
+// FuzzMatchExpression checks that every query mode either rejects a query
+// with a validation error or builds an expression FTS5 accepts, and that the
+// expression matches a document whose content is the query itself, when the
+// NEAR distance, if any, spans the tokens between the query's first and last
+// terms. The empty mode without NEAR passes the query through as FTS5
+// syntax, so it is not fuzzed.
+func FuzzMatchExpression(f *testing.F) {
+	seeds := []string{
+		"sqlite",
+		"database optimization",
+		`"quoted" AND OR NOT`,
+		"NEAR(a b, 2)",
+		"title:term",
+		"prefix* -minus +plus",
+		"über café naïve",
+		"real-time don't",
+		"(((",
+		"",
+	}
+	for _, seed := range seeds {
+		for mode := range len(models.QueryModes) + 1 {
+			f.Add(seed, uint8(mode), uint8(0))
+			f.Add(seed, uint8(mode), uint8(3))
+		}
+	}
+
+	db, err := NewDatabase(filepath.Join(f.TempDir(), "match.db"))
+	if err != nil {
+		f.Fatalf("open database: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := db.InitSchema(ctx); err != nil {
+		f.Fatalf("create schema: %v", err)
+	}
+
+	modes := append([]string{""}, models.QueryModes...)
+	f.Fuzz(func(t *testing.T, query string, modeIndex, near uint8) {
+		mode := modes[int(modeIndex)%len(modes)]
+		if mode == "" && near == 0 {
+			t.Skip("raw FTS5 syntax")
+		}
+		// unicode61 and tokens.Split may disagree on where invalid UTF-8 splits
+		if !utf8.ValidString(query) {
+			t.Skip("invalid UTF-8")
+		}
+
+		match, err := MatchExpression(query, mode, int(near))
+		if err != nil {
+			if !stderrors.Is(err, errors.ErrValidation) {
+				t.Fatalf("MatchExpression(%q, %q, %d) error is not a validation error: %v", query, mode, near, err)
+			}
+			return
+		}
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM documents"); err != nil {
+			t.Fatalf("clear documents: %v", err)
+		}
+		result, err := db.ExecContext(ctx, "INSERT INTO documents (title, content) VALUES ('', ?)", query)
+		if err != nil {
+			t.Fatalf("insert document: %v", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			t.Fatalf("document id: %v", err)
+		}
+
+		var count int
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents_fts WHERE documents_fts MATCH ? AND rowid = ?", match, id).Scan(&count)
+		if err != nil {
+			t.Fatalf("MatchExpression(%q, %q, %d) = %q, which FTS5 rejects: %v", query, mode, near, match, err)
+		}
+		// FTS5 counts every token between the first and last NEAR phrases
+		if near > 0 && int(near) < len(tokens.Split(query))-2 {
+			return
+		}
+		if count != 1 {
+			t.Errorf("MatchExpression(%q, %q, %d) = %q does not match the query's own text", query, mode, near, match)
+		}
+	})
+}
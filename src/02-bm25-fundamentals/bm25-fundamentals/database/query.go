@@ -0,0 +1,200 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+)
+
+// Error is a SQLite error classified under one of the errors sentinels.
+// Its message is the driver's own, so handlers can keep wrapping it with
+// their context while errors.Is still reports the classification.
+type Error struct {
+	// Kind is the sentinel the error was classified as
+	Kind error
+
+	// Err is the underlying driver or context error
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap exposes both the classification and the original error
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Classify tags err with the sentinel that best describes it: ErrCancelled for
+// statements interrupted by cancellation, ErrFTS5 for errors raised by the FTS5
+// module, and ErrDatabase for everything else, including timeouts. sql.ErrNoRows is returned
+// unchanged so callers can keep comparing against it.
+func Classify(err error) error {
+	var classified *Error
+	if err == nil || err == sql.ErrNoRows || stderrors.As(err, &classified) {
+		return err
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return &Error{Kind: errors.ErrDatabase, Err: err}
+	case stderrors.Is(err, context.Canceled), strings.Contains(message, "interrupted"):
+		return &Error{Kind: errors.ErrCancelled, Err: err}
+	case strings.Contains(message, "fts5"):
+		return &Error{Kind: errors.ErrFTS5, Err: err}
+	default:
+		return &Error{Kind: errors.ErrDatabase, Err: err}
+	}
+}
+
+// QueryStats summarizes the statements run through the Exec and Query helpers
+type QueryStats struct {
+	Statements int           `json:"statements"`
+	Total      time.Duration `json:"total"`
+	Slowest    time.Duration `json:"slowest"`
+	SlowestSQL string        `json:"slowest_sql,omitempty"`
+}
+
+// queryRecorder accumulates QueryStats across concurrent callers
+type queryRecorder struct {
+	mu    sync.Mutex
+	stats QueryStats
+}
+
+func (r *queryRecorder) record(query string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Statements++
+	r.stats.Total += elapsed
+	if elapsed > r.stats.Slowest {
+		r.stats.Slowest = elapsed
+		r.stats.SlowestSQL = strings.Join(strings.Fields(query), " ")
+	}
+}
+
+// SetTimeout sets the default timeout for helper calls whose context has no
+// deadline of its own; zero disables it
+func (d *Database) SetTimeout(timeout time.Duration) {
+	d.timeout = timeout
+}
+
+// Timeout returns the default statement timeout
+func (d *Database) Timeout() time.Duration {
+	return d.timeout
+}
+
+// Stats returns the statement count and timings recorded so far
+func (d *Database) Stats() QueryStats {
+	d.recorder.mu.Lock()
+	defer d.recorder.mu.Unlock()
+	return d.recorder.stats
+}
+
+// ExecContext runs a statement that returns no rows
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt := d.startStatement(ctx, query)
+	defer stmt.finish()
+
+	result, err := d.db.ExecContext(stmt.ctx, query, args...)
+	if err != nil {
+		return nil, stmt.classify(err)
+	}
+	return result, nil
+}
+
+// QueryContext runs a query that returns rows. The timeout and timing cover
+// the whole iteration, so the returned Rows must be closed.
+func (d *Database) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	stmt := d.startStatement(ctx, query)
+
+	rows, err := d.db.QueryContext(stmt.ctx, query, args...)
+	if err != nil {
+		stmt.finish()
+		return nil, stmt.classify(err)
+	}
+	return &Rows{Rows: rows, stmt: stmt}, nil
+}
+
+// QueryRowContext runs a query expected to return at most one row. The
+// timeout and timing end when the returned Row is scanned.
+func (d *Database) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	stmt := d.startStatement(ctx, query)
+	return &Row{row: d.db.QueryRowContext(stmt.ctx, query, args...), stmt: stmt}
+}
+
+// Rows wraps sql.Rows so that closing it ends the statement's timeout and
+// timing, and iteration errors are classified
+type Rows struct {
+	*sql.Rows
+	stmt *statement
+}
+
+// Close closes the rows and records the statement; it is safe to call more than once
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.stmt.finish()
+	return err
+}
+
+// Err returns the classified error, if any, encountered during iteration
+func (r *Rows) Err() error {
+	return r.stmt.classify(r.Rows.Err())
+}
+
+// Row wraps sql.Row so that scanning it ends the statement's timeout and
+// timing, and errors are classified
+type Row struct {
+	row  *sql.Row
+	stmt *statement
+}
+
+// Scan copies the row into dest; sql.ErrNoRows is returned unchanged
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.stmt.finish()
+	return r.stmt.classify(r.row.Scan(dest...))
+}
+
+// statement tracks one helper call from start until its result is consumed
+type statement struct {
+	ctx     context.Context
+	timeout time.Duration // the default timeout, when it was applied
+	finish  func()
+}
+
+// startStatement applies the default timeout and starts timing the statement
+func (d *Database) startStatement(ctx context.Context, query string) *statement {
+	stmt := &statement{ctx: ctx}
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && d.timeout > 0 {
+		stmt.ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		stmt.timeout = d.timeout
+	}
+
+	start := time.Now()
+	var once sync.Once
+	stmt.finish = func() {
+		once.Do(func() {
+			d.recorder.record(query, time.Since(start))
+			cancel()
+		})
+	}
+	return stmt
+}
+
+// classify classifies err, reporting the default timeout as a database error
+// when it was the cause rather than a cancellation
+func (s *statement) classify(err error) error {
+	if err != nil && err != sql.ErrNoRows && s.timeout > 0 && stderrors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+		return &Error{Kind: errors.ErrDatabase, Err: fmt.Errorf("statement exceeded the %v timeout: %w", s.timeout, err)}
+	}
+	return Classify(err)
+}
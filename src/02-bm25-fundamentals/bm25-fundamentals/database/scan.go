@@ -0,0 +1,107 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/shared/scan"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ParseTime parses a stored timestamp in any of the layouts go-sqlite3 writes
+// or accepts, reporting whether one matched. Aggregates such as MIN(created)
+// lose the column's DATETIME type, so the driver returns them as text.
+func ParseTime(value string) (time.Time, bool) {
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// DocumentColumns are the columns ScanDocuments reads, in order
+var DocumentColumns = []string{"id", "title", "content", "category", "length", "created"}
+
+// SearchResultColumns are the columns ScanSearchResults reads, in order. The
+// content column is empty when content is omitted; the snippet and
+// highlighted columns hold the FTS5 snippet() and highlight() output, or are
+// empty when not requested.
+var SearchResultColumns = []string{"id", "title", "content", "snippet", "highlighted", "category", "length", "created", "score"}
+
+// ScanDocuments reads every row of a query selecting DocumentColumns. Rows
+// written by other tools may hold NULLs: a NULL category reads as
+// models.DefaultCategory, a NULL created as the zero time, and a NULL title or
+// content as the empty string.
+func ScanDocuments(rows scan.Rows) ([]*models.Document, error) {
+	if err := scan.Columns(rows, DocumentColumns...); err != nil {
+		return nil, err
+	}
+
+	return scan.All(rows, "document", scanDocument)
+}
+
+// EachDocument calls fn with every row of a query selecting DocumentColumns,
+// one document at a time, and returns the number of documents passed to fn.
+// NULLs read as in ScanDocuments. An error from fn stops the scan and is
+// returned as is, not as a scan failure.
+func EachDocument(rows scan.Rows, fn func(doc *models.Document) error) (int, error) {
+	if err := scan.Columns(rows, DocumentColumns...); err != nil {
+		return 0, err
+	}
+
+	var fnErr error
+	count, err := scan.Each(rows, "document", func(row scan.Rows) error {
+		doc, err := scanDocument(row)
+		if err != nil {
+			return err
+		}
+		fnErr = fn(doc)
+		return fnErr
+	})
+	if fnErr != nil {
+		return count, fnErr
+	}
+	return count, err
+}
+
+// scanDocument reads the current row of a query selecting DocumentColumns
+func scanDocument(row scan.Rows) (*models.Document, error) {
+	doc := &models.Document{}
+	err := row.Scan(
+		&doc.ID,
+		scan.Text(&doc.Title),
+		scan.Text(&doc.Content),
+		scan.TextOr(&doc.Category, models.DefaultCategory),
+		&doc.Length,
+		scan.Time(&doc.Created, sqlite3.SQLiteTimestampFormats...),
+	)
+	return doc, err
+}
+
+// ScanSearchResults reads every row of a query selecting SearchResultColumns.
+// NULLs read as in ScanDocuments. Relevance is left to the caller.
+func ScanSearchResults(rows scan.Rows) ([]*models.SearchResult, error) {
+	if err := scan.Columns(rows, SearchResultColumns...); err != nil {
+		return nil, err
+	}
+
+	return scan.All(rows, "search result", func(row scan.Rows) (*models.SearchResult, error) {
+		result := &models.SearchResult{}
+		err := row.Scan(
+			&result.ID,
+			scan.Text(&result.Title),
+			scan.Text(&result.Content),
+			scan.Text(&result.Snippet),
+			scan.Text(&result.Highlighted),
+			scan.TextOr(&result.Category, models.DefaultCategory),
+			&result.Length,
+			scan.Time(&result.Created, sqlite3.SQLiteTimestampFormats...),
+			&result.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+}
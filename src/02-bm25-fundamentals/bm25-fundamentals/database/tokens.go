@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+)
+
+// TokenCount counts the tokens the documents_fts tokenizer produces for the
+// given column values, matching the per-row total FTS5 stores in the docsize
+// shadow table and normalizes BM25 scores by. Tokens never span columns.
+func TokenCount(columns ...string) int {
+	count := 0
+	for _, column := range columns {
+		count += tokens.CountTokens(column)
+	}
+	return count
+}
+
+// IndexedColumns names the documents_fts columns in schema order, which is
+// the order FTS5 records per-column sizes in
+var IndexedColumns = []string{"title", "content", "category"}
+
+// IndexLengths holds the totals FTS5 keeps in its averages record and uses to
+// compute avgdl for bm25
+type IndexLengths struct {
+	// Rows is the number of indexed rows
+	Rows int
+
+	// ColumnTokens is the total token count of each column, in schema order
+	ColumnTokens []int
+}
+
+// Average returns the mean row length across all columns, the avgdl bm25 uses
+func (l *IndexLengths) Average() float64 {
+	total := 0
+	for _, tokens := range l.ColumnTokens {
+		total += tokens
+	}
+	return l.average(total)
+}
+
+// ColumnAverage returns the mean length of column i
+func (l *IndexLengths) ColumnAverage(i int) float64 {
+	if i < 0 || i >= len(l.ColumnTokens) {
+		return 0
+	}
+	return l.average(l.ColumnTokens[i])
+}
+
+func (l *IndexLengths) average(tokens int) float64 {
+	if l.Rows == 0 {
+		return 0
+	}
+	return float64(tokens) / float64(l.Rows)
+}
+
+// IndexLengths reads the averages record (rowid 1 of documents_fts_data). An
+// index with no rows yet has no record and reports zero totals.
+func (d *Database) IndexLengths(ctx context.Context) (*IndexLengths, error) {
+	lengths := &IndexLengths{ColumnTokens: make([]int, len(IndexedColumns))}
+
+	var block []byte
+	err := d.QueryRowContext(ctx, "SELECT block FROM documents_fts_data WHERE id = 1").Scan(&block)
+	if err == sql.ErrNoRows {
+		return lengths, nil
+	}
+	if err != nil {
+		return nil, errors.FTS5f("failed to read index averages: %w", err)
+	}
+
+	values, err := decodeVarints(block)
+	if err != nil {
+		return nil, errors.FTS5f("averages record: %w", err)
+	}
+	if len(values) > 0 {
+		lengths.Rows = values[0]
+		copy(lengths.ColumnTokens, values[1:])
+	}
+	return lengths, nil
+}
+
+// DocSize returns the per-column token counts FTS5 recorded for a document,
+// or nil when the document is not in the index
+func (d *Database) DocSize(ctx context.Context, id int64) ([]int, error) {
+	var sz []byte
+	err := d.QueryRowContext(ctx, "SELECT sz FROM documents_fts_docsize WHERE id = ?", id).Scan(&sz)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.FTS5f("failed to read size of document %d: %w", id, err)
+	}
+	return DecodeDocSize(sz)
+}
+
+// DecodeDocSize decodes a documents_fts_docsize blob into per-column token
+// counts in IndexedColumns order
+func DecodeDocSize(sz []byte) ([]int, error) {
+	return decodeVarints(sz)
+}
+
+// decodeVarints decodes a sequence of SQLite varints, the encoding FTS5 uses
+// for its size records: big-endian groups of 7 bits, with the high bit set on
+// all but the last byte and a full final byte after eight
+func decodeVarints(blob []byte) ([]int, error) {
+	var values []int
+	for i := 0; i < len(blob); {
+		var value uint64
+		n := 0
+		for {
+			if i >= len(blob) {
+				return nil, errors.FTS5f("truncated varint")
+			}
+			b := blob[i]
+			i++
+			n++
+			if n == 9 {
+				value = value<<8 | uint64(b)
+				break
+			}
+			value = value<<7 | uint64(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		values = append(values, int(value))
+	}
+	return values, nil
+}
+
+// Tokenizer returns the tokenize option documents_fts is created with
+func Tokenizer() string {
+	return documentsFTS.Tokenizer
+}
@@ -0,0 +1,337 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+	"github.com/jaime/go-sqlite/shared/scan"
+)
+
+// TermStats holds the index statistics bm25 uses for one query term scored
+// against one document
+type TermStats struct {
+	// Query is the term as written in the query
+	Query string
+
+	// Term is the term as the tokenizer indexed it (case-folded and stemmed)
+	Term string
+
+	// Documents is the number of indexed rows containing the term (n in the IDF)
+	Documents int
+
+	// ColumnCounts holds the term's occurrences in each column of the
+	// document, in IndexedColumns order
+	ColumnCounts []int
+}
+
+// documentsVocab is the fts5vocab table over documents_fts with one row per
+// distinct indexed term. It reads the index directly, so it never needs
+// rebuilding, and is created with the schema.
+const documentsVocab = `CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts_vocab USING fts5vocab(documents_fts, row)`
+
+// Vocabulary summarizes the terms in the documents_fts index
+type Vocabulary struct {
+	// Terms is the number of distinct indexed terms (after case folding and stemming)
+	Terms int
+
+	// Postings is the number of (term, document) pairs: the sum of every
+	// term's document frequency
+	Postings int64
+}
+
+// Vocabulary counts the distinct terms and postings in the index. Databases
+// created before the vocabulary table was part of the schema get it here.
+func (d *Database) Vocabulary(ctx context.Context) (*Vocabulary, error) {
+	if _, err := d.ExecContext(ctx, documentsVocab); err != nil {
+		return nil, errors.FTS5f("failed to create vocabulary table: %w", err)
+	}
+
+	vocab := &Vocabulary{}
+	err := d.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(SUM(doc), 0) FROM documents_fts_vocab").Scan(&vocab.Terms, &vocab.Postings)
+	if err != nil {
+		return nil, errors.FTS5f("failed to read index vocabulary: %w", err)
+	}
+	return vocab, nil
+}
+
+// vocabTables are the fts5vocab views QueryTermStats reads. They live in the
+// temp schema, so they are created on the connection that uses them.
+var vocabTables = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS temp.query_terms USING fts5(
+		query, tokenize='porter unicode61 remove_diacritics 1'
+	)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS temp.query_terms_instance USING fts5vocab(temp, query_terms, instance)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS temp.documents_fts_row USING fts5vocab(main, documents_fts, row)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS temp.documents_fts_instance USING fts5vocab(main, documents_fts, instance)`,
+}
+
+// QueryTermStats tokenizes query the way documents_fts does and returns, for
+// each distinct term in query order, its document frequency across the index
+// and its per-column occurrences in document id. Apart from the uppercase AND,
+// OR, NOT, and NEAR keywords, query syntax such as phrases and column filters
+// is not interpreted; every token is a term.
+func (d *Database) QueryTermStats(ctx context.Context, query string, id int64) ([]TermStats, error) {
+	// Temp tables are per connection, so pin one for the whole lookup
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Databasef("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	for _, table := range vocabTables {
+		if _, err := conn.ExecContext(ctx, table); err != nil {
+			return nil, errors.FTS5f("failed to create vocabulary table: %w", err)
+		}
+	}
+
+	// Run the query text through a scratch table with the same tokenizer
+	if _, err := conn.ExecContext(ctx, "DELETE FROM temp.query_terms"); err != nil {
+		return nil, errors.FTS5f("failed to reset query tokenizer: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "INSERT INTO temp.query_terms(rowid, query) VALUES (1, ?)", query); err != nil {
+		return nil, errors.FTS5f("failed to tokenize query: %w", err)
+	}
+
+	terms, err := queryTerms(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range terms {
+		stats := &terms[i]
+		stats.ColumnCounts = make([]int, len(IndexedColumns))
+
+		err := conn.QueryRowContext(ctx,
+			"SELECT doc FROM temp.documents_fts_row WHERE term = ?", stats.Term).Scan(&stats.Documents)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, errors.FTS5f("failed to read document frequency of %q: %w", stats.Term, err)
+		}
+
+		rows, err := conn.QueryContext(ctx, `
+			SELECT col, COUNT(*) FROM temp.documents_fts_instance
+			WHERE term = ? AND doc = ?
+			GROUP BY col`, stats.Term, id)
+		if err != nil {
+			return nil, errors.FTS5f("failed to read occurrences of %q: %w", stats.Term, err)
+		}
+
+		for row := 1; rows.Next(); row++ {
+			var column string
+			var count int
+			if err := rows.Scan(&column, &count); err != nil {
+				rows.Close()
+				return nil, errors.FTS5f("failed to scan occurrences of %q at row %d: %w", stats.Term, row, err)
+			}
+			for c, name := range IndexedColumns {
+				if name == column {
+					stats.ColumnCounts[c] = count
+				}
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, errors.FTS5f("error iterating occurrences: %w", err)
+		}
+	}
+
+	return terms, nil
+}
+
+// queryOperators are the FTS5 query keywords that are operators when written in uppercase
+var queryOperators = map[string]bool{"AND": true, "OR": true, "NOT": true, "NEAR": true}
+
+// queryTerms lists the distinct tokens of the scratch query row, pairing each
+// indexed form with the query word it came from
+func queryTerms(ctx context.Context, conn *sql.Conn) ([]TermStats, error) {
+	var text string
+	if err := conn.QueryRowContext(ctx, "SELECT query FROM temp.query_terms WHERE rowid = 1").Scan(&text); err != nil {
+		return nil, errors.FTS5f("failed to read query: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx,
+		"SELECT term FROM temp.query_terms_instance ORDER BY offset")
+	if err != nil {
+		return nil, errors.FTS5f("failed to read query terms: %w", err)
+	}
+	defer rows.Close()
+
+	words := tokens.Split(text)
+	var terms []TermStats
+	seen := make(map[string]bool)
+
+	for i := 0; rows.Next(); i++ {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, errors.FTS5f("failed to scan query term at row %d: %w", i+1, err)
+		}
+
+		word := term
+		if i < len(words) {
+			word = words[i]
+		}
+
+		// Uppercase operator keywords are query syntax, not terms
+		if queryOperators[word] || seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		terms = append(terms, TermStats{Query: word, Term: term})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.FTS5f("error iterating query terms: %w", err)
+	}
+
+	return terms, nil
+}
+
+// TermCount counts one indexed term's use across a set of documents
+type TermCount struct {
+	// Term is the term as the tokenizer indexed it (case-folded and stemmed)
+	Term string
+
+	// Occurrences is the number of times the term appears in the documents,
+	// across every indexed column
+	Occurrences int
+
+	// Documents is the number of the documents containing the term
+	Documents int
+
+	// IndexDocuments is the number of indexed rows containing the term, in any
+	// category (n in the IDF)
+	IndexDocuments int
+}
+
+// TermCounts lists the indexed terms found in at least minDocs documents, most
+// documents first and ties by term, stopping after limit terms. With
+// categories, only documents in those categories are counted; the whole index
+// is read through its row vocabulary otherwise.
+func (d *Database) TermCounts(ctx context.Context, categories []string, minDocs, limit int) ([]TermCount, error) {
+	if len(categories) == 0 {
+		if _, err := d.ExecContext(ctx, documentsVocab); err != nil {
+			return nil, errors.FTS5f("failed to create vocabulary table: %w", err)
+		}
+
+		rows, err := d.QueryContext(ctx, `
+			SELECT term, cnt, doc, doc FROM documents_fts_vocab
+			WHERE doc >= ?
+			ORDER BY doc DESC, term
+			LIMIT ?`, minDocs, limit)
+		if err != nil {
+			return nil, errors.FTS5f("failed to read index vocabulary: %w", err)
+		}
+		defer rows.Close()
+
+		return scanTermCounts(rows)
+	}
+
+	// Temp tables are per connection, so pin one for the whole lookup
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Databasef("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	for _, table := range vocabTables {
+		if _, err := conn.ExecContext(ctx, table); err != nil {
+			return nil, errors.FTS5f("failed to create vocabulary table: %w", err)
+		}
+	}
+
+	// The instance vocabulary has a row per occurrence, so it can be narrowed
+	// to the documents in the categories before counting
+	clause, args := InClause("d.category", categories)
+	args = append(args, minDocs, limit)
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT i.term, COUNT(*), COUNT(DISTINCT i.doc), r.doc
+		FROM temp.documents_fts_instance i
+		JOIN documents d ON d.id = i.doc
+		JOIN temp.documents_fts_row r ON r.term = i.term
+		WHERE `+clause+`
+		GROUP BY i.term
+		HAVING COUNT(DISTINCT i.doc) >= ?
+		ORDER BY COUNT(DISTINCT i.doc) DESC, i.term
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, errors.FTS5f("failed to read category vocabulary: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTermCounts(rows)
+}
+
+// scanTermCounts reads term, occurrence, document, and index document counts
+func scanTermCounts(rows scan.Rows) ([]TermCount, error) {
+	return scan.All(rows, "term", func(row scan.Rows) (TermCount, error) {
+		var count TermCount
+		err := row.Scan(&count.Term, &count.Occurrences, &count.Documents, &count.IndexDocuments)
+		return count, err
+	})
+}
+
+// DocumentTerm counts one indexed term's occurrences in a single document
+type DocumentTerm struct {
+	// Term is the term as the tokenizer indexed it (case-folded and stemmed)
+	Term string
+
+	// ColumnCounts holds the term's occurrences in each column, in
+	// IndexedColumns order
+	ColumnCounts []int
+}
+
+// DocumentTerms lists the distinct terms FTS5 indexed for document id, in
+// term order, read from the instance vocabulary so they are exactly the
+// tokens bm25 sees. A document missing from the index has no terms.
+func (d *Database) DocumentTerms(ctx context.Context, id int64) ([]DocumentTerm, error) {
+	// Temp tables are per connection, so pin one for the whole lookup
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Databasef("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	for _, table := range vocabTables {
+		if _, err := conn.ExecContext(ctx, table); err != nil {
+			return nil, errors.FTS5f("failed to create vocabulary table: %w", err)
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT term, col, COUNT(*) FROM temp.documents_fts_instance
+		WHERE doc = ?
+		GROUP BY term, col
+		ORDER BY term`, id)
+	if err != nil {
+		return nil, errors.FTS5f("failed to read terms of document %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	var terms []DocumentTerm
+	_, err = scan.Each(rows, "document term", func(row scan.Rows) error {
+		var term, column string
+		var count int
+		if err := row.Scan(&term, &column, &count); err != nil {
+			return err
+		}
+
+		if len(terms) == 0 || terms[len(terms)-1].Term != term {
+			terms = append(terms, DocumentTerm{Term: term, ColumnCounts: make([]int, len(IndexedColumns))})
+		}
+		for c, name := range IndexedColumns {
+			if name == column {
+				terms[len(terms)-1].ColumnCounts[c] = count
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
@@ -0,0 +1,93 @@
+//go:build fts5
+
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// openVocabDatabase creates a database at path holding two documents whose
+// index has 8 distinct terms and 9 postings:
+//
+//	"red apple" / "red fruit" / "food":     red, appl, fruit, food
+//	"green leaf" / "fruit salad" / "plant": green, leaf, fruit, salad, plant
+func openVocabDatabase(t *testing.T, path string) *Database {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.InitSchema(ctx); err != nil {
+		db.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	for _, doc := range [][3]string{
+		{"red apple", "red fruit", "food"},
+		{"green leaf", "fruit salad", "plant"},
+	} {
+		if _, err := db.ExecContext(ctx, "INSERT INTO documents (title, content, category) VALUES (?, ?, ?)", doc[0], doc[1], doc[2]); err != nil {
+			db.Close()
+			t.Fatalf("insert document: %v", err)
+		}
+	}
+	return db
+}
+
+func TestVocabulary(t *testing.T) {
+	db := openVocabDatabase(t, filepath.Join(t.TempDir(), "vocab.db"))
+	defer db.Close()
+
+	vocab, err := db.Vocabulary(context.Background())
+	if err != nil {
+		t.Fatalf("Vocabulary: %v", err)
+	}
+	if vocab.Terms != 8 || vocab.Postings != 9 {
+		t.Errorf("Vocabulary = %d terms and %d postings, want 8 and 9", vocab.Terms, vocab.Postings)
+	}
+}
+
+// TestVocabularyCreatesMissingTable opens a database made before the
+// vocabulary table was part of the schema; the first count creates it
+func TestVocabularyCreatesMissingTable(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vocab.db")
+
+	db := openVocabDatabase(t, path)
+	if _, err := db.ExecContext(ctx, "DROP TABLE documents_fts_vocab"); err != nil {
+		t.Fatalf("drop vocabulary table: %v", err)
+	}
+	db.Close()
+
+	db, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("reopen database: %v", err)
+	}
+	defer db.Close()
+
+	tables := func() int {
+		var count int
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'documents_fts_vocab'").Scan(&count)
+		if err != nil {
+			t.Fatalf("look up vocabulary table: %v", err)
+		}
+		return count
+	}
+	if tables() != 0 {
+		t.Fatal("vocabulary table still present after it was dropped")
+	}
+
+	vocab, err := db.Vocabulary(ctx)
+	if err != nil {
+		t.Fatalf("Vocabulary: %v", err)
+	}
+	if vocab.Terms != 8 || vocab.Postings != 9 {
+		t.Errorf("Vocabulary = %d terms and %d postings, want 8 and 9", vocab.Terms, vocab.Postings)
+	}
+	if tables() != 1 {
+		t.Error("Vocabulary did not create the missing vocabulary table")
+	}
+}
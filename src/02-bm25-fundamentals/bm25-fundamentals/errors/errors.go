@@ -2,143 +2,119 @@ package errors
 
 import (
 	"errors"
-	"fmt"
-	"os"
-	"strings"
 
-	"github.com/spf13/viper"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	shared "github.com/jaime/go-sqlite/shared/errors"
 )
 
 // Sentinel errors for type checking
 var (
-	ErrValidation   = errors.New("validation failed")
-	ErrDatabase     = errors.New("database operation failed")
-	ErrFTS5         = errors.New("FTS5 operation failed")
-	ErrNotFound     = errors.New("not found")
-	ErrTransaction  = errors.New("transaction failed")
-	ErrAnalysis     = errors.New("analysis failed")
+	ErrValidation  = shared.ErrValidation
+	ErrDatabase    = shared.ErrDatabase
+	ErrFTS5        = shared.ErrFTS5
+	ErrNotFound    = shared.ErrNotFound
+	ErrTransaction = shared.ErrTransaction
+	ErrCancelled   = shared.ErrCancelled
+
+	// Sentinels specific to the BM25 analysis tooling
+	ErrAnalysis      = errors.New("analysis failed")
 	ErrVisualization = errors.New("visualization failed")
 )
 
 // Error creation helpers
 func Validationf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrValidation}, args...)...)
+	return shared.Validationf(format, args...)
 }
 
 func Databasef(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrDatabase}, args...)...)
+	return shared.Databasef(format, args...)
 }
 
 func FTS5f(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrFTS5}, args...)...)
+	return shared.FTS5f(format, args...)
 }
 
 func NotFoundf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrNotFound}, args...)...)
+	return shared.NotFoundf(format, args...)
 }
 
 func Transactionf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrTransaction}, args...)...)
+	return shared.Transactionf(format, args...)
 }
 
 func Analysisf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrAnalysis}, args...)...)
+	return shared.Wrapf(ErrAnalysis, format, args...)
 }
 
 func Visualizationf(format string, args ...interface{}) error {
-	return fmt.Errorf("%w: "+format, append([]interface{}{ErrVisualization}, args...)...)
+	return shared.Wrapf(ErrVisualization, format, args...)
 }
 
-// DisplayError shows the error in an appropriate format based on verbose flag
-func DisplayError(err error) {
-	if err == nil {
-		return
-	}
-
-	if viper.GetBool("verbose") {
-		displayVerboseError(err)
-	} else {
-		displaySimpleError(err)
-	}
+func Cancelledf(format string, args ...interface{}) error {
+	return shared.Cancelledf(format, args...)
 }
 
-// displaySimpleError shows a user-friendly error message
-func displaySimpleError(err error) {
-	switch {
-	case errors.Is(err, ErrValidation):
-		fmt.Fprintf(os.Stderr, "Validation Error: %v\n", unwrapError(err))
-	case errors.Is(err, ErrDatabase):
-		fmt.Fprintf(os.Stderr, "Database Error: %v\n", unwrapError(err))
-		fmt.Fprintln(os.Stderr, "Hint: Check that the database file exists and is accessible")
-	case errors.Is(err, ErrFTS5):
-		fmt.Fprintf(os.Stderr, "FTS5 Error: %v\n", unwrapError(err))
-		fmt.Fprintln(os.Stderr, "Hint: Ensure SQLite is compiled with FTS5 support (go build -tags fts5)")
-	case errors.Is(err, ErrNotFound):
-		fmt.Fprintf(os.Stderr, "Not Found: %v\n", unwrapError(err))
-	case errors.Is(err, ErrTransaction):
-		fmt.Fprintf(os.Stderr, "Transaction Error: %v\n", unwrapError(err))
-		fmt.Fprintln(os.Stderr, "Hint: The operation was rolled back; no changes were made")
-	case errors.Is(err, ErrAnalysis):
-		fmt.Fprintf(os.Stderr, "Analysis Error: %v\n", unwrapError(err))
-		fmt.Fprintln(os.Stderr, "Hint: Check that you have search results to analyze")
-	case errors.Is(err, ErrVisualization):
-		fmt.Fprintf(os.Stderr, "Visualization Error: %v\n", unwrapError(err))
-		fmt.Fprintln(os.Stderr, "Hint: Check terminal width and visualization settings")
-	default:
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-	}
+// display strips the sentinel prefix from messages and prints a full report in verbose mode
+var display = &shared.Display{
+	Registry: shared.NewRegistry(
+		shared.Category{
+			Sentinel:    ErrValidation,
+			Label:       "Validation Error",
+			Description: "Validation Error - Input validation failed",
+		},
+		shared.Category{
+			Sentinel:    ErrDatabase,
+			Label:       "Database Error",
+			Hint:        "Check that the database file exists and is accessible",
+			Description: "Database Error - SQLite operation failed",
+		},
+		shared.Category{
+			Sentinel:    ErrFTS5,
+			Label:       "FTS5 Error",
+			Hint:        "Ensure SQLite is compiled with FTS5 support (go build -tags fts5)",
+			Description: "FTS5 Error - Full-text search operation failed",
+		},
+		shared.Category{
+			Sentinel:    ErrNotFound,
+			Label:       "Not Found",
+			Description: "Not Found Error - Requested resource does not exist",
+		},
+		shared.Category{
+			Sentinel:    ErrTransaction,
+			Label:       "Transaction Error",
+			Hint:        "The operation was rolled back; no changes were made",
+			Description: "Transaction Error - Database transaction failed",
+		},
+		shared.Category{
+			Sentinel:    ErrAnalysis,
+			Label:       "Analysis Error",
+			Hint:        "Check that you have search results to analyze",
+			Description: "Analysis Error - Score analysis operation failed",
+		},
+		shared.Category{
+			Sentinel:    ErrVisualization,
+			Label:       "Visualization Error",
+			Hint:        "Check terminal width and visualization settings",
+			Description: "Visualization Error - Chart rendering failed",
+		},
+		shared.Category{
+			Sentinel:    ErrCancelled,
+			Label:       "Cancelled",
+			Description: "Cancelled - Interrupted by a signal before completion",
+		},
+	),
+	HintPrefix: "Hint: ",
+	Message:    shared.StripSentinel,
+	Verbose:    shared.VerboseReport,
 }
 
-// displayVerboseError shows the full error chain for debugging
-func displayVerboseError(err error) {
-	fmt.Fprintln(os.Stderr, "=== VERBOSE ERROR OUTPUT ===")
-	fmt.Fprintf(os.Stderr, "Error Type: %T\n", err)
-	
-	// Display full error chain
-	fmt.Fprintln(os.Stderr, "\nError Chain:")
-	currentErr := err
-	depth := 0
-	for currentErr != nil {
-		indent := strings.Repeat("  ", depth)
-		fmt.Fprintf(os.Stderr, "%s└─ %v\n", indent, currentErr)
-		currentErr = errors.Unwrap(currentErr)
-		depth++
-	}
-	
-	// Display categorized error type
-	fmt.Fprintln(os.Stderr, "\nError Category:")
-	switch {
-	case errors.Is(err, ErrValidation):
-		fmt.Fprintln(os.Stderr, "  Validation Error - Input validation failed")
-	case errors.Is(err, ErrDatabase):
-		fmt.Fprintln(os.Stderr, "  Database Error - SQLite operation failed")
-	case errors.Is(err, ErrFTS5):
-		fmt.Fprintln(os.Stderr, "  FTS5 Error - Full-text search operation failed")
-	case errors.Is(err, ErrNotFound):
-		fmt.Fprintln(os.Stderr, "  Not Found Error - Requested resource does not exist")
-	case errors.Is(err, ErrTransaction):
-		fmt.Fprintln(os.Stderr, "  Transaction Error - Database transaction failed")
-	case errors.Is(err, ErrAnalysis):
-		fmt.Fprintln(os.Stderr, "  Analysis Error - Score analysis operation failed")
-	case errors.Is(err, ErrVisualization):
-		fmt.Fprintln(os.Stderr, "  Visualization Error - Chart rendering failed")
-	default:
-		fmt.Fprintln(os.Stderr, "  Uncategorized Error")
-	}
-	
-	fmt.Fprintln(os.Stderr, "\n=== END VERBOSE OUTPUT ===")
+// DisplayError shows the error in an appropriate format based on verbose flag
+func DisplayError(err error) {
+	display.Show(err, config.Viper.GetBool("verbose"))
 }
 
-// unwrapError gets the deepest error message without the wrapping context
-func unwrapError(err error) string {
-	if err == nil {
-		return ""
-	}
-	
-	// Get the wrapped error message by removing the prefix
-	parts := strings.SplitN(err.Error(), ": ", 2)
-	if len(parts) > 1 {
-		return parts[1]
-	}
-	return err.Error()
-}
\ No newline at end of file
+// Message returns the error message without its leading sentinel text, for
+// listing errors that are reported rather than returned
+func Message(err error) string {
+	return shared.StripSentinel(err)
+}
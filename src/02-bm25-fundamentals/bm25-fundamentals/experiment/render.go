@@ -0,0 +1,200 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/numfmt"
+)
+
+// Write renders the report in the given format (text, json, csv, or html).
+// Text and HTML write counts and metrics with numbers; JSON and CSV keep plain
+// numbers for scripts.
+func Write(w io.Writer, report *Report, format string, numbers *numfmt.Format) error {
+	switch format {
+	case "text":
+		return writeText(w, report, numbers)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "csv":
+		return writeCSV(w, report)
+	case "html":
+		return template.Must(reportTemplate.Clone()).Funcs(templateFuncs(numbers)).Execute(w, report)
+	default:
+		return errors.Validationf("invalid report format: %s (must be text, json, csv, or html)", format)
+	}
+}
+
+// writeText renders the report for the terminal
+func writeText(w io.Writer, report *Report, numbers *numfmt.Format) error {
+	fmt.Fprintf(w, "Experiment: %s\n", report.Name)
+	fmt.Fprintf(w, "=====================================\n\n")
+
+	fmt.Fprintf(w, "Corpus: %s, %s documents", report.Corpus.Source, numbers.Int(report.Corpus.Documents))
+	if report.Corpus.Seed != 0 {
+		fmt.Fprintf(w, " (seed %d)", report.Corpus.Seed)
+	}
+	if report.Corpus.Fingerprint != "" {
+		fmt.Fprintf(w, "\nCorpus fingerprint: %s", report.Corpus.Fingerprint)
+	}
+	fmt.Fprintf(w, "\nMax results per query: %s\n\n", numbers.Int(report.MaxResults))
+
+	fmt.Fprintf(w, "Strategy Summary:\n")
+	fmt.Fprintf(w, "%-20s %-8s %-10s %-10s %-8s %-8s %-8s %-8s\n",
+		"Strategy", "Judged", "Retrieved", "Precision", "MAP", "NDCG", "Overlap", "Spearman")
+	for _, summary := range report.Strategies {
+		fmt.Fprintf(w, "%-20s %-8s %-10s %-10s %-8s %-8s %-8s %-8s\n",
+			summary.Name, fmt.Sprintf("%d/%d", summary.JudgedQueries, summary.Queries),
+			numbers.Float(summary.MeanRetrieved, 1), numbers.Float(summary.MeanPrecision, 4),
+			numbers.Float(summary.MAP, 4), numbers.Float(summary.MeanNDCG, 4),
+			numbers.Float(summary.MeanOverlap, 4), formatMetric(numbers, summary.MeanSpearman))
+	}
+	fmt.Fprintf(w, "\n")
+
+	fmt.Fprintf(w, "Query Runs:\n")
+	fmt.Fprintf(w, "%-20s %-30s %-10s %-10s %-10s %-8s %-8s\n",
+		"Strategy", "Query", "Retrieved", "Top Score", "Precision", "AP", "NDCG")
+	for _, run := range report.Runs {
+		precision, ap, ndcg := "-", "-", "-"
+		if run.Judged {
+			precision = numbers.Float(run.Precision, 4)
+			ap = numbers.Float(run.AveragePrecision, 4)
+			ndcg = numbers.Float(run.NDCG, 4)
+		}
+		fmt.Fprintf(w, "%-20s %-30s %-10s %-10s %-10s %-8s %-8s\n",
+			run.Strategy, truncate(run.Query, 30), numbers.Int(run.Retrieved),
+			numbers.Float(run.TopScore, 4), precision, ap, ndcg)
+	}
+
+	if len(report.Correlations) > 0 {
+		fmt.Fprintf(w, "\nCorrelation with %s:\n", report.Strategies[0].Name)
+		fmt.Fprintf(w, "%-20s %-30s %-8s %-8s %-8s\n", "Strategy", "Query", "Common", "Overlap", "Spearman")
+		for _, correlation := range report.Correlations {
+			fmt.Fprintf(w, "%-20s %-30s %-8s %-8s %-8s\n",
+				correlation.Strategy, truncate(correlation.Query, 30), numbers.Int(correlation.CommonDocuments),
+				numbers.Float(correlation.Overlap, 4), formatMetric(numbers, correlation.Spearman))
+		}
+	}
+
+	return nil
+}
+
+// writeCSV renders one row per query run, joined with its correlation to the baseline
+func writeCSV(w io.Writer, report *Report) error {
+	correlations := make(map[[2]string]Correlation, len(report.Correlations))
+	for _, correlation := range report.Correlations {
+		correlations[[2]string{correlation.Strategy, correlation.Query}] = correlation
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"strategy", "query", "retrieved", "top_score", "mean_score", "judged",
+		"precision", "recall", "average_precision", "ndcg", "overlap", "spearman"})
+
+	for _, run := range report.Runs {
+		overlap, spearman := "1.0000", "1.0000" // The baseline agrees with itself
+		if correlation, ok := correlations[[2]string{run.Strategy, run.Query}]; ok {
+			overlap = strconv.FormatFloat(correlation.Overlap, 'f', 4, 64)
+			spearman = formatOptional(correlation.Spearman)
+		}
+
+		writer.Write([]string{
+			run.Strategy,
+			run.Query,
+			strconv.Itoa(run.Retrieved),
+			strconv.FormatFloat(run.TopScore, 'f', 4, 64),
+			strconv.FormatFloat(run.MeanScore, 'f', 4, 64),
+			strconv.FormatBool(run.Judged),
+			strconv.FormatFloat(run.Precision, 'f', 4, 64),
+			strconv.FormatFloat(run.Recall, 'f', 4, 64),
+			strconv.FormatFloat(run.AveragePrecision, 'f', 4, 64),
+			strconv.FormatFloat(run.NDCG, 'f', 4, 64),
+			overlap,
+			spearman,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatOptional renders an optional metric, using "-" when it is undefined
+func formatOptional(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*value, 'f', 4, 64)
+}
+
+// formatMetric renders an optional metric for people, using "-" when it is
+// undefined
+func formatMetric(numbers *numfmt.Format, value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return numbers.Float(*value, 4)
+}
+
+// truncate shortens s to at most width characters for table columns
+func truncate(s string, width int) string {
+	if len(s) <= width {
+		return s
+	}
+	return s[:width-3] + "..."
+}
+
+// templateFuncs formats the numbers in the HTML report
+func templateFuncs(numbers *numfmt.Format) template.FuncMap {
+	return template.FuncMap{
+		"count":    numbers.Int,
+		"decimal":  numbers.Float,
+		"metric":   func(value float64) string { return numbers.Float(value, 4) },
+		"optional": func(value *float64) string { return formatMetric(numbers, value) },
+	}
+}
+
+// reportTemplate renders a self-contained HTML report. Write supplies the
+// number formats for each report.
+var reportTemplate = template.Must(template.New("report").Funcs(templateFuncs(nil)).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Experiment: {{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td.text { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Experiment: {{.Name}}</h1>
+<p>Corpus: {{.Corpus.Source}}, {{count .Corpus.Documents}} documents{{if .Corpus.Seed}} (seed {{.Corpus.Seed}}){{end}}{{if .Corpus.Fingerprint}}, fingerprint <code>{{.Corpus.Fingerprint}}</code>{{end}}. Max results per query: {{count .MaxResults}}.</p>
+
+<h2>Strategy Summary</h2>
+<table>
+<tr><th>Strategy</th><th>Weights</th><th>Judged</th><th>Mean Retrieved</th><th>Mean Precision</th><th>Mean Recall</th><th>MAP</th><th>Mean NDCG</th><th>Mean Overlap</th><th>Mean Spearman</th></tr>
+{{range .Strategies}}<tr><td>{{.Name}}</td><td class="text">{{range $field, $weight := .Weights}}{{$field}}:{{$weight}} {{end}}</td><td>{{.JudgedQueries}}/{{.Queries}}</td><td>{{decimal .MeanRetrieved 1}}</td><td>{{metric .MeanPrecision}}</td><td>{{metric .MeanRecall}}</td><td>{{metric .MAP}}</td><td>{{metric .MeanNDCG}}</td><td>{{metric .MeanOverlap}}</td><td>{{optional .MeanSpearman}}</td></tr>
+{{end}}</table>
+
+<h2>Query Runs</h2>
+<table>
+<tr><th>Strategy</th><th>Query</th><th>Retrieved</th><th>Top Score</th><th>Mean Score</th><th>Precision</th><th>Recall</th><th>AP</th><th>NDCG</th></tr>
+{{range .Runs}}<tr><td>{{.Strategy}}</td><td class="text">{{.Query}}</td><td>{{count .Retrieved}}</td><td>{{metric .TopScore}}</td><td>{{metric .MeanScore}}</td>{{if .Judged}}<td>{{metric .Precision}}</td><td>{{metric .Recall}}</td><td>{{metric .AveragePrecision}}</td><td>{{metric .NDCG}}</td>{{else}}<td>-</td><td>-</td><td>-</td><td>-</td>{{end}}</tr>
+{{end}}</table>
+{{if .Correlations}}
+<h2>Correlation with Baseline</h2>
+<table>
+<tr><th>Strategy</th><th>Query</th><th>Common</th><th>Overlap</th><th>Spearman</th></tr>
+{{range .Correlations}}<tr><td>{{.Strategy}}</td><td class="text">{{.Query}}</td><td>{{count .CommonDocuments}}</td><td>{{metric .Overlap}}</td><td>{{optional .Spearman}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,345 @@
+package experiment
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/stats"
+)
+
+// Report combines the results of every query under every strategy of an experiment
+type Report struct {
+	Name         string            `json:"name"`
+	Corpus       CorpusSummary     `json:"corpus"`
+	MaxResults   int               `json:"max_results"`
+	Strategies   []StrategySummary `json:"strategies"`
+	Runs         []QueryRun        `json:"runs"`
+	Correlations []Correlation     `json:"correlations"` // Each strategy against the baseline, per query
+}
+
+// CorpusSummary records where the experiment corpus came from
+type CorpusSummary struct {
+	Source    string `json:"source"` // "generated" or the import file path
+	Seed      int64  `json:"seed,omitempty"`
+	Documents int    `json:"documents"`
+
+	// Fingerprint is the SHA-256 of the built corpus, as 'corpus fingerprint' prints it
+	Fingerprint string `json:"fingerprint"`
+}
+
+// StrategySummary aggregates a strategy's runs across all queries
+type StrategySummary struct {
+	Name          string             `json:"name"`
+	Weights       map[string]float64 `json:"weights,omitempty"`
+	Queries       int                `json:"queries"`
+	JudgedQueries int                `json:"judged_queries"`
+	MeanRetrieved float64            `json:"mean_retrieved"`
+	MeanPrecision float64            `json:"mean_precision"` // Averaged over judged queries
+	MeanRecall    float64            `json:"mean_recall"`
+	MAP           float64            `json:"map"`       // Mean average precision over judged queries
+	MeanNDCG      float64            `json:"mean_ndcg"` // Mean NDCG@max_results over judged queries
+	MeanOverlap   float64            `json:"mean_overlap"`
+	MeanSpearman  *float64           `json:"mean_spearman,omitempty"` // nil when no query had two common documents
+}
+
+// QueryRun is the outcome of one query under one strategy
+type QueryRun struct {
+	Strategy         string  `json:"strategy"`
+	Query            string  `json:"query"`
+	Retrieved        int     `json:"retrieved"`
+	DocumentIDs      []int64 `json:"document_ids"` // In rank order
+	TopScore         float64 `json:"top_score"`
+	MeanScore        float64 `json:"mean_score"`
+	Judged           bool    `json:"judged"`
+	Relevant         int     `json:"relevant,omitempty"`
+	Precision        float64 `json:"precision"`
+	Recall           float64 `json:"recall"`
+	AveragePrecision float64 `json:"average_precision"`
+	NDCG             float64 `json:"ndcg"`
+}
+
+// Correlation compares a strategy's ranking for one query with the baseline's
+type Correlation struct {
+	Strategy        string   `json:"strategy"`
+	Query           string   `json:"query"`
+	CommonDocuments int      `json:"common_documents"`
+	Overlap         float64  `json:"overlap"`            // Jaccard similarity of the result sets
+	Spearman        *float64 `json:"spearman,omitempty"` // Rank correlation over common documents; nil below two
+}
+
+// NewReport starts an empty report for spec
+func NewReport(spec *Spec, corpus CorpusSummary) *Report {
+	return &Report{
+		Name:         spec.Name,
+		Corpus:       corpus,
+		MaxResults:   spec.MaxResults,
+		Strategies:   make([]StrategySummary, 0, len(spec.Strategies)),
+		Runs:         make([]QueryRun, 0, len(spec.Strategies)*len(spec.Queries)),
+		Correlations: make([]Correlation, 0),
+	}
+}
+
+// AddRun records the results of query under strategy. Judgments map document IDs to
+// graded relevance (0 = not relevant); a nil map leaves the run unjudged.
+func (r *Report) AddRun(strategy, query string, results []*models.SearchResult, judgments map[int64]int) {
+	run := QueryRun{
+		Strategy:    strategy,
+		Query:       query,
+		Retrieved:   len(results),
+		DocumentIDs: make([]int64, len(results)),
+	}
+
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		run.DocumentIDs[i] = result.ID
+		scores[i] = result.Score
+	}
+	if len(results) > 0 {
+		run.TopScore = results[0].Score
+		run.MeanScore = stats.Mean(scores)
+	}
+
+	if judgments != nil {
+		run.Judged = true
+		evaluateRun(&run, judgments, r.MaxResults)
+	}
+
+	r.Runs = append(r.Runs, run)
+}
+
+// Finalize computes the per-strategy summaries and the correlations of each strategy
+// against the baseline (the first strategy in spec order)
+func (r *Report) Finalize(spec *Spec) {
+	runs := make(map[string]map[string]QueryRun, len(spec.Strategies))
+	for _, run := range r.Runs {
+		if runs[run.Strategy] == nil {
+			runs[run.Strategy] = make(map[string]QueryRun)
+		}
+		runs[run.Strategy][run.Query] = run
+	}
+
+	baseline := spec.Strategies[0].Name
+
+	for _, strategy := range spec.Strategies {
+		summary := StrategySummary{
+			Name:    strategy.Name,
+			Weights: strategy.Weights,
+		}
+
+		spearmanSum, spearmanCount := 0.0, 0
+		for _, query := range spec.Queries {
+			run := runs[strategy.Name][query.Query]
+			summary.Queries++
+			summary.MeanRetrieved += float64(run.Retrieved)
+
+			if run.Judged {
+				summary.JudgedQueries++
+				summary.MeanPrecision += run.Precision
+				summary.MeanRecall += run.Recall
+				summary.MAP += run.AveragePrecision
+				summary.MeanNDCG += run.NDCG
+			}
+
+			correlation := correlate(runs[baseline][query.Query], run)
+			summary.MeanOverlap += correlation.Overlap
+			if correlation.Spearman != nil {
+				spearmanSum += *correlation.Spearman
+				spearmanCount++
+			}
+
+			if strategy.Name != baseline {
+				r.Correlations = append(r.Correlations, correlation)
+			}
+		}
+
+		if summary.Queries > 0 {
+			summary.MeanRetrieved /= float64(summary.Queries)
+			summary.MeanOverlap /= float64(summary.Queries)
+		}
+		if summary.JudgedQueries > 0 {
+			judged := float64(summary.JudgedQueries)
+			summary.MeanPrecision /= judged
+			summary.MeanRecall /= judged
+			summary.MAP /= judged
+			summary.MeanNDCG /= judged
+		}
+		if spearmanCount > 0 {
+			meanSpearman := spearmanSum / float64(spearmanCount)
+			summary.MeanSpearman = &meanSpearman
+		}
+
+		r.Strategies = append(r.Strategies, summary)
+	}
+}
+
+// evaluateRun fills in the relevance metrics of a judged run
+func evaluateRun(run *QueryRun, judgments map[int64]int, k int) {
+	for _, grade := range judgments {
+		if grade > 0 {
+			run.Relevant++
+		}
+	}
+
+	relevantRetrieved := 0
+	precisionSum := 0.0
+	for i, id := range run.DocumentIDs {
+		if judgments[id] > 0 {
+			relevantRetrieved++
+			precisionSum += float64(relevantRetrieved) / float64(i+1)
+		}
+	}
+
+	if run.Retrieved > 0 {
+		run.Precision = float64(relevantRetrieved) / float64(run.Retrieved)
+	}
+	if run.Relevant > 0 {
+		run.Recall = float64(relevantRetrieved) / float64(run.Relevant)
+		run.AveragePrecision = precisionSum / float64(run.Relevant)
+	}
+
+	run.NDCG = NDCG(run.DocumentIDs, judgments, k)
+}
+
+// NDCG computes normalized discounted cumulative gain at rank k for a ranked list of
+// document IDs, using graded judgments with gain 2^grade - 1. It is 0 when no
+// document is judged relevant.
+func NDCG(ranked []int64, judgments map[int64]int, k int) float64 {
+	dcg := 0.0
+	for i, id := range ranked {
+		if i >= k {
+			break
+		}
+		dcg += gain(judgments[id]) / math.Log2(float64(i+2))
+	}
+
+	// The ideal ranking places the highest grades first
+	grades := make([]int, 0, len(judgments))
+	for _, grade := range judgments {
+		if grade > 0 {
+			grades = append(grades, grade)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(grades)))
+
+	idcg := 0.0
+	for i, grade := range grades {
+		if i >= k {
+			break
+		}
+		idcg += gain(grade) / math.Log2(float64(i+2))
+	}
+
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+// gain converts a relevance grade to its DCG gain
+func gain(grade int) float64 {
+	if grade <= 0 {
+		return 0
+	}
+	return math.Pow(2, float64(grade)) - 1
+}
+
+// correlate measures how far a run's ranking departs from the baseline run of the same query
+func correlate(baseline, run QueryRun) Correlation {
+	correlation := Correlation{
+		Strategy: run.Strategy,
+		Query:    run.Query,
+	}
+
+	pairs := RankPairs(baseline.DocumentIDs, run.DocumentIDs)
+	correlation.CommonDocuments = len(pairs)
+	union := len(baseline.DocumentIDs) + len(run.DocumentIDs) - len(pairs)
+	if union > 0 {
+		correlation.Overlap = float64(len(pairs)) / float64(union)
+	}
+
+	correlation.Spearman = Spearman(pairs)
+	return correlation
+}
+
+// RankPairs pairs the positions of each document found in both rankings as
+// {baseline position, run position}, in run order
+func RankPairs(baseline, run []int64) [][2]int {
+	baselineRanks := make(map[int64]int, len(baseline))
+	for i, id := range baseline {
+		baselineRanks[id] = i
+	}
+
+	var pairs [][2]int
+	for i, id := range run {
+		if baselineRank, ok := baselineRanks[id]; ok {
+			pairs = append(pairs, [2]int{baselineRank, i})
+		}
+	}
+	return pairs
+}
+
+// Spearman computes the Spearman rank correlation of paired positions. Positions are
+// re-ranked among the pairs so documents outside the common set do not count; nil is
+// returned for fewer than two pairs, where the correlation is undefined.
+func Spearman(pairs [][2]int) *float64 {
+	n := len(pairs)
+	if n < 2 {
+		return nil
+	}
+
+	rankA := ranksOf(pairs, 0)
+	rankB := ranksOf(pairs, 1)
+
+	sumSquares := 0.0
+	for i := range pairs {
+		d := float64(rankA[i] - rankB[i])
+		sumSquares += d * d
+	}
+
+	rho := 1 - (6*sumSquares)/(float64(n)*(float64(n*n)-1))
+	return &rho
+}
+
+// ranksOf returns the rank of each pair's positions[side] among all pairs
+func ranksOf(pairs [][2]int, side int) []int {
+	order := make([]int, len(pairs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return pairs[order[a]][side] < pairs[order[b]][side]
+	})
+
+	ranks := make([]int, len(pairs))
+	for rank, index := range order {
+		ranks[index] = rank
+	}
+	return ranks
+}
+
+// KendallTau computes the Kendall rank correlation (tau-a) of paired positions:
+// the share of concordant minus discordant document pairs. Like Spearman it
+// only sees the order of the pairs, and returns nil for fewer than two.
+func KendallTau(pairs [][2]int) *float64 {
+	n := len(pairs)
+	if n < 2 {
+		return nil
+	}
+
+	balance := 0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			a := pairs[i][0] - pairs[j][0]
+			b := pairs[i][1] - pairs[j][1]
+			if (a < 0) == (b < 0) {
+				balance++
+			} else {
+				balance--
+			}
+		}
+	}
+
+	tau := float64(balance) / float64(n*(n-1)/2)
+	return &tau
+}
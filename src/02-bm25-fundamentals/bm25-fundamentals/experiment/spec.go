@@ -0,0 +1,206 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMaxResults bounds each query when the spec does not set max_results
+const defaultMaxResults = 10
+
+// Spec is a declarative experiment: one corpus, a set of queries, and the
+// strategies every query is run under
+type Spec struct {
+	Name       string         `yaml:"name" json:"name"`
+	Corpus     CorpusSpec     `yaml:"corpus" json:"corpus"`
+	Queries    []QuerySpec    `yaml:"queries" json:"queries"`
+	Strategies []StrategySpec `yaml:"strategies" json:"strategies"`
+	MaxResults int            `yaml:"max_results" json:"max_results"`
+}
+
+// CorpusSpec describes the corpus an experiment runs against: either a synthetic
+// corpus (seed, size, and generation options) or documents imported from a JSON file
+type CorpusSpec struct {
+	Import            string                   `yaml:"import,omitempty" json:"import,omitempty"` // JSON array of documents
+	Seed              int64                    `yaml:"seed,omitempty" json:"seed,omitempty"`
+	Size              int                      `yaml:"size,omitempty" json:"size,omitempty"`
+	Style             string                   `yaml:"style,omitempty" json:"style,omitempty"`
+	Categories        []string                 `yaml:"categories,omitempty" json:"categories,omitempty"`
+	MinTokens         int                      `yaml:"min_tokens,omitempty" json:"min_tokens,omitempty"`
+	MaxTokens         int                      `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	TermRates         map[string]float64       `yaml:"term_rates,omitempty" json:"term_rates,omitempty"`
+	Inject            []models.PhraseInjection `yaml:"inject,omitempty" json:"inject,omitempty"`
+	DuplicateRate     float64                  `yaml:"duplicate_rate,omitempty" json:"duplicate_rate,omitempty"`
+	NearDuplicateRate float64                  `yaml:"near_duplicate_rate,omitempty" json:"near_duplicate_rate,omitempty"`
+}
+
+// QuerySpec is a query to run under every strategy, with optional graded relevance
+// judgments keyed by document ID. Queries matching a phrase injected by the corpus
+// spec are judged automatically from the generation manifest.
+type QuerySpec struct {
+	Query     string        `yaml:"query" json:"query"`
+	Category  string        `yaml:"category,omitempty" json:"category,omitempty"`
+	Judgments map[int64]int `yaml:"judgments,omitempty" json:"judgments,omitempty"`
+}
+
+// StrategySpec is a named search configuration; the first strategy is the baseline
+// the others are correlated against
+type StrategySpec struct {
+	Name    string             `yaml:"name" json:"name"`
+	Weights map[string]float64 `yaml:"weights,omitempty" json:"weights,omitempty"`
+}
+
+// LoadSpec reads and validates an experiment spec from a YAML file
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NotFoundf("experiment file %s: %w", path, err)
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, errors.Validationf("failed to parse experiment file %s: %w", path, err)
+	}
+
+	if spec.MaxResults == 0 {
+		spec.MaxResults = defaultMaxResults
+	}
+
+	// Import paths are relative to the experiment file, so specs can travel with their data
+	if spec.Corpus.Import != "" && !filepath.IsAbs(spec.Corpus.Import) {
+		spec.Corpus.Import = filepath.Join(filepath.Dir(path), spec.Corpus.Import)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// Validate checks the spec for missing or inconsistent entries. Corpus generation
+// options are validated separately by the corpus handler.
+func (s *Spec) Validate() error {
+	if s.MaxResults < 1 {
+		return errors.Validationf("max_results must be at least 1, got %d", s.MaxResults)
+	}
+
+	if s.Corpus.Import != "" && s.Corpus.Size > 0 {
+		return errors.Validationf("corpus must set either import or size, not both")
+	}
+
+	if len(s.Queries) == 0 {
+		return errors.Validationf("experiment must declare at least one query")
+	}
+	queries := make(map[string]bool, len(s.Queries))
+	for i, query := range s.Queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return errors.Validationf("query %d is empty", i+1)
+		}
+		if queries[query.Query] {
+			return errors.Validationf("query %q is declared more than once", query.Query)
+		}
+		queries[query.Query] = true
+
+		// Report the lowest offending document so the error is the same every run
+		ids := make([]int64, 0, len(query.Judgments))
+		for id := range query.Judgments {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		for _, id := range ids {
+			if grade := query.Judgments[id]; grade < 0 {
+				return errors.Validationf("query %q: judgment for document %d must not be negative, got %d", query.Query, id, grade)
+			}
+		}
+	}
+
+	if len(s.Strategies) == 0 {
+		return errors.Validationf("experiment must declare at least one strategy")
+	}
+	names := make(map[string]bool, len(s.Strategies))
+	for i, strategy := range s.Strategies {
+		if strings.TrimSpace(strategy.Name) == "" {
+			return errors.Validationf("strategy %d has no name", i+1)
+		}
+		if names[strategy.Name] {
+			return errors.Validationf("strategy %q is declared more than once", strategy.Name)
+		}
+		names[strategy.Name] = true
+
+		if err := flagutil.ValidateWeights(strategy.Weights); err != nil {
+			return fmt.Errorf("strategy %q: %w", strategy.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// CorpusOptions converts the synthetic corpus spec into generation options,
+// starting from the defaults for any field the spec leaves unset
+func (c CorpusSpec) CorpusOptions() models.CorpusOptions {
+	options := models.DefaultCorpusOptions()
+
+	if c.Size > 0 {
+		options.Size = c.Size
+	}
+	if c.Seed != 0 {
+		options.Seed = c.Seed
+	}
+	if c.Style != "" {
+		options.Style = c.Style
+	}
+	if len(c.Categories) > 0 {
+		options.Categories = c.Categories
+	}
+	if c.MinTokens > 0 {
+		options.MinTokens = c.MinTokens
+	}
+	if c.MaxTokens > 0 {
+		options.MaxTokens = c.MaxTokens
+	}
+
+	options.TermRates = c.TermRates
+	options.Injections = c.Inject
+	options.DuplicateRate = c.DuplicateRate
+	options.NearDuplicateRate = c.NearDuplicateRate
+
+	return options
+}
+
+// LoadDocuments reads the JSON array of documents named by the corpus import path
+func LoadDocuments(path string) ([]*models.Document, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NotFoundf("corpus import file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var docs []*models.Document
+	if err := json.NewDecoder(file).Decode(&docs); err != nil {
+		return nil, errors.Validationf("failed to parse corpus import file %s: %w", path, err)
+	}
+
+	if len(docs) == 0 {
+		return nil, errors.Validationf("corpus import file %s contains no documents", path)
+	}
+	for i, doc := range docs {
+		if strings.TrimSpace(doc.Title) == "" || strings.TrimSpace(doc.Content) == "" {
+			return nil, errors.Validationf("corpus import file %s: document %d needs a title and content", path, i+1)
+		}
+		doc.ApplyDefaults(time.Now())
+	}
+
+	return docs, nil
+}
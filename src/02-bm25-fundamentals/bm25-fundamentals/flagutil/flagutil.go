@@ -0,0 +1,896 @@
+package flagutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/completion"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/spf13/cobra"
+)
+
+// weightFields lists the FTS5 columns that accept a BM25 weight, in column order
+var weightFields = []string{"title", "content", "category"}
+
+// RegisterSearchFlags registers the query, category filter, and column weight flags
+// shared by every command that runs a BM25 search, along with the flags that load
+// and save those options as a JSON experiment file.
+//
+// Column weights are given with --weights "field:value,...". The older per-field
+// flags (--title-weight, --content-weight, --category-weight) remain as deprecated
+// aliases; when both are given, a positive per-field flag overrides that field's
+// value from --weights.
+//
+// --mode and --near rewrite the query into an FTS5 MATCH expression (see
+// database.MatchExpression); without them the query is FTS5 query syntax.
+//
+// Options read from --options-file form the base of the search; any flag set
+// explicitly on the command line overrides the matching value from the file.
+func RegisterSearchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("query", "q", "", "search query (required unless set by --options-file)")
+	cmd.Flags().StringP("mode", "", "", "how to match the query terms: any, all, phrase, or prefix (default: FTS5 query syntax as written)")
+	cmd.Flags().IntP("near", "", 0, "match the query terms as one NEAR group, within N tokens of each other (0 = off)")
+	cmd.Flags().StringSliceP("category", "c", nil, "filter by category; repeat or separate with commas to include several")
+	cmd.Flags().BoolP("no-category-check", "", false, "search a --category the corpus does not have instead of failing with suggestions")
+	cmd.Flags().StringP("weights", "w", "", "column weights (format: title:2.0,content:1.0,category:0.5)")
+	cmd.Flags().Float64P("title-weight", "", 0, "title field weight (0 = default)")
+	cmd.Flags().Float64P("content-weight", "", 0, "content field weight (0 = default)")
+	cmd.Flags().Float64P("category-weight", "", 0, "category field weight (0 = default)")
+	cmd.Flags().StringP("options-file", "", "", "load search options from a JSON experiment file (flags override file values)")
+	cmd.Flags().StringP("dump-options", "", "", "write the resolved search options to a JSON experiment file")
+	cmd.Flags().StringP("within", "", "", "refine an earlier search: only match documents listed in its result file (-f json or csv output, or a corpus export)")
+
+	cmd.MarkFlagsMutuallyExclusive("mode", "near")
+
+	for _, field := range weightFields {
+		cmd.Flags().MarkDeprecated(field+"-weight", fmt.Sprintf("use --weights \"%s:N\" instead", field))
+	}
+
+	cmd.RegisterFlagCompletionFunc("query", cobra.NoFileCompletions)
+	cmd.RegisterFlagCompletionFunc("mode", cobra.FixedCompletions(models.QueryModes, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("near", cobra.NoFileCompletions)
+	cmd.RegisterFlagCompletionFunc("category", completion.CategoryList)
+	cmd.RegisterFlagCompletionFunc("options-file", completion.Files("json"))
+	cmd.RegisterFlagCompletionFunc("dump-options", completion.Files("json"))
+	cmd.RegisterFlagCompletionFunc("within", completion.Files("json", "jsonl", "csv"))
+}
+
+// RegisterSnippetFlags registers the content snippet flags for commands that display result text,
+// along with --no-content, which leaves the document content out of the results
+func RegisterSnippetFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("snippets", "s", false, "include content snippets")
+	cmd.Flags().IntP("snippet-length", "", 0, "snippet length in characters (0 = default)")
+	cmd.Flags().IntP("snippet-tokens", "", 0, "snippet length in tokens, 1-64; overrides --snippet-length (0 = display.snippet_tokens)")
+	cmd.Flags().BoolP("no-content", "", false, "omit document content from results")
+}
+
+// RegisterTruncateFlag registers --truncate for commands whose text output
+// prints document or query text of unbounded length
+func RegisterTruncateFlag(cmd *cobra.Command) {
+	cmd.Flags().IntP("truncate", "", 0, "cut long text fields in text output to N characters (0 = display.max_field_chars, -1 = never)")
+}
+
+// RegisterDateFlags registers --since and --until, which restrict a search to
+// documents created within a date range
+func RegisterDateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("since", "", "", "only match documents created at or after this time (RFC3339 or 2006-01-02)")
+	cmd.Flags().StringP("until", "", "", "only match documents created at or before this time (RFC3339, or 2006-01-02 for the whole day)")
+
+	cmd.RegisterFlagCompletionFunc("since", cobra.NoFileCompletions)
+	cmd.RegisterFlagCompletionFunc("until", cobra.NoFileCompletions)
+}
+
+// RegisterSortFlags registers --sort and --desc for commands that list search
+// results, so the results can be ordered by something other than score
+func RegisterSortFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("sort", "", "", "order results by: "+strings.Join(models.SortKeys, ", ")+" (default: score)")
+	cmd.Flags().BoolP("desc", "", false, "reverse the sort order (worst score, newest, longest, or Z to A first)")
+
+	cmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions(models.SortKeys, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// RegisterMinScoreFlag registers --min-score, which drops the weakly matching
+// tail of a search
+func RegisterMinScoreFlag(cmd *cobra.Command) {
+	cmd.Flags().Float64P("min-score", "", 0, "drop matches scoring worse than this BM25 score, e.g. -3.5 (scores are negative; more negative is better)")
+}
+
+// RegisterAllFlag registers --all for commands that analyze the top results of
+// a search, so the analysis can cover every match instead
+func RegisterAllFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("all", "", false, "analyze every match instead of only the best ones")
+}
+
+// ParseDate parses a --since or --until value given as RFC3339 or as a bare
+// date. A bare date is midnight UTC; with endOfDay it is the last instant of
+// that day instead, so --until 2024-08-01 includes documents created on the
+// 1st. name identifies the flag in errors.
+func ParseDate(name, value string, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errors.Validationf("invalid --%s date %q (accepted formats: RFC3339, e.g. 2024-07-16T09:30:00Z, or 2006-01-02)", name, value)
+	}
+	if endOfDay {
+		date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return date, nil
+}
+
+// ParseWeights parses a "field:value,field:value" column weight specification.
+// Fields must be FTS5 columns, may appear only once, and weights must be
+// non-negative numbers. An empty specification returns nil weights.
+func ParseWeights(spec string) (map[string]float64, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 {
+			return nil, errors.Validationf("invalid weight pair %q (expected field:value)", pair)
+		}
+
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !isWeightField(field) {
+			return nil, errors.Validationf("unknown weight field %q (valid: %s)", field, strings.Join(weightFields, ", "))
+		}
+		if _, exists := weights[field]; exists {
+			return nil, errors.Validationf("weight for %s specified more than once", field)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, errors.Validationf("invalid weight value for %s: %q", field, strings.TrimSpace(parts[1]))
+		}
+		if weight < 0 {
+			return nil, errors.Validationf("weight for %s must not be negative, got %.2f", field, weight)
+		}
+
+		weights[field] = weight
+	}
+
+	return weights, nil
+}
+
+// ParseStrategy splits a named weight strategy of the form
+// "name=field:weight,field:weight" into its name and column weights
+func ParseStrategy(spec string) (string, map[string]float64, error) {
+	name, weightSpec, found := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return "", nil, errors.Validationf("invalid strategy %q (expected name=field:weight,...)", spec)
+	}
+
+	weights, err := ParseWeights(weightSpec)
+	if err != nil {
+		return "", nil, fmt.Errorf("strategy %s: %w", name, err)
+	}
+	if len(weights) == 0 {
+		return "", nil, errors.Validationf("strategy %s sets no weights", name)
+	}
+
+	return name, weights, nil
+}
+
+// FormatWeights writes weights in the "field:value,..." form ParseWeights reads,
+// in column order
+func FormatWeights(weights map[string]float64) string {
+	pairs := make([]string, 0, len(weights))
+	for _, field := range weightFields {
+		if weight, ok := weights[field]; ok {
+			pairs = append(pairs, field+":"+strconv.FormatFloat(weight, 'g', -1, 64))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// maxRangeValues caps how many values ParseRange produces
+const maxRangeValues = 200
+
+// ParseRange parses a "start:end:step" parameter range into the values from
+// start to end inclusive. name identifies the flag in errors. The step must be
+// positive and end must not be below start; a range with start equal to end
+// is a single value.
+func ParseRange(name, spec string) ([]float64, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	if len(parts) != 3 {
+		return nil, errors.Validationf("invalid --%s range %q (expected start:end:step)", name, spec)
+	}
+
+	bounds := make([]float64, 3)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, errors.Validationf("invalid --%s range value %q", name, strings.TrimSpace(part))
+		}
+		bounds[i] = value
+	}
+	start, end, step := bounds[0], bounds[1], bounds[2]
+
+	if step <= 0 {
+		return nil, errors.Validationf("--%s step must be positive, got %g", name, step)
+	}
+	if end < start {
+		return nil, errors.Validationf("--%s range end %g is below its start %g", name, end, start)
+	}
+
+	// Allow for rounding so that 0.5:2.0:0.25 ends exactly at 2.0
+	steps := int(math.Floor((end-start)/step + 1e-9))
+	if steps+1 > maxRangeValues {
+		return nil, errors.Validationf("--%s range has %d values (at most %d)", name, steps+1, maxRangeValues)
+	}
+
+	values := make([]float64, steps+1)
+	for i := range values {
+		values[i] = math.Round((start+float64(i)*step)*1e9) / 1e9
+	}
+	return values, nil
+}
+
+// isWeightField reports whether field is a weightable FTS5 column
+func isWeightField(field string) bool {
+	for _, f := range weightFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractSearchOptions builds search options from the flags registered by RegisterSearchFlags.
+// The max-results flag is copied as-is when the command defines it; callers apply their own
+// default for non-positive values. Snippet options are read when RegisterSnippetFlags was used.
+//
+// When --options-file is given, values present in the file replace the flag defaults, and
+// flags set explicitly on the command line replace values from the file.
+func ExtractSearchOptions(cmd *cobra.Command) (models.SearchOptions, error) {
+	options := models.DefaultSearchOptions()
+
+	query, err := cmd.Flags().GetString("query")
+	if err != nil {
+		return options, errors.Validationf("failed to read query flag: %w", err)
+	}
+	options.Query = query
+
+	options.Mode, err = cmd.Flags().GetString("mode")
+	if err != nil {
+		return options, errors.Validationf("failed to read mode flag: %w", err)
+	}
+
+	options.Near, err = cmd.Flags().GetInt("near")
+	if err != nil {
+		return options, errors.Validationf("failed to read near flag: %w", err)
+	}
+
+	categories, err := cmd.Flags().GetStringSlice("category")
+	if err != nil {
+		return options, errors.Validationf("failed to read category flag: %w", err)
+	}
+	options.CategoryFilters = normalizeCategories(categories)
+
+	options.SkipCategoryCheck, err = cmd.Flags().GetBool("no-category-check")
+	if err != nil {
+		return options, errors.Validationf("failed to read no-category-check flag: %w", err)
+	}
+
+	options.Within, err = cmd.Flags().GetString("within")
+	if err != nil {
+		return options, errors.Validationf("failed to read within flag: %w", err)
+	}
+
+	weightSpec, err := cmd.Flags().GetString("weights")
+	if err != nil {
+		return options, errors.Validationf("failed to read weights flag: %w", err)
+	}
+	options.ColumnWeights, err = ParseWeights(weightSpec)
+	if err != nil {
+		return options, err
+	}
+
+	if cmd.Flags().Lookup("max-results") != nil {
+		maxResults, err := MaxResults(cmd)
+		if err != nil {
+			return options, err
+		}
+		options.MaxResults = maxResults
+	}
+
+	if cmd.Flags().Lookup("since") != nil {
+		since, err := cmd.Flags().GetString("since")
+		if err != nil {
+			return options, errors.Validationf("failed to read since flag: %w", err)
+		}
+		if since != "" {
+			if options.CreatedAfter, err = ParseDate("since", since, false); err != nil {
+				return options, err
+			}
+		}
+
+		until, err := cmd.Flags().GetString("until")
+		if err != nil {
+			return options, errors.Validationf("failed to read until flag: %w", err)
+		}
+		if until != "" {
+			if options.CreatedBefore, err = ParseDate("until", until, true); err != nil {
+				return options, err
+			}
+		}
+	}
+
+	if cmd.Flags().Lookup("min-score") != nil && cmd.Flags().Changed("min-score") {
+		minScore, err := cmd.Flags().GetFloat64("min-score")
+		if err != nil {
+			return options, errors.Validationf("failed to read min-score flag: %w", err)
+		}
+		options.MinScore = &minScore
+	}
+
+	if cmd.Flags().Lookup("sort") != nil {
+		options.SortBy, err = cmd.Flags().GetString("sort")
+		if err != nil {
+			return options, errors.Validationf("failed to read sort flag: %w", err)
+		}
+		options.SortDesc, err = cmd.Flags().GetBool("desc")
+		if err != nil {
+			return options, errors.Validationf("failed to read desc flag: %w", err)
+		}
+	}
+
+	if cmd.Flags().Lookup("truncate") != nil {
+		options.Truncate, err = cmd.Flags().GetInt("truncate")
+		if err != nil {
+			return options, errors.Validationf("failed to read truncate flag: %w", err)
+		}
+	}
+
+	// Snippets are off unless the command registered the snippet flags and they were requested
+	options.IncludeSnippet = false
+	if cmd.Flags().Lookup("snippets") != nil {
+		includeSnippets, err := cmd.Flags().GetBool("snippets")
+		if err != nil {
+			return options, errors.Validationf("failed to read snippets flag: %w", err)
+		}
+		snippetLength, err := cmd.Flags().GetInt("snippet-length")
+		if err != nil {
+			return options, errors.Validationf("failed to read snippet-length flag: %w", err)
+		}
+		if snippetLength < 0 {
+			return options, errors.Validationf("--snippet-length must not be negative, got %d", snippetLength)
+		}
+		if err := checkLimit("snippet-length", snippetLength, config.App.Limits.SnippetLength, "snippet_length"); err != nil {
+			return options, err
+		}
+
+		snippetTokens, err := cmd.Flags().GetInt("snippet-tokens")
+		if err != nil {
+			return options, errors.Validationf("failed to read snippet-tokens flag: %w", err)
+		}
+		if err := checkSnippetTokens("--snippet-tokens", snippetTokens); err != nil {
+			return options, err
+		}
+
+		options.IncludeSnippet = includeSnippets
+		if snippetLength > 0 {
+			options.SnippetLength = snippetLength
+		}
+		options.SnippetTokens = snippetTokens
+
+		noContent, err := cmd.Flags().GetBool("no-content")
+		if err != nil {
+			return options, errors.Validationf("failed to read no-content flag: %w", err)
+		}
+		options.IncludeContent = !noContent
+	}
+
+	optionsFile, err := cmd.Flags().GetString("options-file")
+	if err != nil {
+		return options, errors.Validationf("failed to read options-file flag: %w", err)
+	}
+	if optionsFile != "" {
+		options, err = applyOptionsFile(cmd, optionsFile, options)
+		if err != nil {
+			return options, err
+		}
+	}
+
+	// Per-field flags take precedence over --weights and the options file; only
+	// positive values override, since 0 means "use the default"
+	for _, field := range weightFields {
+		flag := field + "-weight"
+		weight, err := cmd.Flags().GetFloat64(flag)
+		if err != nil {
+			return options, errors.Validationf("failed to read %s flag: %w", flag, err)
+		}
+		if weight < 0 {
+			return options, errors.Validationf("--%s must not be negative, got %.2f", flag, weight)
+		}
+		if weight > 0 {
+			if options.ColumnWeights == nil {
+				options.ColumnWeights = make(map[string]float64)
+			}
+			options.ColumnWeights[field] = weight
+		}
+	}
+
+	if strings.TrimSpace(options.Query) == "" {
+		return options, errors.Validationf("query cannot be empty (set --query or \"query\" in --options-file)")
+	}
+
+	if options.Near < 0 {
+		return options, errors.Validationf("--near must not be negative, got %d", options.Near)
+	}
+
+	if options.MinScore != nil && (math.IsNaN(*options.MinScore) || math.IsInf(*options.MinScore, 0)) {
+		return options, errors.Validationf("--min-score must be a number, got %v", *options.MinScore)
+	}
+
+	if options.SortBy != "" && !slices.Contains(models.SortKeys, options.SortBy) {
+		return options, errors.Validationf("invalid sort key: %s (must be %s)", options.SortBy, strings.Join(models.SortKeys, ", "))
+	}
+
+	if !options.CreatedAfter.IsZero() && !options.CreatedBefore.IsZero() && options.CreatedAfter.After(options.CreatedBefore) {
+		return options, errors.Validationf("--since %s is after --until %s",
+			options.CreatedAfter.Format(time.RFC3339), options.CreatedBefore.Format(time.RFC3339))
+	}
+
+	// Reject an unknown mode, or a query with too few terms for it, before searching
+	if _, err := database.MatchExpression(options.Query, options.Mode, options.Near); err != nil {
+		return options, err
+	}
+
+	if options.Within != "" {
+		options.WithinIDs, err = loadResultIDs(options.Within)
+		if err != nil {
+			return options, err
+		}
+	}
+
+	// An options file can carry values the flags would have rejected
+	if optionsFile != "" {
+		if err := checkLimit("max-results", options.MaxResults, config.App.Limits.MaxResults, "max_results"); err != nil {
+			return options, err
+		}
+		if err := checkLimit("snippet-length", options.SnippetLength, config.App.Limits.SnippetLength, "snippet_length"); err != nil {
+			return options, err
+		}
+	}
+
+	return options, nil
+}
+
+// MaxResults reads the --max-results flag. Non-positive values are returned
+// as-is for the caller's default; values above limits.max_results are rejected.
+func MaxResults(cmd *cobra.Command) (int, error) {
+	maxResults, err := cmd.Flags().GetInt("max-results")
+	if err != nil {
+		return 0, errors.Validationf("failed to read max-results flag: %w", err)
+	}
+	if err := checkLimit("max-results", maxResults, config.App.Limits.MaxResults, "max_results"); err != nil {
+		return 0, err
+	}
+	return maxResults, nil
+}
+
+// Categories reads the --category flag, normalizing each name the way stored
+// categories are and dropping blanks and duplicates
+func Categories(cmd *cobra.Command) ([]string, error) {
+	categories, err := cmd.Flags().GetStringSlice("category")
+	if err != nil {
+		return nil, errors.Validationf("failed to read category flag: %w", err)
+	}
+	return normalizeCategories(categories), nil
+}
+
+// Sample reads the --sample flag. Zero turns sampling off; values above
+// limits.max_results are rejected.
+func Sample(cmd *cobra.Command) (int, error) {
+	sample, err := cmd.Flags().GetInt("sample")
+	if err != nil {
+		return 0, errors.Validationf("failed to read sample flag: %w", err)
+	}
+	if sample < 0 {
+		return 0, errors.Validationf("--sample must not be negative, got %d", sample)
+	}
+	if err := checkLimit("sample", sample, config.App.Limits.MaxResults, "max_results"); err != nil {
+		return 0, err
+	}
+	return sample, nil
+}
+
+// Page reads the --page and --page-size flags. A page of 0 turns paging off
+// unless --page-size is set, which starts at page 1; a page size of 0 is
+// returned as-is for the caller's default. Sizes above limits.max_results are
+// rejected.
+func Page(cmd *cobra.Command) (int, int, error) {
+	page, err := cmd.Flags().GetInt("page")
+	if err != nil {
+		return 0, 0, errors.Validationf("failed to read page flag: %w", err)
+	}
+	if page < 0 {
+		return 0, 0, errors.Validationf("--page must not be negative, got %d", page)
+	}
+
+	size, err := cmd.Flags().GetInt("page-size")
+	if err != nil {
+		return 0, 0, errors.Validationf("failed to read page-size flag: %w", err)
+	}
+	if size < 0 {
+		return 0, 0, errors.Validationf("--page-size must not be negative, got %d", size)
+	}
+	if err := checkLimit("page-size", size, config.App.Limits.MaxResults, "max_results"); err != nil {
+		return 0, 0, err
+	}
+
+	if page == 0 && cmd.Flags().Changed("page-size") {
+		page = 1
+	}
+	return page, size, nil
+}
+
+// Buckets reads the --buckets flag, which must be between 1 and limits.buckets
+func Buckets(cmd *cobra.Command) (int, error) {
+	buckets, err := cmd.Flags().GetInt("buckets")
+	if err != nil {
+		return 0, errors.Validationf("failed to read buckets flag: %w", err)
+	}
+	if buckets < 1 {
+		return 0, errors.Validationf("--buckets must be at least 1, got %d", buckets)
+	}
+	if err := checkLimit("buckets", buckets, config.App.Limits.Buckets, "buckets"); err != nil {
+		return 0, err
+	}
+	return buckets, nil
+}
+
+// checkLimit rejects a flag value above its limit, naming the config key that
+// raises it
+func checkLimit(flag string, value, limit int, key string) error {
+	if value > limit {
+		return errors.Validationf("--%s %d exceeds the limit of %d (raise limits.%s in the config file)", flag, value, limit, key)
+	}
+	return nil
+}
+
+// checkSnippetTokens rejects a snippet size FTS5 cannot build; zero selects
+// the default
+func checkSnippetTokens(name string, tokens int) error {
+	if tokens < 0 || tokens > models.MaxSnippetTokens {
+		return errors.Validationf("%s must be between 0 and %d, got %d", name, models.MaxSnippetTokens, tokens)
+	}
+	return nil
+}
+
+// applyOptionsFile layers the options saved in path over the flag-derived options.
+// Fields absent from the file keep their flag defaults, and flags the user set
+// explicitly are re-applied so they win over the file.
+func applyOptionsFile(cmd *cobra.Command, path string, flagOptions models.SearchOptions) (models.SearchOptions, error) {
+	fileOptions := flagOptions
+	fileOptions.ColumnWeights = nil
+	fileOptions.CategoryFilters = nil
+
+	if err := loadSearchOptions(path, &fileOptions); err != nil {
+		return flagOptions, err
+	}
+
+	if err := ValidateWeights(fileOptions.ColumnWeights); err != nil {
+		return flagOptions, fmt.Errorf("options file %s: %w", path, err)
+	}
+	if fileOptions.MaxResults < 0 {
+		return flagOptions, errors.Validationf("options file %s: max_results must not be negative, got %d", path, fileOptions.MaxResults)
+	}
+	if fileOptions.SnippetLength < 0 {
+		return flagOptions, errors.Validationf("options file %s: snippet_length must not be negative, got %d", path, fileOptions.SnippetLength)
+	}
+	if err := checkSnippetTokens("options file "+path+": snippet_tokens", fileOptions.SnippetTokens); err != nil {
+		return flagOptions, err
+	}
+
+	changed := cmd.Flags().Changed
+	if changed("query") {
+		fileOptions.Query = flagOptions.Query
+	}
+	if changed("mode") {
+		fileOptions.Mode = flagOptions.Mode
+	}
+	if changed("near") {
+		fileOptions.Near = flagOptions.Near
+	}
+	if changed("category") {
+		fileOptions.CategoryFilters = flagOptions.CategoryFilters
+	}
+	if changed("since") {
+		fileOptions.CreatedAfter = flagOptions.CreatedAfter
+	}
+	if changed("until") {
+		fileOptions.CreatedBefore = flagOptions.CreatedBefore
+	}
+	if changed("min-score") {
+		fileOptions.MinScore = flagOptions.MinScore
+	}
+	if changed("sort") {
+		fileOptions.SortBy = flagOptions.SortBy
+	}
+	if changed("desc") {
+		fileOptions.SortDesc = flagOptions.SortDesc
+	}
+	if changed("weights") {
+		fileOptions.ColumnWeights = flagOptions.ColumnWeights
+	}
+	if changed("max-results") {
+		fileOptions.MaxResults = flagOptions.MaxResults
+	}
+	if changed("snippets") {
+		fileOptions.IncludeSnippet = flagOptions.IncludeSnippet
+	}
+	if changed("snippet-length") {
+		fileOptions.SnippetLength = flagOptions.SnippetLength
+	}
+	if changed("snippet-tokens") {
+		fileOptions.SnippetTokens = flagOptions.SnippetTokens
+	}
+	if changed("no-content") {
+		fileOptions.IncludeContent = flagOptions.IncludeContent
+	}
+	if changed("within") {
+		fileOptions.Within = flagOptions.Within
+	}
+
+	// Commands without snippet flags never show snippets and always load content,
+	// whatever the file says
+	if cmd.Flags().Lookup("snippets") == nil {
+		fileOptions.IncludeSnippet = false
+		fileOptions.IncludeContent = true
+	}
+	// Commands without a threshold flag search every match
+	if cmd.Flags().Lookup("min-score") == nil {
+		fileOptions.MinScore = nil
+	}
+	// Commands without sort flags analyze results in score order
+	if cmd.Flags().Lookup("sort") == nil {
+		fileOptions.SortBy = ""
+		fileOptions.SortDesc = false
+	}
+	fileOptions.CategoryFilters = normalizeCategories(fileOptions.CategoryFilters)
+
+	return fileOptions, nil
+}
+
+// normalizeCategories stores each category filter the way document categories
+// are stored, dropping empty and repeated names; no names gives nil, which
+// matches every category
+func normalizeCategories(categories []string) []string {
+	var normalized []string
+	for _, category := range categories {
+		category = config.App.NormalizeCategory(category)
+		if category != "" && !slices.Contains(normalized, category) {
+			normalized = append(normalized, category)
+		}
+	}
+	return normalized
+}
+
+// validateWeights applies the ParseWeights rules to weights that did not come from a
+// "field:value" specification
+func ValidateWeights(weights map[string]float64) error {
+	// Check in a fixed order so the same input always reports the same error
+	fields := make([]string, 0, len(weights))
+	for field := range weights {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		weight := weights[field]
+		if !isWeightField(field) {
+			return errors.Validationf("unknown weight field %q (valid: %s)", field, strings.Join(weightFields, ", "))
+		}
+		if math.IsNaN(weight) || math.IsInf(weight, 0) || weight < 0 {
+			return errors.Validationf("weight for %s must be a non-negative number, got %v", field, weight)
+		}
+	}
+	return nil
+}
+
+// loadSearchOptions decodes a JSON experiment file into options. Fields missing
+// from the file leave the existing values in options untouched. Files written
+// before category_filters replaced the single category_filter still load.
+func loadSearchOptions(path string, options *models.SearchOptions) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.NotFoundf("options file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	saved := struct {
+		*models.SearchOptions
+		CategoryFilter string `json:"category_filter"`
+	}{SearchOptions: options}
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&saved); err != nil {
+		return errors.Validationf("failed to parse options file %s: %w", path, err)
+	}
+
+	if saved.CategoryFilter != "" {
+		if len(options.CategoryFilters) > 0 {
+			return errors.Validationf("options file %s sets both category_filter and category_filters", path)
+		}
+		options.CategoryFilters = []string{saved.CategoryFilter}
+	}
+
+	return nil
+}
+
+// loadResultIDs reads the document IDs listed in a result file: the JSON output
+// of a search command (an object with a "results" list), a JSON array or JSONL
+// stream of documents as corpus export writes them, or CSV with an id column.
+// The IDs come back sorted and without duplicates; a file listing no documents
+// gives an empty, non-nil slice, so a refined search finds nothing.
+func loadResultIDs(path string) ([]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NotFoundf("result file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var ids []int64
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		ids, err = readCSVResultIDs(file)
+	} else {
+		ids, err = readJSONResultIDs(file)
+	}
+	if err != nil {
+		return nil, errors.Validationf("failed to read result file %s: %w", path, err)
+	}
+
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// resultRecord is a JSON value in a result file: a search output object with
+// results, or a single document or result with an id. Results stays raw so an
+// output with no results ("results": null) still counts as a search output.
+type resultRecord struct {
+	ID      *int64          `json:"id"`
+	Results json.RawMessage `json:"results"`
+}
+
+// readJSONResultIDs reads every JSON value in r, so a single search output, a
+// JSON array, and a JSONL stream all decode the same way
+func readJSONResultIDs(r io.Reader) ([]int64, error) {
+	ids := make([]int64, 0)
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return ids, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		var records []resultRecord
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(raw, &records); err != nil {
+				return nil, err
+			}
+		} else {
+			var record resultRecord
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+
+		for _, record := range records {
+			switch {
+			case record.Results != nil:
+				var results []struct {
+					ID int64 `json:"id"`
+				}
+				if err := json.Unmarshal(record.Results, &results); err != nil {
+					return nil, err
+				}
+				for _, result := range results {
+					ids = append(ids, result.ID)
+				}
+			case record.ID != nil:
+				ids = append(ids, *record.ID)
+			default:
+				return nil, fmt.Errorf("expected search results or documents with an id")
+			}
+		}
+	}
+}
+
+// readCSVResultIDs reads the id column of a CSV file with a header row. Quotes
+// are read leniently, since search -f csv does not escape quotes in titles.
+func readCSVResultIDs(r io.Reader) ([]int64, error) {
+	reader := csv.NewReader(r)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing CSV header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	column := slices.IndexFunc(header, func(name string) bool {
+		return strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")), "id")
+	})
+	if column < 0 {
+		return nil, fmt.Errorf("CSV header has no id column")
+	}
+
+	ids := make([]int64, 0)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if column >= len(row) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d has no id", line)
+		}
+
+		id, err := strconv.ParseInt(strings.TrimSpace(row[column]), 10, 64)
+		if err != nil {
+			line, _ := reader.FieldPos(column)
+			return nil, fmt.Errorf("line %d: invalid id %q", line, row[column])
+		}
+		ids = append(ids, id)
+	}
+}
+
+// DumpSearchOptions writes options to the file named by --dump-options, if it was given.
+// Handlers call it once their options are fully resolved, so replaying the file with
+// --options-file reproduces the same search.
+func DumpSearchOptions(cmd *cobra.Command, options models.SearchOptions) error {
+	path, err := cmd.Flags().GetString("dump-options")
+	if err != nil {
+		return errors.Validationf("failed to read dump-options flag: %w", err)
+	}
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Validationf("failed to create options file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(options); err != nil {
+		return errors.Validationf("failed to write options file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package flagutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/spf13/cobra"
+)
+
+// newQueryCommand returns a command with the flags search query registers,
+// set from name and value pairs
+func newQueryCommand(t *testing.T, flags ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{Use: "query"}
+	RegisterSearchFlags(cmd)
+	cmd.Flags().IntP("max-results", "n", 0, "maximum results to return")
+	RegisterSnippetFlags(cmd)
+	RegisterTruncateFlag(cmd)
+	RegisterDateFlags(cmd)
+	RegisterSortFlags(cmd)
+	RegisterMinScoreFlag(cmd)
+	for i := 0; i+1 < len(flags); i += 2 {
+		if err := cmd.Flags().Set(flags[i], flags[i+1]); err != nil {
+			t.Fatalf("set --%s: %v", flags[i], err)
+		}
+	}
+	return cmd
+}
+
+func TestExtractSearchOptions(t *testing.T) {
+	day := func(s string) time.Time {
+		parsed, _ := time.Parse(time.RFC3339Nano, s)
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		flags []string
+		check func(t *testing.T, options models.SearchOptions)
+	}{
+		{
+			name:  "defaults",
+			flags: []string{"query", "sqlite"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if options.Query != "sqlite" || options.ColumnWeights != nil || options.CategoryFilters != nil {
+					t.Errorf("options = %+v, want the query with default weights and no category filter", options)
+				}
+				if options.IncludeSnippet || !options.IncludeContent || options.MinScore != nil {
+					t.Errorf("snippets %v, content %v, min score %v; want off, on, unset", options.IncludeSnippet, options.IncludeContent, options.MinScore)
+				}
+			},
+		},
+		{
+			name:  "weights",
+			flags: []string{"query", "sqlite", "weights", "title:2, content:1"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if want := map[string]float64{"title": 2, "content": 1}; !reflect.DeepEqual(options.ColumnWeights, want) {
+					t.Errorf("weights = %v, want %v", options.ColumnWeights, want)
+				}
+			},
+		},
+		{
+			name:  "field flag overrides --weights",
+			flags: []string{"query", "sqlite", "weights", "title:2,content:1", "title-weight", "5", "category-weight", "0"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if want := map[string]float64{"title": 5, "content": 1}; !reflect.DeepEqual(options.ColumnWeights, want) {
+					t.Errorf("weights = %v, want %v", options.ColumnWeights, want)
+				}
+			},
+		},
+		{
+			name:  "field flag without --weights",
+			flags: []string{"query", "sqlite", "content-weight", "3"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if want := map[string]float64{"content": 3}; !reflect.DeepEqual(options.ColumnWeights, want) {
+					t.Errorf("weights = %v, want %v", options.ColumnWeights, want)
+				}
+			},
+		},
+		{
+			name:  "categories are trimmed and deduplicated",
+			flags: []string{"query", "sqlite", "category", "science, science,,technology"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if want := []string{"science", "technology"}; !reflect.DeepEqual(options.CategoryFilters, want) {
+					t.Errorf("categories = %q, want %q", options.CategoryFilters, want)
+				}
+			},
+		},
+		{
+			name:  "bare --until covers the whole day",
+			flags: []string{"query", "sqlite", "since", "2024-08-01", "until", "2024-08-01"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if want := day("2024-08-01T00:00:00Z"); !options.CreatedAfter.Equal(want) {
+					t.Errorf("created after = %v, want %v", options.CreatedAfter, want)
+				}
+				if want := day("2024-08-01T23:59:59.999999999Z"); !options.CreatedBefore.Equal(want) {
+					t.Errorf("created before = %v, want %v", options.CreatedBefore, want)
+				}
+			},
+		},
+		{
+			name:  "min score only when set",
+			flags: []string{"query", "sqlite", "min-score", "0"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if options.MinScore == nil || *options.MinScore != 0 {
+					t.Errorf("min score = %v, want an explicit 0", options.MinScore)
+				}
+			},
+		},
+		{
+			name:  "snippets and max results",
+			flags: []string{"query", "sqlite", "snippets", "true", "snippet-length", "120", "max-results", "7", "no-content", "true"},
+			check: func(t *testing.T, options models.SearchOptions) {
+				if !options.IncludeSnippet || options.SnippetLength != 120 || options.MaxResults != 7 || options.IncludeContent {
+					t.Errorf("snippets %v, length %d, max results %d, content %v; want on, 120, 7, off",
+						options.IncludeSnippet, options.SnippetLength, options.MaxResults, options.IncludeContent)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := ExtractSearchOptions(newQueryCommand(t, tt.flags...))
+			if err != nil {
+				t.Fatalf("ExtractSearchOptions: %v", err)
+			}
+			tt.check(t, options)
+		})
+	}
+}
+
+func TestExtractSearchOptionsLowercasesCategories(t *testing.T) {
+	previous := config.App.Corpus.LowercaseCategories
+	config.App.Corpus.LowercaseCategories = true
+	t.Cleanup(func() { config.App.Corpus.LowercaseCategories = previous })
+
+	options, err := ExtractSearchOptions(newQueryCommand(t, "query", "sqlite", "category", "Science,SCIENCE"))
+	if err != nil {
+		t.Fatalf("ExtractSearchOptions: %v", err)
+	}
+	if want := []string{"science"}; !reflect.DeepEqual(options.CategoryFilters, want) {
+		t.Errorf("categories = %q, want %q", options.CategoryFilters, want)
+	}
+}
+
+func TestExtractSearchOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		want  string
+	}{
+		{"empty query", []string{"query", "  "}, "query cannot be empty"},
+		{"unknown weight field", []string{"query", "sqlite", "weights", "author:2"}, "unknown weight field"},
+		{"malformed weights", []string{"query", "sqlite", "weights", "title=2"}, "invalid weight pair"},
+		{"negative field weight", []string{"query", "sqlite", "title-weight", "-1"}, "--title-weight must not be negative"},
+		{"unknown mode", []string{"query", "sqlite", "mode", "fuzzy"}, "invalid query mode"},
+		{"near with one term", []string{"query", "sqlite", "near", "3"}, "NEAR needs at least two query terms"},
+		{"negative near", []string{"query", "sqlite", "near", "-1"}, "--near must not be negative"},
+		{"unknown sort key", []string{"query", "sqlite", "sort", "popularity"}, "invalid sort key"},
+		{"since after until", []string{"query", "sqlite", "since", "2024-09-01", "until", "2024-08-01"}, "is after --until"},
+		{"bad date", []string{"query", "sqlite", "since", "yesterday"}, "since"},
+		{"max results over the limit", []string{"query", "sqlite", "max-results", "100000000"}, "exceeds the limit"},
+		{"negative snippet length", []string{"query", "sqlite", "snippet-length", "-5"}, "--snippet-length must not be negative"},
+		{"snippet tokens out of range", []string{"query", "sqlite", "snippet-tokens", "65"}, "--snippet-tokens must be between"},
+		{"missing within file", []string{"query", "sqlite", "within", "no-such-results.json"}, "no-such-results.json"},
+		{"missing options file", []string{"query", "sqlite", "options-file", "no-such-options.json"}, "no-such-options.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtractSearchOptions(newQueryCommand(t, tt.flags...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ExtractSearchOptions(%q) error = %v, want one containing %q", tt.flags, err, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzParseWeights checks that any specification ParseWeights accepts holds
+// weights ValidateWeights accepts, and that FormatWeights writes them back in
+// a form ParseWeights reads to the same weights
+func FuzzParseWeights(f *testing.F) {
+	seeds := []string{
+		"",
+		"title:2.0",
+		"title:2,content:1,category:0.5",
+		" Title : 1e3 , content:0 ",
+		"title:1,title:2",
+		"title:-1",
+		"title:NaN",
+		"title:Inf",
+		"author:1",
+		"title:1:2",
+		"title:0x1p-2",
+		",",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, spec string) {
+		weights, err := ParseWeights(spec)
+		if err != nil {
+			return
+		}
+		if err := ValidateWeights(weights); err != nil {
+			t.Fatalf("ParseWeights(%q) = %v, which ValidateWeights rejects: %v", spec, weights, err)
+		}
+
+		formatted := FormatWeights(weights)
+		again, err := ParseWeights(formatted)
+		if err != nil {
+			t.Fatalf("ParseWeights(FormatWeights(%v)) = %v for %q", weights, err, formatted)
+		}
+		if len(weights) == 0 && len(again) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(again, weights) {
+			t.Errorf("weights %v from %q read back as %v from %q", weights, spec, again, formatted)
+		}
+	})
+}
@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	scorestats "github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/stats"
+	"github.com/spf13/cobra"
+)
+
+// Analyze is the global analyze handler instance
+var Analyze AnalyzeHandler
+
+// AnalyzeHandler reports corpus statistics that shape BM25 scores
+type AnalyzeHandler struct {
+	db     *database.Database
+	corpus *CorpusHandler
+	search *SearchHandler
+}
+
+// NewAnalyzeHandler creates an analyze handler that reads statistics from db
+func NewAnalyzeHandler(db *database.Database) *AnalyzeHandler {
+	return &AnalyzeHandler{db: db, corpus: NewCorpusHandler(db), search: NewSearchHandler(db)}
+}
+
+// HandleTerms handles the analyze terms command
+func (h *AnalyzeHandler) HandleTerms(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return errors.Validationf("failed to read limit flag: %w", err)
+	}
+	if limit < 0 {
+		return errors.Validationf("--limit must not be negative, got %d", limit)
+	}
+	if limit == 0 {
+		limit = config.App.Search.TermFreqLimit
+	}
+
+	minDocs, err := cmd.Flags().GetInt("min-doc-count")
+	if err != nil {
+		return errors.Validationf("failed to read min-doc-count flag: %w", err)
+	}
+	if minDocs < 1 {
+		return errors.Validationf("--min-doc-count must be at least 1, got %d", minDocs)
+	}
+
+	categories, err := flagutil.Categories(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	analysis, err := h.Terms(ctx, categories, minDocs, limit)
+	if err != nil {
+		return err
+	}
+
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(analysis)
+
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write([]string{"term", "frequency", "documents", "idf"})
+		for _, term := range analysis.Terms {
+			writer.Write([]string{
+				term.Term,
+				strconv.Itoa(term.Frequency),
+				strconv.Itoa(term.Documents),
+				strconv.FormatFloat(term.IDF, 'f', 6, 64),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default: // text format
+		h.displayTerms(analysis)
+	}
+
+	return nil
+}
+
+// Terms lists up to limit terms found in at least minDocs documents, most
+// documents first. With categories, frequencies and document counts cover
+// only the documents in those categories, but the IDF is still the corpus-wide
+// one bm25() scores with, since a category filter narrows which documents
+// match without changing the index statistics.
+func (h *AnalyzeHandler) Terms(ctx context.Context, categories []string, minDocs, limit int) (*models.TermAnalysis, error) {
+	indexLengths, err := h.db.IndexLengths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &models.TermAnalysis{
+		IndexDocuments: indexLengths.Rows,
+		Documents:      indexLengths.Rows,
+		Categories:     categories,
+		MinDocCount:    minDocs,
+		Limit:          limit,
+		Terms:          []models.TermFreq{},
+	}
+
+	if len(categories) > 0 {
+		summaries, err := h.corpus.Categories(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, len(summaries))
+		documents := make(map[string]int, len(summaries))
+		for i, summary := range summaries {
+			names[i] = summary.Name
+			documents[summary.Name] = summary.Documents
+		}
+
+		analysis.Documents = 0
+		for _, category := range categories {
+			if !slices.Contains(names, category) {
+				return nil, missingCategory(category, names, "")
+			}
+			analysis.Documents += documents[category]
+		}
+	}
+
+	counts, err := h.db.TermCounts(ctx, categories, minDocs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, count := range counts {
+		analysis.Terms = append(analysis.Terms, models.TermFreq{
+			Term:      count.Term,
+			Frequency: count.Occurrences,
+			Documents: count.Documents,
+			IDF:       bm25IDF(indexLengths.Rows, count.IndexDocuments),
+		})
+	}
+
+	return analysis, nil
+}
+
+// displayTerms prints a term analysis as a table
+func (h *AnalyzeHandler) displayTerms(analysis *models.TermAnalysis) {
+	scope := "the corpus"
+	if len(analysis.Categories) > 0 {
+		scope = "category " + strings.Join(analysis.Categories, ", ")
+	}
+
+	title := fmt.Sprintf("Top %d terms by document frequency in %s", analysis.Limit, scope)
+	fmt.Printf("%s\n%s\n\n", title, strings.Repeat("=", len(title)))
+	fmt.Printf("Documents: %d", analysis.Documents)
+	if analysis.Documents != analysis.IndexDocuments {
+		fmt.Printf(" of %d indexed", analysis.IndexDocuments)
+	}
+	fmt.Printf("\n")
+	if analysis.MinDocCount > 1 {
+		fmt.Printf("Minimum document count: %d\n", analysis.MinDocCount)
+	}
+	fmt.Printf("\n")
+
+	if len(analysis.Terms) == 0 {
+		fmt.Printf("No terms found in at least %d documents.\n", analysis.MinDocCount)
+		return
+	}
+
+	width := len("Term")
+	for _, term := range analysis.Terms {
+		width = max(width, len(term.Term))
+	}
+
+	floored := false
+	fmt.Printf("  %-*s %10s %8s %12s %10s\n", width, "Term", "Documents", "% Docs", "Occurrences", "IDF")
+	for _, term := range analysis.Terms {
+		share := 0.0
+		if analysis.Documents > 0 {
+			share = float64(term.Documents) / float64(analysis.Documents) * 100
+		}
+
+		idf := fmt.Sprintf("%.4f", term.IDF)
+		if term.IDF == 1e-6 {
+			idf = "0.000001*"
+			floored = true
+		}
+		fmt.Printf("  %-*s %10d %7.1f%% %12d %10s\n", width, term.Term, term.Documents, share, term.Frequency, idf)
+	}
+
+	fmt.Printf("\nIDF = ln((N - n + 0.5) / (n + 0.5)) over all %d indexed documents, as bm25() computes it.\n", analysis.IndexDocuments)
+	if floored {
+		fmt.Printf("* In more than half the documents: the IDF is at or below 0, so SQLite uses 0.000001\n")
+		fmt.Printf("  and the term barely affects ranking.\n")
+	}
+}
+
+// HandleDocument handles the analyze document command
+func (h *AnalyzeHandler) HandleDocument(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	id, err := cmd.Flags().GetInt64("id")
+	if err != nil {
+		return errors.Validationf("failed to read id flag: %w", err)
+	}
+	if id < 1 {
+		return errors.Validationf("--id must be a positive document ID, got %d", id)
+	}
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	analysis, err := h.Document(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if config.App.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(analysis)
+	}
+
+	h.displayDocument(analysis)
+	return nil
+}
+
+// Document measures document id the way bm25() sees it: its token count and
+// per-column lengths as FTS5 recorded them, its distinct indexed terms, and
+// the length normalization its matches are scored with under the default
+// BM25 parameters
+func (h *AnalyzeHandler) Document(ctx context.Context, id int64) (*models.DocumentAnalysis, error) {
+	doc, err := h.db.Document(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.NotFoundf("document %d not found", id)
+	}
+
+	sizes, err := h.db.DocSize(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sizes == nil {
+		return nil, errors.NotFoundf("document %d is not in the FTS5 index — run 'db check-sync' to compare the index with the documents table", id)
+	}
+
+	terms, err := h.db.DocumentTerms(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	indexLengths, err := h.db.IndexLengths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &models.DocumentAnalysis{
+		Info: models.DocumentInfo{
+			ID:          doc.ID,
+			Title:       doc.Title,
+			Category:    doc.Category,
+			UniqueTerms: len(terms),
+			Created:     doc.Created,
+		},
+		StoredLength:    doc.Length,
+		CorpusAvgLength: indexLengths.Average(),
+		BM25:            models.DefaultBM25Params,
+	}
+
+	for c, column := range database.IndexedColumns {
+		field := models.FieldLength{
+			Field:           column,
+			CorpusAvgLength: indexLengths.ColumnAverage(c),
+		}
+		if c < len(sizes) {
+			field.Tokens = sizes[c]
+		}
+		for _, term := range terms {
+			if term.ColumnCounts[c] > 0 {
+				field.UniqueTerms++
+			}
+		}
+		analysis.Fields = append(analysis.Fields, field)
+		analysis.Info.TokenCount += field.Tokens
+	}
+
+	// Average term length is taken over every occurrence of the indexed
+	// (case-folded and stemmed) forms, so repeated terms count each time
+	characters, occurrences := 0, 0
+	for _, term := range terms {
+		for _, count := range term.ColumnCounts {
+			characters += count * utf8.RuneCountInString(term.Term)
+			occurrences += count
+		}
+	}
+	if occurrences > 0 {
+		analysis.Info.AvgTermLength = float64(characters) / float64(occurrences)
+	}
+
+	if analysis.CorpusAvgLength > 0 {
+		analysis.LengthRatio = float64(analysis.Info.TokenCount) / analysis.CorpusAvgLength
+	}
+	analysis.LengthNorm = h.search.calculateLengthNormalization(analysis.Info.TokenCount, analysis.CorpusAvgLength, analysis.BM25)
+
+	return analysis, nil
+}
+
+// displayDocument prints a document analysis with the length normalization
+// worked through
+func (h *AnalyzeHandler) displayDocument(analysis *models.DocumentAnalysis) {
+	info := analysis.Info
+
+	title := fmt.Sprintf("Document %d: %s", info.ID, info.Title)
+	fmt.Printf("%s\n%s\n\n", title, strings.Repeat("=", utf8.RuneCountInString(title)))
+	fmt.Printf("Category:        %s\n", info.Category)
+	if !info.Created.IsZero() {
+		fmt.Printf("Created:         %s\n", info.Created.Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("Tokens:          %d\n", info.TokenCount)
+	fmt.Printf("Unique terms:    %d\n", info.UniqueTerms)
+	fmt.Printf("Avg term length: %.2f characters (indexed forms)\n", info.AvgTermLength)
+	if analysis.StoredLength != info.TokenCount {
+		fmt.Printf("\nThe stored length (%d) differs from the indexed token count; run 'corpus recount'\n", analysis.StoredLength)
+		fmt.Printf("to update it. bm25() uses the indexed count.\n")
+	}
+
+	fmt.Printf("\nLength by field:\n")
+	fmt.Printf("  %-10s %8s %8s %12s %8s\n", "Field", "Tokens", "Unique", "Corpus Avg", "vs Avg")
+	for _, field := range analysis.Fields {
+		ratio := "-"
+		if field.CorpusAvgLength > 0 {
+			ratio = fmt.Sprintf("%.2fx", float64(field.Tokens)/field.CorpusAvgLength)
+		}
+		fmt.Printf("  %-10s %8d %8d %12.1f %8s\n", field.Field, field.Tokens, field.UniqueTerms, field.CorpusAvgLength, ratio)
+	}
+	fmt.Printf("  %-10s %8d %8d %12.1f %8s\n", "total", info.TokenCount, info.UniqueTerms, analysis.CorpusAvgLength,
+		fmt.Sprintf("%.2fx", analysis.LengthRatio))
+
+	params := analysis.BM25
+	fmt.Printf("\nLength normalization (k1=%.2f, b=%.2f):\n", params.K1, params.B)
+	fmt.Printf("  |d| / avgdl = %d / %.2f = %.4f\n", info.TokenCount, analysis.CorpusAvgLength, analysis.LengthRatio)
+	fmt.Printf("  K = k1 × ((1 - b) + b × |d| / avgdl)\n")
+	fmt.Printf("    = %.2f × (%.2f + %.2f × %.4f) = %.4f\n", params.K1, 1-params.B, params.B, analysis.LengthRatio, analysis.LengthNorm)
+
+	// Each term scores IDF × tf × (k1 + 1) / (tf + K), so K above k1 lowers every match
+	switch {
+	case analysis.LengthNorm > params.K1:
+		fmt.Printf("\nLonger than average: K is above k1, so each match scores lower than the same\n")
+		fmt.Printf("match in an average-length document.\n")
+	case analysis.LengthNorm < params.K1:
+		fmt.Printf("\nShorter than average: K is below k1, so each match scores higher than the same\n")
+		fmt.Printf("match in an average-length document.\n")
+	default:
+		fmt.Printf("\nAverage length: K equals k1, so matches are scored without a length adjustment.\n")
+	}
+}
+
+// HandleLengthImpact handles the analyze length-impact command
+func (h *AnalyzeHandler) HandleLengthImpact(cmd *cobra.Command, args []string) error {
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
+	options.MaxResults = statsMaxResults
+	options.IncludeSnippet = false
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		options.MaxResults = 0
+	}
+
+	buckets, err := flagutil.Buckets(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	if err := h.search.CheckCategory(ctx, options); err != nil {
+		return err
+	}
+
+	results, err := h.search.Search(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	impact := h.LengthImpact(results, buckets)
+	impact.Query = options.Query
+
+	impact.TotalMatches, err = h.search.TotalMatches(ctx, options, results)
+	if err != nil {
+		return err
+	}
+	impact.Truncated = impact.TotalMatches > len(results)
+
+	if config.App.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(impact)
+	}
+
+	WarnTruncated(len(results), impact.TotalMatches)
+	h.displayLengthImpact(impact)
+	return nil
+}
+
+// LengthImpact groups results into up to buckets length quantiles, shortest
+// first, and summarizes the scores in each. Cut points are percentiles of the
+// stored document lengths, and a result belongs to the first bucket whose
+// upper cut it does not exceed, so documents of equal length always share a
+// bucket; quantiles that collapse onto the same length leave fewer buckets.
+func (h *AnalyzeHandler) LengthImpact(results []*models.SearchResult, buckets int) *models.LengthImpact {
+	impact := &models.LengthImpact{
+		Results: len(results),
+		Buckets: []models.LengthBucket{},
+	}
+	if len(results) == 0 {
+		return impact
+	}
+
+	lengths := make([]float64, len(results))
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		lengths[i] = float64(result.Length)
+		scores[i] = result.Score
+	}
+	impact.Pearson = scorestats.Pearson(lengths, scores)
+
+	sorted := slices.Clone(lengths)
+	sort.Float64s(sorted)
+	cuts := make([]float64, buckets-1)
+	for i := range cuts {
+		cuts[i] = scorestats.Percentile(sorted, float64(i+1)*100/float64(buckets))
+	}
+
+	members := make([][]int, buckets)
+	for i, length := range lengths {
+		bucket := sort.SearchFloat64s(cuts, length)
+		members[bucket] = append(members[bucket], i)
+	}
+
+	for _, indexes := range members {
+		if len(indexes) == 0 {
+			continue
+		}
+
+		bucket := models.LengthBucket{
+			MinLength: results[indexes[0]].Length,
+			MaxLength: results[indexes[0]].Length,
+			Count:     len(indexes),
+		}
+		bucketLengths := make([]float64, len(indexes))
+		bucketScores := make([]float64, len(indexes))
+		for j, index := range indexes {
+			bucket.MinLength = min(bucket.MinLength, results[index].Length)
+			bucket.MaxLength = max(bucket.MaxLength, results[index].Length)
+			bucketLengths[j] = lengths[index]
+			bucketScores[j] = scores[index]
+		}
+
+		distribution := h.search.calculateScoreDistribution(bucketScores)
+		bucket.MeanLength = scorestats.Mean(bucketLengths)
+		bucket.MeanScore = distribution.Mean
+		bucket.MedianScore = distribution.Median
+		bucket.StdDev = distribution.StdDev
+
+		impact.Buckets = append(impact.Buckets, bucket)
+	}
+
+	return impact
+}
+
+// displayLengthImpact prints the length buckets and what their correlation
+// says about length normalization
+func (h *AnalyzeHandler) displayLengthImpact(impact *models.LengthImpact) {
+	title := fmt.Sprintf("Length impact for: %q", impact.Query)
+	fmt.Printf("%s\n%s\n\n", title, strings.Repeat("=", utf8.RuneCountInString(title)))
+
+	if impact.Results == 0 {
+		fmt.Printf("No results found.\n")
+		return
+	}
+
+	fmt.Printf("Results analyzed: %d of %d matches\n\n", impact.Results, impact.TotalMatches)
+
+	fmt.Printf("  %-6s %-13s %7s %10s %11s %13s %9s\n", "Bucket", "Length", "Results", "Mean Len", "Mean Score", "Median Score", "Std Dev")
+	for i, bucket := range impact.Buckets {
+		lengths := fmt.Sprintf("%d-%d", bucket.MinLength, bucket.MaxLength)
+		if bucket.MinLength == bucket.MaxLength {
+			lengths = strconv.Itoa(bucket.MinLength)
+		}
+		fmt.Printf("  %-6d %-13s %7d %10.1f %11.4f %13.4f %9.4f\n", i+1, lengths, bucket.Count,
+			bucket.MeanLength, bucket.MeanScore, bucket.MedianScore, bucket.StdDev)
+	}
+
+	fmt.Printf("\nPearson correlation of length with score: %s\n", formatCorrelation(impact.Pearson))
+	if impact.Pearson == nil {
+		fmt.Printf("Undefined: every result has the same length or the same score.\n")
+		return
+	}
+
+	// Scores are negative and closer to zero is worse, so a positive correlation
+	// means longer documents score worse
+	switch r := *impact.Pearson; {
+	case r > 0.1:
+		fmt.Printf("Longer documents score worse (closer to 0): length normalization outweighs the\n")
+		fmt.Printf("extra term occurrences that longer documents tend to have.\n")
+	case r < -0.1:
+		fmt.Printf("Longer documents score better: their extra term occurrences outweigh length\n")
+		fmt.Printf("normalization.\n")
+	default:
+		fmt.Printf("Length has little linear effect on scores for this query.\n")
+	}
+}
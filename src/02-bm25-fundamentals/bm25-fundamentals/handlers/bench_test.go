@@ -0,0 +1,162 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+// benchSizes are the fixture corpus sizes the search benchmarks run against;
+// -short drops the largest
+var benchSizes = []int{1_000, 10_000, 100_000}
+
+// benchQuery is a term from the generated database vocabulary
+const benchQuery = "index"
+
+// fixtures holds the generated fixture corpora, built once per run and shared
+// by every benchmark
+var fixtures struct {
+	sync.Mutex
+	dir   string
+	paths map[int]string
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if fixtures.dir != "" {
+		os.RemoveAll(fixtures.dir)
+	}
+	os.Exit(code)
+}
+
+// fixture returns the path of a database holding a seeded corpus of size
+// documents, generating it on first use
+func fixture(b *testing.B, size int) string {
+	b.Helper()
+	fixtures.Lock()
+	defer fixtures.Unlock()
+
+	if path, ok := fixtures.paths[size]; ok {
+		return path
+	}
+	if fixtures.dir == "" {
+		dir, err := os.MkdirTemp("", "bm25-bench-")
+		if err != nil {
+			b.Fatalf("create fixture directory: %v", err)
+		}
+		fixtures.dir = dir
+		fixtures.paths = make(map[int]string)
+	}
+
+	path := filepath.Join(fixtures.dir, fmt.Sprintf("corpus-%d.db", size))
+	db, err := database.NewDatabase(path)
+	if err != nil {
+		b.Fatalf("open fixture database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.InitSchema(context.Background()); err != nil {
+		b.Fatalf("create fixture schema: %v", err)
+	}
+	options := models.DefaultCorpusOptions()
+	options.Size = size
+	options.Seed = 1
+	options.DeferIndex = true
+	if _, err := NewCorpusHandler(db).GenerateCorpus(context.Background(), options); err != nil {
+		b.Fatalf("generate %d-document fixture: %v", size, err)
+	}
+
+	fixtures.paths[size] = path
+	return path
+}
+
+// runPerSize runs fn as a sub-benchmark against each fixture corpus
+func runPerSize(b *testing.B, fn func(b *testing.B, db *database.Database)) {
+	for _, size := range benchSizes {
+		if testing.Short() && size > 10_000 {
+			continue
+		}
+		b.Run(fmt.Sprintf("docs=%d", size), func(b *testing.B) {
+			db := useDatabaseFile(b, fixture(b, size))
+			b.ResetTimer()
+			fn(b, db)
+		})
+	}
+}
+
+// benchSearch times Search with options against each fixture corpus
+func benchSearch(b *testing.B, options models.SearchOptions) {
+	runPerSize(b, func(b *testing.B, db *database.Database) {
+		search := NewSearchHandler(db)
+		for i := 0; i < b.N; i++ {
+			if _, err := search.Search(context.Background(), options); err != nil {
+				b.Fatalf("Search: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkSearchDefault(b *testing.B) {
+	options := models.DefaultSearchOptions()
+	options.Query = benchQuery
+	options.IncludeSnippet = false
+	benchSearch(b, options)
+}
+
+func BenchmarkSearchWeighted(b *testing.B) {
+	options := models.DefaultSearchOptions()
+	options.Query = benchQuery
+	options.IncludeSnippet = false
+	options.ColumnWeights = map[string]float64{"title": 3, "content": 1, "category": 0.5}
+	benchSearch(b, options)
+}
+
+func BenchmarkSnippetGeneration(b *testing.B) {
+	options := models.DefaultSearchOptions()
+	options.Query = benchQuery
+	options.IncludeSnippet = true
+	options.Highlight = true
+	benchSearch(b, options)
+}
+
+func BenchmarkCorpusStats(b *testing.B) {
+	runPerSize(b, func(b *testing.B, db *database.Database) {
+		corpus := NewCorpusHandler(db)
+		for i := 0; i < b.N; i++ {
+			if _, err := corpus.GetCorpusStats(context.Background()); err != nil {
+				b.Fatalf("GetCorpusStats: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkBatchInsert1000(b *testing.B) {
+	rows := readContents(b, useDatabaseFile(b, fixture(b, 1_000)))
+	corpus := NewCorpusHandler(useTestDatabase(b))
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if err := corpus.ClearDocuments(ctx); err != nil {
+			b.Fatalf("ClearDocuments: %v", err)
+		}
+		docs := make([]*models.Document, len(rows))
+		for j, content := range rows {
+			docs[j] = &models.Document{Title: fmt.Sprintf("document %d", j), Content: content, Category: "technology"}
+		}
+		b.StartTimer()
+
+		if err := corpus.BatchInsertDocuments(ctx, docs); err != nil {
+			b.Fatalf("BatchInsertDocuments: %v", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+func TestBM25IDF(t *testing.T) {
+	tests := []struct {
+		name            string
+		rows, documents int
+		want            float64
+	}{
+		{"rare term", 100, 10, math.Log(90.5 / 10.5)},
+		{"unseen term", 100, 0, math.Log(100.5 / 0.5)},
+		{"single document", 1, 0, math.Log(1.5 / 0.5)},
+		// ln(1) = 0 and below are raised to the floor FTS5 uses
+		{"in exactly half the rows", 10, 5, 1e-6},
+		{"in most rows", 10, 8, 1e-6},
+		{"in every row", 10, 10, 1e-6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bm25IDF(tt.rows, tt.documents); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("bm25IDF(%d, %d) = %.12g, want %.12g", tt.rows, tt.documents, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25TermScoreWeightsColumns(t *testing.T) {
+	params := models.DefaultBM25Params
+	in := &bm25Inputs{rows: 1000, weights: []float64{2, 1, 0.5}}
+	term := database.TermStats{Term: "index", Documents: 10, ColumnCounts: []int{1, 3, 2}}
+	lengthNorm := 1.5
+
+	idf, tf, score := Search.bm25TermScore(in, term, lengthNorm, params)
+
+	wantIDF := math.Log(990.5 / 10.5)
+	// 2 × 1 in the title, 1 × 3 in the content, and 0.5 × 2 in the category
+	wantTF := 6.0
+	wantScore := wantIDF * wantTF * (params.K1 + 1) / (wantTF + lengthNorm)
+
+	if math.Abs(idf-wantIDF) > 1e-12 {
+		t.Errorf("idf = %.12g, want %.12g", idf, wantIDF)
+	}
+	if tf != wantTF {
+		t.Errorf("weighted tf = %g, want %g", tf, wantTF)
+	}
+	if math.Abs(score-wantScore) > 1e-12 {
+		t.Errorf("score = %.12g, want %.12g", score, wantScore)
+	}
+
+	// The per-column split hands each column its weighted share of the score
+	columns := Search.bm25ColumnScores(&bm25Inputs{
+		rows: in.rows, avgdl: 10, docLength: 10, weights: in.weights,
+		terms: []database.TermStats{term},
+	}, params)
+	// A document of average length normalizes by k1 alone
+	_, _, atAverage := Search.bm25TermScore(in, term, params.K1, params)
+	for c, share := range []float64{2.0 / 6, 3.0 / 6, 1.0 / 6} {
+		if want := atAverage * share; math.Abs(columns[c]-want) > 1e-12 {
+			t.Errorf("column %s score = %.12g, want %.12g", database.IndexedColumns[c], columns[c], want)
+		}
+	}
+}
+
+func TestCalculateLengthNormalization(t *testing.T) {
+	params := models.DefaultBM25Params
+	tests := []struct {
+		name      string
+		docLength int
+		avgLength float64
+		want      float64
+	}{
+		{"average length", 50, 50, params.K1},
+		{"twice the average", 100, 50, params.K1 * (0.25 + 0.75*2)},
+		{"empty document", 0, 50, params.K1 * 0.25},
+		{"empty corpus", 10, 0, params.K1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Search.calculateLengthNormalization(tt.docLength, tt.avgLength, params); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("calculateLengthNormalization(%d, %g) = %g, want %g", tt.docLength, tt.avgLength, got, tt.want)
+			}
+		})
+	}
+}
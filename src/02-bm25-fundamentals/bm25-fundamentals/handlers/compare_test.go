@@ -0,0 +1,69 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+func TestShortTitle(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"Short title", "Short title"},
+		{"Exactly twenty-five chars", "Exactly twenty-five chars"},
+		{"Twenty-six characters long", "Twenty-six characters lon..."},
+		// 25 runes are 50 bytes here; a byte cut would split the 13th character
+		{"Ωμέγα και άλφα στη βάση δεδομένων", "Ωμέγα και άλφα στη βάση δ..."},
+	}
+
+	for _, tt := range tests {
+		if got := shortTitle(tt.title); got != tt.want {
+			t.Errorf("shortTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestFormatColumnWeights(t *testing.T) {
+	tests := []struct {
+		weights map[string]float64
+		want    string
+	}{
+		{nil, ""},
+		{map[string]float64{"content": 1, "title": 5}, "title=5.00, content=1.00"},
+		{map[string]float64{"category": 0.25, "title": 2, "content": 1.5}, "title=2.00, content=1.50, category=0.25"},
+	}
+
+	for _, tt := range tests {
+		if got := formatColumnWeights(tt.weights); got != tt.want {
+			t.Errorf("formatColumnWeights(%v) = %q, want %q", tt.weights, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayScoreChangesHeader(t *testing.T) {
+	// Eight common documents, seven of them rescored
+	deltas := make([]models.ScoreDelta, 8)
+	for i := range deltas {
+		deltas[i] = models.ScoreDelta{
+			Strategy:   "weighted",
+			DocumentID: int64(i + 1),
+			Title:      fmt.Sprintf("Document %d", i+1),
+			Delta:      float64(7-i) / 10,
+		}
+	}
+
+	output := string(captureStdout(t, func() error {
+		Search.displayScoreChanges("weighted", deltas)
+		return nil
+	}))
+
+	want := fmt.Sprintf("(7 of 8 common documents changed, %d shown)", maxScoreChanges)
+	if !strings.Contains(output, want) {
+		t.Errorf("score changes header lacks %q:\n%s", want, output)
+	}
+}
@@ -1,27 +1,49 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+	"github.com/jaime/go-sqlite/shared/scan"
 	"github.com/spf13/cobra"
 )
 
 // Corpus is the global corpus handler instance
 var Corpus CorpusHandler
 
-// CorpusHandler manages corpus operations (stateless - accesses global instances)
-type CorpusHandler struct{}
+// CorpusHandler manages corpus operations against its database
+type CorpusHandler struct {
+	db *database.Database
+}
+
+// NewCorpusHandler creates a corpus handler that stores documents in db
+func NewCorpusHandler(db *database.Database) *CorpusHandler {
+	return &CorpusHandler{db: db}
+}
 
 // HandleGenerate handles the corpus generate command
 func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error {
@@ -33,6 +55,14 @@ func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error
 	titleMinTokens, _ := cmd.Flags().GetInt("title-min-tokens")
 	titleMaxTokens, _ := cmd.Flags().GetInt("title-max-tokens")
 	seed, _ := cmd.Flags().GetInt64("seed")
+	style, _ := cmd.Flags().GetString("style")
+	termRates, _ := cmd.Flags().GetString("term-rates")
+	injections, _ := cmd.Flags().GetStringArray("inject")
+	duplicateRate, _ := cmd.Flags().GetFloat64("duplicate-rate")
+	nearDuplicateRate, _ := cmd.Flags().GetFloat64("near-duplicate-rate")
+	manifestPath, _ := cmd.Flags().GetString("manifest")
+	workers, _ := cmd.Flags().GetInt("workers")
+	deferIndex, _ := cmd.Flags().GetBool("defer-index")
 	confirmClear, _ := cmd.Flags().GetBool("confirm")
 
 	// Start with default options
@@ -45,10 +75,6 @@ func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error
 		options.Size = config.App.Corpus.Size
 	}
 
-	if options.Size < 1 {
-		return errors.Validationf("corpus size must be at least 1")
-	}
-
 	// Apply flag overrides
 	if categories != "" {
 		options.Categories = strings.Split(categories, ",")
@@ -77,42 +103,46 @@ func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error
 		options.Seed = seed
 	}
 
-	// Validate configuration
-	if options.MinTokens >= options.MaxTokens {
-		return errors.Validationf("min-tokens (%d) must be less than max-tokens (%d)",
-			options.MinTokens, options.MaxTokens)
+	if style != "" {
+		options.Style = style
 	}
 
-	ctx := context.Background()
+	if termRates != "" {
+		rates, err := h.parseTermRates(termRates)
+		if err != nil {
+			return err
+		}
+		options.TermRates = rates
+	}
 
-	// Initialize schema
-	if err := database.Instance.InitSchema(ctx); err != nil {
-		return err
+	for _, spec := range injections {
+		injection, err := h.parseInjection(spec)
+		if err != nil {
+			return err
+		}
+		options.Injections = append(options.Injections, injection)
 	}
 
-	// Check if corpus already exists
-	existingCount, err := h.GetDocumentCount(ctx)
-	if err != nil {
+	options.DuplicateRate = duplicateRate
+	options.NearDuplicateRate = nearDuplicateRate
+	options.Workers = workers
+	options.DeferIndex = deferIndex
+
+	if err := h.ValidateOptions(options); err != nil {
 		return err
 	}
 
-	if existingCount > 0 && !confirmClear {
-		fmt.Printf("Corpus already contains %d documents.\n", existingCount)
-		fmt.Print("Do you want to clear the existing corpus? (y/N): ")
-
-		var response string
-		fmt.Scanln(&response)
-		response = strings.ToLower(strings.TrimSpace(response))
+	ctx := cmd.Context()
 
-		if response != "y" && response != "yes" {
-			fmt.Println("Corpus generation cancelled.")
-			return nil
-		}
+	// Initialize schema
+	if err := h.db.InitSchema(ctx); err != nil {
+		return err
+	}
 
-		if err := h.ClearDocuments(ctx); err != nil {
-			return err
-		}
-		fmt.Println("Existing corpus cleared.")
+	// Check if corpus already exists
+	proceed, err := h.confirmReplace(ctx, confirmClear, "Corpus generation")
+	if err != nil || !proceed {
+		return err
 	}
 
 	// Generate corpus
@@ -122,12 +152,24 @@ func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error
 		fmt.Printf("  Categories: %v\n", options.Categories)
 		fmt.Printf("  Document length: %d-%d tokens\n", options.MinTokens, options.MaxTokens)
 		fmt.Printf("  Title length: %d-%d tokens\n", options.TitleMinTokens, options.TitleMaxTokens)
+		fmt.Printf("  Content style: %s\n", options.Style)
+		for _, term := range sortedTerms(options.TermRates) {
+			fmt.Printf("  Term injection: %q in %.0f%% of documents\n", term, options.TermRates[term]*100)
+		}
+		for _, injection := range options.Injections {
+			fmt.Printf("  Phrase injection: %q in exactly %d documents\n", injection.Phrase, injection.Count)
+		}
 		if options.Seed != 0 {
 			fmt.Printf("  Random seed: %d\n", options.Seed)
 		}
+		if options.DuplicateRate > 0 || options.NearDuplicateRate > 0 {
+			fmt.Printf("  Duplicate rate: %.2f (near-duplicate rate: %.2f)\n",
+				options.DuplicateRate, options.NearDuplicateRate)
+		}
 	}
 
-	if err := h.GenerateCorpus(ctx, options); err != nil {
+	manifest, err := h.GenerateCorpus(ctx, options)
+	if err != nil {
 		return err
 	}
 
@@ -139,6 +181,22 @@ func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error
 
 	fmt.Printf("✓ Successfully generated %d documents\n", finalCount)
 
+	if len(manifest.Duplicates) > 0 {
+		exact, near := manifest.DuplicateCounts()
+		fmt.Printf("  Injected %d exact duplicates and %d near-duplicates\n", exact, near)
+	}
+
+	for _, injection := range manifest.Injections {
+		fmt.Printf("  Injected %q into %d documents\n", injection.Phrase, len(injection.IDs))
+	}
+
+	if manifestPath != "" {
+		if err := h.WriteManifest(manifestPath, manifest); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Generation manifest written to %s\n", manifestPath)
+	}
+
 	if config.App.Verbose {
 		// Show quick stats
 		stats, err := h.GetCorpusStats(ctx)
@@ -155,9 +213,184 @@ func (h *CorpusHandler) HandleGenerate(cmd *cobra.Command, args []string) error
 	return nil
 }
 
+// HandleImport handles the corpus import command
+func (h *CorpusHandler) HandleImport(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString("file")
+	confirmClear, _ := cmd.Flags().GetBool("confirm")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	deferIndex, _ := cmd.Flags().GetBool("defer-index")
+
+	if path == "" {
+		return errors.Validationf("--file is required")
+	}
+
+	ctx := cmd.Context()
+
+	// A dry run reads and validates the whole file without touching the database
+	if dryRun {
+		summary, err := h.ImportDocuments(ctx, path, true, false)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Dry run: %d documents in %s would be imported, %d would be skipped\n",
+			summary.Valid, path, len(summary.Skipped))
+		h.displaySkipped(summary.Skipped)
+		return nil
+	}
+
+	if err := h.db.InitSchema(ctx); err != nil {
+		return err
+	}
+
+	proceed, err := h.confirmReplace(ctx, confirmClear, "Corpus import")
+	if err != nil || !proceed {
+		return err
+	}
+
+	fmt.Printf("Importing documents from %s...\n", path)
+	start := time.Now()
+
+	summary, err := h.ImportDocuments(ctx, path, false, deferIndex)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Imported %d documents in %v (%s), skipped %d\n",
+		summary.Inserted, time.Since(start).Round(time.Millisecond), summary.Format, len(summary.Skipped))
+	h.displaySkipped(summary.Skipped)
+
+	return nil
+}
+
+// HandleImportFoundation handles the corpus import-foundation command
+func (h *CorpusHandler) HandleImportFoundation(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString("from")
+	confirmClear, _ := cmd.Flags().GetBool("confirm")
+
+	if path == "" {
+		return errors.Validationf("--from is required")
+	}
+
+	ctx := cmd.Context()
+
+	// Check the source before an existing corpus is cleared for it
+	if _, err := h.FoundationDocuments(ctx, path); err != nil {
+		return err
+	}
+
+	if err := h.db.InitSchema(ctx); err != nil {
+		return err
+	}
+
+	proceed, err := h.confirmReplace(ctx, confirmClear, "Foundation import")
+	if err != nil || !proceed {
+		return err
+	}
+
+	fmt.Printf("Converting documents from %s...\n", path)
+	start := time.Now()
+
+	summary, err := h.ImportFoundation(ctx, path, func(read, total int) {
+		fmt.Printf("  %d of %d documents read\n", read, total)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Converted %d documents in %v, skipped %d\n",
+		summary.Inserted, time.Since(start).Round(time.Millisecond), len(summary.Skipped))
+	h.displaySkipped(summary.Skipped)
+
+	return nil
+}
+
+// HandleExport handles the corpus export command
+func (h *CorpusHandler) HandleExport(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output")
+	formatFlag, _ := cmd.Flags().GetString("format")
+
+	format, err := exportFormat(formatFlag, outputPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.RequireSchema(ctx); err != nil {
+		return err
+	}
+
+	// Without --output the documents go to stdout, with nothing else mixed in
+	if outputPath == "" {
+		writer := bufio.NewWriter(os.Stdout)
+		if _, err := h.ExportDocuments(ctx, writer, format); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return errors.Validationf("failed to write export: %w", err)
+		}
+		return nil
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return errors.Validationf("failed to create export file %s: %w", outputPath, err)
+	}
+
+	start := time.Now()
+	writer := bufio.NewWriter(file)
+	count, err := h.ExportDocuments(ctx, writer, format)
+	if err == nil {
+		if flushErr := writer.Flush(); flushErr != nil {
+			err = errors.Validationf("failed to write export file %s: %w", outputPath, flushErr)
+		}
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = errors.Validationf("failed to write export file %s: %w", outputPath, closeErr)
+	}
+
+	// Leave no partial file behind that could later be imported as a whole corpus
+	if err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+
+	fmt.Printf("✓ Exported %d documents to %s (%s) in %v\n",
+		count, outputPath, format, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// maxSkippedShown is the number of skipped records listed without --verbose
+const maxSkippedShown = 10
+
+// displaySkipped lists why records were skipped, all of them in verbose mode
+func (h *CorpusHandler) displaySkipped(skipped []error) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	shown := skipped
+	if !config.App.Verbose && len(shown) > maxSkippedShown {
+		shown = shown[:maxSkippedShown]
+	}
+
+	fmt.Println("  Skipped records:")
+	for _, err := range shown {
+		fmt.Printf("    %s\n", errors.Message(err))
+	}
+	if hidden := len(skipped) - len(shown); hidden > 0 {
+		fmt.Printf("    ... and %d more (use --verbose to list all)\n", hidden)
+	}
+}
+
 // HandleStats handles the corpus stats command
 func (h *CorpusHandler) HandleStats(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx := cmd.Context()
+
+	if err := h.RequireDocuments(ctx); err != nil {
+		return err
+	}
 
 	// Get corpus statistics
 	stats, err := h.GetCorpusStats(ctx)
@@ -165,10 +398,6 @@ func (h *CorpusHandler) HandleStats(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if stats.TotalDocuments == 0 {
-		fmt.Println("No documents in corpus. Use 'corpus generate' to create a synthetic corpus.")
-		return nil
-	}
 
 	// Display statistics based on format
 	switch config.App.Format {
@@ -186,29 +415,49 @@ func (h *CorpusHandler) HandleStats(cmd *cobra.Command, args []string) error {
 		fmt.Printf("min_doc_length,%d\n", stats.MinDocLength)
 		fmt.Printf("max_doc_length,%d\n", stats.MaxDocLength)
 		fmt.Printf("unique_terms,%d\n", stats.UniqueTerms)
+		fmt.Printf("total_postings,%d\n", stats.TotalPostings)
 		fmt.Printf("categories,%d\n", len(stats.Categories))
+		for _, column := range database.IndexedColumns {
+			fmt.Printf("%s_documents,%d\n", column, stats.PerColumn[column].Documents)
+			fmt.Printf("%s_total_tokens,%d\n", column, stats.PerColumn[column].TotalTokens)
+			fmt.Printf("%s_avg_length,%.2f\n", column, stats.PerColumn[column].AvgLength)
+		}
 
 	default: // text format
+		numbers := config.App.Numbers()
+
 		fmt.Printf("Corpus Statistics\n")
 		fmt.Printf("=================\n\n")
 
-		fmt.Printf("Document Count: %d\n", stats.TotalDocuments)
-		fmt.Printf("Total Tokens: %d\n", stats.TotalTokens)
-		fmt.Printf("Unique Terms: %d\n", stats.UniqueTerms)
+		fmt.Printf("Document Count: %s\n", numbers.Int(stats.TotalDocuments))
+		fmt.Printf("Total Tokens: %s\n", numbers.Int64(stats.TotalTokens))
+		fmt.Printf("Unique Terms: %s\n", numbers.Int(stats.UniqueTerms))
+		fmt.Printf("Total Postings: %s\n", numbers.Int64(stats.TotalPostings))
 		fmt.Printf("\n")
 
 		fmt.Printf("Document Length Distribution:\n")
-		fmt.Printf("  Average: %.1f tokens\n", stats.AverageDocLength)
-		fmt.Printf("  Median:  %.1f tokens\n", stats.MedianDocLength)
-		fmt.Printf("  Range:   %d - %d tokens\n", stats.MinDocLength, stats.MaxDocLength)
+		fmt.Printf("  Average: %s tokens\n", numbers.Float(stats.AverageDocLength, 1))
+		fmt.Printf("  Median:  %s tokens\n", numbers.Float(stats.MedianDocLength, 1))
+		fmt.Printf("  Range:   %s - %s tokens\n", numbers.Int(stats.MinDocLength), numbers.Int(stats.MaxDocLength))
 		fmt.Printf("\n")
 
+		if len(stats.PerColumn) > 0 {
+			fmt.Printf("Column Lengths:\n")
+			fmt.Printf("  %-10s %10s %12s %10s\n", "Column", "Documents", "Tokens", "Average")
+			for _, column := range database.IndexedColumns {
+				col := stats.PerColumn[column]
+				fmt.Printf("  %-10s %10s %12s %10s\n", column,
+					numbers.Int(col.Documents), numbers.Int64(col.TotalTokens), numbers.Float(col.AvgLength, 1))
+			}
+			fmt.Printf("\n")
+		}
+
 		if len(stats.Categories) > 0 {
-			fmt.Printf("Categories (%d):\n", len(stats.Categories))
+			fmt.Printf("Categories (%s):\n", numbers.Int(len(stats.Categories)))
 			for _, cat := range stats.Categories {
 				count := stats.CategoryCounts[cat]
 				percentage := float64(count) * 100.0 / float64(stats.TotalDocuments)
-				fmt.Printf("  %-15s: %5d documents (%.1f%%)\n", cat, count, percentage)
+				fmt.Printf("  %-15s: %5s documents (%s)\n", cat, numbers.Int(count), numbers.Percent(percentage))
 			}
 			fmt.Printf("\n")
 		}
@@ -226,11 +475,131 @@ func (h *CorpusHandler) HandleStats(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// HandleCategories lists the categories in the corpus with their document
+// counts, average lengths, and creation ranges
+func (h *CorpusHandler) HandleCategories(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if err := h.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	categories, err := h.Categories(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(categories)
+
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write([]string{"category", "documents", "average_length", "earliest", "latest"})
+		for _, category := range categories {
+			writer.Write([]string{
+				category.Name,
+				strconv.Itoa(category.Documents),
+				strconv.FormatFloat(category.AverageLength, 'f', 2, 64),
+				formatCreated(category.CreatedRange.Start, time.RFC3339Nano),
+				formatCreated(category.CreatedRange.End, time.RFC3339Nano),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default: // text format
+		fmt.Printf("Categories (%d)\n", len(categories))
+		fmt.Printf("==============\n\n")
+
+		width := len("Category")
+		for _, category := range categories {
+			width = max(width, len(category.Name))
+		}
+
+		fmt.Printf("  %-*s %10s %11s  %s\n", width, "Category", "Documents", "Avg Length", "Created")
+		for _, category := range categories {
+			created := "unknown"
+			if !category.CreatedRange.Start.IsZero() {
+				created = formatCreated(category.CreatedRange.Start, "2006-01-02 15:04") + " to " +
+					formatCreated(category.CreatedRange.End, "2006-01-02 15:04")
+			}
+			fmt.Printf("  %-*s %10d %11.1f  %s\n", width, category.Name, category.Documents, category.AverageLength, created)
+		}
+	}
+
+	return nil
+}
+
+// Categories summarizes the documents in each category, most documents first
+// and ties in the display order
+func (h *CorpusHandler) Categories(ctx context.Context) ([]models.CategorySummary, error) {
+	categories, err := h.db.Categories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	order := config.App.CategoryOrder()
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].Documents != categories[j].Documents {
+			return categories[i].Documents > categories[j].Documents
+		}
+		return order.Less(categories[i].Name, categories[j].Name)
+	})
+	return categories, nil
+}
+
+// formatCreated formats a creation time in layout, or returns "" for the zero time
+func formatCreated(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// HandleFingerprint prints the SHA-256 fingerprint of the corpus
+func (h *CorpusHandler) HandleFingerprint(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if err := h.RequireSchema(ctx); err != nil {
+		return err
+	}
+
+	fingerprint, err := h.db.Fingerprint(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(fingerprint)
+
+	case "csv":
+		fmt.Println("sha256,documents")
+		fmt.Printf("%s,%d\n", fingerprint.SHA256, fingerprint.Documents)
+
+	default: // text format
+		fmt.Printf("Corpus fingerprint: %s\n", fingerprint.SHA256)
+		fmt.Printf("Documents: %d\n", fingerprint.Documents)
+	}
+
+	return nil
+}
+
 // HandleClear handles the corpus clear command
 func (h *CorpusHandler) HandleClear(cmd *cobra.Command, args []string) error {
 	confirmClear, _ := cmd.Flags().GetBool("confirm")
+	hard, _ := cmd.Flags().GetBool("hard")
+
+	ctx := cmd.Context()
 
-	ctx := context.Background()
+	if err := h.RequireSchema(ctx); err != nil {
+		return err
+	}
 
 	// Check current document count
 	count, err := h.GetDocumentCount(ctx)
@@ -238,14 +607,19 @@ func (h *CorpusHandler) HandleClear(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if count == 0 {
+	// An empty corpus can still carry index settings, which only a hard clear removes
+	if count == 0 && !hard {
 		fmt.Println("Corpus is already empty.")
 		return nil
 	}
 
 	// Confirm deletion
 	if !confirmClear {
-		fmt.Printf("This will delete all %d documents from the corpus.\n", count)
+		if hard {
+			fmt.Printf("This will delete all %d documents and recreate the corpus schema.\n", count)
+		} else {
+			fmt.Printf("This will delete all %d documents from the corpus.\n", count)
+		}
 		fmt.Print("Are you sure? (y/N): ")
 
 		var response string
@@ -259,233 +633,1495 @@ func (h *CorpusHandler) HandleClear(cmd *cobra.Command, args []string) error {
 	}
 
 	// Clear the corpus
-	fmt.Printf("Clearing %d documents...\n", count)
-	if err := h.ClearDocuments(ctx); err != nil {
-		return err
+	start := time.Now()
+	if hard {
+		fmt.Printf("Dropping %d documents and recreating the schema...\n", count)
+		if err := h.db.ResetSchema(ctx); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Clearing %d documents...\n", count)
+		if err := h.ClearDocuments(ctx); err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("✓ Corpus cleared successfully")
+	fmt.Printf("✓ Corpus cleared successfully in %v\n", time.Since(start).Round(time.Millisecond))
 	return nil
 }
 
-// InsertDocument adds a single document to the corpus
-func (h *CorpusHandler) InsertDocument(ctx context.Context, doc *models.Document) error {
-	// Calculate document length in tokens (simple whitespace tokenization)
-	doc.Length = len(strings.Fields(doc.Title + " " + doc.Content))
-
-	query := `
-		INSERT INTO documents (title, content, category, length, created) 
-		VALUES (?, ?, ?, ?, ?)
-		RETURNING id`
+// HandleRecount handles the corpus recount command
+func (h *CorpusHandler) HandleRecount(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 
-	err := database.Instance.DB().QueryRowContext(ctx, query,
-		doc.Title, doc.Content, doc.Category, doc.Length, doc.Created,
-	).Scan(&doc.ID)
+	if err := h.RequireDocuments(ctx); err != nil {
+		return err
+	}
 
+	checked, updated, err := h.RecountLengths(ctx)
 	if err != nil {
-		return errors.Databasef("failed to insert document: %w", err)
+		return err
 	}
 
-	return nil
-}
-
-// BatchInsertDocuments efficiently inserts multiple documents
-func (h *CorpusHandler) BatchInsertDocuments(ctx context.Context, docs []*models.Document) error {
-	if len(docs) == 0 {
-		return nil
-	}
+	fmt.Printf("✓ Checked %d documents against the FTS5 docsize table, updated %d lengths\n", checked, updated)
 
-	tx, err := database.Instance.Begin(ctx)
+	count, err := h.GetDocumentCount(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
-
-	stmt, err := tx.PrepareContext(ctx,
-		`INSERT INTO documents (title, content, category, length, created) 
-		 VALUES (?, ?, ?, ?, ?)`)
-	if err != nil {
-		return errors.Databasef("failed to prepare batch insert: %w", err)
+	if skipped := count - checked; skipped > 0 {
+		fmt.Printf("  %d documents are missing from the index and kept their length — run 'db resync' and recount\n", skipped)
 	}
-	defer stmt.Close()
 
-	for _, doc := range docs {
-		// Calculate document length
-		doc.Length = len(strings.Fields(doc.Title + " " + doc.Content))
+	return nil
+}
 
-		if _, err := stmt.ExecContext(ctx,
-			doc.Title, doc.Content, doc.Category, doc.Length, doc.Created); err != nil {
-			return errors.Databasef("failed to insert document in batch: %w", err)
-		}
+// RecountLengths sets each document's length to the token total FTS5 recorded
+// for it in the docsize shadow table, returning how many indexed documents were
+// checked and how many lengths changed
+func (h *CorpusHandler) RecountLengths(ctx context.Context) (checked, updated int, err error) {
+	type recount struct {
+		id     int64
+		length int
 	}
 
-	return tx.Commit()
-}
+	err = h.db.WithTx(ctx, func(tx *sql.Tx) error {
+		checked = 0
+		var changes []recount
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT d.id, d.length, s.sz
+			FROM documents d
+			JOIN documents_fts_docsize s ON s.id = d.id
+			ORDER BY d.id`)
+		if err != nil {
+			return errors.FTS5f("failed to read document sizes: %w", err)
+		}
+		defer rows.Close()
+
+		for row := 1; rows.Next(); row++ {
+			var id int64
+			var length int
+			var sz []byte
+			if err := rows.Scan(&id, &length, &sz); err != nil {
+				return errors.Databasef("failed to scan document size at row %d: %w", row, err)
+			}
 
-// GetDocumentCount returns the total number of documents
-func (h *CorpusHandler) GetDocumentCount(ctx context.Context) (int, error) {
-	var count int
-	err := database.Instance.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM documents").Scan(&count)
+			sizes, err := database.DecodeDocSize(sz)
+			if err != nil {
+				return errors.FTS5f("document %d: %w", id, err)
+			}
+
+			total := 0
+			for _, size := range sizes {
+				total += size
+			}
+
+			checked++
+			if total != length {
+				changes = append(changes, recount{id: id, length: total})
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return errors.Databasef("error iterating document sizes after %d rows: %w", checked, err)
+		}
+		rows.Close()
+
+		stmt, err := tx.PrepareContext(ctx, "UPDATE documents SET length = ? WHERE id = ?")
+		if err != nil {
+			return errors.Databasef("failed to prepare length update: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, change := range changes {
+			if _, err := stmt.ExecContext(ctx, change.length, change.id); err != nil {
+				return errors.Databasef("failed to update length of document %d: %w", change.id, err)
+			}
+		}
+
+		updated = len(changes)
+		return nil
+	})
+
+	return checked, updated, err
+}
+
+// InsertDocument adds a single document to the corpus
+func (h *CorpusHandler) InsertDocument(ctx context.Context, doc *models.Document) error {
+	doc.Category = config.App.NormalizeCategory(doc.Category)
+	doc.ApplyDefaults(time.Now())
+
+	// Count tokens the way the FTS5 tokenizer does so length matches BM25's
+	doc.Length = database.TokenCount(doc.Title, doc.Content, doc.Category)
+
+	query := `
+		INSERT INTO documents (title, content, category, length, created) 
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id`
+
+	err := h.db.QueryRowContext(ctx, query,
+		doc.Title, doc.Content, doc.Category, doc.Length, doc.Created,
+	).Scan(&doc.ID)
+
+	if err != nil {
+		return errors.Databasef("failed to insert document: %w", err)
+	}
+
+	return nil
+}
+
+// BatchInsertDocuments efficiently inserts multiple documents
+func (h *CorpusHandler) BatchInsertDocuments(ctx context.Context, docs []*models.Document) error {
+	return h.insertDocuments(ctx, len(docs), false, func(i int) (*models.Document, error) {
+		return docs[i], nil
+	}, nil)
+}
+
+// insertDocuments inserts count documents in one transaction, taking each from
+// next in index order; next may block until the document is ready. With
+// deferIndex the documents are indexed together once all are inserted. A
+// non-nil check runs in the same transaction once the documents are searchable,
+// so an error from it leaves nothing inserted.
+func (h *CorpusHandler) insertDocuments(ctx context.Context, count int, deferIndex bool, next func(i int) (*models.Document, error), check func(tx *sql.Tx) error) error {
+	if count == 0 {
+		return nil
+	}
+
+	var err error
+	if deferIndex {
+		err = h.db.WithDeferredIndex(ctx, func(tx *sql.Tx) error {
+			return h.insertRows(ctx, tx, count, next)
+		}, check)
+	} else {
+		err = h.db.WithTx(ctx, func(tx *sql.Tx) error {
+			if err := h.insertRows(ctx, tx, count, next); err != nil {
+				return err
+			}
+			if check != nil {
+				return check(tx)
+			}
+			return nil
+		})
+	}
+
+	// An interruption that lands on the commit still leaves nothing inserted
+	if err != nil && ctx.Err() != nil && !stderrors.Is(err, errors.ErrCancelled) {
+		return h.batchCancelled(count, count)
+	}
+	return err
+}
+
+// insertRows inserts count documents taken from next in tx, setting their
+// normalized category, defaults, length, and ID
+func (h *CorpusHandler) insertRows(ctx context.Context, tx *sql.Tx, count int, next func(i int) (*models.Document, error)) error {
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO documents (title, content, category, length, created) 
+		 VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return errors.Databasef("failed to prepare batch insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < count; i++ {
+		// Stop between rows on interruption; the transaction rolls back the partial batch
+		if ctx.Err() != nil {
+			return h.batchCancelled(i, count)
+		}
+
+		doc, err := next(i)
+		if err != nil {
+			if ctx.Err() != nil {
+				return h.batchCancelled(i, count)
+			}
+			return err
+		}
+
+		// Store the category in its normalized form and fill missing fields,
+		// then calculate document length
+		doc.Category = config.App.NormalizeCategory(doc.Category)
+		doc.ApplyDefaults(time.Now())
+		doc.Length = database.TokenCount(doc.Title, doc.Content, doc.Category)
+
+		result, err := stmt.ExecContext(ctx,
+			doc.Title, doc.Content, doc.Category, doc.Length, doc.Created)
+		if err != nil {
+			if ctx.Err() != nil {
+				return h.batchCancelled(i, count)
+			}
+			return errors.Databasef("failed to insert document in batch: %w", err)
+		}
+
+		if doc.ID, err = result.LastInsertId(); err != nil {
+			return errors.Databasef("failed to get inserted document ID: %w", err)
+		}
+	}
+	return nil
+}
+
+// batchCancelled reports how far an interrupted batch insert got before it was rolled back
+func (h *CorpusHandler) batchCancelled(inserted, total int) error {
+	return errors.Cancelledf("interrupted after inserting %d of %d documents; the batch was rolled back and no documents were added",
+		inserted, total)
+}
+
+// confirmReplace asks before replacing a corpus that already holds documents,
+// clearing it if the user agrees or confirmed up front. It reports whether the
+// command should go on; action names the command in the cancellation message.
+func (h *CorpusHandler) confirmReplace(ctx context.Context, confirmed bool, action string) (bool, error) {
+	existingCount, err := h.GetDocumentCount(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if existingCount == 0 {
+		return true, nil
+	}
+
+	if !confirmed {
+		fmt.Printf("Corpus already contains %d documents.\n", existingCount)
+		fmt.Print("Do you want to clear the existing corpus? (y/N): ")
+
+		var response string
+		fmt.Scanln(&response)
+		response = strings.ToLower(strings.TrimSpace(response))
+
+		if response != "y" && response != "yes" {
+			fmt.Printf("%s cancelled.\n", action)
+			return false, nil
+		}
+	}
+
+	if err := h.ClearDocuments(ctx); err != nil {
+		return false, err
+	}
+	fmt.Println("Existing corpus cleared.")
+	return true, nil
+}
+
+// GetDocumentCount returns the total number of documents
+func (h *CorpusHandler) GetDocumentCount(ctx context.Context) (int, error) {
+	var count int
+	err := h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents").Scan(&count)
+	if err != nil {
+		return 0, errors.Databasef("failed to get document count: %w", err)
+	}
+	return count, nil
+}
+
+// RequireSchema returns a NotFound error when the corpus tables do not exist.
+// With auto_init set (--auto-init), it creates them instead.
+func (h *CorpusHandler) RequireSchema(ctx context.Context) error {
+	ready, err := h.db.HasSchema(ctx)
+	if err != nil || ready {
+		return err
+	}
+
+	if !config.App.AutoInit {
+		return errors.NotFoundf("corpus schema not found — run 'db init' to create it, or 'corpus generate' to build a corpus")
+	}
+
+	if err := h.db.InitSchema(ctx); err != nil {
+		return err
+	}
+	if config.App.Verbose {
+		fmt.Fprintln(os.Stderr, "Created corpus schema (auto_init)")
+	}
+	return nil
+}
+
+// RequireDocuments returns a NotFound error when there is nothing to search,
+// covering both a database without the corpus schema and an empty corpus
+func (h *CorpusHandler) RequireDocuments(ctx context.Context) error {
+	if err := h.RequireSchema(ctx); err != nil {
+		return err
+	}
+
+	count, err := h.GetDocumentCount(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return errors.NotFoundf("corpus is empty — run 'corpus generate' or 'corpus import'")
+}
+
+// ClearDocuments removes all documents from the corpus
+func (h *CorpusHandler) ClearDocuments(ctx context.Context) error {
+	return h.db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Clear documents table (triggers will handle FTS5 cleanup)
+		if _, err := tx.ExecContext(ctx, "DELETE FROM documents"); err != nil {
+			return errors.Databasef("failed to clear documents: %w", err)
+		}
+
+		// Reset auto-increment counter
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sqlite_sequence WHERE name='documents'"); err != nil {
+			// This might fail if no auto-increment has occurred yet, which is fine
+		}
+
+		// Optimize FTS5 index
+		if _, err := tx.ExecContext(ctx, "INSERT INTO documents_fts(documents_fts) VALUES('optimize')"); err != nil {
+			return errors.FTS5f("failed to optimize FTS5 index: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetCorpusStats calculates comprehensive statistics about the corpus
+func (h *CorpusHandler) GetCorpusStats(ctx context.Context) (*models.CorpusStats, error) {
+	stats := &models.CorpusStats{
+		CategoryCounts: make(map[string]int),
+		LastUpdated:    time.Now(),
+	}
+
+	// Get basic counts and length statistics
+	query := `
+		SELECT 
+			COUNT(*) as total_docs,
+			COALESCE(SUM(length), 0) as total_tokens,
+			COALESCE(AVG(length), 0) as avg_length,
+			COALESCE(MIN(length), 0) as min_length,
+			COALESCE(MAX(length), 0) as max_length,
+			MIN(created) as earliest,
+			MAX(created) as latest
+		FROM documents`
+
+	var earliest, latest sql.NullString
+	err := h.db.QueryRowContext(ctx, query).Scan(
+		&stats.TotalDocuments,
+		&stats.TotalTokens,
+		&stats.AverageDocLength,
+		&stats.MinDocLength,
+		&stats.MaxDocLength,
+		&earliest,
+		&latest,
+	)
+
+	if err != nil {
+		return nil, errors.Databasef("failed to get basic corpus stats: %w", err)
+	}
+
+	// MIN and MAX skip NULL timestamps; an unparseable one leaves the range unset
+	if earliest.Valid {
+		if t, ok := database.ParseTime(earliest.String); ok {
+			stats.CreatedRange.Start = t
+		}
+	}
+	if latest.Valid {
+		if t, ok := database.ParseTime(latest.String); ok {
+			stats.CreatedRange.End = t
+		}
+	}
+
+	// Get median document length
+	medianQuery := `
+		SELECT length 
+		FROM documents 
+		ORDER BY length 
+		LIMIT 1 
+		OFFSET (SELECT (COUNT(*) - 1) / 2 FROM documents)`
+
+	err = h.db.QueryRowContext(ctx, medianQuery).Scan(&stats.MedianDocLength)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.Databasef("failed to get median document length: %w", err)
+	}
+
+	// Get category breakdown
+	categoryQuery := `
+		SELECT category, COUNT(*) 
+		FROM documents 
+		GROUP BY category 
+		ORDER BY COUNT(*) DESC`
+
+	rows, err := h.db.QueryContext(ctx, categoryQuery)
+	if err != nil {
+		return nil, errors.Databasef("failed to get category breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	_, err = scan.Each(rows, "category count", func(row scan.Rows) error {
+		var category string
+		var count int
+		if err := row.Scan(scan.Text(&category), &count); err != nil {
+			return err
+		}
+		stats.Categories = append(stats.Categories, category)
+		stats.CategoryCounts[category] = count
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite orders by count only; break ties in the display order
+	stats.Categories = models.CategoryCounts(stats.CategoryCounts).SortedBy(config.App.CategoryOrder().Less)
+
+	// Get per-column lengths as FTS5 counted them
+	stats.PerColumn, err = h.getColumnStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the vocabulary size from the index
+	vocab, err := h.db.Vocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.UniqueTerms = vocab.Terms
+	stats.TotalPostings = vocab.Postings
+
+	return stats, nil
+}
+
+// getColumnStats totals the per-column token counts in the docsize shadow table
+func (h *CorpusHandler) getColumnStats(ctx context.Context) (map[string]models.ColumnStats, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT sz FROM documents_fts_docsize")
+	if err != nil {
+		return nil, errors.FTS5f("failed to read document sizes: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make([]models.ColumnStats, len(database.IndexedColumns))
+	indexed := 0
+
+	for rows.Next() {
+		var sz []byte
+		if err := rows.Scan(&sz); err != nil {
+			return nil, errors.Databasef("failed to scan document size at row %d: %w", indexed+1, err)
+		}
+
+		sizes, err := database.DecodeDocSize(sz)
+		if err != nil {
+			return nil, errors.FTS5f("document size at row %d: %w", indexed+1, err)
+		}
+
+		for i := range columns {
+			if i < len(sizes) && sizes[i] > 0 {
+				columns[i].Documents++
+				columns[i].TotalTokens += int64(sizes[i])
+			}
+		}
+		indexed++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Databasef("error iterating document sizes after %d rows: %w", indexed, err)
+	}
+
+	perColumn := make(map[string]models.ColumnStats, len(columns))
+	for i, column := range database.IndexedColumns {
+		if indexed > 0 {
+			columns[i].AvgLength = float64(columns[i].TotalTokens) / float64(indexed)
+		}
+		perColumn[column] = columns[i]
+	}
+	return perColumn, nil
+}
+
+// GenerateCorpus creates a synthetic corpus for BM25 experimentation and returns its manifest
+func (h *CorpusHandler) GenerateCorpus(ctx context.Context, options models.CorpusOptions) (*models.CorpusManifest, error) {
+	// Set up random seed for reproducible generation
+	if options.Seed == 0 {
+		options.Seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(options.Seed))
+	baseTime := seedBaseTime(options.Seed)
+
+	// Split the corpus into originals and injected duplicates
+	exactCount := int(math.Round(options.DuplicateRate * float64(options.Size)))
+	nearCount := int(math.Round(options.NearDuplicateRate * float64(options.Size)))
+	originalCount := options.Size - exactCount - nearCount
+	if originalCount < 1 {
+		return nil, errors.Validationf("duplicate rates leave no original documents to copy from")
+	}
+
+	// Plan duplicates and phrase injections up front. The plan depends only on
+	// the counts, not on document content, so the originals can be generated
+	// concurrently while the inserter applies the plan in index order.
+	type duplicate struct {
+		index  int
+		source int
+		kind   string
+	}
+	duplicates := make([]duplicate, 0, exactCount+nearCount)
+	isSource := make(map[int]bool)
+
+	for i := 0; i < exactCount+nearCount; i++ {
+		kind := "exact"
+		if i >= exactCount {
+			kind = "near"
+		}
+		source := rng.Intn(originalCount)
+		duplicates = append(duplicates, duplicate{index: originalCount + i, source: source, kind: kind})
+		isSource[source] = true
+	}
+
+	// Inject phrases into exactly N originals; duplicate sources are skipped so
+	// copies never carry a phrase and the injected counts stay exact
+	candidates := make([]int, 0, originalCount)
+	for i := 0; i < originalCount; i++ {
+		if !isSource[i] {
+			candidates = append(candidates, i)
+		}
+	}
+
+	injected := make([][]int, len(options.Injections))
+	phrasesFor := make(map[int][]string)
+	for i, injection := range options.Injections {
+		if injection.Count > len(candidates) {
+			return nil, errors.Validationf("cannot inject %q into %d documents: only %d eligible documents",
+				injection.Phrase, injection.Count, len(candidates))
+		}
+
+		for _, pick := range rng.Perm(len(candidates))[:injection.Count] {
+			index := candidates[pick]
+			phrasesFor[index] = append(phrasesFor[index], injection.Phrase)
+			injected[i] = append(injected[i], index)
+		}
+		sort.Ints(injected[i])
+	}
+
+	// The inserter finishes documents sequentially with its own generator
+	finisher := &corpusGenerator{
+		rng:      rand.New(rand.NewSource(rng.Int63())),
+		options:  options,
+		baseTime: baseTime,
+	}
+
+	docs := make([]*models.Document, options.Size)
+	pool := newGenerationPool(ctx, options, baseTime, originalCount, docs)
+	defer pool.stop()
+
+	// finished guards against applying the plan twice if the insert transaction is retried
+	finished := make([]bool, len(docs))
+	next := func(i int) (*models.Document, error) {
+		if finished[i] {
+			return docs[i], nil
+		}
+		defer func() { finished[i] = docs[i] != nil }()
+
+		if i < originalCount {
+			if err := pool.wait(i); err != nil {
+				return nil, err
+			}
+			for _, phrase := range phrasesFor[i] {
+				docs[i].Content = finisher.injectPhrase(docs[i].Content, docs[i].Category, phrase)
+			}
+			return docs[i], nil
+		}
+
+		dup := duplicates[i-originalCount]
+		if dup.kind == "near" {
+			docs[i] = finisher.nearDuplicate(docs[dup.source])
+		} else {
+			docs[i] = finisher.exactDuplicate(docs[dup.source])
+		}
+		return docs[i], nil
+	}
+
+	// Insert documents as their shards complete, all in one transaction, and
+	// reject phrases the generator also produced on its own
+	checkInjections := func(tx *sql.Tx) error {
+		return h.checkInjections(ctx, tx, options.Injections, docs[0].ID, docs[len(docs)-1].ID)
+	}
+	if err := h.insertDocuments(ctx, len(docs), options.DeferIndex, next, checkInjections); err != nil {
+		return nil, err
+	}
+
+	manifest := &models.CorpusManifest{
+		Options:       options,
+		Generated:     time.Now(),
+		BaseTime:      baseTime,
+		DocumentCount: len(docs),
+	}
+
+	for _, dup := range duplicates {
+		manifest.Duplicates = append(manifest.Duplicates, models.DuplicateRecord{
+			ID:       docs[dup.index].ID,
+			SourceID: docs[dup.source].ID,
+			Kind:     dup.kind,
+		})
+	}
+
+	for i, injection := range options.Injections {
+		record := models.InjectionRecord{
+			Phrase: injection.Phrase,
+			IDs:    make([]int64, 0, len(injected[i])),
+		}
+		for _, index := range injected[i] {
+			record.IDs = append(record.IDs, docs[index].ID)
+		}
+		manifest.Injections = append(manifest.Injections, record)
+	}
+
+	return manifest, nil
+}
+
+// checkInjections verifies that each injected phrase matches exactly the
+// documents it was injected into among the generated IDs firstID through
+// lastID. The phrase is matched as an FTS5 phrase, with the same case folding
+// and stemming as a search, so a generated document that happens to contain it
+// is caught and the manifest's relevant set stays exact.
+func (h *CorpusHandler) checkInjections(ctx context.Context, tx *sql.Tx, injections []models.PhraseInjection, firstID, lastID int64) error {
+	for _, injection := range injections {
+		match, err := database.MatchExpression(injection.Phrase, models.QueryModePhrase, 0)
+		if err != nil {
+			return err
+		}
+
+		var matches int
+		err = tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM documents_fts
+			 WHERE documents_fts MATCH ? AND rowid BETWEEN ? AND ?`,
+			match, firstID, lastID).Scan(&matches)
+		if err != nil {
+			return errors.Databasef("failed to count documents containing %q: %w", injection.Phrase, err)
+		}
+
+		if matches > injection.Count {
+			return errors.Validationf("phrase %q already occurs in %d generated documents besides the %d it was injected into; choose a phrase the generator does not produce",
+				injection.Phrase, matches-injection.Count, injection.Count)
+		}
+	}
+	return nil
+}
+
+// ValidateOptions checks corpus generation options for inconsistent settings
+func (h *CorpusHandler) ValidateOptions(options models.CorpusOptions) error {
+	if options.Size < 1 {
+		return errors.Validationf("corpus size must be at least 1")
+	}
+
+	if options.MinTokens >= options.MaxTokens {
+		return errors.Validationf("min-tokens (%d) must be less than max-tokens (%d)",
+			options.MinTokens, options.MaxTokens)
+	}
+
+	switch options.Style {
+	case "words", "sentences":
+		// Valid styles
+	default:
+		return errors.Validationf("invalid style: %s (must be words or sentences)", options.Style)
+	}
+
+	if options.DuplicateRate < 0 || options.DuplicateRate >= 1 {
+		return errors.Validationf("duplicate-rate (%.2f) must be in the range [0, 1)", options.DuplicateRate)
+	}
+	if options.NearDuplicateRate < 0 || options.NearDuplicateRate >= 1 {
+		return errors.Validationf("near-duplicate-rate (%.2f) must be in the range [0, 1)", options.NearDuplicateRate)
+	}
+	if options.Workers < 0 {
+		return errors.Validationf("workers (%d) cannot be negative", options.Workers)
+	}
+	if options.DuplicateRate+options.NearDuplicateRate >= 1 {
+		return errors.Validationf("duplicate-rate + near-duplicate-rate (%.2f) must be less than 1",
+			options.DuplicateRate+options.NearDuplicateRate)
+	}
+
+	return nil
+}
+
+// LoadManifest reads a generation manifest written by WriteManifest
+func (h *CorpusHandler) LoadManifest(path string) (*models.CorpusManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NotFoundf("manifest file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var manifest models.CorpusManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, errors.Validationf("failed to parse manifest %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+// WriteManifest saves a generation manifest as indented JSON
+func (h *CorpusHandler) WriteManifest(path string, manifest *models.CorpusManifest) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Validationf("failed to create manifest file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return errors.Validationf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// ImportDocuments reads documents from a file and inserts them in batches of
+// corpus.batch_size, each batch in its own transaction. A .csv file is read as
+// CSV with a header row; any other file holds either a JSON array of documents
+// or one document per line (JSONL), and blank lines are ignored. A record
+// without a title or content is skipped and its validation error kept in the
+// summary; a missing category becomes models.DefaultCategory and a missing
+// created time the time of import. A file that cannot be parsed at all stops
+// the import. With dryRun the file is only validated and counted.
+//
+// With deferIndex the whole import is one transaction run through
+// Database.WithDeferredIndex: the documents are indexed together at the end,
+// and a failure anywhere leaves nothing imported.
+func (h *CorpusHandler) ImportDocuments(ctx context.Context, path string, dryRun, deferIndex bool) (*models.ImportSummary, error) {
+	if dryRun {
+		return h.importFile(ctx, path, nil, false)
+	}
+
+	if !deferIndex {
+		return h.importFile(ctx, path, func(batch []*models.Document) error {
+			return h.BatchInsertDocuments(ctx, batch)
+		}, true)
+	}
+
+	var summary *models.ImportSummary
+	err := h.db.WithDeferredIndex(ctx, func(tx *sql.Tx) error {
+		// A retried transaction reads the file again from the start
+		var err error
+		summary, err = h.importFile(ctx, path, func(batch []*models.Document) error {
+			return h.insertRows(ctx, tx, len(batch), func(i int) (*models.Document, error) {
+				return batch[i], nil
+			})
+		}, false)
+		return err
+	}, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.Cancelledf("import interrupted; the deferred import was rolled back and no documents were added")
+		}
+		return nil, err
+	}
+	return summary, nil
+}
+
+// importFile reads path and passes its valid documents to insert in batches
+// of corpus.batch_size, as ImportDocuments describes; a nil insert only
+// validates and counts them. commits reports whether each insert commits on
+// its own, so that errors mention the batches kept before a failure.
+func (h *CorpusHandler) importFile(ctx context.Context, path string, insert func(batch []*models.Document) error, commits bool) (*models.ImportSummary, error) {
+	dryRun := insert == nil
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NotFoundf("import file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var reader *importReader
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		reader, err = newCSVImportReader(file)
+	} else {
+		reader, err = newImportReader(file)
+	}
+	if err != nil {
+		return nil, errors.Validationf("failed to read import file %s: %w", path, err)
+	}
+
+	summary := &models.ImportSummary{Format: reader.format}
+	batch := make([]*models.Document, 0, config.App.Corpus.BatchSize)
+
+	// Earlier batches are already committed when a later one fails
+	partial := func(err error) error {
+		if summary.Inserted == 0 || !commits {
+			return err
+		}
+		return fmt.Errorf("%w (%d documents from earlier batches were imported)", err, summary.Inserted)
+	}
+
+	flush := func() error {
+		if len(batch) == 0 || dryRun {
+			batch = batch[:0]
+			return nil
+		}
+
+		if err := insert(batch); err != nil {
+			if stderrors.Is(err, errors.ErrCancelled) {
+				return errors.Cancelledf("import interrupted after %d documents; the batch in progress was rolled back",
+					summary.Inserted)
+			}
+			return partial(err)
+		}
+
+		summary.Inserted += len(batch)
+		batch = make([]*models.Document, 0, config.App.Corpus.BatchSize)
+		return nil
+	}
+
+	for {
+		if ctx.Err() != nil {
+			if dryRun {
+				return nil, errors.Cancelledf("dry run interrupted after reading %d records", summary.Valid+len(summary.Skipped))
+			}
+			return nil, errors.Cancelledf("import interrupted after %d documents", summary.Inserted)
+		}
+
+		record, position, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, partial(errors.Validationf("failed to read import file %s: %w", path, err))
+		}
+
+		doc, err := record.document(position)
+		if err != nil {
+			summary.Skipped = append(summary.Skipped, err)
+			continue
+		}
+
+		summary.Valid++
+		batch = append(batch, doc)
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// foundationSchema is the name the phase 1 database is attached under
+const foundationSchema = "foundation"
+
+// ImportFoundation copies the documents of a phase 1 (fts5-foundation)
+// database into the corpus. Phase 1 keeps documents in a standalone FTS5
+// table with no creation time or length, so each document gets the time of
+// the import as its created time and its length is counted as on any insert.
+// Documents are read in rowid order and inserted in batches of
+// corpus.batch_size, each in its own transaction, and receive new ids.
+// Documents without a title or content are skipped as in ImportDocuments.
+// progress, when set, is called after each batch with the documents read so
+// far and the total.
+func (h *CorpusHandler) ImportFoundation(ctx context.Context, path string, progress func(read, total int)) (*models.ImportSummary, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.NotFoundf("foundation database %s: %w", path, err)
+	}
+
+	summary := &models.ImportSummary{Format: "fts5-foundation"}
+	err := h.db.WithAttached(ctx, path, foundationSchema, func(conn *sql.Conn) error {
+		total, err := h.countFoundation(ctx, conn, path)
+		if err != nil {
+			return err
+		}
+
+		// One creation time for the whole conversion
+		imported := time.Now()
+
+		// Earlier batches are already committed when a later one fails
+		partial := func(err error) error {
+			if stderrors.Is(err, errors.ErrCancelled) {
+				return errors.Cancelledf("conversion interrupted after %d documents; the batch in progress was rolled back",
+					summary.Inserted)
+			}
+			if summary.Inserted == 0 {
+				return err
+			}
+			return fmt.Errorf("%w (%d documents from earlier batches were imported)", err, summary.Inserted)
+		}
+
+		var lastRowID int64
+		read := 0
+		for {
+			if ctx.Err() != nil {
+				return errors.Cancelledf("conversion interrupted after %d documents", summary.Inserted)
+			}
+
+			rows, err := conn.QueryContext(ctx, `
+				SELECT rowid, title, content, category FROM foundation.documents
+				WHERE rowid > ? ORDER BY rowid LIMIT ?`, lastRowID, config.App.Corpus.BatchSize)
+			if err != nil {
+				return partial(errors.FTS5f("failed to read documents from %s: %w", path, err))
+			}
+
+			var batch []*models.Document
+			count, err := scan.Each(rows, "foundation document", func(row scan.Rows) error {
+				var title, content, category string
+				if err := row.Scan(&lastRowID, scan.Text(&title), scan.Text(&content), scan.Text(&category)); err != nil {
+					return err
+				}
+
+				record := importRecord{Title: &title, Content: &content, Category: &category}
+				doc, err := record.document(fmt.Sprintf("rowid %d", lastRowID))
+				if err != nil {
+					summary.Skipped = append(summary.Skipped, err)
+					return nil
+				}
+				doc.Created = imported
+				batch = append(batch, doc)
+				return nil
+			})
+			rows.Close()
+			if err != nil {
+				return partial(err)
+			}
+			if count == 0 {
+				return nil
+			}
+			read += count
+			summary.Valid += len(batch)
+
+			tx, err := conn.BeginTx(ctx, nil)
+			if err != nil {
+				return partial(errors.Databasef("failed to begin transaction: %w", err))
+			}
+			err = h.insertRows(ctx, tx, len(batch), func(i int) (*models.Document, error) {
+				return batch[i], nil
+			})
+			if err != nil {
+				tx.Rollback()
+				return partial(err)
+			}
+			if err := tx.Commit(); err != nil {
+				return partial(errors.Databasef("failed to commit batch: %w", err))
+			}
+
+			summary.Inserted += len(batch)
+			if progress != nil {
+				progress(read, total)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// FoundationDocuments checks that path holds a phase 1 database and returns
+// the number of documents in it
+func (h *CorpusHandler) FoundationDocuments(ctx context.Context, path string) (int, error) {
+	// ATTACH creates a missing file, so look for it first
+	if _, err := os.Stat(path); err != nil {
+		return 0, errors.NotFoundf("foundation database %s: %w", path, err)
+	}
+
+	var total int
+	err := h.db.WithAttached(ctx, path, foundationSchema, func(conn *sql.Conn) error {
+		var err error
+		total, err = h.countFoundation(ctx, conn, path)
+		return err
+	})
+	return total, err
+}
+
+// countFoundation counts the documents of the phase 1 database attached on
+// conn, failing when it has no documents FTS5 table
+func (h *CorpusHandler) countFoundation(ctx context.Context, conn *sql.Conn, path string) (int, error) {
+	var schema string
+	err := conn.QueryRowContext(ctx,
+		"SELECT sql FROM foundation.sqlite_master WHERE type = 'table' AND name = 'documents'").Scan(scan.Text(&schema))
+	if err == sql.ErrNoRows || (err == nil && !strings.Contains(strings.ToLower(schema), "using fts5")) {
+		return 0, errors.Validationf("%s is not a phase 1 database: it has no documents FTS5 table", path)
+	}
 	if err != nil {
-		return 0, errors.Databasef("failed to get document count: %w", err)
+		return 0, errors.Databasef("failed to read schema of %s: %w", path, err)
 	}
-	return count, nil
+
+	var total int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM foundation.documents").Scan(&total); err != nil {
+		return 0, errors.FTS5f("failed to count documents in %s: %w", path, err)
+	}
+	return total, nil
 }
 
-// ClearDocuments removes all documents from the corpus
-func (h *CorpusHandler) ClearDocuments(ctx context.Context) error {
-	tx, err := database.Instance.Begin(ctx)
-	if err != nil {
-		return err
+// importRecord is one document as it appears in an import file. Pointer fields
+// tell a missing field apart from an empty one.
+type importRecord struct {
+	Title    *string    `json:"title"`
+	Content  *string    `json:"content"`
+	Category *string    `json:"category"`
+	Created  *time.Time `json:"created"`
+
+	// err records why the record could not be decoded; document reports it
+	err error
+}
+
+// decodeImportRecord decodes one JSON import record. A record that is not a
+// document object comes back with err set rather than failing the import.
+func decodeImportRecord(raw []byte, position string) importRecord {
+	var record importRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case stderrors.As(err, &typeErr) && typeErr.Field == "":
+			err = errors.Validationf("%s: expected a document object, got %s", position, typeErr.Value)
+		case stderrors.As(err, &typeErr):
+			err = errors.Validationf("%s: %s must be a string, got %s", position, typeErr.Field, typeErr.Value)
+		default:
+			err = errors.Validationf("%s: %v", position, err)
+		}
+		return importRecord{err: err}
 	}
-	defer tx.Rollback()
+	return record
+}
 
-	// Clear documents table (triggers will handle FTS5 cleanup)
-	if _, err := tx.ExecContext(ctx, "DELETE FROM documents"); err != nil {
-		return errors.Databasef("failed to clear documents: %w", err)
+// document validates the record and converts it to a document; position
+// locates it in the file for error messages, e.g. "line 12"
+func (r importRecord) document(position string) (*models.Document, error) {
+	if r.err != nil {
+		return nil, r.err
 	}
 
-	// Reset auto-increment counter
-	if _, err := tx.ExecContext(ctx, "DELETE FROM sqlite_sequence WHERE name='documents'"); err != nil {
-		// This might fail if no auto-increment has occurred yet, which is fine
+	var missing []string
+	if r.Title == nil || strings.TrimSpace(*r.Title) == "" {
+		missing = append(missing, "title")
+	}
+	if r.Content == nil || strings.TrimSpace(*r.Content) == "" {
+		missing = append(missing, "content")
+	}
+	if len(missing) > 0 {
+		return nil, errors.Validationf("%s: missing %s", position, strings.Join(missing, " and "))
 	}
 
-	// Optimize FTS5 index
-	if _, err := tx.ExecContext(ctx, "INSERT INTO documents_fts(documents_fts) VALUES('optimize')"); err != nil {
-		return errors.FTS5f("failed to optimize FTS5 index: %w", err)
+	doc := &models.Document{Title: *r.Title, Content: *r.Content}
+	if r.Category != nil {
+		doc.Category = *r.Category
+	}
+	if r.Created != nil {
+		doc.Created = *r.Created
 	}
+	return doc, nil
+}
 
-	return tx.Commit()
+// importReader yields the records of an import file in order
+type importReader struct {
+	format  string
+	lines   *bufio.Reader  // JSONL input
+	decoder *json.Decoder  // JSON array input
+	csv     *csv.Reader    // CSV input
+	columns map[string]int // CSV column index of each header name
+	index   int            // Line number (JSONL, CSV) or element number (array) of the last record
 }
 
-// GetCorpusStats calculates comprehensive statistics about the corpus
-func (h *CorpusHandler) GetCorpusStats(ctx context.Context) (*models.CorpusStats, error) {
-	stats := &models.CorpusStats{
-		CategoryCounts: make(map[string]int),
-		LastUpdated:    time.Now(),
+// newImportReader detects the file format from its first non-blank character:
+// '[' starts a JSON array, anything else is read as JSONL
+func newImportReader(r io.Reader) (*importReader, error) {
+	buffered := bufio.NewReader(r)
+	blankLines := 0
+	for {
+		c, _, err := buffered.ReadRune()
+		if err == io.EOF {
+			return &importReader{format: "jsonl", lines: buffered}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip leading whitespace and a byte order mark, counting lines so
+		// JSONL positions still match the file
+		if c == '\n' {
+			blankLines++
+		}
+		if unicode.IsSpace(c) || c == '\uFEFF' {
+			continue
+		}
+
+		if err := buffered.UnreadRune(); err != nil {
+			return nil, err
+		}
+
+		if c != '[' {
+			return &importReader{format: "jsonl", lines: buffered, index: blankLines}, nil
+		}
+
+		decoder := json.NewDecoder(buffered)
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return &importReader{format: "json", decoder: decoder}, nil
 	}
+}
 
-	// Get basic counts and length statistics
-	query := `
-		SELECT 
-			COUNT(*) as total_docs,
-			SUM(length) as total_tokens,
-			AVG(length) as avg_length,
-			MIN(length) as min_length,
-			MAX(length) as max_length,
-			MIN(created) as earliest,
-			MAX(created) as latest
-		FROM documents`
+// newCSVImportReader reads the header row of a CSV import file. Columns are
+// matched to document fields by name, case-insensitively; title and content
+// columns are required and unknown columns (such as id and length in an
+// export) are ignored.
+func newCSVImportReader(r io.Reader) (*importReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing CSV header row")
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	var earliest, latest sql.NullString
-	err := database.Instance.DB().QueryRowContext(ctx, query).Scan(
-		&stats.TotalDocuments,
-		&stats.TotalTokens,
-		&stats.AverageDocLength,
-		&stats.MinDocLength,
-		&stats.MaxDocLength,
-		&earliest,
-		&latest,
-	)
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		if _, seen := columns[name]; !seen {
+			columns[name] = i
+		}
+	}
+	for _, required := range []string{"title", "content"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", required)
+		}
+	}
+
+	return &importReader{format: "csv", csv: reader, columns: columns}, nil
+}
+
+// next returns the next record and its position, or io.EOF after the last one
+func (r *importReader) next() (importRecord, string, error) {
+	switch {
+	case r.csv != nil:
+		return r.nextCSV()
+
+	case r.decoder != nil:
+		if !r.decoder.More() {
+			// Consume the closing bracket so a truncated array is reported
+			if _, err := r.decoder.Token(); err != nil {
+				return importRecord{}, "", fmt.Errorf("after element %d: %w", r.index, err)
+			}
+			return importRecord{}, "", io.EOF
+		}
 
+		r.index++
+		var raw json.RawMessage
+		if err := r.decoder.Decode(&raw); err != nil {
+			return importRecord{}, "", fmt.Errorf("element %d: %w", r.index, err)
+		}
+		position := fmt.Sprintf("element %d", r.index)
+		return decodeImportRecord(raw, position), position, nil
+	}
+
+	for {
+		line, err := r.lines.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return importRecord{}, "", err
+		}
+		if err != nil && err != io.EOF {
+			return importRecord{}, "", err
+		}
+
+		r.index++
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			position := fmt.Sprintf("line %d", r.index)
+			return decodeImportRecord(trimmed, position), position, nil
+		}
+	}
+}
+
+// nextCSV returns the next CSV row as a record. An empty cell counts as a
+// missing field. A row with the wrong number of fields is skipped; a quoting
+// error ends the import, since the reader cannot tell where the row stops.
+func (r *importReader) nextCSV() (importRecord, string, error) {
+	row, err := r.csv.Read()
 	if err != nil {
-		return nil, errors.Databasef("failed to get basic corpus stats: %w", err)
+		var parseErr *csv.ParseError
+		if stderrors.As(err, &parseErr) && stderrors.Is(parseErr.Err, csv.ErrFieldCount) {
+			position := fmt.Sprintf("line %d", parseErr.StartLine)
+			return importRecord{err: errors.Validationf("%s: expected %d fields, got %d",
+				position, r.csv.FieldsPerRecord, len(row))}, position, nil
+		}
+		return importRecord{}, "", err
 	}
 
-	if earliest.Valid {
-		if t, err := time.Parse("2006-01-02 15:04:05", earliest.String); err == nil {
-			stats.CreatedRange.Start = t
+	line, _ := r.csv.FieldPos(0)
+	position := fmt.Sprintf("line %d", line)
+
+	field := func(name string) *string {
+		i, ok := r.columns[name]
+		if !ok || row[i] == "" {
+			return nil
 		}
+		return &row[i]
 	}
-	if latest.Valid {
-		if t, err := time.Parse("2006-01-02 15:04:05", latest.String); err == nil {
-			stats.CreatedRange.End = t
+
+	record := importRecord{
+		Title:    field("title"),
+		Content:  field("content"),
+		Category: field("category"),
+	}
+	if created := field("created"); created != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *created); err == nil {
+			record.Created = &t
+		} else {
+			record.err = errors.Validationf("%s: created must be an RFC 3339 time, got %q", position, *created)
 		}
 	}
+	return record, position, nil
+}
 
-	// Get median document length
-	medianQuery := `
-		SELECT length 
-		FROM documents 
-		ORDER BY length 
-		LIMIT 1 
-		OFFSET (SELECT (COUNT(*) - 1) / 2 FROM documents)`
+// exportFormats are the file formats corpus export writes and import reads
+var exportFormats = []string{"jsonl", "json", "csv"}
 
-	err = database.Instance.DB().QueryRowContext(ctx, medianQuery).Scan(&stats.MedianDocLength)
-	if err != nil && err != sql.ErrNoRows {
-		return nil, errors.Databasef("failed to get median document length: %w", err)
+// exportFormat picks the export file format: the export command's --format
+// flag, else the output file extension, else JSONL
+func exportFormat(flag, path string) (string, error) {
+	if flag != "" {
+		if !slices.Contains(exportFormats, flag) {
+			return "", errors.Validationf("invalid export format %q (must be jsonl, json, or csv)", flag)
+		}
+		return flag, nil
 	}
 
-	// Get category breakdown
-	categoryQuery := `
-		SELECT category, COUNT(*) 
-		FROM documents 
-		GROUP BY category 
-		ORDER BY COUNT(*) DESC`
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json", nil
+	case ".csv":
+		return "csv", nil
+	default:
+		return "jsonl", nil
+	}
+}
+
+// ExportDocuments writes every document to w in format (jsonl, json, or csv),
+// in id order, streaming rows so the corpus is never held in memory. Every
+// format carries id, title, content, category, length, and created, and reads
+// back with ImportDocuments, which recomputes id and length.
+func (h *CorpusHandler) ExportDocuments(ctx context.Context, w io.Writer, format string) (int, error) {
+	var writer documentWriter
+	switch format {
+	case "json":
+		writer = &jsonDocumentWriter{w: w}
+	case "csv":
+		writer = &csvDocumentWriter{w: csv.NewWriter(w)}
+	default:
+		writer = &jsonlDocumentWriter{encoder: json.NewEncoder(w)}
+	}
+
+	if err := writer.begin(); err != nil {
+		return 0, errors.Validationf("failed to write export: %w", err)
+	}
 
-	rows, err := database.Instance.DB().QueryContext(ctx, categoryQuery)
+	query := fmt.Sprintf("SELECT %s FROM documents ORDER BY id", strings.Join(database.DocumentColumns, ", "))
+	rows, err := h.db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, errors.Databasef("failed to get category breakdown: %w", err)
+		return 0, h.exportFailed(ctx, 0, err)
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		var category string
-		var count int
-		if err := rows.Scan(&category, &count); err != nil {
-			return nil, errors.Databasef("failed to scan category data: %w", err)
+	count, err := database.EachDocument(rows, func(doc *models.Document) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-		stats.Categories = append(stats.Categories, category)
-		stats.CategoryCounts[category] = count
+		if err := writer.write(doc); err != nil {
+			return errors.Validationf("failed to write document %d: %w", doc.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return count, h.exportFailed(ctx, count, err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, errors.Databasef("error iterating category data: %w", err)
+	if err := writer.end(); err != nil {
+		return count, errors.Validationf("failed to write export: %w", err)
+	}
+	return count, nil
+}
+
+// exportFailed reports an export that stopped after count documents,
+// as a cancellation when it was interrupted
+func (h *CorpusHandler) exportFailed(ctx context.Context, count int, err error) error {
+	if ctx.Err() != nil {
+		return errors.Cancelledf("export interrupted after %d documents", count)
 	}
+	return err
+}
+
+// documentWriter writes exported documents in one file format
+type documentWriter interface {
+	begin() error
+	write(doc *models.Document) error
+	end() error
+}
+
+// jsonlDocumentWriter writes one JSON document per line
+type jsonlDocumentWriter struct {
+	encoder *json.Encoder
+}
 
-	// Get unique terms count (approximate)
-	uniqueTermsQuery := `
-		SELECT COUNT(DISTINCT term) 
-		FROM documents_fts_data 
-		WHERE col = '*'`
+func (j *jsonlDocumentWriter) begin() error { return nil }
 
-	err = database.Instance.DB().QueryRowContext(ctx, uniqueTermsQuery).Scan(&stats.UniqueTerms)
+func (j *jsonlDocumentWriter) write(doc *models.Document) error { return j.encoder.Encode(doc) }
+
+func (j *jsonlDocumentWriter) end() error { return nil }
+
+// jsonDocumentWriter writes a JSON array with one document per line
+type jsonDocumentWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonDocumentWriter) begin() error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonDocumentWriter) write(doc *models.Document) error {
+	data, err := json.Marshal(doc)
 	if err != nil {
-		// If this fails, it's not critical - just set to 0
-		stats.UniqueTerms = 0
+		return err
 	}
 
-	return stats, nil
+	separator := ",\n  "
+	if j.count == 0 {
+		separator = "\n  "
+	}
+	j.count++
+
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
 }
 
-// GenerateCorpus creates a synthetic corpus for BM25 experimentation
-func (h *CorpusHandler) GenerateCorpus(ctx context.Context, options models.CorpusOptions) error {
-	// Set up random seed for reproducible generation
-	if options.Seed == 0 {
-		options.Seed = time.Now().UnixNano()
+func (j *jsonDocumentWriter) end() error {
+	closing := "\n]\n"
+	if j.count == 0 {
+		closing = "]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// csvDocumentWriter writes a header row and one row per document. Created
+// times are RFC 3339 with fractional seconds, and empty when unknown.
+type csvDocumentWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvDocumentWriter) begin() error {
+	return c.w.Write(database.DocumentColumns)
+}
+
+func (c *csvDocumentWriter) write(doc *models.Document) error {
+	created := ""
+	if !doc.Created.IsZero() {
+		created = doc.Created.Format(time.RFC3339Nano)
+	}
+	return c.w.Write([]string{
+		strconv.FormatInt(doc.ID, 10),
+		doc.Title,
+		doc.Content,
+		doc.Category,
+		strconv.Itoa(doc.Length),
+		created,
+	})
+}
+
+func (c *csvDocumentWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// generationShardSize is the number of consecutive original documents generated
+// from one shard RNG. Shards are fixed by document index and each shard's RNG is
+// derived from the seed and the shard number, so a seed produces the same corpus
+// whatever the number of workers.
+const generationShardSize = 256
+
+// generationPool generates original documents concurrently, one shard at a time
+type generationPool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   []chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newGenerationPool starts options.Workers goroutines (one per CPU when zero)
+// that fill docs[:count] shard by shard
+func newGenerationPool(ctx context.Context, options models.CorpusOptions, baseTime time.Time, count int, docs []*models.Document) *generationPool {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &generationPool{ctx: ctx, cancel: cancel}
+
+	shards := (count + generationShardSize - 1) / generationShardSize
+	p.done = make([]chan struct{}, shards)
+	jobs := make(chan int, shards)
+	for shard := range p.done {
+		p.done[shard] = make(chan struct{})
+		jobs <- shard
+	}
+	close(jobs)
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > shards {
+		workers = shards
 	}
-	rng := rand.New(rand.NewSource(options.Seed))
 
-	// Generate documents
-	docs := make([]*models.Document, 0, options.Size)
-	generator := &corpusGenerator{
-		rng:     rng,
-		options: options,
+	for w := 0; w < workers; w++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for shard := range jobs {
+				generator := &corpusGenerator{
+					rng:      rand.New(rand.NewSource(shardSeed(options.Seed, shard))),
+					options:  options,
+					baseTime: baseTime,
+				}
+				end := min((shard+1)*generationShardSize, count)
+				for i := shard * generationShardSize; i < end; i++ {
+					if ctx.Err() != nil {
+						return
+					}
+					docs[i] = generator.generateDocument()
+				}
+				close(p.done[shard])
+			}
+		}()
 	}
 
-	for i := 0; i < options.Size; i++ {
-		doc := generator.generateDocument()
-		docs = append(docs, doc)
+	return p
+}
+
+// wait blocks until the shard holding document i is generated
+func (p *generationPool) wait(i int) error {
+	select {
+	case <-p.done[i/generationShardSize]:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
 	}
+}
+
+// stop cancels any remaining generation and waits for the workers to exit
+func (p *generationPool) stop() {
+	p.cancel()
+	p.wg.Wait()
+}
 
-	// Insert in batches for efficiency
-	return h.BatchInsertDocuments(ctx, docs)
+// shardSeed derives an independent RNG seed for a shard (SplitMix64 finalizer)
+func shardSeed(seed int64, shard int) int64 {
+	z := uint64(seed) + uint64(shard+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// corpusEpoch anchors seed-derived base times so they stay in a plausible range
+var corpusEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// seedBaseTime derives the generation clock from the seed: a whole second
+// within the year after corpusEpoch, so the same seed always yields the same
+// created timestamps
+func seedBaseTime(seed int64) time.Time {
+	offset := rand.New(rand.NewSource(seed)).Int63n(365 * 24 * 60 * 60)
+	return corpusEpoch.Add(time.Duration(offset) * time.Second)
 }
 
 // corpusGenerator handles synthetic document generation
 type corpusGenerator struct {
 	rng     *rand.Rand
 	options models.CorpusOptions
+
+	// baseTime stands in for the current time so created timestamps are reproducible
+	baseTime time.Time
 }
 
 // generateDocument creates a single synthetic document
@@ -495,9 +2131,9 @@ func (g *corpusGenerator) generateDocument() *models.Document {
 	title := g.generateTitle(category)
 	content := g.generateContent(category)
 
-	// Random creation time within the last 30 days
+	// Random creation time within the 30 days before the base time
 	createdOffset := time.Duration(g.rng.Intn(30*24*60)) * time.Minute
-	created := time.Now().Add(-createdOffset)
+	created := g.baseTime.Add(-createdOffset)
 
 	return &models.Document{
 		Title:    title,
@@ -575,23 +2211,13 @@ func (g *corpusGenerator) generateTitle(category string) string {
 func (g *corpusGenerator) generateContent(category string) string {
 	targetTokens := g.options.MinTokens + g.rng.Intn(g.options.MaxTokens-g.options.MinTokens+1)
 
-	// Base vocabulary for different categories
-	vocabulary := map[string][]string{
-		"technology":  {"system", "development", "architecture", "framework", "platform", "solution", "design", "implementation", "scalable", "efficient", "robust", "secure", "modern", "advanced", "innovative"},
-		"science":     {"research", "analysis", "methodology", "hypothesis", "experiment", "data", "results", "conclusion", "theory", "evidence", "statistical", "empirical", "quantitative", "qualitative", "validation"},
-		"programming": {"function", "variable", "algorithm", "optimization", "performance", "debugging", "testing", "refactoring", "maintainable", "readable", "efficient", "scalable", "object", "method", "interface"},
-		"database":    {"query", "index", "transaction", "optimization", "performance", "schema", "normalization", "relational", "primary", "foreign", "key", "table", "column", "constraint", "integrity"},
-		"algorithms":  {"complexity", "efficiency", "optimization", "iteration", "recursion", "sorting", "searching", "traversal", "comparison", "analysis", "space", "time", "linear", "logarithmic", "polynomial"},
+	if g.options.Style == "sentences" {
+		return g.generateSentences(category, targetTokens)
 	}
 
-	// Common connecting words
-	connectors := []string{"and", "the", "of", "in", "to", "for", "with", "by", "from", "on", "at", "as", "is", "are", "can", "will", "this", "that", "these", "those"}
-
 	words := make([]string, 0, targetTokens)
-	categoryWords := vocabulary[category]
-	if len(categoryWords) == 0 {
-		categoryWords = vocabulary["technology"] // fallback
-	}
+	categoryWords := categoryVocabulary(category)
+	connectors := connectorWords
 
 	for len(words) < targetTokens {
 		if g.rng.Float64() < 0.3 { // 30% chance for category-specific word
@@ -603,5 +2229,241 @@ func (g *corpusGenerator) generateContent(category string) string {
 		}
 	}
 
+	// Inject requested terms at random positions
+	for _, term := range g.selectInjectedTerms() {
+		pos := g.rng.Intn(len(words) + 1)
+		words = append(words[:pos], append([]string{term}, words[pos:]...)...)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// generateSentences assembles grammatical template sentences until the target length is reached
+func (g *corpusGenerator) generateSentences(category string, targetTokens int) string {
+	pools := sentencePoolsFor(category)
+
+	var sentences []string
+	count := 0
+	for count < targetTokens {
+		sentence := g.buildSentence(pools, pools.objects[g.rng.Intn(len(pools.objects))])
+		sentences = append(sentences, sentence)
+		count += tokens.CountTokens(sentence)
+	}
+
+	// Inject requested terms as the object of an additional sentence
+	for _, term := range g.selectInjectedTerms() {
+		pos := g.rng.Intn(len(sentences) + 1)
+		sentence := g.buildSentence(pools, term)
+		sentences = append(sentences[:pos], append([]string{sentence}, sentences[pos:]...)...)
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// buildSentence creates a single "subject verb object [adjunct]." sentence
+func (g *corpusGenerator) buildSentence(pools sentencePools, object string) string {
+	subject := pools.subjects[g.rng.Intn(len(pools.subjects))]
+	verb := pools.verbs[g.rng.Intn(len(pools.verbs))]
+
+	sentence := fmt.Sprintf("%s %s %s", subject, verb, object)
+	if g.rng.Float64() < 0.5 {
+		sentence += " " + sentenceAdjuncts[g.rng.Intn(len(sentenceAdjuncts))]
+	}
+
+	return sentence + "."
+}
+
+// selectInjectedTerms decides which configured terms this document receives
+func (g *corpusGenerator) selectInjectedTerms() []string {
+	var selected []string
+	// Iterate in sorted order so generation stays deterministic under a seed
+	for _, term := range sortedTerms(g.options.TermRates) {
+		if g.rng.Float64() < g.options.TermRates[term] {
+			selected = append(selected, term)
+		}
+	}
+	return selected
+}
+
+// injectPhrase inserts a phrase into existing content using the configured style
+func (g *corpusGenerator) injectPhrase(content, category, phrase string) string {
+	if g.options.Style == "sentences" {
+		sentences := strings.SplitAfter(content, ". ")
+		for i := range sentences {
+			sentences[i] = strings.TrimSpace(sentences[i])
+		}
+		pos := g.rng.Intn(len(sentences) + 1)
+		sentence := g.buildSentence(sentencePoolsFor(category), phrase)
+		sentences = append(sentences[:pos], append([]string{sentence}, sentences[pos:]...)...)
+		return strings.Join(sentences, " ")
+	}
+
+	words := strings.Fields(content)
+	pos := g.rng.Intn(len(words) + 1)
+	words = append(words[:pos], append([]string{phrase}, words[pos:]...)...)
 	return strings.Join(words, " ")
 }
+
+// exactDuplicate creates an identical copy of a document
+func (g *corpusGenerator) exactDuplicate(src *models.Document) *models.Document {
+	return &models.Document{
+		Title:    src.Title,
+		Content:  src.Content,
+		Category: src.Category,
+		Created:  src.Created,
+	}
+}
+
+// nearDuplicate creates a copy of a document with a few content words substituted
+func (g *corpusGenerator) nearDuplicate(src *models.Document) *models.Document {
+	doc := g.exactDuplicate(src)
+
+	words := strings.Fields(doc.Content)
+	if len(words) == 0 {
+		return doc
+	}
+
+	substitutions := nearDuplicateSubstitutions
+	if substitutions > len(words) {
+		substitutions = len(words)
+	}
+
+	categoryWords := categoryVocabulary(doc.Category)
+	for _, pos := range g.rng.Perm(len(words))[:substitutions] {
+		replacement := categoryWords[g.rng.Intn(len(categoryWords))]
+		if replacement == words[pos] {
+			// Guarantee the word actually changes
+			replacement = connectorWords[g.rng.Intn(len(connectorWords))]
+		}
+		words[pos] = replacement
+	}
+
+	doc.Content = strings.Join(words, " ")
+	return doc
+}
+
+// nearDuplicateSubstitutions is the number of content words changed in a near-duplicate
+const nearDuplicateSubstitutions = 3
+
+// vocabulary holds the base vocabulary for different categories
+var vocabulary = map[string][]string{
+	"technology":  {"system", "development", "architecture", "framework", "platform", "solution", "design", "implementation", "scalable", "efficient", "robust", "secure", "modern", "advanced", "innovative"},
+	"science":     {"research", "analysis", "methodology", "hypothesis", "experiment", "data", "results", "conclusion", "theory", "evidence", "statistical", "empirical", "quantitative", "qualitative", "validation"},
+	"programming": {"function", "variable", "algorithm", "optimization", "performance", "debugging", "testing", "refactoring", "maintainable", "readable", "efficient", "scalable", "object", "method", "interface"},
+	"database":    {"query", "index", "transaction", "optimization", "performance", "schema", "normalization", "relational", "primary", "foreign", "key", "table", "column", "constraint", "integrity"},
+	"algorithms":  {"complexity", "efficiency", "optimization", "iteration", "recursion", "sorting", "searching", "traversal", "comparison", "analysis", "space", "time", "linear", "logarithmic", "polynomial"},
+}
+
+// connectorWords holds common connecting words shared by all categories
+var connectorWords = []string{"and", "the", "of", "in", "to", "for", "with", "by", "from", "on", "at", "as", "is", "are", "can", "will", "this", "that", "these", "those"}
+
+// categoryVocabulary returns the vocabulary for a category, falling back to technology
+func categoryVocabulary(category string) []string {
+	if words := vocabulary[category]; len(words) > 0 {
+		return words
+	}
+	return vocabulary["technology"]
+}
+
+// sentencePools holds the subject/verb/object pools used by the sentences style
+type sentencePools struct {
+	subjects []string
+	verbs    []string
+	objects  []string
+}
+
+// sentenceVerbs are shared by all categories
+var sentenceVerbs = []string{"improves", "requires", "simplifies", "supports", "enables", "complicates", "depends on", "benefits from", "reduces", "describes"}
+
+// sentenceAdjuncts optionally extend a sentence
+var sentenceAdjuncts = []string{"in production systems", "for large datasets", "with minimal overhead", "under heavy load", "in most cases", "across many teams", "over time"}
+
+// sentenceSubjects holds category-specific sentence subjects
+var sentenceSubjects = map[string][]string{
+	"technology":  {"The platform", "A cloud service", "The development team", "Modern architecture", "The framework"},
+	"science":     {"The research group", "A controlled experiment", "The statistical model", "Recent evidence", "The methodology"},
+	"programming": {"The compiler", "A well-tested function", "The code review", "Careful refactoring", "The interface"},
+	"database":    {"The query planner", "A composite index", "The transaction log", "Schema normalization", "The storage engine"},
+	"algorithms":  {"The sorting routine", "A greedy strategy", "Recursive traversal", "The complexity analysis", "Dynamic programming"},
+}
+
+// sentencePoolsFor returns the sentence pools for a category, falling back to technology
+func sentencePoolsFor(category string) sentencePools {
+	subjects := sentenceSubjects[category]
+	if len(subjects) == 0 {
+		subjects = sentenceSubjects["technology"]
+	}
+
+	objects := make([]string, 0, len(categoryVocabulary(category)))
+	for _, word := range categoryVocabulary(category) {
+		objects = append(objects, word+" "+objectNouns[len(objects)%len(objectNouns)])
+	}
+
+	return sentencePools{
+		subjects: subjects,
+		verbs:    sentenceVerbs,
+		objects:  objects,
+	}
+}
+
+// objectNouns turn vocabulary words into readable noun phrases
+var objectNouns = []string{"decisions", "work", "results", "patterns", "goals"}
+
+// sortedTerms returns the keys of a term rate map in sorted order
+func sortedTerms(rates map[string]float64) []string {
+	terms := make([]string, 0, len(rates))
+	for term := range rates {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// parseTermRates parses a term injection specification (term:rate,term:rate)
+func (h *CorpusHandler) parseTermRates(spec string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 {
+			return nil, errors.Validationf("invalid term rate pair: %s (format: term:rate)", pair)
+		}
+
+		term := strings.TrimSpace(parts[0])
+		if term == "" {
+			return nil, errors.Validationf("invalid term rate pair: %s (term is empty)", pair)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, errors.Validationf("invalid rate value for %s: %w", term, err)
+		}
+		if rate < 0 || rate > 1 {
+			return nil, errors.Validationf("rate for %s (%.2f) must be in the range [0, 1]", term, rate)
+		}
+
+		rates[term] = rate
+	}
+
+	return rates, nil
+}
+
+// parseInjection parses a phrase injection specification (phrase:count)
+func (h *CorpusHandler) parseInjection(spec string) (models.PhraseInjection, error) {
+	sep := strings.LastIndex(spec, ":")
+	if sep < 0 {
+		return models.PhraseInjection{}, errors.Validationf("invalid injection: %s (format: phrase:count)", spec)
+	}
+
+	phrase := strings.TrimSpace(spec[:sep])
+	if phrase == "" {
+		return models.PhraseInjection{}, errors.Validationf("invalid injection: %s (phrase is empty)", spec)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(spec[sep+1:]))
+	if err != nil || count < 1 {
+		return models.PhraseInjection{}, errors.Validationf("invalid injection count for %q: must be a positive integer", phrase)
+	}
+
+	return models.PhraseInjection{Phrase: phrase, Count: count}, nil
+}
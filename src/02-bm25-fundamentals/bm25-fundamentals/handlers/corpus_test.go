@@ -0,0 +1,278 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+)
+
+func TestInjectedPhraseEvaluatesWithFullRecall(t *testing.T) {
+	db := useTestDatabase(t)
+
+	// The term rates scatter both words across the corpus, so a query that
+	// matched them apart would retrieve documents outside the injected set
+	manifest := generateTestCorpus(t, db, 200, 7, func(options *models.CorpusOptions) {
+		options.TermRates = map[string]float64{"database": 0.15, "optimization": 0.15}
+		options.Injections = []models.PhraseInjection{{Phrase: "database optimization", Count: 20}}
+	})
+
+	if got := len(manifest.Injections); got != 1 {
+		t.Fatalf("manifest records %d injections, want 1", got)
+	}
+	if got := len(manifest.Injections[0].IDs); got != 20 {
+		t.Fatalf("manifest records %d injected IDs, want 20", got)
+	}
+
+	// Confirm the corpus holds the words apart, or the test proves nothing
+	search := NewSearchHandler(db)
+	options := models.DefaultSearchOptions()
+	options.Query = "database optimization"
+	options.MaxResults = 200
+	apart, err := search.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(apart) <= 20 {
+		t.Fatalf("implicit AND query matched %d documents, want more than the 20 injected", len(apart))
+	}
+
+	evaluations, err := search.EvaluateManifest(context.Background(), manifest, 100)
+	if err != nil {
+		t.Fatalf("EvaluateManifest: %v", err)
+	}
+
+	eval := evaluations[0]
+	if eval.Relevant != 20 || eval.RelevantRetrieved != 20 {
+		t.Errorf("retrieved %d of %d relevant documents, want 20 of 20", eval.RelevantRetrieved, eval.Relevant)
+	}
+	if eval.Recall != 1 {
+		t.Errorf("recall = %.4f, want 1", eval.Recall)
+	}
+	if eval.Precision != 1 {
+		t.Errorf("precision = %.4f, want 1: %d documents without the phrase were retrieved",
+			eval.Precision, eval.Retrieved-eval.RelevantRetrieved)
+	}
+}
+
+func TestGenerateCorpusRejectsPhraseThatOccursNaturally(t *testing.T) {
+	db := useTestDatabase(t)
+
+	// Every generated technology document draws from the same vocabulary, so
+	// one of its words is certain to appear outside the injected documents
+	probe := generateTestCorpus(t, db, 50, 11, func(options *models.CorpusOptions) {
+		options.Categories = []string{"technology"}
+	})
+	word := commonWord(readContents(t, db))
+	if word == "" {
+		t.Fatalf("no word occurs in most of the %d probe documents", probe.DocumentCount)
+	}
+
+	options := models.DefaultCorpusOptions()
+	options.Size = 50
+	options.Seed = 11
+	options.MinTokens = 20
+	options.MaxTokens = 80
+	options.Categories = []string{"technology"}
+	options.Injections = []models.PhraseInjection{{Phrase: word, Count: 3}}
+
+	_, err := NewCorpusHandler(db).GenerateCorpus(context.Background(), options)
+	if err == nil || !strings.Contains(err.Error(), "already occurs") {
+		t.Fatalf("GenerateCorpus with natural phrase %q: err = %v, want an 'already occurs' error", word, err)
+	}
+
+	// The rejected corpus is rolled back, leaving only the probe documents
+	var count int
+	if err := db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM documents").Scan(&count); err != nil {
+		t.Fatalf("count documents: %v", err)
+	}
+	if count != probe.DocumentCount {
+		t.Errorf("documents after rejected generation = %d, want the %d probe documents", count, probe.DocumentCount)
+	}
+}
+
+func TestGenerateCorpusInjectsDuplicates(t *testing.T) {
+	db := useTestDatabase(t)
+	manifest := generateTestCorpus(t, db, 400, 13, func(options *models.CorpusOptions) {
+		options.DuplicateRate = 0.1
+		options.NearDuplicateRate = 0.05
+	})
+
+	// 10% and 5% of 400 documents
+	if exact, near := manifest.DuplicateCounts(); exact != 40 || near != 20 {
+		t.Fatalf("manifest records %d exact and %d near duplicates, want 40 and 20", exact, near)
+	}
+
+	ctx := context.Background()
+	for _, dup := range manifest.Duplicates {
+		copied, err := db.Document(ctx, dup.ID)
+		if err != nil {
+			t.Fatalf("read duplicate %d: %v", dup.ID, err)
+		}
+		source, err := db.Document(ctx, dup.SourceID)
+		if err != nil {
+			t.Fatalf("read source %d: %v", dup.SourceID, err)
+		}
+		if dup.ID <= dup.SourceID {
+			t.Errorf("duplicate %d precedes its source %d", dup.ID, dup.SourceID)
+		}
+		if copied.Title != source.Title || copied.Category != source.Category {
+			t.Errorf("%s duplicate %d = %q (%s), want the title and category of %d: %q (%s)",
+				dup.Kind, dup.ID, copied.Title, copied.Category, dup.SourceID, source.Title, source.Category)
+		}
+
+		want := 0
+		if dup.Kind == "near" {
+			want = nearDuplicateSubstitutions
+		}
+		if got := changedWords(t, source.Content, copied.Content); got != want {
+			t.Errorf("%s duplicate %d differs from source %d in %d words, want %d", dup.Kind, dup.ID, dup.SourceID, got, want)
+		}
+	}
+}
+
+// changedWords returns the number of positions at which two texts of the same
+// word count hold different words
+func changedWords(t *testing.T, a, b string) int {
+	t.Helper()
+
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	if len(wordsA) != len(wordsB) {
+		t.Fatalf("texts have %d and %d words, want the same count", len(wordsA), len(wordsB))
+	}
+	changed := 0
+	for i := range wordsA {
+		if wordsA[i] != wordsB[i] {
+			changed++
+		}
+	}
+	return changed
+}
+
+// readContents returns the content of every document in db
+func readContents(t testing.TB, db *database.Database) []string {
+	t.Helper()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT content FROM documents ORDER BY id")
+	if err != nil {
+		t.Fatalf("read contents: %v", err)
+	}
+	defer rows.Close()
+
+	var contents []string
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			t.Fatalf("scan content: %v", err)
+		}
+		contents = append(contents, content)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read contents: %v", err)
+	}
+	return contents
+}
+
+// commonWord returns the most widespread word of at least four letters, if it
+// is found in more than half of contents, or "" when there is none
+func commonWord(contents []string) string {
+	seen := make(map[string]int)
+	for _, content := range contents {
+		words := make(map[string]bool)
+		for _, word := range tokens.Split(strings.ToLower(content)) {
+			if len(word) >= 4 {
+				words[word] = true
+			}
+		}
+		for word := range words {
+			seen[word]++
+		}
+	}
+
+	best, bestCount := "", len(contents)/2
+	for word, count := range seen {
+		if count > bestCount || (count == bestCount && best != "" && word < best) {
+			best, bestCount = word, count
+		}
+	}
+	return best
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		flag, path string
+		want       string
+	}{
+		{"jsonl", "", "jsonl"},
+		{"csv", "corpus.json", "csv"},
+		{"", "corpus.json", "json"},
+		{"", "corpus.CSV", "csv"},
+		{"", "corpus.txt", "jsonl"},
+		{"", "", "jsonl"},
+	}
+
+	for _, tt := range tests {
+		got, err := exportFormat(tt.flag, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("exportFormat(%q, %q) = %q, %v; want %q", tt.flag, tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := exportFormat("xml", ""); err == nil {
+		t.Error("exportFormat accepted xml")
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range exportFormats {
+		t.Run(format, func(t *testing.T) {
+			db := useTestDatabase(t)
+			corpus := NewCorpusHandler(db)
+			original := insertTestDocuments(t, db,
+				[3]string{`Quotes "inside"`, "line one\nline two, with a comma", "text"},
+				[3]string{"Plain", `a "quoted" word`, "text"},
+			)
+
+			path := filepath.Join(t.TempDir(), "corpus."+format)
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, err := corpus.ExportDocuments(context.Background(), file, format)
+			file.Close()
+			if err != nil || count != len(original) {
+				t.Fatalf("ExportDocuments = %d, %v; want %d documents", count, err, len(original))
+			}
+
+			if err := corpus.ClearDocuments(context.Background()); err != nil {
+				t.Fatalf("ClearDocuments: %v", err)
+			}
+			summary, err := corpus.ImportDocuments(context.Background(), path, false, false)
+			if err != nil || summary.Inserted != len(original) {
+				t.Fatalf("ImportDocuments = %+v, %v; want %d inserted", summary, err, len(original))
+			}
+
+			rows, err := db.QueryContext(context.Background(), "SELECT title, content FROM documents ORDER BY id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			for i := 0; rows.Next(); i++ {
+				var title, content string
+				if err := rows.Scan(&title, &content); err != nil {
+					t.Fatal(err)
+				}
+				if title != original[i].Title || content != original[i].Content {
+					t.Errorf("document %d = %q / %q, want %q / %q", i, title, content, original[i].Title, original[i].Content)
+				}
+			}
+		})
+	}
+}
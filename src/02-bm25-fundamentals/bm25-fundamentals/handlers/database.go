@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/spf13/cobra"
+)
+
+// Database is the global database maintenance handler instance
+var Database DatabaseHandler
+
+// DatabaseHandler manages FTS5 index maintenance for its database
+type DatabaseHandler struct {
+	db     *database.Database
+	corpus *CorpusHandler
+}
+
+// NewDatabaseHandler creates a database handler that maintains db
+func NewDatabaseHandler(db *database.Database) *DatabaseHandler {
+	return &DatabaseHandler{db: db, corpus: NewCorpusHandler(db)}
+}
+
+// HandleCheckSync handles the db check-sync command
+func (h *DatabaseHandler) HandleCheckSync(cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	ctx := cmd.Context()
+
+	report, err := h.CheckSync(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	if err := h.displaySyncReport(report); err != nil {
+		return err
+	}
+
+	if !report.InSync() {
+		return errors.FTS5f("index is out of sync with the documents table — run 'db resync' to rebuild it")
+	}
+
+	return nil
+}
+
+// HandleInit handles the db init command
+func (h *DatabaseHandler) HandleInit(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if err := h.db.InitSchema(ctx); err != nil {
+		return err
+	}
+
+	count, err := h.corpus.GetDocumentCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Corpus schema ready (%d documents)\n", count)
+	return nil
+}
+
+// HandleResync handles the db resync command
+func (h *DatabaseHandler) HandleResync(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if err := h.Resync(ctx); err != nil {
+		return err
+	}
+
+	report, err := h.CheckSync(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Rebuilt FTS5 index from the documents table (%d documents indexed)\n", report.IndexedCount)
+
+	if !report.InSync() {
+		return errors.FTS5f("index still out of sync after rebuild")
+	}
+
+	return nil
+}
+
+// CheckSync compares the documents table with the FTS5 index and runs the FTS5 integrity-check.
+// At most limit missing and orphaned rowids are listed; the counts are always exact.
+func (h *DatabaseHandler) CheckSync(ctx context.Context, limit int) (*models.SyncReport, error) {
+	if err := h.corpus.RequireSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	db := h.db
+	report := &models.SyncReport{}
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents").Scan(&report.DocumentCount); err != nil {
+		return nil, errors.Databasef("failed to count documents: %w", err)
+	}
+
+	// An external content table reads rows from documents, so indexed rows are
+	// counted through the docsize shadow table that FTS5 maintains per rowid
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents_fts_docsize").Scan(&report.IndexedCount); err != nil {
+		return nil, errors.FTS5f("failed to count indexed rows: %w", err)
+	}
+
+	missingQuery := `
+		SELECT id FROM documents
+		WHERE id NOT IN (SELECT id FROM documents_fts_docsize)
+		ORDER BY id`
+
+	orphanedQuery := `
+		SELECT id FROM documents_fts_docsize
+		WHERE id NOT IN (SELECT id FROM documents)
+		ORDER BY id`
+
+	var err error
+	report.MissingIDs, report.MissingCount, err = h.collectIDs(ctx, missingQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report.OrphanedIDs, report.OrphanedCount, err = h.collectIDs(ctx, orphanedQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// rank = 1 also verifies the index against the external content table
+	_, err = db.ExecContext(ctx, "INSERT INTO documents_fts(documents_fts, rank) VALUES('integrity-check', 1)")
+	if err != nil {
+		report.IntegrityError = err.Error()
+	} else {
+		report.IntegrityOK = true
+	}
+
+	return report, nil
+}
+
+// Resync rebuilds the FTS5 index from the documents table
+func (h *DatabaseHandler) Resync(ctx context.Context) error {
+	if err := h.corpus.RequireSchema(ctx); err != nil {
+		return err
+	}
+
+	return h.db.WithTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO documents_fts(documents_fts) VALUES('rebuild')"); err != nil {
+			return errors.FTS5f("failed to rebuild FTS5 index: %w", err)
+		}
+		return nil
+	})
+}
+
+// collectIDs returns up to limit rowids from the query along with the total number of rows
+func (h *DatabaseHandler) collectIDs(ctx context.Context, query string, limit int) ([]int64, int, error) {
+	rows, err := h.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, 0, errors.Databasef("failed to compare index rowids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	total := 0
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, 0, errors.Databasef("failed to scan rowid at row %d: %w", total+1, err)
+		}
+		if total < limit {
+			ids = append(ids, id)
+		}
+		total++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.Databasef("error iterating rowids after %d rows: %w", total, err)
+	}
+
+	return ids, total, nil
+}
+
+// displaySyncReport formats and displays an index synchronization report
+func (h *DatabaseHandler) displaySyncReport(report *models.SyncReport) error {
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+
+	case "csv":
+		fmt.Println("metric,value")
+		fmt.Printf("document_count,%d\n", report.DocumentCount)
+		fmt.Printf("indexed_count,%d\n", report.IndexedCount)
+		fmt.Printf("missing_count,%d\n", report.MissingCount)
+		fmt.Printf("orphaned_count,%d\n", report.OrphanedCount)
+		fmt.Printf("integrity_ok,%t\n", report.IntegrityOK)
+		fmt.Printf("in_sync,%t\n", report.InSync())
+
+	default: // text format
+		fmt.Printf("FTS5 Index Synchronization\n")
+		fmt.Printf("==========================\n\n")
+
+		fmt.Printf("Documents table: %d rows\n", report.DocumentCount)
+		fmt.Printf("FTS5 index:      %d rows\n", report.IndexedCount)
+		fmt.Printf("\n")
+
+		if report.MissingCount > 0 {
+			fmt.Printf("Missing from index: %d document(s)\n", report.MissingCount)
+			fmt.Printf("  IDs: %s\n", formatIDs(report.MissingIDs, report.MissingCount))
+		}
+
+		if report.OrphanedCount > 0 {
+			fmt.Printf("Orphaned in index:  %d row(s)\n", report.OrphanedCount)
+			fmt.Printf("  IDs: %s\n", formatIDs(report.OrphanedIDs, report.OrphanedCount))
+		}
+
+		if report.IntegrityOK {
+			fmt.Printf("Integrity check: passed\n")
+		} else {
+			fmt.Printf("Integrity check: FAILED (%s)\n", report.IntegrityError)
+		}
+
+		fmt.Printf("\n")
+		if report.InSync() {
+			fmt.Printf("✓ Index is in sync with the documents table\n")
+		} else {
+			fmt.Printf("✗ Index is out of sync — searches may miss or misreport documents\n")
+		}
+	}
+
+	return nil
+}
+
+// formatIDs joins a bounded rowid list, noting how many were left out
+func formatIDs(ids []int64, total int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%d", id)
+	}
+
+	result := strings.Join(parts, ", ")
+	if omitted := total - len(ids); omitted > 0 {
+		if result != "" {
+			result += ", "
+		}
+		result += fmt.Sprintf("... (%d more)", omitted)
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+// TestCheckSyncDetectsWritesThatBypassTriggers edits the documents table with
+// the index triggers dropped, as a raw write from another tool would, and
+// checks that check-sync reports the drift and resync repairs it.
+func TestCheckSyncDetectsWritesThatBypassTriggers(t *testing.T) {
+	db := useTestDatabase(t)
+	docs := insertTestDocuments(t, db,
+		[3]string{"Kept", "indexed through the triggers", "text"},
+		[3]string{"Deleted", "removed behind the index's back", "text"},
+		[3]string{"Edited", "original wording", "text"},
+	)
+
+	ctx := context.Background()
+	maintenance := NewDatabaseHandler(db)
+	report, err := maintenance.CheckSync(ctx, 10)
+	if err != nil {
+		t.Fatalf("CheckSync: %v", err)
+	}
+	if !report.InSync() {
+		t.Fatalf("fresh corpus out of sync: %+v", report)
+	}
+
+	raw := []string{
+		`DROP TRIGGER documents_after_insert`,
+		`DROP TRIGGER documents_after_update`,
+		`DROP TRIGGER documents_after_delete`,
+		`INSERT INTO documents (title, content, category) VALUES ('Added', 'never indexed marmalade', 'text')`,
+	}
+	for _, statement := range raw {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	var added int64
+	if err := db.QueryRowContext(ctx, "SELECT MAX(id) FROM documents").Scan(&added); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", docs[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE documents SET content = 'replacement wording' WHERE id = ?", docs[2].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = maintenance.CheckSync(ctx, 10)
+	if err != nil {
+		t.Fatalf("CheckSync: %v", err)
+	}
+	if report.InSync() {
+		t.Fatalf("CheckSync missed the raw writes: %+v", report)
+	}
+	if report.DocumentCount != 3 || report.IndexedCount != 3 {
+		t.Errorf("counted %d documents and %d indexed rows, want 3 and 3", report.DocumentCount, report.IndexedCount)
+	}
+	if !slices.Equal(report.MissingIDs, []int64{added}) || report.MissingCount != 1 {
+		t.Errorf("missing = %v (%d), want [%d]", report.MissingIDs, report.MissingCount, added)
+	}
+	if !slices.Equal(report.OrphanedIDs, []int64{docs[1].ID}) || report.OrphanedCount != 1 {
+		t.Errorf("orphaned = %v (%d), want [%d]", report.OrphanedIDs, report.OrphanedCount, docs[1].ID)
+	}
+	if report.IntegrityOK {
+		t.Error("integrity-check passed on an index that no longer matches the documents")
+	}
+
+	if err := maintenance.Resync(ctx); err != nil {
+		t.Fatalf("Resync: %v", err)
+	}
+	report, err = maintenance.CheckSync(ctx, 10)
+	if err != nil {
+		t.Fatalf("CheckSync: %v", err)
+	}
+	if !report.InSync() {
+		t.Fatalf("still out of sync after Resync: %+v", report)
+	}
+
+	// The rebuilt index serves the raw writes to searches
+	for query, want := range map[string][]int64{
+		"marmalade":   {added},
+		"removed":     nil,
+		"replacement": {docs[2].ID},
+		"original":    nil,
+	} {
+		options := models.DefaultSearchOptions()
+		options.Query = query
+		results, err := NewSearchHandler(db).Search(ctx, options)
+		if err != nil {
+			t.Fatalf("Search %q: %v", query, err)
+		}
+		if got := resultIDs(results); !slices.Equal(got, want) && len(got)+len(want) > 0 {
+			t.Errorf("search %q after Resync = %v, want %v", query, got, want)
+		}
+	}
+}
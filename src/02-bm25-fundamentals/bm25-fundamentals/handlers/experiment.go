@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/experiment"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/spf13/cobra"
+)
+
+// Experiment is the global experiment handler instance
+var Experiment ExperimentHandler
+
+// ExperimentHandler runs declarative experiments, each in a database of its own
+type ExperimentHandler struct{}
+
+// HandleRun handles the experiment run command
+func (h *ExperimentHandler) HandleRun(cmd *cobra.Command, args []string) error {
+	specPath, _ := cmd.Flags().GetString("file")
+	outputPath, _ := cmd.Flags().GetString("output")
+	reportFormat, _ := cmd.Flags().GetString("report-format")
+
+	if reportFormat == "" {
+		reportFormat = config.App.Format
+		// Reports have no markdown rendering; fall back to text like other commands
+		if reportFormat == "markdown" {
+			reportFormat = "text"
+		}
+	}
+
+	spec, err := experiment.LoadSpec(specPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	report, err := h.Run(ctx, spec)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		return experiment.Write(os.Stdout, report, reportFormat, config.App.Numbers())
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return errors.Validationf("failed to create report file %s: %w", outputPath, err)
+	}
+	defer file.Close()
+
+	return experiment.Write(file, report, reportFormat, config.App.Numbers())
+}
+
+// Run builds the experiment corpus in a temporary database, executes every query
+// under every strategy, and returns the combined report. The run searches only the
+// temporary database, so the caller's corpus is never touched.
+func (h *ExperimentHandler) Run(ctx context.Context, spec *experiment.Spec) (*experiment.Report, error) {
+	tempDir, err := os.MkdirTemp("", "bm25-experiment-")
+	if err != nil {
+		return nil, errors.Databasef("failed to create experiment directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	experimentDB, err := database.NewDatabase(filepath.Join(tempDir, "experiment.db"))
+	if err != nil {
+		return nil, err
+	}
+	defer experimentDB.Close()
+	experimentDB.SetTimeout(config.App.QueryTimeout)
+
+	if err := experimentDB.InitSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	corpus, injected, err := h.buildCorpus(ctx, NewCorpusHandler(experimentDB), spec.Corpus)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := experimentDB.Fingerprint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	corpus.Fingerprint = fingerprint.SHA256
+
+	report := experiment.NewReport(spec, corpus)
+	search := NewSearchHandler(experimentDB)
+
+	total := len(spec.Strategies) * len(spec.Queries)
+	for _, strategy := range spec.Strategies {
+		for _, query := range spec.Queries {
+			if ctx.Err() != nil {
+				return nil, errors.Cancelledf("experiment interrupted after %d of %d query runs; no report was written",
+					len(report.Runs), total)
+			}
+
+			options := models.DefaultSearchOptions()
+			options.Query = query.Query
+			if query.Category != "" {
+				options.CategoryFilters = []string{query.Category}
+			}
+			options.ColumnWeights = strategy.Weights
+			options.MaxResults = spec.MaxResults
+			options.IncludeSnippet = false
+
+			results, err := search.Search(ctx, options)
+			if err != nil {
+				return nil, err
+			}
+
+			report.AddRun(strategy.Name, query.Query, results, h.judgments(query, injected))
+		}
+	}
+
+	report.Finalize(spec)
+	return report, nil
+}
+
+// buildCorpus fills the experiment database through documents from the corpus spec and
+// returns its summary, along with the documents that received each injected phrase
+func (h *ExperimentHandler) buildCorpus(ctx context.Context, documents *CorpusHandler, spec experiment.CorpusSpec) (experiment.CorpusSummary, map[string][]int64, error) {
+	if spec.Import != "" {
+		docs, err := experiment.LoadDocuments(spec.Import)
+		if err != nil {
+			return experiment.CorpusSummary{}, nil, err
+		}
+		if err := documents.BatchInsertDocuments(ctx, docs); err != nil {
+			return experiment.CorpusSummary{}, nil, err
+		}
+		return experiment.CorpusSummary{Source: spec.Import, Documents: len(docs)}, nil, nil
+	}
+
+	options := spec.CorpusOptions()
+	if err := documents.ValidateOptions(options); err != nil {
+		return experiment.CorpusSummary{}, nil, err
+	}
+
+	manifest, err := documents.GenerateCorpus(ctx, options)
+	if err != nil {
+		return experiment.CorpusSummary{}, nil, err
+	}
+
+	injected := make(map[string][]int64, len(manifest.Injections))
+	for _, record := range manifest.Injections {
+		injected[record.Phrase] = record.IDs
+	}
+
+	return experiment.CorpusSummary{
+		Source:    "generated",
+		Seed:      manifest.Options.Seed,
+		Documents: manifest.DocumentCount,
+	}, injected, nil
+}
+
+// judgments returns the relevance judgments for a query: explicit judgments from the spec,
+// or the documents that received the query as an injected phrase. Nil means unjudged.
+func (h *ExperimentHandler) judgments(query experiment.QuerySpec, injected map[string][]int64) map[int64]int {
+	if len(query.Judgments) > 0 {
+		return query.Judgments
+	}
+
+	ids, ok := injected[query.Query]
+	if !ok {
+		return nil
+	}
+
+	judgments := make(map[int64]int, len(ids))
+	for _, id := range ids {
+		judgments[id] = 1
+	}
+	return judgments
+}
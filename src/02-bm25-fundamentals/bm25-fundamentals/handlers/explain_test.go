@@ -0,0 +1,148 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+)
+
+// scoreTolerance is how far the recomputed BM25 sum may drift from bm25()
+const scoreTolerance = 1e-6
+
+// TestTermContributionsSumToBM25 checks the explain math against SQLite: for
+// seeded corpora and single-term queries drawn from them, the per-term
+// contributions of every result add up to the score bm25() reported. Column
+// weights are drawn at the two decimals search passes to bm25().
+func TestTermContributionsSumToBM25(t *testing.T) {
+	for seed := int64(1); seed <= 6; seed++ {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			db := useTestDatabase(t)
+			generateTestCorpus(t, db, 80, seed, nil)
+			contents := readContents(t, db)
+
+			random := rand.New(rand.NewSource(seed))
+			for q := 0; q < 5; q++ {
+				words := tokens.Split(strings.ToLower(contents[random.Intn(len(contents))]))
+				options := models.DefaultSearchOptions()
+				options.Query = words[random.Intn(len(words))]
+				options.MaxResults = 0
+				if q%2 == 1 {
+					options.ColumnWeights = map[string]float64{
+						"title":    float64(random.Intn(500)) / 100,
+						"content":  float64(1+random.Intn(500)) / 100,
+						"category": float64(random.Intn(500)) / 100,
+					}
+				}
+
+				checkTermContributions(t, NewSearchHandler(db), seed, options)
+			}
+		})
+	}
+}
+
+// checkTermContributions compares each result's bm25() score from search with
+// the sum of its explained term contributions
+func checkTermContributions(t *testing.T, search *SearchHandler, seed int64, options models.SearchOptions) {
+	t.Helper()
+	ctx := context.Background()
+
+	results, err := search.Search(ctx, options)
+	if err != nil {
+		t.Fatalf("seed %d, query %q: Search: %v", seed, options.Query, err)
+	}
+	if len(results) == 0 {
+		t.Fatalf("seed %d, query %q: no results for a word taken from the corpus", seed, options.Query)
+	}
+
+	explanations, err := search.GenerateScoreExplanations(ctx, results, options)
+	if err != nil {
+		t.Fatalf("seed %d, query %q: GenerateScoreExplanations: %v", seed, options.Query, err)
+	}
+
+	for _, explanation := range explanations {
+		sum := 0.0
+		for _, term := range explanation.QueryTerms {
+			sum += term.Score
+		}
+		// Contributions are positive; bm25() reports their negated sum
+		if diff := math.Abs(-sum - explanation.TotalScore); diff > scoreTolerance {
+			t.Errorf("seed %d, query %q, weights %v, document %d: term contributions sum to %.9f, bm25() = %.9f (off by %.2g)",
+				seed, options.Query, options.ColumnWeights, explanation.DocumentID, -sum, explanation.TotalScore, diff)
+		}
+	}
+}
+
+// TestExplainRecordRoundTrip saves real explanations, loads them back, and
+// saves them again; the two files must match byte for byte so a replay
+// renders exactly what was saved
+func TestExplainRecordRoundTrip(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	generateTestCorpus(t, db, 60, 3, nil)
+	ctx := context.Background()
+
+	options := models.DefaultSearchOptions()
+	options.Query = tokens.Split(strings.ToLower(readContents(t, db)[0]))[0]
+	options.ColumnWeights = map[string]float64{"title": 2.5, "content": 1}
+	options.IncludeSnippet = false
+	options.ExplainScores = true
+	options.MaxResults = 4
+
+	results, err := search.Search(ctx, options)
+	if err != nil || len(results) == 0 {
+		t.Fatalf("Search %q = %d results, %v", options.Query, len(results), err)
+	}
+	explanations, err := search.GenerateScoreExplanations(ctx, results, options)
+	if err != nil {
+		t.Fatalf("GenerateScoreExplanations: %v", err)
+	}
+	snapshot, err := search.corpusSnapshot(ctx)
+	if err != nil {
+		t.Fatalf("corpusSnapshot: %v", err)
+	}
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	record := &models.ExplainRecord{
+		Saved:        time.Now(),
+		Options:      options,
+		Corpus:       *snapshot,
+		Explanations: explanations,
+	}
+	if err := search.WriteExplainRecord(first, record); err != nil {
+		t.Fatalf("WriteExplainRecord: %v", err)
+	}
+
+	loaded, err := search.LoadExplainRecord(first)
+	if err != nil {
+		t.Fatalf("LoadExplainRecord: %v", err)
+	}
+	if err := search.WriteExplainRecord(second, loaded); err != nil {
+		t.Fatalf("WriteExplainRecord: %v", err)
+	}
+
+	saved, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resaved, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, resaved) {
+		t.Errorf("explain record changed on a load and save:\n%s\nbecame\n%s", saved, resaved)
+	}
+}
@@ -0,0 +1,79 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+)
+
+// fingerprint returns the fingerprint of db
+func fingerprint(t *testing.T, db *database.Database) string {
+	t.Helper()
+
+	fp, err := db.Fingerprint(context.Background())
+	if err != nil {
+		t.Fatalf("Fingerprint: %v", err)
+	}
+	return fp.SHA256
+}
+
+func TestFingerprintIsStableAcrossDatabases(t *testing.T) {
+	generated := make([]string, 3)
+	for i, seed := range []int64{21, 21, 22} {
+		db := useTestDatabase(t)
+		generateTestCorpus(t, db, 80, seed, nil)
+		generated[i] = fingerprint(t, db)
+	}
+
+	if generated[0] != generated[1] {
+		t.Errorf("two databases generated with seed 21 have fingerprints %s and %s", generated[0], generated[1])
+	}
+	if generated[0] == generated[2] {
+		t.Errorf("seeds 21 and 22 share the fingerprint %s", generated[0])
+	}
+}
+
+func TestFingerprintIgnoresRecountAndTracksEdits(t *testing.T) {
+	db := useTestDatabase(t)
+	manifest := generateTestCorpus(t, db, 80, 23, nil)
+	ctx := context.Background()
+	original := fingerprint(t, db)
+
+	// Recount rewrites every stored length without changing what is indexed
+	if _, err := db.ExecContext(ctx, "UPDATE documents SET length = 0"); err != nil {
+		t.Fatal(err)
+	}
+	checked, updated, err := NewCorpusHandler(db).RecountLengths(ctx)
+	if err != nil {
+		t.Fatalf("RecountLengths: %v", err)
+	}
+	if checked != manifest.DocumentCount || updated != manifest.DocumentCount {
+		t.Fatalf("recount checked %d and updated %d lengths, want %d of each", checked, updated, manifest.DocumentCount)
+	}
+	if got := fingerprint(t, db); got != original {
+		t.Errorf("fingerprint after recount = %s, want the unchanged %s", got, original)
+	}
+
+	var id int64
+	var content string
+	if err := db.QueryRowContext(ctx, "SELECT id, content FROM documents ORDER BY id DESC LIMIT 1").Scan(&id, &content); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE documents SET content = ? WHERE id = ?", content+" edited", id); err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(t, db); got == original {
+		t.Errorf("fingerprint %s did not change after document %d was edited", got, id)
+	}
+
+	// Undoing the edit restores the fingerprint
+	if _, err := db.ExecContext(ctx, "UPDATE documents SET content = ? WHERE id = ?", content, id); err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(t, db); got != original {
+		t.Errorf("fingerprint after the edit was undone = %s, want %s", got, original)
+	}
+}
@@ -1,3 +1,16 @@
 package handlers
 
-// No initialization needed - handlers are stateless and access global instances directly
\ No newline at end of file
+import (
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+)
+
+// Init points the global handler instances at db. Commands bind the global
+// handlers when they are built, so Init runs once the database is open and
+// before any command executes.
+func Init(db *database.Database) {
+	Corpus = *NewCorpusHandler(db)
+	Search = *NewSearchHandler(db)
+	Database = *NewDatabaseHandler(db)
+	Analyze = *NewAnalyzeHandler(db)
+	Visualize = *NewVisualizeHandler(db)
+}
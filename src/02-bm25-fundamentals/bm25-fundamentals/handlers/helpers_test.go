@@ -0,0 +1,124 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/spf13/cobra"
+)
+
+// useTestDatabase opens a new database with the corpus schema for the rest of
+// the test. The database is a file in a temporary directory rather than
+// :memory:, because the connection pool would give each connection its own
+// in-memory database.
+func useTestDatabase(t testing.TB) *database.Database {
+	t.Helper()
+	return useDatabaseFile(t, filepath.Join(t.TempDir(), "corpus.db"))
+}
+
+// useDatabaseFile opens the database file at path for the rest of the test,
+// creating the corpus schema if it is missing
+func useDatabaseFile(t testing.TB, path string) *database.Database {
+	t.Helper()
+
+	db, err := database.NewDatabase(path)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.InitSchema(context.Background()); err != nil {
+		t.Fatalf("create corpus schema: %v", err)
+	}
+	return db
+}
+
+// generateTestCorpus generates a seeded corpus of size documents into db,
+// letting adjust change the options first
+func generateTestCorpus(t testing.TB, db *database.Database, size int, seed int64, adjust func(*models.CorpusOptions)) *models.CorpusManifest {
+	t.Helper()
+
+	options := models.DefaultCorpusOptions()
+	options.Size = size
+	options.Seed = seed
+	options.MinTokens = 20
+	options.MaxTokens = 80
+	options.Workers = 1
+	if adjust != nil {
+		adjust(&options)
+	}
+
+	manifest, err := NewCorpusHandler(db).GenerateCorpus(context.Background(), options)
+	if err != nil {
+		t.Fatalf("generate corpus (seed %d): %v", seed, err)
+	}
+	return manifest
+}
+
+// insertTestDocuments inserts documents built from title, content, and
+// category triples into db, returning them with their assigned IDs
+func insertTestDocuments(t testing.TB, db *database.Database, rows ...[3]string) []*models.Document {
+	t.Helper()
+
+	docs := make([]*models.Document, len(rows))
+	for i, row := range rows {
+		docs[i] = &models.Document{Title: row[0], Content: row[1], Category: row[2]}
+	}
+	if err := NewCorpusHandler(db).BatchInsertDocuments(context.Background(), docs); err != nil {
+		t.Fatalf("insert documents: %v", err)
+	}
+	return docs
+}
+
+// newSearchCommand returns a command with the search query flags that
+// flagutil.ExtractSearchOptions reads, set from name and value pairs
+func newSearchCommand(t testing.TB, flags ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{Use: "query"}
+	flagutil.RegisterSearchFlags(cmd)
+	cmd.Flags().IntP("max-results", "n", 0, "maximum results to return")
+	flagutil.RegisterSnippetFlags(cmd)
+	for i := 0; i+1 < len(flags); i += 2 {
+		if err := cmd.Flags().Set(flags[i], flags[i+1]); err != nil {
+			t.Fatalf("set --%s: %v", flags[i], err)
+		}
+	}
+	return cmd
+}
+
+// captureStdout returns what fn writes to standard output
+func captureStdout(t testing.TB, fn func() error) []byte {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	previous := os.Stdout
+	os.Stdout = writer
+
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- data
+	}()
+
+	err = fn()
+	os.Stdout = previous
+	writer.Close()
+	data := <-output
+	reader.Close()
+	if err != nil {
+		t.Fatalf("write output: %v", err)
+	}
+	return data
+}
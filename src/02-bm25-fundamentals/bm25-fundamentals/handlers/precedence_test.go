@@ -0,0 +1,66 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
+)
+
+// TestSearchOptionPrecedence sets the same options at every layer that can
+// supply them and checks which value a search uses: a flag overrides the
+// options file, which overrides the config file, which overrides the default.
+func TestSearchOptionPrecedence(t *testing.T) {
+	previous := config.App.Display.SnippetTokens
+	t.Cleanup(func() { config.App.Display.SnippetTokens = previous })
+
+	optionsFile := filepath.Join(t.TempDir(), "options.json")
+	err := os.WriteFile(optionsFile, []byte(`{"query": "sqlite", "snippet_tokens": 24, "column_weights": {"title": 3, "content": 2}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  int // display.snippet_tokens
+		flags   []string
+		tokens  int
+		weights map[string]float64
+	}{
+		// 200 characters at about six per token
+		{"default", 0, []string{"query", "sqlite"}, 33, nil},
+		{"config over default", 12, []string{"query", "sqlite"}, 12, nil},
+		{"options file over config", 12, []string{"options-file", optionsFile}, 24, map[string]float64{"title": 3, "content": 2}},
+		{
+			"flag over options file", 12,
+			[]string{"options-file", optionsFile, "snippet-tokens", "8", "weights", "title:5"},
+			8, map[string]float64{"title": 5},
+		},
+		{
+			"field flag over --weights", 12,
+			[]string{"options-file", optionsFile, "weights", "title:5,content:1", "content-weight", "4"},
+			24, map[string]float64{"title": 5, "content": 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.App.Display.SnippetTokens = tt.config
+			options, err := flagutil.ExtractSearchOptions(newSearchCommand(t, tt.flags...))
+			if err != nil {
+				t.Fatalf("ExtractSearchOptions: %v", err)
+			}
+			if got := snippetTokens(options); got != tt.tokens {
+				t.Errorf("snippet tokens = %d, want %d", got, tt.tokens)
+			}
+			if !reflect.DeepEqual(options.ColumnWeights, tt.weights) {
+				t.Errorf("column weights = %v, want %v", options.ColumnWeights, tt.weights)
+			}
+		})
+	}
+}
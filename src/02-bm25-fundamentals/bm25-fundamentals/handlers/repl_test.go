@@ -0,0 +1,103 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+// TestReplRefineStaysWithinPreviousMatches refines one query by another and
+// checks the refined set is exactly the second query's matches that the first
+// query also matched
+func TestReplRefineStaysWithinPreviousMatches(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	insertTestDocuments(t, db,
+		[3]string{"Index tuning", "database index optimization", "database"},
+		[3]string{"Query plans", "database query optimization", "database"},
+		[3]string{"Compilers", "compiler optimization passes", "programming"},
+		[3]string{"Storage", "database storage engines", "database"},
+		[3]string{"Caches", "cache optimization for services", "systems"},
+	)
+	ctx := context.Background()
+
+	matches := func(query string, within []int64) []int64 {
+		options := models.DefaultSearchOptions()
+		options.Query = query
+		options.MaxResults = 2
+		options.WithinIDs = within
+		var ids []int64
+		captureStdout(t, func() error {
+			var err error
+			ids, err = search.replSearch(ctx, options)
+			return err
+		})
+		return ids
+	}
+
+	first := matches("database", nil)
+	optimization := matches("optimization", nil)
+	refined := matches("optimization", first)
+
+	// More matches than are shown, so the refinement sees the whole set
+	if len(first) != 3 || len(optimization) != 4 {
+		t.Fatalf("matched %d and %d documents, want 3 and 4", len(first), len(optimization))
+	}
+	var want []int64
+	for _, id := range optimization {
+		if slices.Contains(first, id) {
+			want = append(want, id)
+		}
+	}
+	slices.Sort(want)
+	slices.Sort(refined)
+	if !slices.Equal(refined, want) {
+		t.Errorf("refined matches = %v, want the optimization matches within database: %v", refined, want)
+	}
+}
+
+func TestRunRepl(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	insertTestDocuments(t, db,
+		[3]string{"Index tuning", "database index optimization", "database"},
+		[3]string{"Query plans", "database query optimization", "database"},
+		[3]string{"Compilers", "compiler optimization passes", "programming"},
+	)
+
+	script := strings.Join([]string{
+		".refine optimization",
+		"database",
+		".refine optimization",
+		".refine compiler",
+		"NEAR(",
+		".quit",
+		"never read",
+	}, "\n")
+	output := string(captureStdout(t, func() error {
+		return search.RunRepl(context.Background(), strings.NewReader(script), 10)
+	}))
+
+	for _, want := range []string{
+		"Nothing to refine: run a query first",
+		"2 documents matched",
+		// The refinement chains: compiler is searched within database AND optimization
+		fmt.Sprintf("Within: 2 documents from the results of %q", "optimization"),
+		"0 documents matched",
+		"Error:",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("REPL output lacks %q:\n%s", want, output)
+		}
+	}
+	// One prompt per line up to and including .quit
+	if prompts := strings.Count(output, "bm25> "); prompts != 6 {
+		t.Errorf("REPL prompted %d times, want 6 and none after .quit:\n%s", prompts, output)
+	}
+}
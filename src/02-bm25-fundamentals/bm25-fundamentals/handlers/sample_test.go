@@ -0,0 +1,69 @@
+package handlers
+
+import "testing"
+
+// TestSamplePositions checks every sample size against every match count up
+// to 310, past the point where rounding the spacing could repeat a rank
+func TestSamplePositions(t *testing.T) {
+	for matches := 1; matches <= 310; matches++ {
+		for n := 1; n <= matches+1; n++ {
+			positions := samplePositions(matches, n)
+
+			want := min(n, matches)
+			if len(positions) != want {
+				t.Fatalf("samplePositions(%d, %d) returned %d ranks, want %d", matches, n, len(positions), want)
+			}
+			if positions[0] != 0 {
+				t.Errorf("samplePositions(%d, %d) starts at rank %d, want the best match", matches, n, positions[0])
+			}
+			if want > 1 && positions[want-1] != matches-1 {
+				t.Errorf("samplePositions(%d, %d) ends at rank %d, want the worst match %d", matches, n, positions[want-1], matches-1)
+			}
+
+			// Gaps are as even as whole ranks allow, and never zero
+			gap := float64(matches-1) / float64(max(want-1, 1))
+			for i := 1; i < want; i++ {
+				step := positions[i] - positions[i-1]
+				if step < 1 || float64(step) < gap-1 || float64(step) > gap+1 {
+					t.Fatalf("samplePositions(%d, %d) = %v: step %d at %d, want about %.2f and at least 1", matches, n, positions, step, i, gap)
+				}
+			}
+		}
+	}
+}
+
+func TestRankPercentile(t *testing.T) {
+	tests := []struct {
+		rank, matches int
+		want          float64
+	}{
+		{1, 1, 100},
+		{1, 2, 100},
+		{2, 2, 0},
+		{1, 5, 100},
+		{3, 5, 50},
+		{5, 5, 0},
+		{2, 101, 99},
+	}
+
+	for _, tt := range tests {
+		if got := rankPercentile(tt.rank, tt.matches); got != tt.want {
+			t.Errorf("rankPercentile(%d, %d) = %g, want %g", tt.rank, tt.matches, got, tt.want)
+		}
+	}
+
+	// Percentiles of a sample fall from 100 at the best match to 0 at the worst
+	for matches := 2; matches <= 310; matches++ {
+		previous := 101.0
+		for _, position := range samplePositions(matches, 10) {
+			percentile := rankPercentile(position+1, matches)
+			if percentile >= previous || percentile < 0 || percentile > 100 {
+				t.Fatalf("%d matches: rank %d has percentile %g after %g", matches, position+1, percentile, previous)
+			}
+			previous = percentile
+		}
+		if previous != 0 {
+			t.Errorf("%d matches: the worst sampled rank has percentile %g, want 0", matches, previous)
+		}
+	}
+}
@@ -1,44 +1,80 @@
 package handlers
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	scorestats "github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/stats"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/tokens"
+	"github.com/jaime/go-sqlite/shared/relevance"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Search is the global search handler instance
 var Search SearchHandler
 
-// SearchHandler manages search operations and BM25 analysis (stateless - accesses global instances)
-type SearchHandler struct{}
+// statsMaxResults bounds the results search stats analyzes unless --all is given
+const statsMaxResults = 1000
+
+// defaultExplainResults bounds explain output when --max-results is not positive
+const defaultExplainResults = 5
+
+// maxScoreChanges bounds the "largest score changes" table in the comparison summary
+const maxScoreChanges = 5
+
+// SearchHandler manages search operations and BM25 analysis against its database
+type SearchHandler struct {
+	db     *database.Database
+	corpus *CorpusHandler
+}
+
+// NewSearchHandler creates a search handler that queries db
+func NewSearchHandler(db *database.Database) *SearchHandler {
+	return &SearchHandler{db: db, corpus: NewCorpusHandler(db)}
+}
 
 // GetSearchStats generates comprehensive statistics about search results
-func (h *SearchHandler) GetSearchStats(ctx context.Context, results []*models.SearchResult, query string, executionTime time.Duration) (*models.SearchStats, error) {
+func (h *SearchHandler) GetSearchStats(ctx context.Context, results []*models.SearchResult, options models.SearchOptions, executionTime time.Duration) (*models.SearchStats, error) {
 	if len(results) == 0 {
 		return &models.SearchStats{
-			Query:         query,
+			Query:         options.Query,
+			Mode:          options.Mode,
+			Near:          options.Near,
+			CreatedAfter:  options.CreatedAfter,
+			CreatedBefore: options.CreatedBefore,
 			TotalResults:  0,
 			ExecutionTime: executionTime,
 		}, nil
 	}
 
 	stats := &models.SearchStats{
-		Query:             query,
+		Query:             options.Query,
+		Mode:              options.Mode,
+		Near:              options.Near,
+		CreatedAfter:      options.CreatedAfter,
+		CreatedBefore:     options.CreatedBefore,
 		TotalResults:      len(results),
 		ExecutionTime:     executionTime,
-		CategoryBreakdown: make(map[string]int),
+		CategoryBreakdown: make(models.CategoryCounts),
 	}
 
 	// Collect scores and categories
@@ -63,88 +99,202 @@ func (h *SearchHandler) GetSearchStats(ctx context.Context, results []*models.Se
 
 // HandleCompare handles the search comparison command
 func (h *SearchHandler) HandleCompare(cmd *cobra.Command, args []string) error {
-	query, _ := cmd.Flags().GetString("query")
+	// Baseline search uses the shared search flags
+	baselineOptions, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	compareWeights, _ := cmd.Flags().GetString("compare-weights")
-	maxResults, _ := cmd.Flags().GetInt("max-results")
+	compareNear, _ := cmd.Flags().GetInt("compare-near")
+	if compareNear < 0 {
+		return errors.Validationf("--compare-near must not be negative, got %d", compareNear)
+	}
+	queryB, _ := cmd.Flags().GetString("query-b")
+	if cmd.Flags().Changed("query-b") && strings.TrimSpace(queryB) == "" {
+		return errors.Validationf("--query-b cannot be empty")
+	}
+
+	analyze, _ := cmd.Flags().GetBool("analyze")
+
+	topK, _ := cmd.Flags().GetInt("top-k")
+	if topK < 0 {
+		return errors.Validationf("--top-k must not be negative, got %d", topK)
+	}
 
-	ctx := context.Background()
+	explainID, _ := cmd.Flags().GetInt64("explain-id")
+	if explainID < 0 {
+		return errors.Validationf("--explain-id must be a positive document ID, got %d", explainID)
+	}
+
+	strategySpecs, _ := cmd.Flags().GetStringArray("strategy")
+	if len(strategySpecs) > 1 && explainID > 0 {
+		return errors.Validationf("--explain-id compares two strategies; pass a single --strategy")
+	}
+	namedWeights := make([]map[string]float64, len(strategySpecs))
+	strategyNames := make([]string, len(strategySpecs))
+	for i, spec := range strategySpecs {
+		name, weights, err := flagutil.ParseStrategy(spec)
+		if err != nil {
+			return err
+		}
+		if name == "baseline" || slices.Contains(strategyNames[:i], name) {
+			return errors.Validationf("strategy name %q is already in use", name)
+		}
+		strategyNames[i] = name
+		namedWeights[i] = weights
+	}
 
-	// Create baseline search (default weights)
-	baselineOptions := models.DefaultSearchOptions()
-	baselineOptions.Query = query
-	baselineOptions.MaxResults = maxResults
-	baselineOptions.IncludeSnippet = false
+	// The options file records the baseline; the comparison comes from its own flags
+	if err := flagutil.DumpSearchOptions(cmd, baselineOptions); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	if err := h.CheckCategory(ctx, baselineOptions); err != nil {
+		return err
+	}
 
 	baselineResults, err := h.Search(ctx, baselineOptions)
 	if err != nil {
 		return err
 	}
 
-	// Create comparison with custom weights
-	comparisonResults := baselineResults // Default to same results
-	var weights map[string]float64
-	
-	if compareWeights != "" {
-		weights, err = h.parseWeights(compareWeights)
+	strategies := []comparedStrategy{{name: "baseline", options: baselineOptions, results: baselineResults}}
+
+	// Create the comparison strategy from a different query and/or custom weights;
+	// filters and snippet options are copied from the baseline so both strategies
+	// search the same subset
+	if compareWeights != "" || queryB != "" || compareNear > 0 {
+		options := baselineOptions
+
+		if queryB != "" {
+			options.Query = queryB
+		}
+
+		// The same terms as one NEAR group; the group quotes each term, so it
+		// replaces any mode
+		if compareNear > 0 {
+			options.Mode = ""
+			options.Near = compareNear
+			if _, err := database.MatchExpression(options.Query, options.Mode, options.Near); err != nil {
+				return err
+			}
+		}
+
+		if compareWeights != "" {
+			weights, err := flagutil.ParseWeights(compareWeights)
+			if err != nil {
+				return err
+			}
+			options.ColumnWeights = weights
+		}
+
+		comparisonResults, err := h.Search(ctx, options)
 		if err != nil {
-			return errors.Validationf("invalid weight format: %w", err)
+			return err
 		}
+		strategies = append(strategies, comparedStrategy{name: "comparison", options: options, results: comparisonResults})
+	}
 
-		comparisonOptions := models.DefaultSearchOptions()
-		comparisonOptions.Query = query
-		comparisonOptions.MaxResults = maxResults
-		comparisonOptions.ColumnWeights = weights
-		comparisonOptions.IncludeSnippet = false
+	// Named strategies differ from the baseline by column weights only
+	for i, name := range strategyNames {
+		options := baselineOptions
+		options.ColumnWeights = namedWeights[i]
 
-		comparisonResults, err = h.Search(ctx, comparisonOptions)
+		results, err := h.Search(ctx, options)
 		if err != nil {
 			return err
 		}
+		strategies = append(strategies, comparedStrategy{name: name, options: options, results: results})
 	}
 
 	// Generate comparison analysis
-	comparison := h.generateComparison(query, baselineResults, comparisonResults, weights)
+	comparison := h.generateComparison(strategies, analyze, topK)
+
+	// Explain the chosen document under both strategies
+	if explainID > 0 {
+		var comparisonOptions *models.SearchOptions
+		if len(strategies) > 1 {
+			comparisonOptions = &strategies[1].options
+		}
+		comparison.Explanation, err = h.compareExplanations(ctx, explainID, baselineOptions, comparisonOptions)
+		if err != nil {
+			return err
+		}
+	}
 	
 	// Display comparison
-	return h.displayComparison(comparison)
+	return h.displayComparison(comparison, fieldLimit(baselineOptions))
 }
 
 // HandleExplain handles the search explanation command
 func (h *SearchHandler) HandleExplain(cmd *cobra.Command, args []string) error {
-	// Extract flags
-	query, _ := cmd.Flags().GetString("query")
-	category, _ := cmd.Flags().GetString("category")
-	titleWeight, _ := cmd.Flags().GetFloat64("title-weight")
-	contentWeight, _ := cmd.Flags().GetFloat64("content-weight")
-	categoryWeight, _ := cmd.Flags().GetFloat64("category-weight")
-	maxResults, _ := cmd.Flags().GetInt("max-results")
+	// A replay only renders a saved file, so no other flag applies
+	replay, _ := cmd.Flags().GetString("replay")
+	if replay != "" {
+		var conflict string
+		cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Changed && flag.Name != "replay" && conflict == "" {
+				conflict = flag.Name
+			}
+		})
+		if conflict != "" {
+			return errors.Validationf("--replay renders a saved file and cannot be combined with --%s", conflict)
+		}
+		return h.replayExplanations(replay)
+	}
+	savePath, _ := cmd.Flags().GetString("save-explain")
 
-	// Build search options
-	options := models.DefaultSearchOptions()
-	options.Query = query
-	options.MaxResults = maxResults
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
 	options.IncludeSnippet = false // Don't need snippets for explanations
 	options.ExplainScores = true
 
-	if category != "" {
-		options.CategoryFilter = category
+	query := options.Query
+	maxResults := options.MaxResults
+	rank, _ := cmd.Flags().GetInt("rank")
+	teach, _ := cmd.Flags().GetBool("teach")
+
+	if rank < 0 {
+		return errors.Validationf("rank must be 1 or greater, got %d", rank)
+	}
+	if rank > config.App.Limits.MaxResults {
+		return errors.Validationf("--rank %d exceeds the limit of %d (raise limits.max_results in the config file)", rank, config.App.Limits.MaxResults)
 	}
 
-	// Set column weights if specified
-	if titleWeight > 0 || contentWeight > 0 || categoryWeight > 0 {
-		options.ColumnWeights = make(map[string]float64)
-		if titleWeight > 0 {
-			options.ColumnWeights["title"] = titleWeight
-		}
-		if contentWeight > 0 {
-			options.ColumnWeights["content"] = contentWeight
-		}
-		if categoryWeight > 0 {
-			options.ColumnWeights["category"] = categoryWeight
-		}
+	// Explanations are verbose, so never fall back to an unbounded search
+	if maxResults <= 0 {
+		maxResults = defaultExplainResults
+	}
+
+	// A single rank only needs the results up to and including it
+	if rank > 0 {
+		maxResults = rank
+	}
+	options.MaxResults = maxResults
+
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	if err := h.CheckCategory(ctx, options); err != nil {
+		return err
+	}
 
 	// Perform search
 	results, err := h.Search(ctx, options)
@@ -157,917 +307,2996 @@ func (h *SearchHandler) HandleExplain(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+
+	firstRank := 1
+	if rank > 0 {
+		if len(results) < rank {
+			return errors.NotFoundf("query \"%s\" returned %d results; rank %d does not exist", query, len(results), rank)
+		}
+		results = results[rank-1:]
+		firstRank = rank
+	}
+
 	// Generate detailed explanations
 	explanations, err := h.GenerateScoreExplanations(ctx, results, options)
 	if err != nil {
 		return err
 	}
 
-	// Display detailed explanations
-	return h.displayScoreExplanations(explanations, options)
-}
-
-// HandleQuery handles the search query command
-func (h *SearchHandler) HandleQuery(cmd *cobra.Command, args []string) error {
-	// Extract flags
-	query, _ := cmd.Flags().GetString("query")
-	maxResults, _ := cmd.Flags().GetInt("max-results")
-	category, _ := cmd.Flags().GetString("category")
-	titleWeight, _ := cmd.Flags().GetFloat64("title-weight")
-	contentWeight, _ := cmd.Flags().GetFloat64("content-weight")
-	categoryWeight, _ := cmd.Flags().GetFloat64("category-weight")
-	includeSnippets, _ := cmd.Flags().GetBool("snippets")
-	snippetLength, _ := cmd.Flags().GetInt("snippet-length")
-
-	// Build search options
-	options := models.DefaultSearchOptions()
-	options.Query = query
-
-	if maxResults > 0 {
-		options.MaxResults = maxResults
-	} else {
-		options.MaxResults = config.App.Search.MaxResults
-	}
-
-	if category != "" {
-		options.CategoryFilter = category
+	for i, explanation := range explanations {
+		explanation.Rank = firstRank + i
 	}
 
-	// Set column weights if specified
-	if titleWeight > 0 || contentWeight > 0 || categoryWeight > 0 {
-		options.ColumnWeights = make(map[string]float64)
-		if titleWeight > 0 {
-			options.ColumnWeights["title"] = titleWeight
+	if savePath != "" {
+		snapshot, err := h.corpusSnapshot(ctx)
+		if err != nil {
+			return err
 		}
-		if contentWeight > 0 {
-			options.ColumnWeights["content"] = contentWeight
+		record := &models.ExplainRecord{
+			Saved:        time.Now(),
+			Options:      options,
+			Corpus:       *snapshot,
+			Explanations: explanations,
 		}
-		if categoryWeight > 0 {
-			options.ColumnWeights["category"] = categoryWeight
+		if err := h.WriteExplainRecord(savePath, record); err != nil {
+			return err
 		}
 	}
 
-	options.IncludeSnippet = includeSnippets
-	if snippetLength > 0 {
-		options.SnippetLength = snippetLength
+	// Display detailed explanations
+	if err := h.displayScoreExplanations(explanations, options); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
+	if teach {
+		if err := h.displayTeachingWalkthrough(ctx, results[0], firstRank, options); err != nil {
+			return err
+		}
+	}
 
-	// Perform search
-	startTime := time.Now()
-	results, err := h.Search(ctx, options)
-	if err != nil {
-		return err
+	if savePath != "" {
+		fmt.Printf("✓ Explanations saved to %s\n", savePath)
 	}
-	executionTime := time.Since(startTime)
 
-	// Display results
-	return h.displaySearchResults(results, options, executionTime)
+	return nil
 }
 
-// HandleStats handles the search statistics command
-func (h *SearchHandler) HandleStats(cmd *cobra.Command, args []string) error {
-	// Extract flags
-	query, _ := cmd.Flags().GetString("query")
-	category, _ := cmd.Flags().GetString("category")
-	titleWeight, _ := cmd.Flags().GetFloat64("title-weight")
-	contentWeight, _ := cmd.Flags().GetFloat64("content-weight")
-	categoryWeight, _ := cmd.Flags().GetFloat64("category-weight")
-
-	// Build search options
-	options := models.DefaultSearchOptions()
-	options.Query = query
-	options.MaxResults = 1000      // Get more results for better statistics
-	options.IncludeSnippet = false // Don't need snippets for stats
-
-	if category != "" {
-		options.CategoryFilter = category
+// corpusSnapshot reads the index statistics an explanation depends on
+func (h *SearchHandler) corpusSnapshot(ctx context.Context) (*models.CorpusSnapshot, error) {
+	indexLengths, err := h.db.IndexLengths(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Set column weights if specified
-	if titleWeight > 0 || contentWeight > 0 || categoryWeight > 0 {
-		options.ColumnWeights = make(map[string]float64)
-		if titleWeight > 0 {
-			options.ColumnWeights["title"] = titleWeight
-		}
-		if contentWeight > 0 {
-			options.ColumnWeights["content"] = contentWeight
-		}
-		if categoryWeight > 0 {
-			options.ColumnWeights["category"] = categoryWeight
-		}
+	snapshot := &models.CorpusSnapshot{
+		Documents:       indexLengths.Rows,
+		AvgLength:       indexLengths.Average(),
+		FieldAvgLengths: make(map[string]float64, len(database.IndexedColumns)),
+		Tokenizer:       database.Tokenizer(),
+		BM25:            models.DefaultBM25Params,
 	}
+	for i, column := range database.IndexedColumns {
+		snapshot.FieldAvgLengths[column] = indexLengths.ColumnAverage(i)
+	}
+	return snapshot, nil
+}
 
-	ctx := context.Background()
-
-	// Perform search
-	startTime := time.Now()
-	results, err := h.Search(ctx, options)
+// replayExplanations renders explanations saved by --save-explain. The
+// database is not read, so the output is the same however the corpus has
+// changed since.
+func (h *SearchHandler) replayExplanations(path string) error {
+	record, err := h.LoadExplainRecord(path)
 	if err != nil {
 		return err
 	}
-	executionTime := time.Since(startTime)
 
-	// Generate statistics
-	stats, err := h.GetSearchStats(ctx, results, query, executionTime)
-	if err != nil {
-		return err
+	saved := "at an unknown time"
+	if !record.Saved.IsZero() {
+		saved = record.Saved.Format("2006-01-02 15:04")
 	}
+	fmt.Printf("Replay of %s, saved %s\n", path, saved)
+	fmt.Printf("Corpus then: %d documents, average length %.1f tokens, tokenizer %q\n\n",
+		record.Corpus.Documents, record.Corpus.AvgLength, record.Corpus.Tokenizer)
 
-	// Display statistics
-	return h.displaySearchStats(stats)
+	return h.displayScoreExplanations(record.Explanations, record.Options)
 }
 
-// Search performs FTS5 search with BM25 scoring
-func (h *SearchHandler) Search(ctx context.Context, options models.SearchOptions) ([]*models.SearchResult, error) {
-	// Build the search query
-	query, args := h.buildSearchQuery(options)
-
-	// Execute search
-	rows, err := database.Instance.DB().QueryContext(ctx, query, args...)
+// LoadExplainRecord reads explanations written by WriteExplainRecord
+func (h *SearchHandler) LoadExplainRecord(path string) (*models.ExplainRecord, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, errors.FTS5f("search query failed: %w", err)
+		return nil, errors.NotFoundf("explain file %s: %w", path, err)
 	}
-	defer rows.Close()
+	defer file.Close()
 
-	var results []*models.SearchResult
-
-	for rows.Next() {
-		result := &models.SearchResult{}
-
-		err := rows.Scan(
-			&result.ID,
-			&result.Title,
-			&result.Content,
-			&result.Category,
-			&result.Length,
-			&result.Created,
-			&result.Score,
-		)
-		if err != nil {
-			return nil, errors.Databasef("failed to scan search result: %w", err)
-		}
-
-		// Add snippet if requested
-		if options.IncludeSnippet {
-			result.Snippet = h.generateSnippet(result.Content, options.Query, options.SnippetLength)
+	var record models.ExplainRecord
+	if err := json.NewDecoder(file).Decode(&record); err != nil {
+		return nil, errors.Validationf("failed to parse explain file %s: %w", path, err)
+	}
+	if record.Options.Query == "" || len(record.Explanations) == 0 {
+		return nil, errors.Validationf("%s holds no saved explanations (write one with search explain --save-explain)", path)
+	}
+	for i, explanation := range record.Explanations {
+		if explanation == nil {
+			return nil, errors.Validationf("explain file %s: explanation %d is null", path, i+1)
 		}
+	}
 
-		// Classify relevance based on score
-		result.Relevance = h.classifyRelevance(result.Score)
+	return &record, nil
+}
 
-		results = append(results, result)
+// WriteExplainRecord saves explanations and their corpus snapshot as indented JSON
+func (h *SearchHandler) WriteExplainRecord(path string, record *models.ExplainRecord) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Validationf("failed to create explain file %s: %w", path, err)
 	}
+	defer file.Close()
 
-	if err := rows.Err(); err != nil {
-		return nil, errors.Databasef("error iterating search results: %w", err)
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(record); err != nil {
+		return errors.Validationf("failed to write explain file: %w", err)
 	}
 
-	return results, nil
+	return nil
 }
 
-// buildSearchQuery constructs the FTS5 search query with optional column weighting
-func (h *SearchHandler) buildSearchQuery(options models.SearchOptions) (string, []interface{}) {
-	var queryParts []string
-	var args []interface{}
+// HandleQuery handles the search query command
+func (h *SearchHandler) HandleQuery(cmd *cobra.Command, args []string) error {
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
 
-	// Base query with BM25 scoring
-	baseQuery := `
-		SELECT 
-			d.id, d.title, d.content, d.category, d.length, d.created,
-			%s as score
-		FROM documents d
-		JOIN documents_fts fts ON d.id = fts.rowid
-		WHERE documents_fts MATCH ?`
+	if options.MaxResults <= 0 {
+		options.MaxResults = config.App.Search.MaxResults
+	}
+	options.ExplainInline, _ = cmd.Flags().GetBool("explain-inline")
+	options.Highlight, _ = cmd.Flags().GetBool("highlight")
+	options.Sample, err = flagutil.Sample(cmd)
+	if err != nil {
+		return err
+	}
 
-	// Determine scoring method based on column weights
-	var scoreExpr string
-	if len(options.ColumnWeights) > 0 {
-		// Custom column weighting
-		weights := make([]string, 0, 3)
-		if w, ok := options.ColumnWeights["title"]; ok {
-			weights = append(weights, fmt.Sprintf("%.2f", w))
-		} else {
-			weights = append(weights, "1.0")
-		}
-		if w, ok := options.ColumnWeights["content"]; ok {
-			weights = append(weights, fmt.Sprintf("%.2f", w))
-		} else {
-			weights = append(weights, "1.0")
-		}
-		if w, ok := options.ColumnWeights["category"]; ok {
-			weights = append(weights, fmt.Sprintf("%.2f", w))
-		} else {
-			weights = append(weights, "1.0")
+	pageNumber, pageSize, err := flagutil.Page(cmd)
+	if err != nil {
+		return err
+	}
+	if pageNumber > 0 {
+		if pageSize > 0 {
+			options.MaxResults = pageSize
 		}
-		scoreExpr = fmt.Sprintf("bm25(documents_fts, %s)", strings.Join(weights, ", "))
-	} else {
-		// Default BM25 scoring
-		scoreExpr = "bm25(documents_fts)"
+		options.Offset = (pageNumber - 1) * options.MaxResults
 	}
 
-	queryParts = append(queryParts, fmt.Sprintf(baseQuery, scoreExpr))
-	args = append(args, options.Query)
-
-	// Add category filter if specified
-	if options.CategoryFilter != "" {
-		queryParts = append(queryParts, "AND d.category = ?")
-		args = append(args, options.CategoryFilter)
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
 	}
 
-	// Order by BM25 score (remember: lower = better in SQLite FTS5)
-	queryParts = append(queryParts, "ORDER BY score")
+	ctx := cmd.Context()
 
-	// Add limit
-	if options.MaxResults > 0 {
-		queryParts = append(queryParts, "LIMIT ?")
-		args = append(args, options.MaxResults)
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
 	}
 
-	query := strings.Join(queryParts, " ")
-	return query, args
-}
-
-// calculateScoreDistribution computes statistical measures of score distribution
-func (h *SearchHandler) calculateScoreDistribution(scores []float64) models.ScoreDistribution {
-	sort.Float64s(scores) // Sort for percentile calculation
-
-	distrib := models.ScoreDistribution{
-		Percentiles: make(map[int]float64),
+	if err := h.CheckCategory(ctx, options); err != nil {
+		return err
 	}
 
-	n := len(scores)
-	if n == 0 {
-		return distrib
+	// Perform search
+	startTime := time.Now()
+	var results []*models.SearchResult
+	if options.Sample > 0 {
+		results, err = h.sampleRanking(ctx, options)
+	} else {
+		results, err = h.Search(ctx, options)
 	}
-
-	// Calculate mean
-	sum := 0.0
-	for _, score := range scores {
-		sum += score
+	if err != nil {
+		return err
 	}
-	distrib.Mean = sum / float64(n)
+	executionTime := time.Since(startTime)
 
-	// Calculate median (50th percentile)
-	if n%2 == 0 {
-		distrib.Median = (scores[n/2-1] + scores[n/2]) / 2
-	} else {
-		distrib.Median = scores[n/2]
+	// A page is placed within the total, which takes a count of every match
+	var page *models.ResultPage
+	if pageNumber > 0 {
+		total, err := h.CountMatches(ctx, options)
+		if err != nil {
+			return err
+		}
+		page = &models.ResultPage{Number: pageNumber, Size: options.MaxResults, Total: total}
 	}
 
-	// Calculate standard deviation
-	sumSquareDiff := 0.0
-	for _, score := range scores {
-		diff := score - distrib.Mean
-		sumSquareDiff += diff * diff
+	// The breakdown reads index statistics per result, so it is timed separately
+	if options.ExplainInline {
+		if err := h.addFieldShares(ctx, results, options); err != nil {
+			return err
+		}
 	}
-	distrib.StdDev = math.Sqrt(sumSquareDiff / float64(n))
 
-	// Calculate percentiles
-	percentiles := []int{25, 50, 75, 90, 95, 99}
-	for _, p := range percentiles {
-		index := float64(p) / 100.0 * float64(n-1)
-		lower := int(math.Floor(index))
-		upper := int(math.Ceil(index))
+	excluded, err := h.ExcludedByThreshold(ctx, options)
+	if err != nil {
+		return err
+	}
 
-		if lower == upper {
-			distrib.Percentiles[p] = scores[lower]
-		} else {
-			// Linear interpolation
-			weight := index - float64(lower)
-			distrib.Percentiles[p] = scores[lower]*(1-weight) + scores[upper]*weight
+	// JSON output records the corpus it came from; the fingerprint reads every
+	// document, so other formats skip it
+	var fingerprint *models.CorpusFingerprint
+	if config.App.Format == "json" {
+		if fingerprint, err = h.db.Fingerprint(ctx); err != nil {
+			return err
 		}
 	}
 
-	// Create score buckets for histogram
-	distrib.Buckets = h.createScoreBuckets(scores, 10)
-
-	return distrib
+	// Display results
+	return h.displaySearchResults(results, options, executionTime, page, excluded, fingerprint)
 }
 
-// classifyRelevance assigns a relevance label based on BM25 score
-func (h *SearchHandler) classifyRelevance(score float64) string {
-	// Remember: SQLite FTS5 BM25 scores are negative (lower = better)
-	switch {
-	case score >= -1.0:
-		return "excellent"
-	case score >= -2.0:
-		return "good"
-	case score >= -4.0:
-		return "fair"
-	default:
-		return "poor"
+// HandleRepl handles the search repl command
+func (h *SearchHandler) HandleRepl(cmd *cobra.Command, args []string) error {
+	maxResults, _ := cmd.Flags().GetInt("max-results")
+	if maxResults <= 0 {
+		maxResults = config.App.Search.MaxResults
 	}
-}
-
-// createScoreBuckets creates histogram buckets for score distribution
-func (h *SearchHandler) createScoreBuckets(scores []float64, numBuckets int) []models.ScoreBucket {
-	if len(scores) == 0 {
-		return nil
+	if maxResults > config.App.Limits.MaxResults {
+		return errors.Validationf("--max-results %d exceeds the limit of %d (raise limits.max_results in the config file)", maxResults, config.App.Limits.MaxResults)
 	}
 
-	min := scores[0]
-	max := scores[len(scores)-1]
+	ctx := cmd.Context()
 
-	// Handle case where all scores are the same
-	if min == max {
-		return []models.ScoreBucket{{
-			Min:   min,
-			Max:   max,
-			Count: len(scores),
-			Label: fmt.Sprintf("%.2f", min),
-		}}
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
 	}
 
-	buckets := make([]models.ScoreBucket, numBuckets)
-	bucketWidth := (max - min) / float64(numBuckets)
+	return h.RunRepl(ctx, cmd.InOrStdin(), maxResults)
+}
+
+// replHelp lists the commands the search REPL accepts
+const replHelp = `Enter a query to search the whole corpus, or:
+  .refine <query>  search only the documents the previous search matched
+  .help            show this help
+  .quit            leave the REPL (or press Ctrl-D)`
+
+// RunRepl reads queries from in, one per line, and shows the best maxResults
+// matches of each. Every search remembers all the documents it matched, so
+// ".refine <query>" can run the next query within them; refinements chain, each
+// narrowing the set further. A query that fails is reported and the REPL reads
+// the next line.
+func (h *SearchHandler) RunRepl(ctx context.Context, in io.Reader, maxResults int) error {
+	fmt.Println("BM25 search REPL. Type .help for commands.")
+
+	var matched []int64 // Every document the previous search matched
+	var previous string // The previous search's query
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Print("bm25> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			break
+		}
+		if ctx.Err() != nil {
+			return errors.Cancelledf("search REPL interrupted")
+		}
 
-	// Initialize buckets
-	for i := 0; i < numBuckets; i++ {
-		buckets[i].Min = min + float64(i)*bucketWidth
-		buckets[i].Max = min + float64(i+1)*bucketWidth
-		buckets[i].Label = fmt.Sprintf("%.2f to %.2f", buckets[i].Min, buckets[i].Max)
-	}
+		line := strings.TrimSpace(scanner.Text())
+		command, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		options := models.DefaultSearchOptions()
+		options.MaxResults = maxResults
+		switch command {
+		case "":
+			continue
+		case ".quit", ".exit":
+			return nil
+		case ".help":
+			fmt.Println(replHelp)
+			continue
+		case ".refine":
+			if rest == "" {
+				fmt.Println("Usage: .refine <query>")
+				continue
+			}
+			if previous == "" {
+				fmt.Println("Nothing to refine: run a query first")
+				continue
+			}
+			options.Query = rest
+			options.WithinIDs = matched
+			options.Within = fmt.Sprintf("the results of %q", previous)
+		default:
+			options.Query = line
+		}
 
-	// Count scores in each bucket
-	for _, score := range scores {
-		bucketIndex := int((score - min) / bucketWidth)
-		if bucketIndex >= numBuckets {
-			bucketIndex = numBuckets - 1
+		ids, err := h.replSearch(ctx, options)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			fmt.Printf("Error: %v\n", err)
+			continue
 		}
-		buckets[bucketIndex].Count++
+		matched, previous = ids, options.Query
 	}
 
-	return buckets
+	if err := scanner.Err(); err != nil {
+		return errors.Validationf("failed to read REPL input: %w", err)
+	}
+	return nil
 }
 
-// displaySearchResults formats and displays search results
-func (h *SearchHandler) displaySearchResults(results []*models.SearchResult, options models.SearchOptions, executionTime time.Duration) error {
-	switch config.App.Format {
-	case "json":
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(map[string]interface{}{
-			"query":          options.Query,
-			"total_results":  len(results),
-			"execution_time": executionTime.String(),
+// replSearch shows the best matches for one REPL query and returns the ID of
+// every document it matched, in rank order
+func (h *SearchHandler) replSearch(ctx context.Context, options models.SearchOptions) ([]int64, error) {
+	startTime := time.Now()
+	results, err := h.Search(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	executionTime := time.Since(startTime)
+
+	// A second pass without content collects the whole match set to refine
+	ranking := options
+	ranking.MaxResults = 0
+	ranking.IncludeContent = false
+	ranking.IncludeSnippet = false
+	ranked, err := h.Search(ctx, ranking)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(ranked))
+	for i, result := range ranked {
+		ids[i] = result.ID
+	}
+
+	if err := h.displaySearchResults(results, options, executionTime, nil, 0, nil); err != nil {
+		return nil, err
+	}
+	fmt.Printf("%d documents matched; .refine <query> searches within them\n\n", len(ids))
+	return ids, nil
+}
+
+// HandleStats handles the search statistics command
+func (h *SearchHandler) HandleStats(cmd *cobra.Command, args []string) error {
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
+	options.MaxResults = statsMaxResults // Get more results for better statistics
+	options.IncludeSnippet = false       // Don't need snippets for stats
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		options.MaxResults = 0
+	}
+
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	if err := h.CheckCategory(ctx, options); err != nil {
+		return err
+	}
+
+	// Perform search
+	startTime := time.Now()
+	results, err := h.Search(ctx, options)
+	if err != nil {
+		return err
+	}
+	executionTime := time.Since(startTime)
+
+	// The breakdown reads index statistics per result, so it is timed separately
+	if options.ExplainInline {
+		if err := h.addFieldShares(ctx, results, options); err != nil {
+			return err
+		}
+	}
+
+	// Generate statistics
+	stats, err := h.GetSearchStats(ctx, results, options, executionTime)
+	if err != nil {
+		return err
+	}
+
+	stats.TotalMatches, err = h.TotalMatches(ctx, options, results)
+	if err != nil {
+		return err
+	}
+	stats.MinScore = options.MinScore
+	stats.ExcludedByThreshold, err = h.ExcludedByThreshold(ctx, options)
+	if err != nil {
+		return err
+	}
+	stats.Truncated = stats.TotalMatches > len(results)
+	if config.App.Format != "json" {
+		WarnTruncated(len(results), stats.TotalMatches)
+	}
+
+	// Display statistics
+	return h.displaySearchStats(stats)
+}
+
+// HandleEvaluate handles the search evaluation command
+func (h *SearchHandler) HandleEvaluate(cmd *cobra.Command, args []string) error {
+	manifestPath, _ := cmd.Flags().GetString("from-manifest")
+	maxResults, err := flagutil.MaxResults(cmd)
+	if err != nil {
+		return err
+	}
+
+	if maxResults <= 0 {
+		maxResults = config.App.Search.MaxResults
+	}
+
+	manifest, err := h.corpus.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	if len(manifest.Injections) == 0 {
+		return errors.Validationf("manifest %s has no injected phrases (generate with --inject \"phrase:N\")", manifestPath)
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	evaluations, err := h.EvaluateManifest(ctx, manifest, maxResults)
+	if err != nil {
+		return err
+	}
+
+	fingerprint, err := h.db.Fingerprint(ctx)
+	if err != nil {
+		return err
+	}
+
+	return h.displayEvaluations(evaluations, fingerprint)
+}
+
+// EvaluateManifest runs each phrase injected by the manifest's generation as a
+// phrase query returning up to maxResults results, and scores the results
+// against the documents that received the phrase
+func (h *SearchHandler) EvaluateManifest(ctx context.Context, manifest *models.CorpusManifest, maxResults int) ([]*models.QueryEvaluation, error) {
+	evaluations := make([]*models.QueryEvaluation, 0, len(manifest.Injections))
+	for i, injection := range manifest.Injections {
+		if ctx.Err() != nil {
+			return nil, errors.Cancelledf("evaluation interrupted after %d of %d queries", i, len(manifest.Injections))
+		}
+
+		// Search for the phrase itself, so documents that only contain its
+		// words apart are not counted as retrieved
+		options := models.DefaultSearchOptions()
+		options.Query = injection.Phrase
+		options.Mode = models.QueryModePhrase
+		options.MaxResults = maxResults
+		options.IncludeSnippet = false
+
+		results, err := h.Search(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+
+		evaluations = append(evaluations, h.EvaluateResults(injection.Phrase, results, injection.IDs))
+	}
+	return evaluations, nil
+}
+
+// EvaluateResults computes retrieval metrics for ranked results against a set of relevant document IDs
+func (h *SearchHandler) EvaluateResults(query string, results []*models.SearchResult, relevantIDs []int64) *models.QueryEvaluation {
+	relevant := make(map[int64]bool, len(relevantIDs))
+	for _, id := range relevantIDs {
+		relevant[id] = true
+	}
+
+	eval := &models.QueryEvaluation{
+		Query:     query,
+		Relevant:  len(relevant),
+		Retrieved: len(results),
+	}
+
+	precisionSum := 0.0
+	for i, result := range results {
+		if !relevant[result.ID] {
+			continue
+		}
+		eval.RelevantRetrieved++
+		precisionSum += float64(eval.RelevantRetrieved) / float64(i+1)
+
+		if i < eval.Relevant {
+			eval.RPrecision++
+		}
+	}
+
+	if eval.Retrieved > 0 {
+		eval.Precision = float64(eval.RelevantRetrieved) / float64(eval.Retrieved)
+	}
+	if eval.Relevant > 0 {
+		eval.Recall = float64(eval.RelevantRetrieved) / float64(eval.Relevant)
+		eval.RPrecision /= float64(eval.Relevant)
+		eval.AveragePrecision = precisionSum / float64(eval.Relevant)
+	}
+
+	return eval
+}
+
+// Search performs FTS5 search with BM25 scoring. With options.WithinIDs set,
+// only those documents can match.
+func (h *SearchHandler) Search(ctx context.Context, options models.SearchOptions) ([]*models.SearchResult, error) {
+	var results []*models.SearchResult
+	var err error
+	if options.WithinIDs != nil {
+		results, err = h.searchWithin(ctx, options)
+	} else {
+		results, err = h.runSearch(ctx, options, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		// Classify relevance based on score
+		result.Relevance = h.classifyRelevance(result.Score)
+	}
+
+	return results, nil
+}
+
+// sampleRanking returns options.Sample results spread across the full
+// ranking: the best, the worst, and evenly spaced ranks between, each labeled
+// with its position. A first pass ranks every match without reading content;
+// the chosen documents are then fetched by ID with the requested content,
+// snippets, and highlighting.
+func (h *SearchHandler) sampleRanking(ctx context.Context, options models.SearchOptions) ([]*models.SearchResult, error) {
+	ranking := options
+	ranking.MaxResults = 0
+	ranking.IncludeContent = false
+	ranking.IncludeSnippet = false
+	ranking.Highlight = false
+
+	ranked, err := h.Search(ctx, ranking)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+
+	positions := make(map[int64]*models.SamplePosition, options.Sample)
+	ids := make([]int64, 0, options.Sample)
+	for _, index := range samplePositions(len(ranked), options.Sample) {
+		rank := index + 1
+		positions[ranked[index].ID] = &models.SamplePosition{
+			Rank:       rank,
+			Matches:    len(ranked),
+			Percentile: rankPercentile(rank, len(ranked)),
+		}
+		ids = append(ids, ranked[index].ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	fetch := options
+	fetch.MaxResults = len(ids)
+	fetch.WithinIDs = ids
+	results, err := h.Search(ctx, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tied scores may come back in another order, so rank by the first pass
+	for _, result := range results {
+		result.Sample = positions[result.ID]
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Sample.Rank < results[j].Sample.Rank
+	})
+	return results, nil
+}
+
+// samplePositions picks n 0-based ranks out of matches, evenly spaced from
+// the best (0) to the worst (matches-1). Every rank is returned when n covers
+// them all; otherwise the spacing is at least one rank, so no rank repeats.
+func samplePositions(matches, n int) []int {
+	if n >= matches {
+		n = matches
+	}
+	positions := make([]int, n)
+	if n == 1 {
+		return positions
+	}
+	for i := range positions {
+		positions[i] = int(math.Round(float64(i*(matches-1)) / float64(n-1)))
+	}
+	return positions
+}
+
+// rankPercentile returns the share of the other matches ranked below the
+// 1-based rank, as a percentage: 100 for the best match and 0 for the worst.
+// A single match is the best.
+func rankPercentile(rank, matches int) float64 {
+	if matches <= 1 {
+		return 100
+	}
+	return 100 * float64(matches-rank) / float64(matches-1)
+}
+
+// runSearch executes one search query, restricted to ids when any are given
+func (h *SearchHandler) runSearch(ctx context.Context, options models.SearchOptions, ids []int64) ([]*models.SearchResult, error) {
+	query, args, err := h.buildSearchQuery(options, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.FTS5f("search query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return database.ScanSearchResults(rows)
+}
+
+// searchParameters is the most parameters buildSearchQuery binds besides
+// document IDs, categories, created bounds, and the score threshold: the snippet and highlight
+// markers, the MATCH query, the LIMIT, and the OFFSET
+const searchParameters = 7
+
+// reservedParameters is the number of parameters a search binds besides
+// document IDs
+func reservedParameters(options models.SearchOptions) int {
+	reserved := searchParameters + len(options.CategoryFilters)
+	if !options.CreatedAfter.IsZero() {
+		reserved++
+	}
+	if !options.CreatedBefore.IsZero() {
+		reserved++
+	}
+	if options.MinScore != nil {
+		reserved++
+	}
+	return reserved
+}
+
+// searchWithin runs the search over options.WithinIDs in chunks that fit the
+// parameter limit and merges the chunks into one ranking. bm25() scores
+// against the whole index, not the rows a query selects, so scores from
+// different chunks compare directly; each chunk's top MaxResults holds every
+// document of the overall top MaxResults.
+func (h *SearchHandler) searchWithin(ctx context.Context, options models.SearchOptions) ([]*models.SearchResult, error) {
+	// A page can only be cut from the merged ranking, so each chunk returns
+	// everything up to the end of the page
+	chunkOptions := options
+	chunkOptions.Offset = 0
+	if options.MaxResults > 0 {
+		chunkOptions.MaxResults = options.Offset + options.MaxResults
+	}
+
+	var results []*models.SearchResult
+	for chunk := range database.ChunkIDs(options.WithinIDs, reservedParameters(options)) {
+		chunkResults, err := h.runSearch(ctx, chunkOptions, chunk)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunkResults...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if c := compareSortKey(results[i], results[j], options.SortBy); c != 0 {
+			return (c < 0) != options.SortDesc
+		}
+		if results[i].Score != results[j].Score {
+			return results[i].Score < results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if options.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[options.Offset:]
+	if options.MaxResults > 0 && len(results) > options.MaxResults {
+		results = results[:options.MaxResults]
+	}
+	return results, nil
+}
+
+// CountMatches returns the number of documents the search matches, ignoring
+// MaxResults and Offset
+func (h *SearchHandler) CountMatches(ctx context.Context, options models.SearchOptions) (int, error) {
+	if options.WithinIDs == nil {
+		return h.countMatches(ctx, options, nil)
+	}
+
+	total := 0
+	for chunk := range database.ChunkIDs(options.WithinIDs, reservedParameters(options)) {
+		count, err := h.countMatches(ctx, options, chunk)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// ExcludedByThreshold returns the number of matches options.MinScore drops:
+// the matches of the search without the threshold less those with it. It is
+// zero when no threshold is set.
+func (h *SearchHandler) ExcludedByThreshold(ctx context.Context, options models.SearchOptions) (int, error) {
+	if options.MinScore == nil {
+		return 0, nil
+	}
+
+	kept, err := h.CountMatches(ctx, options)
+	if err != nil {
+		return 0, err
+	}
+	unfiltered := options
+	unfiltered.MinScore = nil
+	all, err := h.CountMatches(ctx, unfiltered)
+	if err != nil {
+		return 0, err
+	}
+	return all - kept, nil
+}
+
+// TotalMatches returns the number of documents a search matched, given the
+// results it returned. Only results that filled MaxResults can have been cut
+// short, so the matches are counted only then.
+func (h *SearchHandler) TotalMatches(ctx context.Context, options models.SearchOptions, results []*models.SearchResult) (int, error) {
+	if options.MaxResults <= 0 || len(results) < options.MaxResults {
+		return len(results), nil
+	}
+	return h.CountMatches(ctx, options)
+}
+
+// WarnTruncated tells the user on stderr that an analysis saw only the best
+// analyzed of matches documents, which biases it toward the best scores
+func WarnTruncated(analyzed, matches int) {
+	if analyzed < matches {
+		fmt.Fprintf(os.Stderr, "Warning: analyzing %d of %d matches — distribution is truncated; use --all\n", analyzed, matches)
+	}
+}
+
+// sortColumns maps each sort key to the column buildSearchQuery orders by, so
+// the ORDER BY clause is built only from these fixed names
+var sortColumns = map[string]string{
+	models.SortScore:   "score",
+	models.SortCreated: "d.created",
+	models.SortLength:  "d.length",
+	models.SortTitle:   "d.title",
+}
+
+// orderBy returns the ORDER BY clause for options. Keys other than score
+// break ties by score, so equal dates, lengths, or titles keep their
+// relevance order.
+func orderBy(options models.SearchOptions) (string, error) {
+	key := options.SortBy
+	if key == "" {
+		key = models.SortScore
+	}
+	column, ok := sortColumns[key]
+	if !ok {
+		return "", errors.Validationf("invalid sort key: %s (must be %s)", key, strings.Join(models.SortKeys, ", "))
+	}
+
+	if options.SortDesc {
+		column += " DESC"
+	}
+	if key != models.SortScore {
+		column += ", score"
+	}
+	return "ORDER BY " + column, nil
+}
+
+// compareSortKey compares two results by a sort key, returning a negative
+// number when a sorts first. Creation times compare as instants, which is the
+// order of the stored text when documents share a UTC offset. An empty key
+// compares scores.
+func compareSortKey(a, b *models.SearchResult, key string) int {
+	switch key {
+	case models.SortCreated:
+		return a.Created.Compare(b.Created)
+	case models.SortLength:
+		return cmp.Compare(a.Length, b.Length)
+	case models.SortTitle:
+		return strings.Compare(a.Title, b.Title)
+	default:
+		return cmp.Compare(a.Score, b.Score)
+	}
+}
+
+// countMatches counts the matches of one search, restricted to ids when any are given
+func (h *SearchHandler) countMatches(ctx context.Context, options models.SearchOptions, ids []int64) (int, error) {
+	filters, args, err := matchFilters(options, ids)
+	if err != nil {
+		return 0, err
+	}
+	query := `
+		SELECT COUNT(*)
+		FROM documents d
+		JOIN documents_fts fts ON d.id = fts.rowid
+		WHERE documents_fts MATCH ? ` + filters
+
+	var count int
+	if err := h.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, errors.FTS5f("search count failed: %w", err)
+	}
+	return count, nil
+}
+
+// matchFilters returns the conditions that follow the MATCH in a search
+// query, with their arguments preceded by the MATCH expression for the query
+// and mode: the categories to include, the created range, the score
+// threshold and, when ids are given, the documents to search within
+func matchFilters(options models.SearchOptions, ids []int64) (string, []interface{}, error) {
+	match, err := database.MatchExpression(options.Query, options.Mode, options.Near)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var parts []string
+	args := []interface{}{match}
+
+	// Include only the listed categories
+	if len(options.CategoryFilters) > 0 {
+		clause, categoryArgs := database.InClause("d.category", options.CategoryFilters)
+		parts = append(parts, "AND "+clause)
+		args = append(args, categoryArgs...)
+	}
+
+	// Created bounds bind as UTC times, which the driver writes in the same
+	// "2006-01-02 15:04:05+00:00" text that documents are stored with, so the
+	// comparison is a plain text comparison on d.created
+	if !options.CreatedAfter.IsZero() {
+		parts = append(parts, "AND d.created >= ?")
+		args = append(args, options.CreatedAfter.UTC())
+	}
+	if !options.CreatedBefore.IsZero() {
+		parts = append(parts, "AND d.created <= ?")
+		args = append(args, options.CreatedBefore.UTC())
+	}
+
+	// Drop matches scoring worse than the threshold. Scores are negative and
+	// better matches are more negative, so the kept scores are the smaller ones.
+	if options.MinScore != nil {
+		parts = append(parts, "AND "+scoreExpression(options)+" <= ?")
+		args = append(args, *options.MinScore)
+	}
+
+	// Refine an earlier result set
+	if len(ids) > 0 {
+		clause, idArgs := database.InClause("d.id", ids)
+		parts = append(parts, "AND "+clause)
+		args = append(args, idArgs...)
+	}
+
+	return strings.Join(parts, " "), args, nil
+}
+
+// scoreExpression returns the bm25() call that scores a search, with the
+// column weights when any are set
+func scoreExpression(options models.SearchOptions) string {
+	if len(options.ColumnWeights) == 0 {
+		// Default BM25 scoring
+		return "bm25(documents_fts)"
+	}
+
+	// Custom column weighting
+	weights := make([]string, 0, 3)
+	if w, ok := options.ColumnWeights["title"]; ok {
+		weights = append(weights, fmt.Sprintf("%.2f", w))
+	} else {
+		weights = append(weights, "1.0")
+	}
+	if w, ok := options.ColumnWeights["content"]; ok {
+		weights = append(weights, fmt.Sprintf("%.2f", w))
+	} else {
+		weights = append(weights, "1.0")
+	}
+	if w, ok := options.ColumnWeights["category"]; ok {
+		weights = append(weights, fmt.Sprintf("%.2f", w))
+	} else {
+		weights = append(weights, "1.0")
+	}
+	return fmt.Sprintf("bm25(documents_fts, %s)", strings.Join(weights, ", "))
+}
+
+// buildSearchQuery constructs the FTS5 search query with optional column
+// weighting, restricted to the documents in ids when any are given
+func (h *SearchHandler) buildSearchQuery(options models.SearchOptions, ids []int64) (string, []interface{}, error) {
+	var queryParts []string
+	var args []interface{}
+
+	// Base query with BM25 scoring
+	baseQuery := `
+		SELECT 
+			d.id, d.title, %s as content, %s as snippet, %s as highlighted,
+			d.category, d.length, d.created,
+			%s as score
+		FROM documents d
+		JOIN documents_fts fts ON d.id = fts.rowid
+		WHERE documents_fts MATCH ?`
+
+	contentExpr := "d.content"
+	if !options.IncludeContent {
+		contentExpr = "''"
+	}
+
+	// FTS5 cuts snippets from the content column around the best matching
+	// terms, so stemmed matches are found and marked
+	snippetExpr := "''"
+	if options.IncludeSnippet {
+		start, end := snippetMarkers(options)
+		snippetExpr = fmt.Sprintf("snippet(documents_fts, 1, ?, ?, '...', %d)", snippetTokens(options))
+		args = append(args, start, end)
+	}
+
+	// highlight() returns the whole content column with every match marked
+	highlightExpr := "''"
+	if options.Highlight {
+		start, end := snippetMarkers(options)
+		highlightExpr = "highlight(documents_fts, 1, ?, ?)"
+		args = append(args, start, end)
+	}
+
+	queryParts = append(queryParts, fmt.Sprintf(baseQuery, contentExpr, snippetExpr, highlightExpr, scoreExpression(options)))
+	filters, filterArgs, err := matchFilters(options, ids)
+	if err != nil {
+		return "", nil, err
+	}
+	if filters != "" {
+		queryParts = append(queryParts, filters)
+	}
+	args = append(args, filterArgs...)
+
+	// Order by BM25 score unless another key was chosen (remember: lower =
+	// better in SQLite FTS5)
+	order, err := orderBy(options)
+	if err != nil {
+		return "", nil, err
+	}
+	queryParts = append(queryParts, order)
+
+	// Add limit; SQLite takes an OFFSET only after a LIMIT, where -1 is none
+	if options.MaxResults > 0 {
+		queryParts = append(queryParts, "LIMIT ?")
+		args = append(args, options.MaxResults)
+	} else if options.Offset > 0 {
+		queryParts = append(queryParts, "LIMIT -1")
+	}
+	if options.Offset > 0 {
+		queryParts = append(queryParts, "OFFSET ?")
+		args = append(args, options.Offset)
+	}
+
+	query := strings.Join(queryParts, " ")
+	return query, args, nil
+}
+
+// calculateScoreDistribution computes statistical measures of score distribution
+func (h *SearchHandler) calculateScoreDistribution(scores []float64) models.ScoreDistribution {
+	sort.Float64s(scores) // Sort for percentile calculation
+
+	distrib := models.ScoreDistribution{
+		Percentiles: make(models.Percentiles),
+	}
+
+	if len(scores) == 0 {
+		return distrib
+	}
+
+	distrib.Mean = scorestats.Mean(scores)
+	distrib.Median = scorestats.Median(scores)
+	distrib.StdDev = scorestats.StdDev(scores)
+	distrib.Percentiles = scorestats.Percentiles(scores)
+
+	// Create score buckets for histogram
+	distrib.Buckets = h.createScoreBuckets(scores, 10)
+
+	return distrib
+}
+
+// classifyRelevance assigns a relevance label based on BM25 score, using the
+// thresholds phase 1 defaults to so both phases label a score the same way
+func (h *SearchHandler) classifyRelevance(score float64) string {
+	// Remember: SQLite FTS5 BM25 scores are negative (lower = better)
+	return relevance.DefaultThresholds().Classify(score)
+}
+
+// createScoreBuckets creates histogram buckets for sorted scores, labeled in
+// interval notation at the configured score precision
+func (h *SearchHandler) createScoreBuckets(scores []float64, numBuckets int) []models.ScoreBucket {
+	bins := scorestats.Histogram(scores, numBuckets)
+	if bins == nil {
+		return nil
+	}
+
+	buckets := make([]models.ScoreBucket, len(bins))
+	for i, bin := range bins {
+		buckets[i] = models.ScoreBucket{
+			Min:   bin.Min,
+			Max:   bin.Max,
+			Count: bin.Count,
+			Label: bin.Label(config.App.Display.ScorePrecision),
+		}
+	}
+	return buckets
+}
+
+// displaySearchResults formats and displays search results
+func (h *SearchHandler) displaySearchResults(results []*models.SearchResult, options models.SearchOptions, executionTime time.Duration, page *models.ResultPage, excluded int, fingerprint *models.CorpusFingerprint) error {
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false) // Keep snippet markers such as << and >> readable
+		output := map[string]interface{}{
+			"query":          options.Query,
+			"total_results":  len(results),
+			"execution_time": executionTime.String(),
 			"results":        results,
-		})
+		}
+		if options.Mode != "" {
+			output["mode"] = options.Mode
+		}
+		if options.Near > 0 {
+			output["near"] = options.Near
+		}
+		if options.MinScore != nil {
+			output["min_score"] = *options.MinScore
+			output["excluded_by_threshold"] = excluded
+		}
+		if options.SortBy != "" {
+			output["sort_by"] = options.SortBy
+			output["sort_desc"] = options.SortDesc
+		}
+		if !options.CreatedAfter.IsZero() {
+			output["created_after"] = options.CreatedAfter
+		}
+		if !options.CreatedBefore.IsZero() {
+			output["created_before"] = options.CreatedBefore
+		}
+		if options.Sample > 0 {
+			output["sampled_from"] = sampledFrom(results)
+		}
+		if page != nil {
+			output["total"] = page.Total
+			output["page"] = page.Number
+			output["page_size"] = page.Size
+		}
+		if fingerprint != nil {
+			output["corpus_fingerprint"] = fingerprint.SHA256
+		}
+		return encoder.Encode(output)
+
+	case "csv":
+		if options.Sample > 0 {
+			fmt.Println("id,title,category,score,relevance,rank,percentile")
+			for _, result := range results {
+				fmt.Printf("%d,\"%s\",\"%s\",%.4f,%s,%d,%.1f\n",
+					result.ID, result.Title, result.Category, result.Score, result.Relevance,
+					result.Sample.Rank, result.Sample.Percentile)
+			}
+			return nil
+		}
+		fmt.Println("id,title,category,score,relevance")
+		for _, result := range results {
+			fmt.Printf("%d,\"%s\",\"%s\",%.4f,%s\n",
+				result.ID, result.Title, result.Category, result.Score, result.Relevance)
+		}
+
+	case "markdown":
+		h.displaySearchResultsMarkdown(results, options, executionTime, page, excluded)
+
+	default: // text format
+		fmt.Printf("Search Results for: \"%s\"\n", options.Query)
+		if options.Sample > 0 && len(results) > 0 {
+			fmt.Printf("Sampled %d of %d matching documents across the ranking in %v\n",
+				len(results), sampledFrom(results), executionTime)
+		} else if page != nil {
+			fmt.Printf("%s in %v\n", pageSummary(page, len(results)), executionTime)
+		} else {
+			fmt.Printf("Found %d documents in %v\n", len(results), executionTime)
+		}
+
+		if mode, match := matchMode(options.Query, options.Mode, options.Near); match != "" {
+			fmt.Printf("Mode: %s (MATCH %s)\n", mode, match)
+		}
+
+		if created := createdRange(options.CreatedAfter, options.CreatedBefore); created != "" {
+			fmt.Printf("Created: %s\n", created)
+		}
+
+		if options.MinScore != nil {
+			fmt.Printf("Min score: %s\n", thresholdSummary(*options.MinScore, excluded))
+		}
+
+		if order := sortOrder(options); order != "" {
+			fmt.Printf("Sorted by: %s\n", order)
+		}
+
+		if len(options.ColumnWeights) > 0 {
+			fmt.Printf("Column weights: %v\n", options.ColumnWeights)
+		}
+
+		if options.WithinIDs != nil {
+			fmt.Printf("Within: %d documents from %s\n", len(options.WithinIDs), options.Within)
+		}
+
+		fmt.Printf("\n")
+
+		if len(results) == 0 {
+			fmt.Println("No documents found.")
+			return nil
+		}
+
+		snippetStart, snippetEnd := snippetMarkers(options)
+		limit := fieldLimit(options)
+		for i, result := range results {
+			fmt.Printf("%d. %s\n", options.Offset+i+1, truncateField(result.Title, limit))
+			fmt.Printf("   Score: %.4f (%s relevance)\n", result.Score, result.Relevance)
+			if options.SortBy == models.SortCreated {
+				fmt.Printf("   Category: %s | Length: %d tokens | Created: %s\n", result.Category, result.Length, createdLabel(result.Created))
+			} else {
+				fmt.Printf("   Category: %s | Length: %d tokens\n", result.Category, result.Length)
+			}
+
+			if result.Sample != nil {
+				fmt.Printf("   Rank: %d of %d (percentile %.1f)\n", result.Sample.Rank, result.Sample.Matches, result.Sample.Percentile)
+			}
+
+			if result.Snippet != "" {
+				fmt.Printf("   Snippet: %s\n", highlightMatches(truncateField(result.Snippet, limit), snippetStart, snippetEnd))
+			}
+
+			if result.Highlighted != "" {
+				fmt.Printf("   Content: %s\n", highlightMatches(truncateField(result.Highlighted, limit), snippetStart, snippetEnd))
+			}
+
+			if options.ExplainInline {
+				const prefix = "   Fields: "
+				if result.FieldShares != nil {
+					fmt.Printf("%s%s\n", prefix, fieldSparkline(result.FieldShares, resultLineWidth-len(prefix)))
+				} else {
+					fmt.Printf("%sunavailable (query operators are not split by column)\n", prefix)
+				}
+			}
+
+			if config.App.Verbose {
+				fmt.Printf("   ID: %d | Created: %s\n", result.ID, createdLabel(result.Created))
+			}
+
+			fmt.Printf("\n")
+		}
+	}
+
+	return nil
+}
+
+// maxCategorySuggestions bounds the names a category check suggests
+const maxCategorySuggestions = 3
+
+// CheckCategory returns a validation error when options filter on a category
+// the corpus does not have, which would otherwise look like a query with no
+// matches. The error names the first missing category and suggests the
+// closest category names, or lists every category when none is close.
+// Options without category filters, or with SkipCategoryCheck, pass
+// unchecked.
+func (h *SearchHandler) CheckCategory(ctx context.Context, options models.SearchOptions) error {
+	if len(options.CategoryFilters) == 0 || options.SkipCategoryCheck {
+		return nil
+	}
+
+	categories, err := h.corpus.Categories(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(categories))
+	for i, summary := range categories {
+		names[i] = summary.Name
+	}
+
+	for _, category := range options.CategoryFilters {
+		if !slices.Contains(names, category) {
+			return missingCategory(category, names, "--no-category-check searches anyway")
+		}
+	}
+	return nil
+}
+
+// missingCategory reports a category filter naming none of names, ending with
+// hint when it is set
+func missingCategory(category string, names []string, hint string) error {
+	suggestions := closestCategories(category, names, maxCategorySuggestions)
+	if len(suggestions) == 0 {
+		if hint != "" {
+			hint = "; " + hint
+		}
+		return errors.Validationf("category %q is not in the corpus (categories: %s%s)",
+			category, strings.Join(names, ", "), hint)
+	}
+
+	quoted := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		quoted[i] = strconv.Quote(suggestion)
+	}
+	if hint != "" {
+		hint = " (" + hint + ")"
+	}
+	return errors.Validationf("category %q is not in the corpus; did you mean %s?%s",
+		category, strings.Join(quoted, " or "), hint)
+}
+
+// closestCategories returns up to limit names nearest to category by edit
+// distance, ignoring case, closest first. Names too far to be a likely typo
+// are left out: a name must be within a third of its length of category, or
+// extend category as a prefix.
+func closestCategories(category string, names []string, limit int) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	target := strings.ToLower(category)
+	var candidates []candidate
+	for _, name := range names {
+		folded := strings.ToLower(name)
+		distance := editDistance(target, folded)
+		if distance > utf8.RuneCountInString(folded)/3 && !strings.HasPrefix(folded, target) {
+			continue
+		}
+		candidates = append(candidates, candidate{name, distance})
+	}
+
+	// names arrive in display order, which breaks ties
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	suggestions := make([]string, 0, min(limit, len(candidates)))
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		suggestions = append(suggestions, c.name)
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b in runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// pageSummary describes where a page of results falls among all matches,
+// e.g. "Showing results 21–40 of 463 (page 2 of 24)"
+func pageSummary(page *models.ResultPage, results int) string {
+	if results == 0 {
+		return fmt.Sprintf("No results on page %d; %d matching documents fill %d pages", page.Number, page.Total, page.Pages())
+	}
+	return fmt.Sprintf("Showing results %d–%d of %d (page %d of %d)",
+		page.First(), page.First()+results-1, page.Total, page.Number, page.Pages())
+}
+
+// sampledFrom returns the number of matches sampled results were drawn from
+func sampledFrom(results []*models.SearchResult) int {
+	if len(results) == 0 || results[0].Sample == nil {
+		return 0
+	}
+	return results[0].Sample.Matches
+}
+
+// displaySearchStats formats and displays search statistics
+func (h *SearchHandler) displaySearchStats(stats *models.SearchStats) error {
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+
+	case "csv":
+		fmt.Println("metric,value")
+		fmt.Printf("query,\"%s\"\n", stats.Query)
+		fmt.Printf("total_results,%d\n", stats.TotalResults)
+		fmt.Printf("total_matches,%d\n", stats.TotalMatches)
+		fmt.Printf("truncated,%t\n", stats.Truncated)
+		if stats.MinScore != nil {
+			fmt.Printf("min_score,%g\n", *stats.MinScore)
+			fmt.Printf("excluded_by_threshold,%d\n", stats.ExcludedByThreshold)
+		}
+		fmt.Printf("execution_time,%v\n", stats.ExecutionTime)
+		fmt.Printf("score_best,%.4f\n", stats.ScoreRange.Best)
+		fmt.Printf("score_worst,%.4f\n", stats.ScoreRange.Worst)
+		fmt.Printf("score_mean,%.4f\n", stats.ScoreRange.Mean)
+		fmt.Printf("score_median,%.4f\n", stats.ScoreRange.Median)
+		fmt.Printf("score_stddev,%.4f\n", stats.ScoreRange.StdDev)
+
+	case "markdown":
+		h.displaySearchStatsMarkdown(stats)
+
+	default: // text format
+		numbers := config.App.Numbers()
+
+		fmt.Printf("Search Statistics for: \"%s\"\n", stats.Query)
+		fmt.Printf("========================================\n\n")
+
+		if mode, match := matchMode(stats.Query, stats.Mode, stats.Near); match != "" {
+			fmt.Printf("Mode: %s (MATCH %s)\n\n", mode, match)
+		}
+
+		if created := createdRange(stats.CreatedAfter, stats.CreatedBefore); created != "" {
+			fmt.Printf("Created: %s\n\n", created)
+		}
+
+		if stats.MinScore != nil {
+			fmt.Printf("Min score: %s\n\n", thresholdSummary(*stats.MinScore, stats.ExcludedByThreshold))
+		}
+
+		fmt.Printf("Results: %s documents in %v\n\n", numbers.Int(stats.TotalResults), stats.ExecutionTime)
+
+		if stats.TotalResults == 0 {
+			return nil
+		}
+
+		fmt.Printf("Score Distribution:\n")
+		fmt.Printf("  Range:     %s to %s\n", numbers.Float(stats.ScoreRange.Best, 4), numbers.Float(stats.ScoreRange.Worst, 4))
+		fmt.Printf("  Mean:      %s\n", numbers.Float(stats.ScoreRange.Mean, 4))
+		fmt.Printf("  Median:    %s\n", numbers.Float(stats.ScoreRange.Median, 4))
+		fmt.Printf("  Std Dev:   %s\n\n", numbers.Float(stats.ScoreRange.StdDev, 4))
+
+		fmt.Printf("Percentiles:\n")
+		for _, p := range scorestats.ReportedPercentiles {
+			if score, ok := stats.ScoreDistrib.Percentiles[p]; ok {
+				fmt.Printf("  %2dth:     %s\n", p, numbers.Float(score, 4))
+			}
+		}
+		fmt.Printf("\n")
+
+		if len(stats.CategoryBreakdown) > 0 {
+			fmt.Printf("Category Breakdown:\n")
+			for _, category := range stats.CategoryBreakdown.SortedBy(config.App.CategoryOrder().Less) {
+				count := stats.CategoryBreakdown[category]
+				percentage := float64(count) * 100.0 / float64(stats.TotalResults)
+				fmt.Printf("  %-15s: %3s documents (%s)\n", category, numbers.Int(count), numbers.Percent(percentage))
+			}
+			fmt.Printf("\n")
+		}
+
+		if len(stats.ScoreDistrib.Buckets) > 0 {
+			fmt.Printf("Score Distribution Buckets:\n")
+			for _, bucket := range stats.ScoreDistrib.Buckets {
+				if bucket.Count > 0 {
+					fmt.Printf("  %s: %s documents\n", bucket.Label, numbers.Int(bucket.Count))
+				}
+			}
+		}
+	}
 
-	case "csv":
-		fmt.Println("id,title,category,score,relevance")
+	return nil
+}
+
+// displaySearchResultsMarkdown renders search results as a GitHub-flavored
+// markdown table for pasting into documentation, issues, and pull requests
+func (h *SearchHandler) displaySearchResultsMarkdown(results []*models.SearchResult, options models.SearchOptions, executionTime time.Duration, page *models.ResultPage, excluded int) {
+	fmt.Printf("### Search results for `%s`\n\n", markdownCode(options.Query))
+	if options.Sample > 0 && len(results) > 0 {
+		fmt.Printf("Sampled %d of %d matching documents across the ranking in %v", len(results), sampledFrom(results), executionTime)
+	} else if page != nil {
+		fmt.Printf("%s in %v", pageSummary(page, len(results)), executionTime)
+	} else {
+		fmt.Printf("Found %d documents in %v", len(results), executionTime)
+	}
+	if len(options.ColumnWeights) > 0 {
+		fmt.Printf(" with column weights `%s`", flagutil.FormatWeights(options.ColumnWeights))
+	}
+	if mode, match := matchMode(options.Query, options.Mode, options.Near); match != "" {
+		fmt.Printf(", matched in %s mode as `%s`", mode, markdownCode(match))
+	}
+	if options.MinScore != nil {
+		fmt.Printf(", min score %s", thresholdSummary(*options.MinScore, excluded))
+	}
+	fmt.Printf(".\n\n")
+
+	if len(results) == 0 {
+		return
+	}
+
+	if options.Sample > 0 {
+		fmt.Printf("| Rank | Percentile | Title | Category | Score | Relevance |\n")
+		fmt.Printf("| ---: | ---: | --- | --- | ---: | --- |\n")
 		for _, result := range results {
-			fmt.Printf("%d,\"%s\",\"%s\",%.4f,%s\n",
-				result.ID, result.Title, result.Category, result.Score, result.Relevance)
+			fmt.Printf("| %d | %.1f | %s | %s | %.4f | %s |\n",
+				result.Sample.Rank, result.Sample.Percentile, markdownCell(result.Title),
+				markdownCell(result.Category), result.Score, result.Relevance)
 		}
+		return
+	}
 
-	default: // text format
-		fmt.Printf("Search Results for: \"%s\"\n", options.Query)
-		fmt.Printf("Found %d documents in %v\n", len(results), executionTime)
+	fmt.Printf("| Rank | Title | Category | Score | Relevance |\n")
+	fmt.Printf("| ---: | --- | --- | ---: | --- |\n")
+	for i, result := range results {
+		fmt.Printf("| %d | %s | %s | %.4f | %s |\n",
+			options.Offset+i+1, markdownCell(result.Title), markdownCell(result.Category), result.Score, result.Relevance)
+	}
+}
 
-		if len(options.ColumnWeights) > 0 {
-			fmt.Printf("Column weights: %v\n", options.ColumnWeights)
+// displaySearchStatsMarkdown renders search statistics as markdown: the summary
+// as a list of bold terms, the percentiles and breakdowns as tables
+func (h *SearchHandler) displaySearchStatsMarkdown(stats *models.SearchStats) {
+	numbers := config.App.Numbers()
+
+	fmt.Printf("### Search statistics for `%s`\n\n", markdownCode(stats.Query))
+	if mode, match := matchMode(stats.Query, stats.Mode, stats.Near); match != "" {
+		fmt.Printf("- **Mode:** %s (`%s`)\n", mode, markdownCode(match))
+	}
+	if created := createdRange(stats.CreatedAfter, stats.CreatedBefore); created != "" {
+		fmt.Printf("- **Created:** %s\n", created)
+	}
+	if stats.MinScore != nil {
+		fmt.Printf("- **Min score:** %s\n", thresholdSummary(*stats.MinScore, stats.ExcludedByThreshold))
+	}
+	fmt.Printf("- **Results:** %s documents in %v\n", numbers.Int(stats.TotalResults), stats.ExecutionTime)
+
+	if stats.TotalResults == 0 {
+		return
+	}
+
+	fmt.Printf("- **Range:** %s to %s\n", numbers.Float(stats.ScoreRange.Best, 4), numbers.Float(stats.ScoreRange.Worst, 4))
+	fmt.Printf("- **Mean:** %s\n", numbers.Float(stats.ScoreRange.Mean, 4))
+	fmt.Printf("- **Median:** %s\n", numbers.Float(stats.ScoreRange.Median, 4))
+	fmt.Printf("- **Std Dev:** %s\n\n", numbers.Float(stats.ScoreRange.StdDev, 4))
+
+	fmt.Printf("| Percentile | Score |\n")
+	fmt.Printf("| ---: | ---: |\n")
+	for _, p := range scorestats.ReportedPercentiles {
+		if score, ok := stats.ScoreDistrib.Percentiles[p]; ok {
+			fmt.Printf("| %dth | %s |\n", p, numbers.Float(score, 4))
 		}
+	}
+	fmt.Printf("\n")
 
+	if len(stats.CategoryBreakdown) > 0 {
+		fmt.Printf("| Category | Documents | Share |\n")
+		fmt.Printf("| --- | ---: | ---: |\n")
+		for _, category := range stats.CategoryBreakdown.SortedBy(config.App.CategoryOrder().Less) {
+			count := stats.CategoryBreakdown[category]
+			percentage := float64(count) * 100.0 / float64(stats.TotalResults)
+			fmt.Printf("| %s | %s | %s |\n", markdownCell(category), numbers.Int(count), numbers.Percent(percentage))
+		}
 		fmt.Printf("\n")
+	}
 
-		if len(results) == 0 {
-			fmt.Println("No documents found.")
-			return nil
+	if len(stats.ScoreDistrib.Buckets) > 0 {
+		fmt.Printf("| Score Range | Documents |\n")
+		fmt.Printf("| --- | ---: |\n")
+		for _, bucket := range stats.ScoreDistrib.Buckets {
+			if bucket.Count > 0 {
+				fmt.Printf("| `%s` | %s |\n", bucket.Label, numbers.Int(bucket.Count))
+			}
 		}
+	}
+}
 
-		for i, result := range results {
-			fmt.Printf("%d. %s\n", i+1, result.Title)
-			fmt.Printf("   Score: %.4f (%s relevance)\n", result.Score, result.Relevance)
-			fmt.Printf("   Category: %s | Length: %d tokens\n", result.Category, result.Length)
+// markdownCell escapes text for a markdown table cell: pipes would end the
+// cell and line breaks the row
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.Join(strings.Fields(text), " ")
+}
 
-			if result.Snippet != "" {
-				fmt.Printf("   Snippet: %s\n", result.Snippet)
-			}
+// matchMode returns how --mode or --near rewrote query, e.g. "any" or
+// "near 5", with the MATCH expression it became, or two empty strings when
+// query was searched as FTS5 query syntax. Options are validated before the
+// search runs, so invalid options also return empty strings.
+func matchMode(query, mode string, near int) (string, string) {
+	if mode == "" && near <= 0 {
+		return "", ""
+	}
+	match, err := database.MatchExpression(query, mode, near)
+	if err != nil {
+		return "", ""
+	}
+	if near > 0 {
+		mode = fmt.Sprintf("near %d", near)
+	}
+	return mode, match
+}
 
-			if config.App.Verbose {
-				fmt.Printf("   ID: %d | Created: %s\n", result.ID, result.Created.Format("2006-01-02 15:04"))
-			}
+// thresholdSummary describes a --min-score threshold with the matches it
+// dropped, e.g. "-3.5 (12 weaker matches excluded)"
+func thresholdSummary(minScore float64, excluded int) string {
+	if excluded == 1 {
+		return fmt.Sprintf("%g (1 weaker match excluded)", minScore)
+	}
+	return fmt.Sprintf("%g (%d weaker matches excluded)", minScore, excluded)
+}
 
-			fmt.Printf("\n")
+// sortOrder describes a result order other than best score first, e.g.
+// "created, newest first", or returns an empty string for the default
+func sortOrder(options models.SearchOptions) string {
+	switch options.SortBy {
+	case models.SortCreated:
+		if options.SortDesc {
+			return "created, newest first"
+		}
+		return "created, oldest first"
+	case models.SortLength:
+		if options.SortDesc {
+			return "length, longest first"
 		}
+		return "length, shortest first"
+	case models.SortTitle:
+		if options.SortDesc {
+			return "title, Z to A"
+		}
+		return "title, A to Z"
+	}
+	if options.SortDesc {
+		return "score, worst first"
 	}
+	return ""
+}
 
-	return nil
+// createdLabel formats a result's creation time for text output, or
+// "unknown" for the zero time
+func createdLabel(created time.Time) string {
+	if created.IsZero() {
+		return "unknown"
+	}
+	return created.Format("2006-01-02 15:04")
 }
 
-// displaySearchStats formats and displays search statistics
-func (h *SearchHandler) displaySearchStats(stats *models.SearchStats) error {
+// createdRange describes the created bounds of a search, e.g. "2024-07-20T00:00:00Z
+// to 2024-08-01T23:59:59Z", or returns an empty string when neither is set
+func createdRange(after, before time.Time) string {
+	switch {
+	case after.IsZero() && before.IsZero():
+		return ""
+	case before.IsZero():
+		return "since " + after.Format(time.RFC3339)
+	case after.IsZero():
+		return "until " + before.Format(time.RFC3339)
+	default:
+		return after.Format(time.RFC3339) + " to " + before.Format(time.RFC3339)
+	}
+}
+
+// markdownCode makes text safe inside a single-backtick code span
+func markdownCode(text string) string {
+	return strings.ReplaceAll(text, "`", "'")
+}
+
+// displayEvaluations formats and displays retrieval evaluation metrics along
+// with the fingerprint of the corpus they were measured on
+func (h *SearchHandler) displayEvaluations(evaluations []*models.QueryEvaluation, fingerprint *models.CorpusFingerprint) error {
 	switch config.App.Format {
 	case "json":
 		encoder := json.NewEncoder(os.Stdout)
 		encoder.SetIndent("", "  ")
-		return encoder.Encode(stats)
+		return encoder.Encode(map[string]interface{}{
+			"corpus_fingerprint": fingerprint.SHA256,
+			"evaluations":        evaluations,
+		})
 
 	case "csv":
-		fmt.Println("metric,value")
-		fmt.Printf("query,\"%s\"\n", stats.Query)
-		fmt.Printf("total_results,%d\n", stats.TotalResults)
-		fmt.Printf("execution_time,%v\n", stats.ExecutionTime)
-		fmt.Printf("score_best,%.4f\n", stats.ScoreRange.Best)
-		fmt.Printf("score_worst,%.4f\n", stats.ScoreRange.Worst)
-		fmt.Printf("score_mean,%.4f\n", stats.ScoreRange.Mean)
-		fmt.Printf("score_median,%.4f\n", stats.ScoreRange.Median)
-		fmt.Printf("score_stddev,%.4f\n", stats.ScoreRange.StdDev)
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write([]string{"query", "relevant", "retrieved", "relevant_retrieved", "precision", "recall", "r_precision", "average_precision"})
+		for _, eval := range evaluations {
+			writer.Write([]string{
+				eval.Query,
+				strconv.Itoa(eval.Relevant),
+				strconv.Itoa(eval.Retrieved),
+				strconv.Itoa(eval.RelevantRetrieved),
+				strconv.FormatFloat(eval.Precision, 'f', 4, 64),
+				strconv.FormatFloat(eval.Recall, 'f', 4, 64),
+				strconv.FormatFloat(eval.RPrecision, 'f', 4, 64),
+				strconv.FormatFloat(eval.AveragePrecision, 'f', 4, 64),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
 
 	default: // text format
-		fmt.Printf("Search Statistics for: \"%s\"\n", stats.Query)
-		fmt.Printf("========================================\n\n")
+		fmt.Printf("Retrieval Evaluation (ground truth from manifest)\n")
+		fmt.Printf("=================================================\n\n")
+		fmt.Printf("Corpus fingerprint: %s\n\n", fingerprint.SHA256)
+
+		for _, eval := range evaluations {
+			fmt.Printf("Query: \"%s\"\n", eval.Query)
+			fmt.Printf("  Relevant documents:  %d\n", eval.Relevant)
+			fmt.Printf("  Retrieved:           %d (%d relevant)\n", eval.Retrieved, eval.RelevantRetrieved)
+			fmt.Printf("  Precision:           %.4f\n", eval.Precision)
+			fmt.Printf("  Recall:              %.4f\n", eval.Recall)
+			fmt.Printf("  R-Precision:         %.4f\n", eval.RPrecision)
+			fmt.Printf("  Average Precision:   %.4f\n\n", eval.AveragePrecision)
+		}
+
+		if config.App.Verbose {
+			fmt.Printf("Note: recall below 1.0 usually means --max-results is smaller than the relevant set\n")
+		}
+	}
 
-		fmt.Printf("Results: %d documents in %v\n\n", stats.TotalResults, stats.ExecutionTime)
+	return nil
+}
 
-		if stats.TotalResults == 0 {
-			return nil
+// snippetTokens returns the token count passed to the FTS5 snippet function:
+// options.SnippetTokens, then display.snippet_tokens, then the snippet length
+// in characters at about six characters per word. FTS5 accepts at most
+// models.MaxSnippetTokens.
+func snippetTokens(options models.SearchOptions) int {
+	count := options.SnippetTokens
+	if count <= 0 {
+		count = config.App.Display.SnippetTokens
+	}
+	if count <= 0 {
+		count = options.SnippetLength / 6
+	}
+	if count < 1 {
+		return 1
+	}
+	if count > models.MaxSnippetTokens {
+		return models.MaxSnippetTokens
+	}
+	return count
+}
+
+// snippetMarkers returns the strings placed around matched terms in snippets
+// and highlighted content, falling back to the display config for markers the
+// options leave empty
+func snippetMarkers(options models.SearchOptions) (string, string) {
+	start, end := options.HighlightStart, options.HighlightEnd
+	if start == "" {
+		start = config.App.Display.HighlightStart
+	}
+	if end == "" {
+		end = config.App.Display.HighlightEnd
+	}
+	return start, end
+}
+
+// fieldLimit returns the number of characters text fields are cut to in text
+// output, or 0 when they are printed whole
+func fieldLimit(options models.SearchOptions) int {
+	switch {
+	case options.Truncate < 0:
+		return 0
+	case options.Truncate > 0:
+		return options.Truncate
+	default:
+		return config.App.Display.MaxFieldChars
+	}
+}
+
+// truncateField keeps the first limit characters (runes) of text and replaces
+// the rest with "… (+N chars)", N counting the characters removed. The cut
+// falls between runes, so multi-byte characters are never split. A limit of
+// 0 or less returns text unchanged.
+func truncateField(text string, limit int) string {
+	if limit <= 0 {
+		return text
+	}
+
+	kept := 0
+	for i := range text {
+		if kept == limit {
+			return fmt.Sprintf("%s… (+%d chars)", text[:i], utf8.RuneCountInString(text[i:]))
 		}
+		kept++
+	}
+	return text
+}
 
-		fmt.Printf("Score Distribution:\n")
-		fmt.Printf("  Range:     %.4f to %.4f\n", stats.ScoreRange.Best, stats.ScoreRange.Worst)
-		fmt.Printf("  Mean:      %.4f\n", stats.ScoreRange.Mean)
-		fmt.Printf("  Median:    %.4f\n", stats.ScoreRange.Median)
-		fmt.Printf("  Std Dev:   %.4f\n\n", stats.ScoreRange.StdDev)
+// highlightMatches renders the start and end markers around matched terms in
+// text as colored text when stdout is a terminal. Elsewhere, including golden
+// output, the markers are left in place to show the matches.
+func highlightMatches(text, start, end string) string {
+	if start == "" || end == "" || !colorOutput() {
+		return text
+	}
 
-		fmt.Printf("Percentiles:\n")
-		for _, p := range []int{25, 50, 75, 90, 95, 99} {
-			if score, ok := stats.ScoreDistrib.Percentiles[p]; ok {
-				fmt.Printf("  %2dth:     %.4f\n", p, score)
+	var b strings.Builder
+	for {
+		open := strings.Index(text, start)
+		if open < 0 {
+			break
+		}
+		close := strings.Index(text[open+len(start):], end)
+		if close < 0 {
+			break
+		}
+		close += open + len(start)
+
+		b.WriteString(text[:open])
+		b.WriteString("\033[1;33m")
+		b.WriteString(text[open+len(start) : close])
+		b.WriteString("\033[0m")
+		text = text[close+len(end):]
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// colorOutput reports whether text output may use terminal escape codes:
+// stdout is a terminal, output is not golden, and NO_COLOR is unset
+func colorOutput() bool {
+	if config.App.Golden || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// GenerateScoreExplanations creates detailed BM25 score explanations for
+// search results. Term and field scores are recomputed from the FTS5 index
+// statistics the way bm25() combines them, so for queries made only of plain
+// terms they add up to the reported score.
+func (h *SearchHandler) GenerateScoreExplanations(ctx context.Context, results []*models.SearchResult, options models.SearchOptions) ([]*models.ScoreExplanation, error) {
+	explanations := make([]*models.ScoreExplanation, 0, len(results))
+	params := models.DefaultBM25Params
+
+	// Use the index totals bm25 itself averages over rather than documents.length
+	indexLengths, err := h.db.IndexLengths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldAvgLengths := make(map[string]float64, len(database.IndexedColumns))
+	for i, column := range database.IndexedColumns {
+		fieldAvgLengths[column] = indexLengths.ColumnAverage(i)
+	}
+
+	for i, result := range results {
+		inputs, err := h.loadBM25Inputs(ctx, indexLengths, result, options)
+		if err != nil {
+			return nil, err
+		}
+		lengthNorm := h.calculateLengthNormalization(inputs.docLength, inputs.avgdl, params)
+
+		explanation := &models.ScoreExplanation{
+			Rank:        i + 1,
+			DocumentID:  result.ID,
+			TotalScore:  result.Score,
+			FieldScores: make(map[string]models.FieldScore, len(database.IndexedColumns)),
+			QueryTerms:  make([]models.TermScore, 0, len(inputs.terms)),
+			DocumentStats: models.DocumentStats{
+				Length:          inputs.docLength,
+				AvgLength:       inputs.avgdl,
+				LengthNorm:      lengthNorm,
+				FieldLengths:    inputs.fieldLengths,
+				FieldAvgLengths: fieldAvgLengths,
+			},
+		}
+
+		fieldScores := make([]models.FieldScore, len(database.IndexedColumns))
+		for c := range fieldScores {
+			fieldScores[c] = models.FieldScore{Weight: inputs.weights[c], Terms: make([]models.TermScore, 0, len(inputs.terms))}
+		}
+
+		total := 0.0
+		for _, term := range inputs.terms {
+			idf, tf, score := h.bm25TermScore(inputs, term, lengthNorm, params)
+			total += score
+
+			count := 0
+			for c := range database.IndexedColumns {
+				count += term.ColumnCounts[c]
+			}
+			explanation.QueryTerms = append(explanation.QueryTerms, models.TermScore{
+				Term:      term.Query,
+				Indexed:   term.Term,
+				Documents: term.Documents,
+				TF:        tf,
+				IDF:       idf,
+				FieldTF:   float64(count),
+				Score:     score,
+			})
+
+			// Each column gets the share of the term score its weighted count contributes
+			for c := range database.IndexedColumns {
+				share := 0.0
+				if tf > 0 {
+					share = score * inputs.weights[c] * float64(term.ColumnCounts[c]) / tf
+				}
+				fieldScores[c].Score += share
+				fieldScores[c].Terms = append(fieldScores[c].Terms, models.TermScore{
+					Term:      term.Query,
+					Indexed:   term.Term,
+					Documents: term.Documents,
+					TF:        inputs.weights[c] * float64(term.ColumnCounts[c]),
+					IDF:       idf,
+					FieldTF:   float64(term.ColumnCounts[c]),
+					Score:     share,
+				})
 			}
 		}
+		explanation.RecomputedScore = -total
+
+		for c, column := range database.IndexedColumns {
+			explanation.FieldScores[column] = fieldScores[c]
+		}
+
+		explanations = append(explanations, explanation)
+	}
+
+	return explanations, nil
+}
+
+// displayScoreExplanations formats and displays detailed score explanations
+func (h *SearchHandler) displayScoreExplanations(explanations []*models.ScoreExplanation, options models.SearchOptions) error {
+	limit := fieldLimit(options)
+	fmt.Printf("Score Explanations for: \"%s\"\n", truncateField(options.Query, limit))
+	fmt.Printf("=====================================\n\n")
+
+	if len(options.ColumnWeights) > 0 {
+		fmt.Printf("Custom column weights: %v\n", options.ColumnWeights)
+	} else {
+		fmt.Printf("Using default FTS5 column weights (all fields weighted equally)\n")
+	}
+	
+	fmt.Printf("BM25 parameters: k1=1.2, b=0.75 (SQLite FTS5 defaults)\n")
+
+	if options.Near > 0 {
+		fmt.Printf("NEAR %d: the terms must fall within %d tokens of each other for a document to match.\n", options.Near, options.Near)
+		fmt.Printf("NEAR restricts matching; bm25 weighting is unchanged (same IDF, k1, and b), though a\n")
+		fmt.Printf("term's frequency only counts the occurrences inside a NEAR match.\n")
+	}
+	fmt.Printf("\n")
+
+	for _, explanation := range explanations {
+		fmt.Printf("Document %d (ID: %d)\n", explanation.Rank, explanation.DocumentID)
+		fmt.Printf("Total Score: %.4f\n", explanation.TotalScore)
+		if explainsScore(explanation) {
+			fmt.Printf("Recomputed:  %.4f from the index statistics below\n", explanation.RecomputedScore)
+		} else {
+			fmt.Printf("Recomputed:  %.4f (differs: phrases, NEAR, OR/NOT, prefixes, and column filters are not recomputed)\n",
+				explanation.RecomputedScore)
+		}
+		fmt.Printf("Document Length: %d tokens (avg: %.1f)\n", 
+			explanation.DocumentStats.Length, explanation.DocumentStats.AvgLength)
+		fmt.Printf("Length Normalization Factor: %.3f\n", explanation.DocumentStats.LengthNorm)
 		fmt.Printf("\n")
 
-		if len(stats.CategoryBreakdown) > 0 {
-			fmt.Printf("Category Breakdown:\n")
-			for category, count := range stats.CategoryBreakdown {
-				percentage := float64(count) * 100.0 / float64(stats.TotalResults)
-				fmt.Printf("  %-15s: %3d documents (%.1f%%)\n", category, count, percentage)
+		// Display field contributions
+		fmt.Printf("Field Contributions:\n")
+		for _, fieldName := range database.IndexedColumns {
+			fieldScore := explanation.FieldScores[fieldName]
+			fmt.Printf("  %s: score=%.4f, weight=%.2f, length=%d tokens (avg: %.1f)\n",
+				fieldName, fieldScore.Score, fieldScore.Weight,
+				explanation.DocumentStats.FieldLengths[fieldName],
+				explanation.DocumentStats.FieldAvgLengths[fieldName])
+		}
+		fmt.Printf("\n")
+
+		// Display term analysis
+		fmt.Printf("Query Term Analysis:\n")
+		for _, termScore := range explanation.QueryTerms {
+			term := fmt.Sprintf("%q", truncateField(termScore.Term, limit))
+			if termScore.Indexed != strings.ToLower(termScore.Term) {
+				term += fmt.Sprintf(" (indexed as %q)", truncateField(termScore.Indexed, limit))
 			}
-			fmt.Printf("\n")
+			fmt.Printf("  %s: n=%d, tf=%.3f, idf=%.3f, score=%.4f\n",
+				term, termScore.Documents, termScore.TF, termScore.IDF, termScore.Score)
 		}
+		
+		fmt.Print("\n" + strings.Repeat("-", 50) + "\n\n")
+	}
 
-		if len(stats.ScoreDistrib.Buckets) > 0 {
-			fmt.Printf("Score Distribution Buckets:\n")
-			for _, bucket := range stats.ScoreDistrib.Buckets {
-				if bucket.Count > 0 {
-					fmt.Printf("  %s: %d documents\n", bucket.Label, bucket.Count)
-				}
+	return nil
+}
+
+// explainsScore reports whether an explanation's recomputed score matches the
+// score bm25() reported, to within rounding
+func explainsScore(explanation *models.ScoreExplanation) bool {
+	return math.Abs(explanation.RecomputedScore-explanation.TotalScore) < 1e-4
+}
+
+// teachWidth is the column limit for the --teach walkthrough
+const teachWidth = 79
+
+// displayTeachingWalkthrough works through the BM25 arithmetic SQLite performs
+// for one result, substituting the real index statistics into each equation
+// and checking the total against bm25()
+func (h *SearchHandler) displayTeachingWalkthrough(ctx context.Context, result *models.SearchResult, rank int, options models.SearchOptions) error {
+	params := models.DefaultBM25Params
+	k1, b := params.K1, params.B
+
+	indexLengths, err := h.db.IndexLengths(ctx)
+	if err != nil {
+		return err
+	}
+
+	fieldLengths, err := h.getFieldLengths(ctx, result)
+	if err != nil {
+		return err
+	}
+
+	terms, err := h.db.QueryTermStats(ctx, options.Query, result.ID)
+	if err != nil {
+		return err
+	}
+
+	weights := columnWeights(options)
+
+	totalTokens := 0
+	for _, columnTokens := range indexLengths.ColumnTokens {
+		totalTokens += columnTokens
+	}
+	avgdl := indexLengths.Average()
+
+	docLength := 0
+	lengthParts := make([]string, len(database.IndexedColumns))
+	for i, column := range database.IndexedColumns {
+		docLength += fieldLengths[column]
+		lengthParts[i] = fmt.Sprintf("%d (%s)", fieldLengths[column], column)
+	}
+
+	title := fmt.Sprintf("Step-by-Step BM25 for Document %d (rank %d)", result.ID, rank)
+	fmt.Printf("%s\n%s\n\n", title, strings.Repeat("=", len(title)))
+
+	fmt.Printf("Corpus statistics (from the FTS5 index):\n")
+	fmt.Printf("  N     = %d indexed documents\n", indexLengths.Rows)
+	fmt.Printf("  avgdl = %d tokens / %d documents = %.2f\n", totalTokens, indexLengths.Rows, avgdl)
+	fmt.Printf("Document length (all columns):\n")
+	printWrapped("  |D|   = ", lengthParts, " + ")
+	fmt.Printf("        = %d\n", docLength)
+	fmt.Printf("Parameters: k1 = %.1f, b = %.2f\n\n", k1, b)
+
+	lengthNorm := h.calculateLengthNormalization(docLength, avgdl, params)
+	fmt.Printf("Length norm = k1 × (1 - b + b × |D| / avgdl)\n")
+	fmt.Printf("            = %.1f × (1 - %.2f + %.2f × %d / %.2f)\n", k1, b, b, docLength, avgdl)
+	fmt.Printf("            = %.4f\n\n", lengthNorm)
+
+	total := 0.0
+	contributions := make([]string, 0, len(terms))
+
+	for i, term := range terms {
+		label := fmt.Sprintf("Term %d: %q", i+1, term.Query)
+		if term.Term != strings.ToLower(term.Query) {
+			label += fmt.Sprintf(" (indexed as %q)", term.Term)
+		}
+		fmt.Printf("%s\n", label)
+
+		idf := math.Log((float64(indexLengths.Rows) - float64(term.Documents) + 0.5) / (float64(term.Documents) + 0.5))
+		fmt.Printf("  n     = %d documents contain the term\n", term.Documents)
+		fmt.Printf("  IDF   = ln((N - n + 0.5) / (n + 0.5))\n")
+		fmt.Printf("        = ln((%d - %d + 0.5) / (%d + 0.5))\n", indexLengths.Rows, term.Documents, term.Documents)
+		if idf <= 0 {
+			fmt.Printf("        = %.4f, at or below 0, so SQLite uses 0.000001\n", idf)
+			idf = 1e-6
+		} else {
+			fmt.Printf("        = %.4f\n", idf)
+		}
+
+		tf := 0.0
+		tfParts := make([]string, len(database.IndexedColumns))
+		for c := range database.IndexedColumns {
+			tf += weights[c] * float64(term.ColumnCounts[c])
+			tfParts[c] = fmt.Sprintf("%.2f×%d", weights[c], term.ColumnCounts[c])
+		}
+		fmt.Printf("  tf    = Σ weight × count, over %s\n", strings.Join(database.IndexedColumns, ", "))
+		printWrapped("        = ", tfParts, " + ")
+		fmt.Printf("        = %.2f\n", tf)
+
+		tfComponent := tf * (k1 + 1) / (tf + lengthNorm)
+		fmt.Printf("  TF    = tf × (k1 + 1) / (tf + length norm)\n")
+		fmt.Printf("        = %.2f × %.1f / (%.2f + %.4f)\n", tf, k1+1, tf, lengthNorm)
+		fmt.Printf("        = %.4f\n", tfComponent)
+
+		idfText := fmt.Sprintf("%.4f", idf)
+		if idf == 1e-6 {
+			idfText = "0.000001"
+		}
+
+		score := idf * tfComponent
+		fmt.Printf("  score = IDF × TF = %s × %.4f = %.4f\n\n", idfText, tfComponent, score)
+
+		total += score
+		contributions = append(contributions, fmt.Sprintf("%.4f", score))
+	}
+
+	fmt.Printf("Final score = -(sum of term scores)\n")
+	if len(contributions) > 0 {
+		printWrapped("            = -(", contributions, " + ")
+	}
+	fmt.Printf("            = %.4f\n", -total)
+
+	fmt.Printf("SQLite bm25() = %.4f", result.Score)
+	if math.Abs(-total-result.Score) < 1e-4 {
+		fmt.Printf(" (matches)\n\n")
+	} else {
+		fmt.Printf(" (differs)\n")
+		fmt.Printf("The walkthrough scores every query token as a separate term, so phrases,\n")
+		fmt.Printf("NEAR groups, OR/NOT, prefixes, and column filters are scored differently.\n\n")
+	}
+
+	fmt.Printf("SQLite negates the sum so that better matches sort first in ascending\n")
+	fmt.Printf("order. Its IDF has no \"+ 1\" inside the logarithm (unlike Lucene's), so a\n")
+	fmt.Printf("term in more than half of the documents would have a negative IDF; SQLite\n")
+	fmt.Printf("floors it at 0.000001 instead.\n")
+
+	return nil
+}
+
+// printWrapped prints prefix followed by parts joined with sep, wrapping
+// before teachWidth and indenting continuation lines under the first part.
+// A prefix ending in "(" gets the closing parenthesis after the last part.
+func printWrapped(prefix string, parts []string, sep string) {
+	closing := ""
+	if strings.HasSuffix(prefix, "(") {
+		closing = ")"
+	}
+
+	indent := strings.Repeat(" ", utf8.RuneCountInString(prefix))
+	line := prefix
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			part += closing
+		} else {
+			part += strings.TrimRight(sep, " ")
+		}
+
+		if i > 0 {
+			if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(part) > teachWidth {
+				fmt.Println(line)
+				line = indent + part
+				continue
 			}
+			line += " "
 		}
+		line += part
 	}
-
-	return nil
+	fmt.Println(line)
 }
 
-// generateSnippet creates a contextual snippet around search terms
-func (h *SearchHandler) generateSnippet(content, query string, maxLength int) string {
-	if maxLength <= 0 {
-		maxLength = 200
-	}
-
-	// Simple snippet generation - find first occurrence of query terms
-	queryTerms := strings.Fields(strings.ToLower(query))
-	contentLower := strings.ToLower(content)
+// Helper methods for BM25 calculations
 
-	var earliestPos int = len(content)
-	for _, term := range queryTerms {
-		if pos := strings.Index(contentLower, term); pos != -1 && pos < earliestPos {
-			earliestPos = pos
+// getFieldLengths returns the per-column token counts FTS5 recorded for the
+// result, counting them from the text when the document is missing from the index
+func (h *SearchHandler) getFieldLengths(ctx context.Context, result *models.SearchResult) (map[string]int, error) {
+	sizes, err := h.db.DocSize(ctx, result.ID)
+	if err != nil {
+		return nil, err
+	}
+	if sizes == nil {
+		sizes = []int{
+			tokens.CountTokens(result.Title),
+			tokens.CountTokens(result.Content),
+			tokens.CountTokens(result.Category),
 		}
 	}
 
-	// If no terms found, return beginning of content
-	if earliestPos == len(content) {
-		earliestPos = 0
+	lengths := make(map[string]int, len(database.IndexedColumns))
+	for i, column := range database.IndexedColumns {
+		if i < len(sizes) {
+			lengths[column] = sizes[i]
+		}
 	}
+	return lengths, nil
+}
 
-	// Calculate snippet boundaries
-	start := earliestPos - maxLength/4
-	if start < 0 {
-		start = 0
+func (h *SearchHandler) calculateLengthNormalization(docLength int, avgLength float64, params models.BM25Params) float64 {
+	// BM25 length normalization: k1 * ((1 - b) + b * (|d| / avgdl))
+	if avgLength <= 0 {
+		// No length information (empty corpus): treat the document as average length
+		return params.K1
 	}
+	return params.K1 * ((1 - params.B) + params.B*(float64(docLength)/avgLength))
+}
 
-	end := start + maxLength
-	if end > len(content) {
-		end = len(content)
-		start = end - maxLength
-		if start < 0 {
-			start = 0
+// columnWeights returns the weight of each indexed column, rounded the same
+// way as the bm25() call in the search
+func columnWeights(options models.SearchOptions) []float64 {
+	weights := make([]float64, len(database.IndexedColumns))
+	for i, column := range database.IndexedColumns {
+		weights[i] = 1.0
+		if w, ok := options.ColumnWeights[column]; ok {
+			weights[i] = math.Round(w*100) / 100
 		}
 	}
+	return weights
+}
 
-	snippet := content[start:end]
+// bm25Inputs are the index statistics that BM25 combines into one document's score
+type bm25Inputs struct {
+	rows         int
+	avgdl        float64
+	docLength    int
+	fieldLengths map[string]int
+	terms        []database.TermStats
+	weights      []float64
+}
 
-	// Add ellipsis if truncated
-	if start > 0 {
-		snippet = "..." + snippet
+// loadBM25Inputs reads the statistics for scoring result against the query in
+// options. The index lengths are the same for every document, so callers
+// scoring several results read them once.
+func (h *SearchHandler) loadBM25Inputs(ctx context.Context, indexLengths *database.IndexLengths, result *models.SearchResult, options models.SearchOptions) (*bm25Inputs, error) {
+	fieldLengths, err := h.getFieldLengths(ctx, result)
+	if err != nil {
+		return nil, err
 	}
-	if end < len(content) {
-		snippet = snippet + "..."
+
+	terms, err := h.db.QueryTermStats(ctx, options.Query, result.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	return strings.TrimSpace(snippet)
+	inputs := &bm25Inputs{
+		rows:         indexLengths.Rows,
+		avgdl:        indexLengths.Average(),
+		fieldLengths: fieldLengths,
+		terms:        terms,
+		weights:      columnWeights(options),
+	}
+	for _, column := range database.IndexedColumns {
+		inputs.docLength += fieldLengths[column]
+	}
+	return inputs, nil
 }
 
-// parseWeights parses weight specification string
-func (h *SearchHandler) parseWeights(weightStr string) (map[string]float64, error) {
-	if weightStr == "" {
-		return nil, nil
+// bm25Score computes a document's BM25 score under params, negated as bm25()
+// reports it. With models.DefaultBM25Params it reproduces bm25() for queries
+// made only of plain terms, like the search explain --teach walkthrough.
+func (h *SearchHandler) bm25Score(in *bm25Inputs, params models.BM25Params) float64 {
+	total := 0.0
+	for _, score := range h.bm25ColumnScores(in, params) {
+		total += score
 	}
+	return -total
+}
 
-	weights := make(map[string]float64)
-	pairs := strings.Split(weightStr, ",")
-
-	for _, pair := range pairs {
-		parts := strings.Split(strings.TrimSpace(pair), ":")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid weight pair: %s", pair)
+// bm25ColumnScores splits the (positive) BM25 sum between the indexed columns,
+// in IndexedColumns order. Each term's score is divided in proportion to
+// weight × count in each column, so the column scores add up to the sum exactly.
+func (h *SearchHandler) bm25ColumnScores(in *bm25Inputs, params models.BM25Params) []float64 {
+	lengthNorm := h.calculateLengthNormalization(in.docLength, in.avgdl, params)
+
+	scores := make([]float64, len(in.weights))
+	for _, term := range in.terms {
+		_, tf, termScore := h.bm25TermScore(in, term, lengthNorm, params)
+		if tf == 0 {
+			continue
 		}
-
-		field := strings.TrimSpace(parts[0])
-		weightStr := strings.TrimSpace(parts[1])
-
-		weight, err := strconv.ParseFloat(weightStr, 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid weight value for %s: %w", field, err)
+		for c, weight := range in.weights {
+			scores[c] += termScore * weight * float64(term.ColumnCounts[c]) / tf
 		}
+	}
+	return scores
+}
 
-		weights[field] = weight
+// bm25TermScore returns one term's IDF, weighted term frequency, and
+// (positive) contribution to a document's BM25 sum
+func (h *SearchHandler) bm25TermScore(in *bm25Inputs, term database.TermStats, lengthNorm float64, params models.BM25Params) (idf, tf, score float64) {
+	idf = bm25IDF(in.rows, term.Documents)
+	for c, weight := range in.weights {
+		tf += weight * float64(term.ColumnCounts[c])
 	}
+	return idf, tf, idf * tf * (params.K1 + 1) / (tf + lengthNorm)
+}
 
-	return weights, nil
+// bm25IDF returns the IDF FTS5 uses for a term found in documents of rows
+// indexed rows: ln((N - n + 0.5) / (n + 0.5)), raised to 0.000001 when it
+// would be zero or negative (terms in more than half the rows)
+func bm25IDF(rows, documents int) float64 {
+	idf := math.Log((float64(rows) - float64(documents) + 0.5) / (float64(documents) + 0.5))
+	if idf <= 0 {
+		return 1e-6
+	}
+	return idf
 }
 
-// GenerateScoreExplanations creates detailed BM25 score explanations for search results
-func (h *SearchHandler) GenerateScoreExplanations(ctx context.Context, results []*models.SearchResult, options models.SearchOptions) ([]*models.ScoreExplanation, error) {
-	explanations := make([]*models.ScoreExplanation, 0, len(results))
-	
-	// Get corpus statistics for average document length
-	avgDocLength, err := h.getAverageDocumentLength(ctx)
+// addFieldShares sets FieldShares on each result whose score the per-column
+// decomposition reproduces. Results of queries it cannot score the way bm25()
+// does (phrases, NEAR groups, OR/NOT, prefixes, column filters) are left
+// without shares.
+func (h *SearchHandler) addFieldShares(ctx context.Context, results []*models.SearchResult, options models.SearchOptions) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	indexLengths, err := h.db.IndexLengths(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Parse query terms
-	queryTerms := strings.Fields(strings.ToLower(options.Query))
-	
 	for _, result := range results {
-		explanation := &models.ScoreExplanation{
-			DocumentID: result.ID,
-			TotalScore: result.Score,
-			FieldScores: make(map[string]models.FieldScore),
-			QueryTerms: make([]models.TermScore, 0, len(queryTerms)),
-			DocumentStats: models.DocumentStats{
-				Length:       result.Length,
-				AvgLength:    avgDocLength,
-				LengthNorm:   h.calculateLengthNormalization(result.Length, avgDocLength),
-				FieldLengths: map[string]int{
-					"title":    len(strings.Fields(result.Title)),
-					"content":  len(strings.Fields(result.Content)),
-					"category": len(strings.Fields(result.Category)),
-				},
-			},
+		inputs, err := h.loadBM25Inputs(ctx, indexLengths, result, options)
+		if err != nil {
+			return err
 		}
 
-		// Calculate term scores and field contributions
-		for _, term := range queryTerms {
-			termScore := h.calculateTermScore(term, result, avgDocLength)
-			explanation.QueryTerms = append(explanation.QueryTerms, termScore)
+		scores := h.bm25ColumnScores(inputs, models.DefaultBM25Params)
+		total := 0.0
+		for _, score := range scores {
+			total += score
+		}
+		if total <= 0 || math.Abs(-total-result.Score) >= 1e-4 {
+			continue
 		}
 
-		// Calculate field scores
-		explanation.FieldScores["title"] = h.calculateFieldScore("title", result.Title, queryTerms, options.ColumnWeights)
-		explanation.FieldScores["content"] = h.calculateFieldScore("content", result.Content, queryTerms, options.ColumnWeights)
-		explanation.FieldScores["category"] = h.calculateFieldScore("category", result.Category, queryTerms, options.ColumnWeights)
-
-		explanations = append(explanations, explanation)
+		result.FieldShares = make(map[string]float64, len(scores))
+		for c, column := range database.IndexedColumns {
+			result.FieldShares[column] = scores[c] / total
+		}
 	}
+	return nil
+}
 
-	return explanations, nil
+// resultLineWidth is the column limit for per-result lines in text output
+const resultLineWidth = 79
+
+// maxShareBar is the widest bar fieldSparkline draws for one column
+const maxShareBar = 8
+
+// fieldSparkline renders column shares as "title ███░ 62% | content ██░░ 31% |
+// category ░░░░ 7%" in IndexedColumns order within width runes. Bars shrink
+// to fit and are left out when even one-cell bars would not fit; the names and
+// percentages are always shown.
+func fieldSparkline(shares map[string]float64, width int) string {
+	labels := make([]string, len(database.IndexedColumns))
+	percents := make([]string, len(database.IndexedColumns))
+	textWidth := len(" | ") * (len(labels) - 1)
+	for i, column := range database.IndexedColumns {
+		labels[i] = column
+		percents[i] = fmt.Sprintf("%.0f%%", shares[column]*100)
+		textWidth += utf8.RuneCountInString(labels[i]) + 1 + len(percents[i])
+	}
+
+	// Each bar also needs a space after it
+	barWidth := min((width-textWidth)/len(labels)-1, maxShareBar)
+
+	parts := make([]string, len(labels))
+	for i, column := range database.IndexedColumns {
+		if barWidth < 1 {
+			parts[i] = labels[i] + " " + percents[i]
+			continue
+		}
+		filled := min(int(math.Round(shares[column]*float64(barWidth))), barWidth)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+		parts[i] = labels[i] + " " + bar + " " + percents[i]
+	}
+	return strings.Join(parts, " | ")
 }
 
-// displayScoreExplanations formats and displays detailed score explanations
-func (h *SearchHandler) displayScoreExplanations(explanations []*models.ScoreExplanation, options models.SearchOptions) error {
-	fmt.Printf("Score Explanations for: \"%s\"\n", options.Query)
-	fmt.Printf("=====================================\n\n")
+// comparedStrategy is one set of search options in a comparison and the results they returned
+type comparedStrategy struct {
+	name    string
+	options models.SearchOptions
+	results []*models.SearchResult
+}
 
-	if len(options.ColumnWeights) > 0 {
-		fmt.Printf("Custom column weights: %v\n", options.ColumnWeights)
-	} else {
-		fmt.Printf("Using default FTS5 column weights (all fields weighted equally)\n")
+// generateComparison creates a comparison analysis of strategies, the first of which
+// is the baseline; the others differ from it by query text, column weights, or both.
+// With analyze set, each strategy also carries a ScoreAnalysis of its results.
+// The top-k overlap of each pair compares the first topK results, or all of them
+// when topK is zero.
+func (h *SearchHandler) generateComparison(strategies []comparedStrategy, analyze bool, topK int) *models.SearchComparison {
+	baselineOptions := strategies[0].options
+	comp := &models.SearchComparison{
+		Query:      baselineOptions.Query,
+		Strategies: make(map[string]models.SearchStrategy, len(strategies)),
+		Order:      make([]string, 0, len(strategies)),
+		CommonDocs: make([]models.SearchResult, 0),
+		CommonDocDeltas: make([]models.ScoreDelta, 0),
+		UniqueDocs: make(map[string][]models.SearchResult),
+		Overlaps:   make([]models.StrategyOverlap, 0),
 	}
-	
-	fmt.Printf("BM25 parameters: k1=1.2, b=0.75 (SQLite FTS5 defaults)\n\n")
 
-	for i, explanation := range explanations {
-		fmt.Printf("Document %d (ID: %d)\n", i+1, explanation.DocumentID)
-		fmt.Printf("Total Score: %.4f\n", explanation.TotalScore)
-		fmt.Printf("Document Length: %d tokens (avg: %.1f)\n", 
-			explanation.DocumentStats.Length, explanation.DocumentStats.AvgLength)
-		fmt.Printf("Length Normalization Factor: %.3f\n", explanation.DocumentStats.LengthNorm)
-		fmt.Printf("\n")
+	for i, strategy := range strategies {
+		name := "Default FTS5"
+		description := "Standard FTS5 BM25 scoring with equal field weights"
+		if i == 0 {
+			if len(baselineOptions.ColumnWeights) > 0 {
+				description = fmt.Sprintf("BM25 scoring with baseline field weights: %s", formatColumnWeights(baselineOptions.ColumnWeights))
+			}
+		} else {
+			name, description = h.describeComparison(baselineOptions, strategy.options)
+			if strategy.options.Query != baselineOptions.Query {
+				comp.QueryB = strategy.options.Query
+			}
+		}
 
-		// Display field contributions
-		fmt.Printf("Field Contributions:\n")
-		for fieldName, fieldScore := range explanation.FieldScores {
-			fmt.Printf("  %s: score=%.4f, weight=%.2f, length=%d tokens\n",
-				fieldName, fieldScore.Score, fieldScore.Weight,
-				explanation.DocumentStats.FieldLengths[fieldName])
+		var analysis *models.ScoreAnalysis
+		if analyze {
+			analysis = h.analyzeScores(strategy.options.Query, strategy.results)
 		}
-		fmt.Printf("\n")
 
-		// Display term analysis
-		fmt.Printf("Query Term Analysis:\n")
-		for _, termScore := range explanation.QueryTerms {
-			fmt.Printf("  \"%s\": tf=%.3f, idf=%.3f, score=%.4f\n",
-				termScore.Term, termScore.TF, termScore.IDF, termScore.Score)
+		// Copy the results (dereference pointers)
+		results := make([]models.SearchResult, len(strategy.results))
+		for j, result := range strategy.results {
+			results[j] = *result
 		}
-		
-		fmt.Print("\n" + strings.Repeat("-", 50) + "\n\n")
+
+		comp.Strategies[strategy.name] = models.SearchStrategy{
+			Name:        name,
+			Description: description,
+			Config:      h.strategyConfig(strategy.options),
+			Results:     results,
+			Analysis:    analysis,
+		}
+		comp.Order = append(comp.Order, strategy.name)
 	}
 
-	return nil
+	// Find common and unique documents
+	h.analyzeResultOverlap(comp, topK)
+
+	return comp
 }
 
-// Helper methods for BM25 calculations
+// analyzeScores summarizes a result set's scores: the overall range, the
+// histogram, and the range within each category
+func (h *SearchHandler) analyzeScores(query string, results []*models.SearchResult) *models.ScoreAnalysis {
+	analysis := &models.ScoreAnalysis{
+		Query:        query,
+		TotalResults: len(results),
+	}
+	if len(results) == 0 {
+		return analysis
+	}
 
-func (h *SearchHandler) getAverageDocumentLength(ctx context.Context) (float64, error) {
-	query := "SELECT AVG(length) FROM documents"
-	var avgLength float64
-	err := database.Instance.DB().QueryRowContext(ctx, query).Scan(&avgLength)
-	if err != nil {
-		return 0, errors.Databasef("failed to get average document length: %w", err)
+	scores := make([]float64, len(results))
+	byCategory := make(map[string][]float64)
+	for i, result := range results {
+		scores[i] = result.Score
+		byCategory[result.Category] = append(byCategory[result.Category], result.Score)
+	}
+
+	// calculateScoreDistribution sorts scores, so the range ends are its first and last values
+	distrib := h.calculateScoreDistribution(scores)
+	analysis.ScoreRange = scoreRange(scores, distrib.Mean, distrib.Median, distrib.StdDev)
+	analysis.Distribution = distrib.Buckets
+
+	analysis.CategoryBreakdown = make(models.CategoryScores, len(byCategory))
+	for category, categoryScores := range byCategory {
+		sort.Float64s(categoryScores)
+		mean := scorestats.Mean(categoryScores)
+		analysis.CategoryBreakdown[category] = models.CategoryStats{
+			DocumentCount: len(categoryScores),
+			AverageScore:  mean,
+			ScoreRange: scoreRange(categoryScores, mean,
+				scorestats.Median(categoryScores), scorestats.StdDev(categoryScores)),
+		}
 	}
-	return avgLength, nil
-}
 
-func (h *SearchHandler) calculateLengthNormalization(docLength int, avgLength float64) float64 {
-	// BM25 length normalization: k1 * ((1 - b) + b * (|d| / avgdl))
-	k1 := 1.2
-	b := 0.75
-	return k1 * ((1 - b) + b * (float64(docLength) / avgLength))
+	return analysis
 }
 
-func (h *SearchHandler) calculateTermScore(term string, result *models.SearchResult, avgDocLength float64) models.TermScore {
-	// Simplified term frequency calculation (count occurrences in title + content)
-	text := strings.ToLower(result.Title + " " + result.Content + " " + result.Category)
-	termCount := strings.Count(text, term)
-	tf := float64(termCount)
-	
-	// Simplified IDF calculation (would need corpus-wide term frequency in real implementation)
-	// For now, use a mock IDF based on term length and frequency
-	idf := 3.0 - (tf * 0.1) // Higher frequency terms get lower IDF
-	if idf < 0.1 {
-		idf = 0.1
+// scoreRange builds a ScoreRange from sorted scores, taking the ends the way
+// GetSearchStats does
+func scoreRange(sorted []float64, mean, median, stdDev float64) models.ScoreRange {
+	return models.ScoreRange{
+		Best:   sorted[0],
+		Worst:  sorted[len(sorted)-1],
+		Mean:   mean,
+		Median: median,
+		StdDev: stdDev,
 	}
-	
-	// Simplified BM25 score for this term
-	lengthNorm := h.calculateLengthNormalization(result.Length, avgDocLength)
-	score := (idf * tf) / lengthNorm
-	
-	return models.TermScore{
-		Term:    term,
-		TF:      tf,
-		IDF:     idf,
-		FieldTF: tf, // Simplified - would normally be per-field
-		Score:   score,
+}
+
+// describeComparison names the comparison strategy after what differs from the baseline
+func (h *SearchHandler) describeComparison(baselineOptions, comparisonOptions models.SearchOptions) (string, string) {
+	queryChanged := comparisonOptions.Query != baselineOptions.Query
+	weightsChanged := fmt.Sprint(comparisonOptions.ColumnWeights) != fmt.Sprint(baselineOptions.ColumnWeights)
+	nearChanged := comparisonOptions.Near != baselineOptions.Near
+
+	switch {
+	case nearChanged && !queryChanged && !weightsChanged:
+		match, _ := database.MatchExpression(comparisonOptions.Query, comparisonOptions.Mode, comparisonOptions.Near)
+		return "NEAR Proximity",
+			fmt.Sprintf("BM25 scoring for the same terms within %d tokens of each other: %s", comparisonOptions.Near, match)
+	case queryChanged && weightsChanged:
+		return "Alternate Query, Custom Weighted",
+			fmt.Sprintf("BM25 scoring for query %q with custom field weights: %s", comparisonOptions.Query, formatColumnWeights(comparisonOptions.ColumnWeights))
+	case queryChanged:
+		return "Alternate Query",
+			fmt.Sprintf("BM25 scoring for query %q with the baseline field weights", comparisonOptions.Query)
+	default:
+		return "Custom Weighted",
+			fmt.Sprintf("BM25 scoring with custom field weights: %s", formatColumnWeights(comparisonOptions.ColumnWeights))
 	}
 }
 
-func (h *SearchHandler) calculateFieldScore(fieldName, fieldContent string, queryTerms []string, weights map[string]float64) models.FieldScore {
-	weight := 1.0
-	if weights != nil {
-		if w, ok := weights[fieldName]; ok {
-			weight = w
-		}
+// strategyConfig records the search options that produced a strategy's results
+func (h *SearchHandler) strategyConfig(options models.SearchOptions) models.StrategyConfig {
+	strategyConfig := models.StrategyConfig{
+		Query:          options.Query,
+		Mode:           options.Mode,
+		Near:           options.Near,
+		ColumnWeights:  options.ColumnWeights,
+		MaxResults:     options.MaxResults,
+		CategoryFilters: options.CategoryFilters,
+		IncludeSnippet: options.IncludeSnippet,
+	}
+	if options.IncludeSnippet {
+		strategyConfig.SnippetLength = options.SnippetLength
+		strategyConfig.SnippetTokens = snippetTokens(options)
+		strategyConfig.HighlightStart, strategyConfig.HighlightEnd = snippetMarkers(options)
+	}
+	return strategyConfig
+}
+
+// compareExplanations explains one document under the baseline and comparison strategies.
+// A strategy that does not match the document has a nil explanation; it is an error for
+// neither strategy to match it.
+func (h *SearchHandler) compareExplanations(ctx context.Context, documentID int64, baselineOptions models.SearchOptions, comparisonOptions *models.SearchOptions) (*models.ExplanationComparison, error) {
+	explanationComparison := &models.ExplanationComparison{
+		DocumentID:    documentID,
+		ChangedFields: make([]string, 0),
 	}
-	
-	// Calculate field-specific score
-	fieldText := strings.ToLower(fieldContent)
-	score := 0.0
-	termScores := make([]models.TermScore, 0, len(queryTerms))
-	
-	for _, term := range queryTerms {
-		termCount := strings.Count(fieldText, term)
-		tf := float64(termCount)
-		
-		// Field contributes to score based on term frequency and weight
-		fieldContrib := tf * weight * 0.1 // Simplified scoring
-		score += fieldContrib
-		
-		termScores = append(termScores, models.TermScore{
-			Term:    term,
-			TF:      tf,
-			FieldTF: tf,
-			Score:   fieldContrib,
-		})
+
+	var err error
+	explanationComparison.Baseline, err = h.explainDocument(ctx, documentID, baselineOptions)
+	if err != nil {
+		return nil, err
 	}
-	
-	return models.FieldScore{
-		Score:  score,
-		Weight: weight,
-		Terms:  termScores,
+
+	if comparisonOptions != nil {
+		explanationComparison.Comparison, err = h.explainDocument(ctx, documentID, *comparisonOptions)
+		if err != nil {
+			return nil, err
+		}
 	}
-}
 
-// generateComparison creates a comparison analysis between baseline and custom weighted results
-func (h *SearchHandler) generateComparison(query string, baseline, comparison []*models.SearchResult, weights map[string]float64) *models.SearchComparison {
-	comp := &models.SearchComparison{
-		Query:      query,
-		Strategies: make(map[string]models.SearchStrategy),
-		CommonDocs: make([]models.SearchResult, 0),
-		UniqueDocs: make(map[string][]models.SearchResult),
+	if explanationComparison.Baseline == nil && explanationComparison.Comparison == nil {
+		return nil, errors.NotFoundf("document %d is not matched by any compared strategy", documentID)
 	}
 
-	// Create baseline strategy
-	comp.Strategies["baseline"] = models.SearchStrategy{
-		Name:        "Default FTS5",
-		Description: "Standard FTS5 BM25 scoring with equal field weights",
-		Config: models.StrategyConfig{
-			ColumnWeights: nil,
-			MaxResults:    len(baseline),
-		},
-		Results:  make([]models.SearchResult, len(baseline)),
-		Analysis: models.ScoreAnalysis{}, // Would populate with actual analysis
+	// Record which field contributions differ between the strategies
+	if explanationComparison.Baseline != nil && explanationComparison.Comparison != nil {
+		for _, field := range []string{"title", "content", "category"} {
+			baseField := explanationComparison.Baseline.FieldScores[field]
+			comparisonField := explanationComparison.Comparison.FieldScores[field]
+			if math.Abs(baseField.Score-comparisonField.Score) > 0.0001 || baseField.Weight != comparisonField.Weight {
+				explanationComparison.ChangedFields = append(explanationComparison.ChangedFields, field)
+			}
+		}
 	}
 
-	// Copy baseline results (dereference pointers)
-	for i, result := range baseline {
-		comp.Strategies["baseline"].Results[i] = *result
+	return explanationComparison, nil
+}
+
+// explainDocument explains a single document under the given search options. The search
+// is unbounded so the explanation carries the document's true rank; nil is returned when
+// the options do not match the document.
+func (h *SearchHandler) explainDocument(ctx context.Context, documentID int64, options models.SearchOptions) (*models.ScoreExplanation, error) {
+	options.MaxResults = 0
+	options.IncludeSnippet = false
+
+	results, err := h.Search(ctx, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create comparison strategy if weights provided
-	if weights != nil {
-		comp.Strategies["weighted"] = models.SearchStrategy{
-			Name:        "Custom Weighted",
-			Description: fmt.Sprintf("BM25 scoring with custom field weights: %v", weights),
-			Config: models.StrategyConfig{
-				ColumnWeights: weights,
-				MaxResults:    len(comparison),
-			},
-			Results:  make([]models.SearchResult, len(comparison)),
-			Analysis: models.ScoreAnalysis{},
+	for i, result := range results {
+		if result.ID != documentID {
+			continue
 		}
 
-		// Copy comparison results
-		for i, result := range comparison {
-			comp.Strategies["weighted"].Results[i] = *result
+		explanations, err := h.GenerateScoreExplanations(ctx, []*models.SearchResult{result}, options)
+		if err != nil {
+			return nil, err
 		}
+		explanations[0].Rank = i + 1
+		return explanations[0], nil
 	}
 
-	// Find common and unique documents
-	h.analyzeResultOverlap(comp)
-
-	return comp
+	return nil, nil
 }
 
-// analyzeResultOverlap identifies common and unique documents between strategies
-func (h *SearchHandler) analyzeResultOverlap(comp *models.SearchComparison) {
-	if len(comp.Strategies) < 2 {
+// analyzeResultOverlap identifies common and unique documents between strategies,
+// the score changes of each strategy against the baseline, and the overlap and
+// rank correlation of every pair of strategies
+func (h *SearchHandler) analyzeResultOverlap(comp *models.SearchComparison, topK int) {
+	if len(comp.Order) < 2 {
 		return
 	}
 
-	baselineResults := comp.Strategies["baseline"].Results
-	weightedResults := comp.Strategies["weighted"].Results
+	// Overlap is by document ID, so it holds whether the strategies differ by
+	// weights or by query text. Index each strategy's results by ID; the index
+	// is the 0-based rank.
+	ranks := make(map[string]map[int64]int, len(comp.Order))
+	for _, name := range comp.Order {
+		results := comp.Strategies[name].Results
+		ranks[name] = make(map[int64]int, len(results))
+		for i, result := range results {
+			ranks[name][result.ID] = i
+		}
+	}
 
-	// Create maps for quick lookup
-	baselineMap := make(map[int64]models.SearchResult)
-	weightedMap := make(map[int64]models.SearchResult)
+	// foundElsewhere counts the strategies other than name that returned id
+	foundElsewhere := func(name string, id int64) int {
+		count := 0
+		for _, other := range comp.Order {
+			if _, found := ranks[other][id]; found && other != name {
+				count++
+			}
+		}
+		return count
+	}
 
+	// Walk the result slices rather than the sets so every list keeps rank order
+	// and repeated runs produce the same output. Common documents carry their
+	// baseline result.
+	baselineName := comp.Order[0]
+	baselineResults := comp.Strategies[baselineName].Results
 	for _, result := range baselineResults {
-		baselineMap[result.ID] = result
+		if foundElsewhere(baselineName, result.ID) == len(comp.Order)-1 {
+			comp.CommonDocs = append(comp.CommonDocs, result)
+		}
 	}
 
-	for _, result := range weightedResults {
-		weightedMap[result.ID] = result
+	for _, name := range comp.Order {
+		comp.UniqueDocs[name] = make([]models.SearchResult, 0)
+		for _, result := range comp.Strategies[name].Results {
+			if foundElsewhere(name, result.ID) == 0 {
+				comp.UniqueDocs[name] = append(comp.UniqueDocs[name], result)
+			}
+		}
 	}
 
-	// Find common documents
-	for id, baselineResult := range baselineMap {
-		if weightedResult, exists := weightedMap[id]; exists {
-			// Document appears in both - compare scores
-			if baselineResult.Score != weightedResult.Score {
-				// Use the baseline version but note the score difference
-				common := baselineResult
-				comp.CommonDocs = append(comp.CommonDocs, common)
-			} else {
-				comp.CommonDocs = append(comp.CommonDocs, baselineResult)
+	// Score changes of the documents each strategy shares with the baseline
+	for _, name := range comp.Order[1:] {
+		comparisonResults := comp.Strategies[name].Results
+		deltas := make([]models.ScoreDelta, 0)
+		for i, result := range baselineResults {
+			j, common := ranks[name][result.ID]
+			if !common {
+				continue
 			}
+
+			comparisonScore := comparisonResults[j].Score
+			deltas = append(deltas, models.ScoreDelta{
+				Strategy:        name,
+				DocumentID:      result.ID,
+				Title:           result.Title,
+				BaselineScore:   result.Score,
+				ComparisonScore: comparisonScore,
+				Delta:           comparisonScore - result.Score,
+				BaselineRank:    i + 1,
+				ComparisonRank:  j + 1,
+				RankChange:      i - j,
+			})
 		}
+
+		// Largest change first; ties keep baseline rank order
+		sort.SliceStable(deltas, func(a, b int) bool {
+			return math.Abs(deltas[a].Delta) > math.Abs(deltas[b].Delta)
+		})
+		comp.CommonDocDeltas = append(comp.CommonDocDeltas, deltas...)
 	}
 
-	// Find unique documents
-	comp.UniqueDocs["baseline"] = make([]models.SearchResult, 0)
-	comp.UniqueDocs["weighted"] = make([]models.SearchResult, 0)
+	for a, first := range comp.Order {
+		for _, second := range comp.Order[a+1:] {
+			firstResults := comp.Strategies[first].Results
+			secondResults := comp.Strategies[second].Results
+
+			overlap := models.StrategyOverlap{First: first, Second: second}
+			var firstScores, secondScores []float64
+			for _, result := range firstResults {
+				if j, common := ranks[second][result.ID]; common {
+					overlap.CommonDocuments++
+					firstScores = append(firstScores, result.Score)
+					secondScores = append(secondScores, secondResults[j].Score)
+				}
+			}
+			overlap.FirstOnly = len(firstResults) - overlap.CommonDocuments
+			overlap.SecondOnly = len(secondResults) - overlap.CommonDocuments
+			overlap.Jaccard = jaccard(firstResults, secondResults)
+
+			overlap.Correlation = models.RankCorrelation{
+				KendallTau: scorestats.KendallTau(firstScores, secondScores),
+				Spearman:   scorestats.Spearman(firstScores, secondScores),
+				TopK:       max(len(firstResults), len(secondResults)),
+			}
+			if topK > 0 && topK < overlap.Correlation.TopK {
+				overlap.Correlation.TopK = topK
+			}
+			k := overlap.Correlation.TopK
+			overlap.Correlation.TopKJaccard = jaccard(firstResults[:min(k, len(firstResults))], secondResults[:min(k, len(secondResults))])
 
-	// Documents only in baseline
-	for id, result := range baselineMap {
-		if _, exists := weightedMap[id]; !exists {
-			comp.UniqueDocs["baseline"] = append(comp.UniqueDocs["baseline"], result)
+			comp.Overlaps = append(comp.Overlaps, overlap)
 		}
 	}
+}
+
+// jaccard returns the number of documents in both result sets over the number in
+// either, or 0 when both are empty
+func jaccard(a, b []models.SearchResult) float64 {
+	inA := make(map[int64]bool, len(a))
+	for _, result := range a {
+		inA[result.ID] = true
+	}
 
-	// Documents only in weighted results
-	for id, result := range weightedMap {
-		if _, exists := baselineMap[id]; !exists {
-			comp.UniqueDocs["weighted"] = append(comp.UniqueDocs["weighted"], result)
+	common := 0
+	for _, result := range b {
+		if inA[result.ID] {
+			common++
 		}
 	}
+
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// formatCorrelation renders a rank correlation, or "-" when it is undefined
+func formatCorrelation(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", *value)
 }
 
 // displayComparison formats and displays search strategy comparison
-func (h *SearchHandler) displayComparison(comp *models.SearchComparison) error {
-	fmt.Printf("Search Strategy Comparison for: \"%s\"\n", comp.Query)
+func (h *SearchHandler) displayComparison(comp *models.SearchComparison, limit int) error {
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false) // Keep snippet markers such as << and >> readable
+		return encoder.Encode(comp)
+
+	case "csv":
+		fmt.Println("strategy,query,rank,id,title,category,score")
+		for _, name := range comp.Order {
+			strategy := comp.Strategies[name]
+			for i, result := range strategy.Results {
+				fmt.Printf("%s,\"%s\",%d,%d,\"%s\",\"%s\",%.4f\n",
+					name, strategy.Config.Query, i+1, result.ID, result.Title, result.Category, result.Score)
+			}
+		}
+		return nil
+	}
+
+	if comp.QueryB != "" {
+		fmt.Printf("Search Strategy Comparison for: \"%s\" vs \"%s\"\n", comp.Query, comp.QueryB)
+	} else {
+		fmt.Printf("Search Strategy Comparison for: \"%s\"\n", comp.Query)
+	}
 	fmt.Printf("===============================================\n\n")
 
-	// Display strategies
-	for name, strategy := range comp.Strategies {
+	// Display strategies, baseline first
+	for _, name := range comp.Order {
+		strategy := comp.Strategies[name]
 		fmt.Printf("Strategy: %s (%s)\n", strategy.Name, name)
+		fmt.Printf("Query: \"%s\"\n", strategy.Config.Query)
 		fmt.Printf("Description: %s\n", strategy.Description)
 		if len(strategy.Config.ColumnWeights) > 0 {
-			fmt.Printf("Weights: %v\n", strategy.Config.ColumnWeights)
+			fmt.Printf("Weights: %s\n", formatColumnWeights(strategy.Config.ColumnWeights))
 		}
-		fmt.Printf("Results: %d documents\n\n", len(strategy.Results))
+		if len(strategy.Config.CategoryFilters) > 0 {
+			fmt.Printf("Category filter: %s\n", strings.Join(strategy.Config.CategoryFilters, ", "))
+		}
+		fmt.Printf("Results: %d documents\n", len(strategy.Results))
+		if analysis := strategy.Analysis; analysis != nil && analysis.TotalResults > 0 {
+			fmt.Printf("Scores: %.4f to %.4f (mean %.4f, median %.4f, std dev %.4f)\n",
+				analysis.ScoreRange.Best, analysis.ScoreRange.Worst,
+				analysis.ScoreRange.Mean, analysis.ScoreRange.Median, analysis.ScoreRange.StdDev)
+			counts := make(models.CategoryCounts, len(analysis.CategoryBreakdown))
+			for category, stats := range analysis.CategoryBreakdown {
+				counts[category] = stats.DocumentCount
+			}
+			for _, category := range counts.SortedBy(config.App.CategoryOrder().Less) {
+				stats := analysis.CategoryBreakdown[category]
+				fmt.Printf("  %-15s: %3d documents, average %.4f (%.4f to %.4f)\n", category,
+					stats.DocumentCount, stats.AverageScore, stats.ScoreRange.Best, stats.ScoreRange.Worst)
+			}
+		}
+		fmt.Printf("\n")
 	}
 
-	// Show ranking comparison if we have both strategies
-	if baseline, hasBaseline := comp.Strategies["baseline"]; hasBaseline {
-		if weighted, hasWeighted := comp.Strategies["weighted"]; hasWeighted {
-			fmt.Printf("Ranking Comparison:\n")
-			fmt.Printf("%-4s %-30s %-12s %-12s %-10s\n", "Rank", "Document", "Baseline", "Weighted", "Change")
-			fmt.Printf("%s\n", strings.Repeat("-", 70))
+	// Show a score-by-score ranking comparison for two strategies, and each
+	// document's position under every strategy for more
+	switch {
+	case len(comp.Order) == 2:
+		baseline, comparison := comp.Strategies[comp.Order[0]], comp.Strategies[comp.Order[1]]
+		// Ranks of baseline documents, so a comparison document at a different
+		// rank reads as reordered rather than different
+		baselineRanks := make(map[int64]int, len(baseline.Results))
+		for i, result := range baseline.Results {
+			baselineRanks[result.ID] = i + 1
+		}
 
-			maxLen := len(baseline.Results)
-			if len(weighted.Results) > maxLen {
-				maxLen = len(weighted.Results)
-			}
+		fmt.Printf("Ranking Comparison:\n")
+		fmt.Printf("%-4s %-30s %-12s %-12s %-10s\n", "Rank", "Document", "Baseline", "Comparison", "Change")
+		fmt.Printf("%s\n", strings.Repeat("-", 70))
 
-			for i := 0; i < maxLen; i++ {
-				var baseDoc, weightedDoc *models.SearchResult
-				var baseScore, weightedScore float64 = 0, 0
-				var title string = "N/A"
-
-				if i < len(baseline.Results) {
-					baseDoc = &baseline.Results[i]
-					baseScore = baseDoc.Score
-					title = baseDoc.Title
-					if len(title) > 25 {
-						title = title[:25] + "..."
-					}
-				}
+		maxLen := len(baseline.Results)
+		if len(comparison.Results) > maxLen {
+			maxLen = len(comparison.Results)
+		}
 
-				if i < len(weighted.Results) {
-					weightedDoc = &weighted.Results[i]
-					weightedScore = weightedDoc.Score
-					if baseDoc == nil {
-						title = weightedDoc.Title
-						if len(title) > 25 {
-							title = title[:25] + "..."
-						}
-					}
+		for i := 0; i < maxLen; i++ {
+			var baseDoc, comparisonDoc *models.SearchResult
+			var baseScore, comparisonScore float64 = 0, 0
+			var title string = "N/A"
+
+			if i < len(baseline.Results) {
+				baseDoc = &baseline.Results[i]
+				baseScore = baseDoc.Score
+				title = shortTitle(baseDoc.Title)
+			}
+
+			if i < len(comparison.Results) {
+				comparisonDoc = &comparison.Results[i]
+				comparisonScore = comparisonDoc.Score
+				if baseDoc == nil {
+					title = shortTitle(comparisonDoc.Title)
 				}
+			}
 
-				var change string
-				if baseDoc != nil && weightedDoc != nil {
-					if baseDoc.ID == weightedDoc.ID {
-						scoreDiff := weightedScore - baseScore
-						if scoreDiff > 0.001 {
-							change = fmt.Sprintf("+%.3f", scoreDiff)
-						} else if scoreDiff < -0.001 {
-							change = fmt.Sprintf("%.3f", scoreDiff)
-						} else {
-							change = "same"
-						}
+			var change string
+			if baseDoc != nil && comparisonDoc != nil {
+				if baseDoc.ID == comparisonDoc.ID {
+					scoreDiff := comparisonScore - baseScore
+					if scoreDiff > 0.001 {
+						change = fmt.Sprintf("+%.3f", scoreDiff)
+					} else if scoreDiff < -0.001 {
+						change = fmt.Sprintf("%.3f", scoreDiff)
 					} else {
-						change = "reordered"
+						change = "same"
 					}
-				} else if baseDoc != nil {
-					change = "dropped"
+				} else if _, inBaseline := baselineRanks[comparisonDoc.ID]; inBaseline {
+					change = "reordered"
 				} else {
-					change = "new"
+					change = "different"
 				}
+			} else if baseDoc != nil {
+				change = "dropped"
+			} else {
+				change = "new"
+			}
 
-				fmt.Printf("%-4d %-30s %-12.4f %-12.4f %-10s\n", 
-					i+1, title, baseScore, weightedScore, change)
+			fmt.Printf("%-4d %-30s %-12.4f %-12.4f %-10s\n", 
+				i+1, title, baseScore, comparisonScore, change)
+
+			// Name the comparison document when it is not the one in the title column
+			if baseDoc != nil && comparisonDoc != nil && baseDoc.ID != comparisonDoc.ID {
+				fmt.Printf("     %s: %s\n", comp.Order[1], shortTitle(comparisonDoc.Title))
+			}
+
+			if baseDoc != nil && baseDoc.Snippet != "" {
+				fmt.Printf("     %s\n", highlightMatches(truncateField(baseDoc.Snippet, limit), baseline.Config.HighlightStart, baseline.Config.HighlightEnd))
 			}
-			fmt.Printf("\n")
 		}
+		fmt.Printf("\n")
+
+	case len(comp.Order) > 2:
+		h.displayRankPositions(comp)
+	}
+
+	if comp.Explanation != nil {
+		h.displayExplanationComparison(comp.Explanation)
 	}
 
 	// Summary statistics
 	fmt.Printf("Summary:\n")
 	fmt.Printf("Common documents: %d\n", len(comp.CommonDocs))
 	
-	if len(comp.UniqueDocs) > 0 {
-		for strategy, uniqueDocs := range comp.UniqueDocs {
-			if len(uniqueDocs) > 0 {
-				fmt.Printf("Unique to %s: %d documents\n", strategy, len(uniqueDocs))
+	for _, strategy := range comp.Order {
+		if uniqueDocs := comp.UniqueDocs[strategy]; len(uniqueDocs) > 0 {
+			fmt.Printf("Unique to %s: %d documents\n", strategy, len(uniqueDocs))
+		}
+	}
+
+	// Pairwise overlap only adds to the counts above when there are more than two strategies
+	switch {
+	case len(comp.Overlaps) == 1:
+		correlation := comp.Overlaps[0].Correlation
+		fmt.Printf("Rank correlation over %d common documents: Kendall tau %s, Spearman rho %s\n",
+			comp.Overlaps[0].CommonDocuments, formatCorrelation(correlation.KendallTau), formatCorrelation(correlation.Spearman))
+		fmt.Printf("Top-%d overlap (Jaccard): %.3f\n", correlation.TopK, correlation.TopKJaccard)
+
+	case len(comp.Overlaps) > 1:
+		fmt.Printf("\nPairwise overlap:\n")
+		fmt.Printf("%-20s %-20s %-8s %-8s %-8s %-8s %-8s %-8s %-8s\n", "Strategy", "Strategy", "Common", "Only 1st", "Only 2nd",
+			"Jaccard", "Tau", "Rho", "Top-k")
+		fmt.Printf("%s\n", strings.Repeat("-", 104))
+		for _, overlap := range comp.Overlaps {
+			fmt.Printf("%-20s %-20s %-8d %-8d %-8d %-8.3f %-8s %-8s %-8.3f\n", overlap.First, overlap.Second,
+				overlap.CommonDocuments, overlap.FirstOnly, overlap.SecondOnly, overlap.Jaccard,
+				formatCorrelation(overlap.Correlation.KendallTau), formatCorrelation(overlap.Correlation.Spearman),
+				overlap.Correlation.TopKJaccard)
+		}
+	}
+
+	for _, name := range comp.Order[1:] {
+		deltas := make([]models.ScoreDelta, 0)
+		for _, delta := range comp.CommonDocDeltas {
+			if delta.Strategy == name {
+				deltas = append(deltas, delta)
 			}
 		}
+		h.displayScoreChanges(name, deltas)
 	}
 
 	return nil
 }
+
+// displayRankPositions lists every returned document with its 1-based rank under each
+// strategy, or "-" where the strategy did not return it. Documents appear in the order
+// the strategies first return them, baseline first.
+func (h *SearchHandler) displayRankPositions(comp *models.SearchComparison) {
+	ranks := make(map[string]map[int64]int, len(comp.Order))
+	var documents []models.SearchResult
+	seen := make(map[int64]bool)
+	for _, name := range comp.Order {
+		ranks[name] = make(map[int64]int)
+		for i, result := range comp.Strategies[name].Results {
+			ranks[name][result.ID] = i + 1
+			if !seen[result.ID] {
+				seen[result.ID] = true
+				documents = append(documents, result)
+			}
+		}
+	}
+
+	// Each strategy column is as wide as its name
+	widths := make([]int, len(comp.Order))
+	ruleWidth := 39
+	fmt.Printf("Ranking Positions:\n")
+	fmt.Printf("%-8s %-30s", "ID", "Document")
+	for i, name := range comp.Order {
+		widths[i] = max(len(name), 4)
+		ruleWidth += widths[i] + 1
+		fmt.Printf(" %-*s", widths[i], name)
+	}
+	fmt.Printf("\n%s\n", strings.Repeat("-", ruleWidth))
+
+	for _, document := range documents {
+		fmt.Printf("%-8d %-30s", document.ID, shortTitle(document.Title))
+		for i, name := range comp.Order {
+			position := "-"
+			if rank, found := ranks[name][document.ID]; found {
+				position = strconv.Itoa(rank)
+			}
+			fmt.Printf(" %-*s", widths[i], position)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("\n")
+}
+
+// displayScoreChanges lists the documents common to the baseline and the named
+// strategy whose score moved the most. Documents scored identically under both
+// strategies are left out.
+func (h *SearchHandler) displayScoreChanges(name string, deltas []models.ScoreDelta) {
+	changed := 0
+	for _, delta := range deltas {
+		if math.Abs(delta.Delta) >= 0.0001 {
+			changed++
+		}
+	}
+	if changed == 0 {
+		return
+	}
+
+	shown := min(changed, maxScoreChanges)
+	fmt.Printf("\nLargest score changes under %s (%d of %d common documents changed, %d shown):\n",
+		name, changed, len(deltas), shown)
+	fmt.Printf("%-8s %-30s %-12s %-12s %-10s %-10s\n", "ID", "Document", "Baseline", "Comparison", "Delta", "Rank")
+	fmt.Printf("%s\n", strings.Repeat("-", 85))
+
+	for _, delta := range deltas[:shown] {
+		fmt.Printf("%-8d %-30s %-12.4f %-12.4f %+-10.4f %-10s\n", delta.DocumentID, shortTitle(delta.Title),
+			delta.BaselineScore, delta.ComparisonScore, delta.Delta,
+			fmt.Sprintf("%d -> %d", delta.BaselineRank, delta.ComparisonRank))
+	}
+}
+
+// shortTitle cuts a title to the 25 characters (runes) that fit the document
+// column of the comparison tables, so multi-byte characters are never split
+func shortTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) <= 25 {
+		return title
+	}
+	return string(runes[:25]) + "..."
+}
+
+// formatColumnWeights lists weights as "title=5.00, content=1.00" in column
+// order, the form the explain output gives each field's weight
+func formatColumnWeights(weights map[string]float64) string {
+	pairs := make([]string, 0, len(weights))
+	for _, column := range database.IndexedColumns {
+		if weight, ok := weights[column]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%.2f", column, weight))
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// displayExplanationComparison renders a document's score explanations side by side,
+// marking field contributions that changed between the strategies with "*"
+func (h *SearchHandler) displayExplanationComparison(explanationComparison *models.ExplanationComparison) {
+	baseline := explanationComparison.Baseline
+	comparison := explanationComparison.Comparison
+
+	changed := make(map[string]bool, len(explanationComparison.ChangedFields))
+	for _, field := range explanationComparison.ChangedFields {
+		changed[field] = true
+	}
+
+	// column formats one strategy's value, or "-" when the strategy did not match the document
+	column := func(explanation *models.ScoreExplanation, value func(*models.ScoreExplanation) string) string {
+		if explanation == nil {
+			return "-"
+		}
+		return value(explanation)
+	}
+
+	fmt.Printf("Score Explanation for Document ID %d:\n", explanationComparison.DocumentID)
+	fmt.Printf("%-2s %-24s %-22s %-22s\n", "", "", "Baseline", "Comparison")
+	fmt.Printf("%s\n", strings.Repeat("-", 70))
+
+	fmt.Printf("%-2s %-24s %-22s %-22s\n", "", "Rank",
+		column(baseline, func(e *models.ScoreExplanation) string { return fmt.Sprintf("%d", e.Rank) }),
+		column(comparison, func(e *models.ScoreExplanation) string { return fmt.Sprintf("%d", e.Rank) }))
+	fmt.Printf("%-2s %-24s %-22s %-22s\n", "", "Total Score",
+		column(baseline, func(e *models.ScoreExplanation) string { return fmt.Sprintf("%.4f", e.TotalScore) }),
+		column(comparison, func(e *models.ScoreExplanation) string { return fmt.Sprintf("%.4f", e.TotalScore) }))
+	fmt.Printf("%-2s %-24s %-22s %-22s\n", "", "Length Normalization",
+		column(baseline, func(e *models.ScoreExplanation) string { return fmt.Sprintf("%.3f", e.DocumentStats.LengthNorm) }),
+		column(comparison, func(e *models.ScoreExplanation) string { return fmt.Sprintf("%.3f", e.DocumentStats.LengthNorm) }))
+
+	fmt.Printf("\nField Contributions:\n")
+	for _, field := range []string{"title", "content", "category"} {
+		marker := ""
+		if changed[field] {
+			marker = "*"
+		}
+		fieldColumn := func(e *models.ScoreExplanation) string {
+			fieldScore := e.FieldScores[field]
+			return fmt.Sprintf("%.4f (weight %.2f)", fieldScore.Score, fieldScore.Weight)
+		}
+		fmt.Printf("%-2s %-24s %-22s %-22s\n", marker, field, column(baseline, fieldColumn), column(comparison, fieldColumn))
+	}
+
+	// Terms differ when the strategies use different queries, so list each side's terms in turn
+	fmt.Printf("\nQuery Term Analysis:\n")
+	for _, side := range []struct {
+		name        string
+		explanation *models.ScoreExplanation
+	}{{"baseline", baseline}, {"comparison", comparison}} {
+		if side.explanation == nil {
+			fmt.Printf("   %s: document not matched\n", side.name)
+			continue
+		}
+		for _, termScore := range side.explanation.QueryTerms {
+			fmt.Printf("   %s \"%s\": tf=%.3f, idf=%.3f, score=%.4f\n",
+				side.name, termScore.Term, termScore.TF, termScore.IDF, termScore.Score)
+		}
+	}
+
+	if len(explanationComparison.ChangedFields) > 0 {
+		fmt.Printf("\n* field contribution changed between strategies\n")
+	}
+	fmt.Printf("\n")
+}
@@ -0,0 +1,310 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+func TestSearchColumnWeightsReorderResults(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	docs := insertTestDocuments(t, db,
+		[3]string{"sqlite internals", "notes on storage pages and the btree layout", "technology"},
+		[3]string{"storage notes", "sqlite keeps pages in a btree layout on disk", "technology"},
+	)
+	inTitle, inContent := docs[0].ID, docs[1].ID
+
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		first   int64
+	}{
+		{"title weighted", map[string]float64{"title": 10, "content": 1}, inTitle},
+		{"content weighted", map[string]float64{"title": 1, "content": 10}, inContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := models.DefaultSearchOptions()
+			options.Query = "sqlite"
+			options.ColumnWeights = tt.weights
+
+			results, err := search.Search(context.Background(), options)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("Search returned %d results, want 2", len(results))
+			}
+			if results[0].ID != tt.first {
+				t.Errorf("first result = %d, want %d", results[0].ID, tt.first)
+			}
+			if results[0].Score > results[1].Score {
+				t.Errorf("scores %.4f, %.4f are not ordered best (most negative) first", results[0].Score, results[1].Score)
+			}
+			for _, result := range results {
+				if result.Relevance == "" {
+					t.Errorf("result %d has no relevance label", result.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchCategoryFilterAndLimit(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	insertTestDocuments(t, db,
+		[3]string{"one", "indexing with sqlite", "technology"},
+		[3]string{"two", "sqlite for research data", "science"},
+		[3]string{"three", "sqlite query planning", "technology"},
+		[3]string{"four", "sqlite in the lab", "science"},
+		[3]string{"five", "sqlite page cache", "technology"},
+	)
+
+	options := models.DefaultSearchOptions()
+	options.Query = "sqlite"
+	options.CategoryFilters = []string{"science"}
+	results, err := search.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("category filter returned %d results, want 2", len(results))
+	}
+	for _, result := range results {
+		if result.Category != "science" {
+			t.Errorf("result %d has category %q, want science", result.ID, result.Category)
+		}
+	}
+
+	options = models.DefaultSearchOptions()
+	options.Query = "sqlite"
+	options.MaxResults = 3
+	results, err = search.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("MaxResults 3 returned %d results", len(results))
+	}
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	minScore := -2.5
+	after := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		adjust   func(*models.SearchOptions)
+		ids      []int64
+		contains []string
+		args     []interface{}
+	}{
+		{
+			name:     "defaults",
+			contains: []string{"bm25(documents_fts) as score", "WHERE documents_fts MATCH ?", "ORDER BY score", "LIMIT ?"},
+			args:     []interface{}{"sqlite", 20},
+		},
+		{
+			name: "column weights",
+			adjust: func(o *models.SearchOptions) {
+				o.ColumnWeights = map[string]float64{"title": 2.5}
+			},
+			contains: []string{"bm25(documents_fts, 2.50, 1.0, 1.0) as score"},
+			args:     []interface{}{"sqlite", 20},
+		},
+		{
+			name: "categories, created after, and min score",
+			adjust: func(o *models.SearchOptions) {
+				o.CategoryFilters = []string{"science", "technology"}
+				o.CreatedAfter = after
+				o.MinScore = &minScore
+			},
+			contains: []string{"AND d.category IN (?, ?)", "AND d.created >= ?", "AND bm25(documents_fts) <= ?"},
+			args:     []interface{}{"sqlite", "science", "technology", after, minScore, 20},
+		},
+		{
+			name:     "within ids",
+			ids:      []int64{4, 9},
+			contains: []string{"AND d.id IN (?, ?)"},
+			args:     []interface{}{"sqlite", int64(4), int64(9), 20},
+		},
+		{
+			name: "offset without limit",
+			adjust: func(o *models.SearchOptions) {
+				o.MaxResults = 0
+				o.Offset = 10
+			},
+			contains: []string{"LIMIT -1 OFFSET ?"},
+			args:     []interface{}{"sqlite", 10},
+		},
+		{
+			name: "sort by length descending",
+			adjust: func(o *models.SearchOptions) {
+				o.SortBy = models.SortLength
+				o.SortDesc = true
+			},
+			contains: []string{"DESC, score"},
+			args:     []interface{}{"sqlite", 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := models.DefaultSearchOptions()
+			options.Query = "sqlite"
+			options.IncludeSnippet = false
+			if tt.adjust != nil {
+				tt.adjust(&options)
+			}
+
+			query, args, err := Search.buildSearchQuery(options, tt.ids)
+			if err != nil {
+				t.Fatalf("buildSearchQuery: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(query, want) {
+					t.Errorf("query does not contain %q:\n%s", want, query)
+				}
+			}
+			if got := strings.Count(query, "?"); got != len(args) {
+				t.Errorf("query has %d placeholders for %d arguments", got, len(args))
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestBuildSearchQueryRejectsUnknownSortKey(t *testing.T) {
+	options := models.DefaultSearchOptions()
+	options.Query = "sqlite"
+	options.SortBy = "popularity"
+
+	if _, _, err := Search.buildSearchQuery(options, nil); err == nil {
+		t.Error("buildSearchQuery accepted an unknown sort key")
+	}
+}
+
+func TestGetSearchStats(t *testing.T) {
+	var results []*models.SearchResult
+	for i, category := range []string{"science", "technology", "science", "science"} {
+		result := &models.SearchResult{Score: float64(i - 4)}
+		result.Category = category
+		results = append(results, result)
+	}
+	options := models.SearchOptions{Query: "sqlite"}
+
+	stats, err := Search.GetSearchStats(context.Background(), results, options, time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetSearchStats: %v", err)
+	}
+
+	if stats.TotalResults != 4 {
+		t.Errorf("TotalResults = %d, want 4", stats.TotalResults)
+	}
+	if stats.ScoreRange.Best != -4 || stats.ScoreRange.Worst != -1 {
+		t.Errorf("score range = %.2f to %.2f, want -4 to -1", stats.ScoreRange.Best, stats.ScoreRange.Worst)
+	}
+	if stats.ScoreRange.Mean != -2.5 || stats.ScoreRange.Median != -2.5 {
+		t.Errorf("mean, median = %.4f, %.4f, want -2.5, -2.5", stats.ScoreRange.Mean, stats.ScoreRange.Median)
+	}
+	if want := math.Sqrt(1.25); math.Abs(stats.ScoreRange.StdDev-want) > 1e-9 {
+		t.Errorf("std dev = %.6f, want the population deviation %.6f", stats.ScoreRange.StdDev, want)
+	}
+	if stats.CategoryBreakdown["science"] != 3 || stats.CategoryBreakdown["technology"] != 1 {
+		t.Errorf("category breakdown = %v, want science 3, technology 1", stats.CategoryBreakdown)
+	}
+
+	total := 0
+	for _, bucket := range stats.ScoreDistrib.Buckets {
+		total += bucket.Count
+	}
+	if total != 4 {
+		t.Errorf("histogram buckets hold %d scores, want 4", total)
+	}
+
+	empty, err := Search.GetSearchStats(context.Background(), nil, options, time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetSearchStats with no results: %v", err)
+	}
+	if empty.TotalResults != 0 || empty.Query != "sqlite" {
+		t.Errorf("empty stats = %+v, want zero results for the query", empty)
+	}
+}
+
+func TestGenerateCorpusIsDeterministicAcrossWorkers(t *testing.T) {
+	generate := func(t *testing.T, workers int) []string {
+		db := useTestDatabase(t)
+		generateTestCorpus(t, db, 60, 42, func(options *models.CorpusOptions) {
+			options.Workers = workers
+		})
+		return readContents(t, db)
+	}
+
+	var single []string
+	t.Run("one worker", func(t *testing.T) { single = generate(t, 1) })
+	var pooled []string
+	t.Run("four workers", func(t *testing.T) { pooled = generate(t, 4) })
+
+	if len(single) != 60 || len(pooled) != 60 {
+		t.Fatalf("generated %d and %d documents, want 60 each", len(single), len(pooled))
+	}
+	for i := range single {
+		if single[i] != pooled[i] {
+			t.Fatalf("document %d differs between 1 and 4 workers (seed 42):\n%q\n%q", i, single[i], pooled[i])
+		}
+	}
+}
+
+func TestMinScoreKeepsStrongMatches(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	docs := insertTestDocuments(t, db,
+		[3]string{"hypothesis", "hypothesis testing with a hypothesis", "science"},
+		[3]string{"field notes", "a long entry about sampling, lab equipment, weather, and one hypothesis among many other words", "science"},
+		[3]string{"unrelated", "storage engines and page caches", "technology"},
+		[3]string{"unrelated too", "query planners and indexes", "technology"},
+	)
+	strong, weak := docs[0].ID, docs[1].ID
+
+	options := models.DefaultSearchOptions()
+	options.Query = "hypothesis"
+	all, err := search.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != strong {
+		t.Fatalf("unfiltered search = %d results, want the strong match %d first of 2", len(all), strong)
+	}
+
+	// A threshold between the two scores keeps the more negative one
+	threshold := (all[0].Score + all[1].Score) / 2
+	options.MinScore = &threshold
+	kept, err := search.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Search with min score: %v", err)
+	}
+	if len(kept) != 1 || kept[0].ID != strong {
+		t.Fatalf("min score %.4f kept %d results, want only the strong match %d (scores %.4f, %.4f)",
+			threshold, len(kept), strong, all[0].Score, all[1].Score)
+	}
+
+	excluded, err := search.ExcludedByThreshold(context.Background(), options)
+	if err != nil {
+		t.Fatalf("ExcludedByThreshold: %v", err)
+	}
+	if excluded != 1 {
+		t.Errorf("ExcludedByThreshold = %d, want 1 (the weak match %d)", excluded, weak)
+	}
+}
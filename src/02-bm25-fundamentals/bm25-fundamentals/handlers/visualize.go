@@ -2,62 +2,78 @@ package handlers
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"math"
+	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/guptarohit/asciigraph"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/experiment"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/stats"
 	"github.com/spf13/cobra"
 )
 
 // Visualize is the global visualize handler instance
 var Visualize VisualizeHandler
 
-// VisualizeHandler manages visualization operations (stateless - accesses global instances)
-type VisualizeHandler struct{}
+// VisualizeHandler manages visualization operations over searches of its database
+type VisualizeHandler struct {
+	db     *database.Database
+	corpus *CorpusHandler
+	search *SearchHandler
+}
+
+// NewVisualizeHandler creates a visualize handler that searches db
+func NewVisualizeHandler(db *database.Database) *VisualizeHandler {
+	return &VisualizeHandler{db: db, corpus: NewCorpusHandler(db), search: NewSearchHandler(db)}
+}
 
 // HandleDistribution handles the score distribution visualization command
 func (h *VisualizeHandler) HandleDistribution(cmd *cobra.Command, args []string) error {
-	// Extract flags
-	query, _ := cmd.Flags().GetString("query")
-	category, _ := cmd.Flags().GetString("category")
-	titleWeight, _ := cmd.Flags().GetFloat64("title-weight")
-	contentWeight, _ := cmd.Flags().GetFloat64("content-weight")
-	categoryWeight, _ := cmd.Flags().GetFloat64("category-weight")
-	buckets, _ := cmd.Flags().GetInt("buckets")
-	maxResults, _ := cmd.Flags().GetInt("max-results")
-
-	// Build search options
-	options := models.DefaultSearchOptions()
-	options.Query = query
-	options.MaxResults = maxResults
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
 	options.IncludeSnippet = false
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		options.MaxResults = 0
+	}
 
-	if category != "" {
-		options.CategoryFilter = category
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
 	}
 
-	// Set column weights if specified
-	if titleWeight > 0 || contentWeight > 0 || categoryWeight > 0 {
-		options.ColumnWeights = make(map[string]float64)
-		if titleWeight > 0 {
-			options.ColumnWeights["title"] = titleWeight
-		}
-		if contentWeight > 0 {
-			options.ColumnWeights["content"] = contentWeight
-		}
-		if categoryWeight > 0 {
-			options.ColumnWeights["category"] = categoryWeight
-		}
+	query := options.Query
+
+	buckets, err := flagutil.Buckets(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
+	if err := h.search.CheckCategory(ctx, options); err != nil {
+		return err
+	}
 
 	// Perform search to get results
-	results, err := Search.Search(ctx, options)
+	results, err := h.search.Search(ctx, options)
 	if err != nil {
 		return err
 	}
@@ -67,6 +83,10 @@ func (h *VisualizeHandler) HandleDistribution(cmd *cobra.Command, args []string)
 		return nil
 	}
 
+	if err := h.warnTruncated(ctx, options, results); err != nil {
+		return err
+	}
+
 	// Generate score distribution
 	distribution := h.generateScoreDistribution(results, buckets)
 	
@@ -76,38 +96,36 @@ func (h *VisualizeHandler) HandleDistribution(cmd *cobra.Command, args []string)
 
 // HandleCategories handles the category comparison visualization command
 func (h *VisualizeHandler) HandleCategories(cmd *cobra.Command, args []string) error {
-	// Extract flags
-	query, _ := cmd.Flags().GetString("query")
-	filter, _ := cmd.Flags().GetString("filter")
-	titleWeight, _ := cmd.Flags().GetFloat64("title-weight")
-	contentWeight, _ := cmd.Flags().GetFloat64("content-weight")
-	categoryWeight, _ := cmd.Flags().GetFloat64("category-weight")
-	maxResults, _ := cmd.Flags().GetInt("max-results")
-
-	// Build search options
-	options := models.DefaultSearchOptions()
-	options.Query = query
-	options.MaxResults = maxResults
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
 	options.IncludeSnippet = false
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		options.MaxResults = 0
+	}
 
-	// Set column weights if specified
-	if titleWeight > 0 || contentWeight > 0 || categoryWeight > 0 {
-		options.ColumnWeights = make(map[string]float64)
-		if titleWeight > 0 {
-			options.ColumnWeights["title"] = titleWeight
-		}
-		if contentWeight > 0 {
-			options.ColumnWeights["content"] = contentWeight
-		}
-		if categoryWeight > 0 {
-			options.ColumnWeights["category"] = categoryWeight
-		}
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
+	}
+
+	query := options.Query
+
+	filter, _ := cmd.Flags().GetString("filter")
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
+	if err := h.search.CheckCategory(ctx, options); err != nil {
+		return err
+	}
 
 	// Perform search to get results
-	results, err := Search.Search(ctx, options)
+	results, err := h.search.Search(ctx, options)
 	if err != nil {
 		return err
 	}
@@ -117,6 +135,10 @@ func (h *VisualizeHandler) HandleCategories(cmd *cobra.Command, args []string) e
 		return nil
 	}
 
+	if err := h.warnTruncated(ctx, options, results); err != nil {
+		return err
+	}
+
 	// Parse filter categories
 	var filterCategories []string
 	if filter != "" {
@@ -135,42 +157,34 @@ func (h *VisualizeHandler) HandleCategories(cmd *cobra.Command, args []string) e
 
 // HandleRange handles the score range visualization command
 func (h *VisualizeHandler) HandleRange(cmd *cobra.Command, args []string) error {
-	// Extract flags
-	query, _ := cmd.Flags().GetString("query")
-	category, _ := cmd.Flags().GetString("category")
-	titleWeight, _ := cmd.Flags().GetFloat64("title-weight")
-	contentWeight, _ := cmd.Flags().GetFloat64("content-weight")
-	categoryWeight, _ := cmd.Flags().GetFloat64("category-weight")
-	maxResults, _ := cmd.Flags().GetInt("max-results")
-
-	// Build search options
-	options := models.DefaultSearchOptions()
-	options.Query = query
-	options.MaxResults = maxResults
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
 	options.IncludeSnippet = false
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		options.MaxResults = 0
+	}
 
-	if category != "" {
-		options.CategoryFilter = category
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
 	}
 
-	// Set column weights if specified
-	if titleWeight > 0 || contentWeight > 0 || categoryWeight > 0 {
-		options.ColumnWeights = make(map[string]float64)
-		if titleWeight > 0 {
-			options.ColumnWeights["title"] = titleWeight
-		}
-		if contentWeight > 0 {
-			options.ColumnWeights["content"] = contentWeight
-		}
-		if categoryWeight > 0 {
-			options.ColumnWeights["category"] = categoryWeight
-		}
+	query := options.Query
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
+	if err := h.search.CheckCategory(ctx, options); err != nil {
+		return err
+	}
 
 	// Perform search to get results
-	results, err := Search.Search(ctx, options)
+	results, err := h.search.Search(ctx, options)
 	if err != nil {
 		return err
 	}
@@ -180,6 +194,10 @@ func (h *VisualizeHandler) HandleRange(cmd *cobra.Command, args []string) error
 		return nil
 	}
 
+	if err := h.warnTruncated(ctx, options, results); err != nil {
+		return err
+	}
+
 	// Generate range analysis
 	rangeAnalysis := h.generateRangeAnalysis(results)
 	
@@ -187,54 +205,235 @@ func (h *VisualizeHandler) HandleRange(cmd *cobra.Command, args []string) error
 	return h.displayRangeVisualization(rangeAnalysis, options)
 }
 
-// Helper methods for visualization generation
+// HandleK1Sweep handles the BM25 parameter sweep visualization command
+func (h *VisualizeHandler) HandleK1Sweep(cmd *cobra.Command, args []string) error {
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
+	options.IncludeSnippet = false
+
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
+	}
+
+	k1Spec, _ := cmd.Flags().GetString("k1")
+	bSpec, _ := cmd.Flags().GetString("b")
+	docID, _ := cmd.Flags().GetInt64("doc-id")
+
+	if docID < 0 {
+		return errors.Validationf("--doc-id must be a positive document ID, got %d", docID)
+	}
+	if k1Spec == "" && bSpec == "" {
+		return errors.Validationf("nothing to sweep: give a --k1 or --b range")
+	}
+
+	var k1Values, bValues []float64
+	if k1Spec != "" {
+		if k1Values, err = flagutil.ParseRange("k1", k1Spec); err != nil {
+			return err
+		}
+		if k1Values[0] < 0 {
+			return errors.Validationf("k1 must not be negative, got %g", k1Values[0])
+		}
+	}
+	if bSpec != "" {
+		if bValues, err = flagutil.ParseRange("b", bSpec); err != nil {
+			return err
+		}
+		if bValues[0] < 0 || bValues[len(bValues)-1] > 1 {
+			return errors.Validationf("b must be between 0 and 1, got %g to %g", bValues[0], bValues[len(bValues)-1])
+		}
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	if err := h.search.CheckCategory(ctx, options); err != nil {
+		return err
+	}
+
+	// A chosen document is looked up among all matches so that its rank is real
+	searchOptions := options
+	if docID > 0 {
+		searchOptions.MaxResults = 0
+	}
+
+	results, err := h.search.Search(ctx, searchOptions)
+	if err != nil {
+		return err
+	}
+
+	target := &sweepTarget{}
+	if docID > 0 {
+		for i, result := range results {
+			if result.ID == docID {
+				target.document = result
+				target.rank = i + 1
+				results = []*models.SearchResult{result}
+				break
+			}
+		}
+		if target.document == nil {
+			return errors.NotFoundf("document %d is not matched by query %q", docID, options.Query)
+		}
+	}
 
-// generateScoreDistribution creates histogram buckets for score distribution
-func (h *VisualizeHandler) generateScoreDistribution(results []*models.SearchResult, numBuckets int) []models.ScoreBucket {
 	if len(results) == 0 {
+		fmt.Printf("No results found for query: \"%s\"\n", options.Query)
 		return nil
 	}
 
-	scores := make([]float64, len(results))
-	for i, result := range results {
-		scores[i] = result.Score
+	if err := h.loadSweepTarget(ctx, target, results, options); err != nil {
+		return err
 	}
 
-	sort.Float64s(scores)
-	
-	min := scores[0]
-	max := scores[len(scores)-1]
+	var sweeps []models.ParameterSweep
+	if len(k1Values) > 0 {
+		sweeps = append(sweeps, h.generateSweep(target, "k1", k1Values))
+	}
+	if len(bValues) > 0 {
+		sweeps = append(sweeps, h.generateSweep(target, "b", bValues))
+	}
+
+	return h.displayParameterSweeps(target, sweeps, options)
+}
+
+// HandleWeightSweep handles the column weight sensitivity visualization command
+func (h *VisualizeHandler) HandleWeightSweep(cmd *cobra.Command, args []string) error {
+	// Build search options from flags
+	options, err := flagutil.ExtractSearchOptions(cmd)
+	if err != nil {
+		return err
+	}
+	options.IncludeSnippet = false
+
+	if err := flagutil.DumpSearchOptions(cmd, options); err != nil {
+		return err
+	}
+
+	field, _ := cmd.Flags().GetString("field")
+	rangeSpec, _ := cmd.Flags().GetString("range")
+
+	field = strings.ToLower(strings.TrimSpace(field))
+	if !slices.Contains(database.IndexedColumns, field) {
+		return errors.Validationf("unknown weight field %q (valid: %s)", field, strings.Join(database.IndexedColumns, ", "))
+	}
 
-	// Handle case where all scores are the same
-	if min == max {
-		return []models.ScoreBucket{{
-			Min:   min,
-			Max:   max,
-			Count: len(scores),
-			Label: fmt.Sprintf("%.3f", min),
-		}}
+	weights, err := flagutil.ParseRange("range", rangeSpec)
+	if err != nil {
+		return err
+	}
+	if weights[0] < 0 {
+		return errors.Validationf("weights must not be negative, got %g", weights[0])
+	}
+
+	maxResults := options.MaxResults
+	if maxResults <= 0 {
+		maxResults = config.App.Search.MaxResults
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	if err := h.search.CheckCategory(ctx, options); err != nil {
+		return err
 	}
 
-	buckets := make([]models.ScoreBucket, numBuckets)
-	bucketWidth := (max - min) / float64(numBuckets)
+	sweep, err := h.generateWeightSweep(ctx, options, field, weights, maxResults)
+	if err != nil {
+		return err
+	}
+	if sweep == nil {
+		fmt.Printf("No results found for query: \"%s\"\n", options.Query)
+		return nil
+	}
+
+	return h.displayWeightSweep(sweep)
+}
 
-	// Initialize buckets
-	for i := 0; i < numBuckets; i++ {
-		buckets[i].Min = min + float64(i)*bucketWidth
-		buckets[i].Max = min + float64(i+1)*bucketWidth
-		buckets[i].Label = fmt.Sprintf("%.3f to %.3f", buckets[i].Min, buckets[i].Max)
+// HandleHeatmap handles the category score heatmap command
+func (h *VisualizeHandler) HandleHeatmap(cmd *cobra.Command, args []string) error {
+	queriesSpec, _ := cmd.Flags().GetString("queries")
+	weightSpec, _ := cmd.Flags().GetString("weights")
+	maxResults, err := flagutil.MaxResults(cmd)
+	if err != nil {
+		return err
 	}
 
-	// Count scores in each bucket
-	for _, score := range scores {
-		bucketIndex := int((score - min) / bucketWidth)
-		if bucketIndex >= numBuckets {
-			bucketIndex = numBuckets - 1
+	var queries []string
+	for _, query := range strings.Split(queriesSpec, ";") {
+		if query = strings.TrimSpace(query); query != "" {
+			queries = append(queries, query)
 		}
-		buckets[bucketIndex].Count++
+	}
+	if len(queries) == 0 {
+		return errors.Validationf("--queries must list at least one query (format: \"q1;q2;q3\")")
 	}
 
-	return buckets
+	weights, err := flagutil.ParseWeights(weightSpec)
+	if err != nil {
+		return err
+	}
+
+	if maxResults <= 0 {
+		maxResults = config.App.Search.MaxResults
+	}
+
+	ctx := cmd.Context()
+
+	if err := h.corpus.RequireDocuments(ctx); err != nil {
+		return err
+	}
+
+	resultSets := make([][]*models.SearchResult, len(queries))
+	for i, query := range queries {
+		if ctx.Err() != nil {
+			return errors.Cancelledf("heatmap interrupted after %d of %d queries", i, len(queries))
+		}
+
+		options := models.DefaultSearchOptions()
+		options.Query = query
+		options.ColumnWeights = weights
+		options.MaxResults = maxResults
+		options.IncludeSnippet = false
+
+		resultSets[i], err = h.search.Search(ctx, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	heatmap := h.generateHeatmap(queries, resultSets)
+	return h.displayHeatmap(heatmap)
+}
+
+// Helper methods for visualization generation
+
+// generateScoreDistribution creates histogram buckets for score distribution
+func (h *VisualizeHandler) generateScoreDistribution(results []*models.SearchResult, numBuckets int) []models.ScoreBucket {
+	if len(results) == 0 {
+		return nil
+	}
+	if numBuckets < 1 {
+		numBuckets = 1
+	}
+
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		scores[i] = result.Score
+	}
+
+	sort.Float64s(scores)
+
+	return h.search.createScoreBuckets(scores, numBuckets)
 }
 
 // CategoryData holds score data for a specific category
@@ -292,13 +491,7 @@ func (h *VisualizeHandler) generateCategoryComparison(results []*models.SearchRe
 
 	// Calculate averages
 	for _, data := range categoryMap {
-		if data.Count > 0 {
-			sum := 0.0
-			for _, score := range data.Scores {
-				sum += score
-			}
-			data.AvgScore = sum / float64(data.Count)
-		}
+		data.AvgScore = stats.Mean(data.Scores)
 	}
 
 	return categoryMap
@@ -326,50 +519,16 @@ func (h *VisualizeHandler) generateRangeAnalysis(results []*models.SearchResult)
 	sort.Float64s(scores)
 
 	analysis := &RangeAnalysis{
-		Scores:      scores,
-		Min:         scores[0],
-		Max:         scores[len(scores)-1],
-		Percentiles: make(map[int]float64),
-		Quartiles:   make([]float64, 3),
+		Scores:    scores,
+		Min:       scores[0],
+		Max:       scores[len(scores)-1],
+		Quartiles: make([]float64, 3),
 	}
 
-	// Calculate mean
-	sum := 0.0
-	for _, score := range scores {
-		sum += score
-	}
-	analysis.Mean = sum / float64(len(scores))
-
-	// Calculate median
-	n := len(scores)
-	if n%2 == 0 {
-		analysis.Median = (scores[n/2-1] + scores[n/2]) / 2
-	} else {
-		analysis.Median = scores[n/2]
-	}
-
-	// Calculate standard deviation
-	sumSquareDiff := 0.0
-	for _, score := range scores {
-		diff := score - analysis.Mean
-		sumSquareDiff += diff * diff
-	}
-	analysis.StdDev = math.Sqrt(sumSquareDiff / float64(n))
-
-	// Calculate percentiles
-	percentiles := []int{25, 50, 75, 90, 95, 99}
-	for _, p := range percentiles {
-		index := float64(p) / 100.0 * float64(n-1)
-		lower := int(math.Floor(index))
-		upper := int(math.Ceil(index))
-
-		if lower == upper {
-			analysis.Percentiles[p] = scores[lower]
-		} else {
-			weight := index - float64(lower)
-			analysis.Percentiles[p] = scores[lower]*(1-weight) + scores[upper]*weight
-		}
-	}
+	analysis.Mean = stats.Mean(scores)
+	analysis.Median = stats.Median(scores)
+	analysis.StdDev = stats.StdDev(scores)
+	analysis.Percentiles = stats.Percentiles(scores)
 
 	// Set quartiles
 	analysis.Quartiles[0] = analysis.Percentiles[25] // Q1
@@ -379,6 +538,17 @@ func (h *VisualizeHandler) generateRangeAnalysis(results []*models.SearchResult)
 	return analysis
 }
 
+// warnTruncated warns when results stopped at --max-results short of every
+// match, since a distribution of the best matches leaves out the tail
+func (h *VisualizeHandler) warnTruncated(ctx context.Context, options models.SearchOptions, results []*models.SearchResult) error {
+	matches, err := h.search.TotalMatches(ctx, options, results)
+	if err != nil {
+		return err
+	}
+	WarnTruncated(len(results), matches)
+	return nil
+}
+
 // Display methods for different visualizations
 
 // displayDistributionHistogram shows an ASCII histogram of score distribution using asciigraph
@@ -390,6 +560,10 @@ func (h *VisualizeHandler) displayDistributionHistogram(buckets []models.ScoreBu
 		fmt.Printf("Column weights: %v\n\n", options.ColumnWeights)
 	}
 
+	if mode, match := matchMode(options.Query, options.Mode, options.Near); match != "" {
+		fmt.Printf("Mode: %s (MATCH %s)\n\n", mode, match)
+	}
+
 	if len(buckets) == 0 {
 		fmt.Println("No data to display.")
 		return nil
@@ -440,6 +614,10 @@ func (h *VisualizeHandler) displayCategoryComparison(categories map[string]*Cate
 		fmt.Printf("Column weights: %v\n\n", options.ColumnWeights)
 	}
 
+	if mode, match := matchMode(options.Query, options.Mode, options.Near); match != "" {
+		fmt.Printf("Mode: %s (MATCH %s)\n\n", mode, match)
+	}
+
 	if len(categories) == 0 {
 		fmt.Println("No categories to display.")
 		return nil
@@ -456,8 +634,12 @@ func (h *VisualizeHandler) displayCategoryComparison(categories map[string]*Cate
 		sortedCategories = append(sortedCategories, categoryPair{name, data})
 	}
 	
+	order := config.App.CategoryOrder()
 	sort.Slice(sortedCategories, func(i, j int) bool {
-		return sortedCategories[i].data.AvgScore > sortedCategories[j].data.AvgScore // Higher scores first (less negative)
+		if sortedCategories[i].data.AvgScore != sortedCategories[j].data.AvgScore {
+			return sortedCategories[i].data.AvgScore > sortedCategories[j].data.AvgScore // Higher scores first (less negative)
+		}
+		return order.Less(sortedCategories[i].name, sortedCategories[j].name)
 	})
 
 	// Prepare data for average score comparison chart
@@ -488,18 +670,7 @@ func (h *VisualizeHandler) displayCategoryComparison(categories map[string]*Cate
 		// Calculate standard deviation for this category
 		var stdDev float64
 		if len(data.Scores) > 1 {
-			sum := 0.0
-			for _, score := range data.Scores {
-				sum += score
-			}
-			mean := sum / float64(len(data.Scores))
-			
-			sumSquareDiff := 0.0
-			for _, score := range data.Scores {
-				diff := score - mean
-				sumSquareDiff += diff * diff
-			}
-			stdDev = math.Sqrt(sumSquareDiff / float64(len(data.Scores)))
+			stdDev = stats.StdDev(data.Scores)
 		}
 		
 		fmt.Printf("%-15s │ %8.3f │ %5d │ %5.3f-%5.3f │ %8.3f\n",
@@ -535,6 +706,10 @@ func (h *VisualizeHandler) displayRangeVisualization(analysis *RangeAnalysis, op
 		fmt.Printf("Column weights: %v\n\n", options.ColumnWeights)
 	}
 
+	if mode, match := matchMode(options.Query, options.Mode, options.Near); match != "" {
+		fmt.Printf("Mode: %s (MATCH %s)\n\n", mode, match)
+	}
+
 	// Display statistics
 	fmt.Printf("Statistical Summary:\n")
 	fmt.Printf("  Range:     %.4f to %.4f (span: %.4f)\n", analysis.Min, analysis.Max, analysis.Max-analysis.Min)
@@ -544,8 +719,7 @@ func (h *VisualizeHandler) displayRangeVisualization(analysis *RangeAnalysis, op
 
 	// Display percentiles
 	fmt.Printf("Percentiles:\n")
-	percentiles := []int{25, 50, 75, 90, 95, 99}
-	for _, p := range percentiles {
+	for _, p := range stats.ReportedPercentiles {
 		if score, ok := analysis.Percentiles[p]; ok {
 			fmt.Printf("  %2dth:     %.4f\n", p, score)
 		}
@@ -607,10 +781,14 @@ func (h *VisualizeHandler) displayRangeVisualization(analysis *RangeAnalysis, op
 		iqr := analysis.Quartiles[2] - analysis.Quartiles[0]
 		fmt.Printf("  IQR:       %.4f\n", iqr)
 		
-		// Outlier boundaries
-		lowerBound := analysis.Quartiles[0] - 1.5*iqr
-		upperBound := analysis.Quartiles[2] + 1.5*iqr
-		fmt.Printf("  Outliers:  < %.4f or > %.4f\n", lowerBound, upperBound)
+		// Outlier boundaries; identical scores leave no fence to fall outside of
+		if analysis.Max == analysis.Min {
+			fmt.Printf("  Outliers:  none (all scores identical)\n")
+		} else {
+			lowerBound := analysis.Quartiles[0] - 1.5*iqr
+			upperBound := analysis.Quartiles[2] + 1.5*iqr
+			fmt.Printf("  Outliers:  < %.4f or > %.4f\n", lowerBound, upperBound)
+		}
 	}
 
 	if config.App.Verbose {
@@ -628,4 +806,499 @@ func (h *VisualizeHandler) displayRangeVisualization(analysis *RangeAnalysis, op
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// sweepTarget is what a parameter sweep scores: one document, or the mean
+// over a result set
+type sweepTarget struct {
+	document *models.SearchResult // Chosen document; nil for the result set mean
+	rank     int                  // Rank of the chosen document among all matches
+	inputs   []*bm25Inputs
+	bm25     float64 // Score (or mean score) that SQLite's bm25() reported
+}
+
+// loadSweepTarget reads the index statistics for every scored result
+func (h *VisualizeHandler) loadSweepTarget(ctx context.Context, target *sweepTarget, results []*models.SearchResult, options models.SearchOptions) error {
+	indexLengths, err := h.db.IndexLengths(ctx)
+	if err != nil {
+		return err
+	}
+
+	target.inputs = make([]*bm25Inputs, len(results))
+	total := 0.0
+	for i, result := range results {
+		if target.inputs[i], err = h.search.loadBM25Inputs(ctx, indexLengths, result, options); err != nil {
+			return err
+		}
+		total += result.Score
+	}
+	target.bm25 = total / float64(len(results))
+	return nil
+}
+
+// score returns the target's score (or mean score) under params
+func (t *sweepTarget) score(params models.BM25Params) float64 {
+	total := 0.0
+	for _, inputs := range t.inputs {
+		total += Search.bm25Score(inputs, params)
+	}
+	return total / float64(len(t.inputs))
+}
+
+// generateSweep scores the target at each value of parameter ("k1" or "b"),
+// holding the other parameter at its FTS5 default
+func (h *VisualizeHandler) generateSweep(target *sweepTarget, parameter string, values []float64) models.ParameterSweep {
+	sweep := models.ParameterSweep{
+		Parameter: parameter,
+		Fixed:     models.DefaultBM25Params,
+		Values:    values,
+		Scores:    make([]float64, len(values)),
+	}
+
+	for i, value := range values {
+		params := models.DefaultBM25Params
+		if parameter == "k1" {
+			params.K1 = value
+		} else {
+			params.B = value
+		}
+		sweep.Scores[i] = target.score(params)
+	}
+	return sweep
+}
+
+// sweepGraphWidth is the plot width of a parameter sweep, in columns
+const sweepGraphWidth = 60
+
+// displayParameterSweeps shows one line chart per sweep, parameter on the x axis
+func (h *VisualizeHandler) displayParameterSweeps(target *sweepTarget, sweeps []models.ParameterSweep, options models.SearchOptions) error {
+	fmt.Printf("BM25 Parameter Sweep for: \"%s\"\n", options.Query)
+	fmt.Printf("=====================================\n\n")
+
+	if len(options.ColumnWeights) > 0 {
+		fmt.Printf("Column weights: %v\n\n", options.ColumnWeights)
+	}
+
+	if mode, match := matchMode(options.Query, options.Mode, options.Near); match != "" {
+		fmt.Printf("Mode: %s (MATCH %s)\n\n", mode, match)
+	}
+
+	if target.document != nil {
+		fmt.Printf("Scoring document %d (rank %d): %s\n", target.document.ID, target.rank, target.document.Title)
+	} else {
+		fmt.Printf("Scoring the mean of the top %d results\n", len(target.inputs))
+	}
+
+	// The sweep recomputes bm25(); at the FTS5 parameters the two should agree
+	defaults := models.DefaultBM25Params
+	recomputed := target.score(defaults)
+	fmt.Printf("At k1 = %.2f, b = %.2f: recomputed %.4f, SQLite bm25() %.4f", defaults.K1, defaults.B, recomputed, target.bm25)
+	if math.Abs(recomputed-target.bm25) < 1e-4 {
+		fmt.Printf(" (matches)\n\n")
+	} else {
+		fmt.Printf(" (differs)\n")
+		fmt.Printf("Every query token is scored as a separate term, so phrases, NEAR groups,\n")
+		fmt.Printf("OR/NOT, prefixes, and column filters are scored differently than by bm25().\n\n")
+	}
+
+	for _, sweep := range sweeps {
+		fixed := fmt.Sprintf("b = %.2f", sweep.Fixed.B)
+		if sweep.Parameter == "b" {
+			fixed = fmt.Sprintf("k1 = %.2f", sweep.Fixed.K1)
+		}
+
+		fmt.Printf("Score vs %s (%s):\n", sweep.Parameter, fixed)
+
+		if len(sweep.Scores) > 1 {
+			graph := asciigraph.Plot(sweep.Scores,
+				asciigraph.Height(12),
+				asciigraph.Width(sweepGraphWidth),
+				asciigraph.Precision(3))
+			fmt.Println(graph)
+			fmt.Println(sweepAxis(graph, sweep.Parameter, sweep.Values))
+			fmt.Printf("\n")
+		}
+
+		fmt.Printf("%8s │ %10s │ %10s\n", sweep.Parameter, "Score", "vs default")
+		fmt.Printf("%s\n", strings.Repeat("-", 34))
+		for i, value := range sweep.Values {
+			fmt.Printf("%8.3f │ %10.4f │ %+10.4f\n", value, sweep.Scores[i], sweep.Scores[i]-recomputed)
+		}
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("Note: Lower scores indicate better relevance (SQLite FTS5 uses negative BM25).\n")
+	fmt.Printf("FTS5 fixes k1 = %.1f and b = %.2f; other values are computed from the index\n", defaults.K1, defaults.B)
+	fmt.Printf("statistics the same way search explain --teach does.\n")
+
+	return nil
+}
+
+// sweepAxis returns an x axis line for a sweep graph, labelling the first,
+// middle, and last of the swept values under the plot area
+func sweepAxis(graph, name string, values []float64) string {
+	// The plot starts one column after the y axis on the first line
+	firstLine, _, _ := strings.Cut(graph, "\n")
+	origin := 0
+	for i, r := range []rune(firstLine) {
+		if r == '┤' || r == '┼' {
+			origin = i + 1
+			break
+		}
+	}
+
+	axis := []rune(strings.Repeat(" ", origin+sweepGraphWidth+8))
+	place := func(column int, label string) {
+		// Keep labels inside the plot area and clear of each other
+		column = min(max(column, origin), origin+sweepGraphWidth-len(label))
+		copy(axis[column:], []rune(label))
+	}
+
+	last := len(values) - 1
+	place(origin, fmt.Sprintf("%s=%g", name, values[0]))
+	if last >= 2 {
+		middle := fmt.Sprintf("%g", values[last/2])
+		place(origin+sweepGraphWidth*(last/2)/last-len(middle)/2, middle)
+	}
+	place(origin+sweepGraphWidth, fmt.Sprintf("%g", values[last]))
+
+	return strings.TrimRight(string(axis), " ")
+}
+
+// generateWeightSweep ranks the matches for options at each weight of field,
+// keeping the other columns at their weights in options (1.0 unless given),
+// and compares the top maxResults with the ranking under options itself. It
+// returns nil when the query matches nothing.
+func (h *VisualizeHandler) generateWeightSweep(ctx context.Context, options models.SearchOptions, field string, weights []float64, maxResults int) (*models.WeightSweep, error) {
+	// Every match is ranked so the top document's rank is known past maxResults
+	options.MaxResults = 0
+
+	baseline, err := h.search.Search(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(baseline) == 0 {
+		return nil, nil
+	}
+	baselineIDs := topIDs(baseline, maxResults)
+
+	sweep := &models.WeightSweep{
+		Query:           options.Query,
+		Mode:            options.Mode,
+		Near:            options.Near,
+		Field:           field,
+		BaselineWeights: options.ColumnWeights,
+		MaxResults:      maxResults,
+		TopDocumentID:   baseline[0].ID,
+		TopTitle:        baseline[0].Title,
+		Steps:           make([]models.WeightStep, len(weights)),
+	}
+
+	for i, weight := range weights {
+		stepOptions := options
+		stepOptions.ColumnWeights = make(map[string]float64, len(options.ColumnWeights)+1)
+		for column, w := range options.ColumnWeights {
+			stepOptions.ColumnWeights[column] = w
+		}
+		stepOptions.ColumnWeights[field] = weight
+
+		results, err := h.search.Search(ctx, stepOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		step := models.WeightStep{
+			Weight:      weight,
+			DocumentIDs: topIDs(results, maxResults),
+		}
+		for rank, result := range results {
+			if result.ID == sweep.TopDocumentID {
+				step.TopDocumentRank = rank + 1
+				break
+			}
+		}
+
+		pairs := experiment.RankPairs(baselineIDs, step.DocumentIDs)
+		step.CommonDocuments = len(pairs)
+		step.KendallTau = experiment.KendallTau(pairs)
+
+		sweep.Steps[i] = step
+	}
+
+	// The ranking is stable from the first step that every later step repeats
+	stable := len(sweep.Steps) - 1
+	for stable > 0 && slices.Equal(sweep.Steps[stable-1].DocumentIDs, sweep.Steps[stable].DocumentIDs) {
+		stable--
+	}
+	if stable < len(sweep.Steps)-1 {
+		sweep.StableFrom = &sweep.Steps[stable].Weight
+	}
+
+	return sweep, nil
+}
+
+// topIDs returns the IDs of the first n results
+func topIDs(results []*models.SearchResult, n int) []int64 {
+	ids := make([]int64, 0, min(n, len(results)))
+	for _, result := range results[:min(n, len(results))] {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+// displayWeightSweep shows the top document's rank and the rank correlation
+// with the baseline at each weight
+func (h *VisualizeHandler) displayWeightSweep(sweep *models.WeightSweep) error {
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sweep)
+
+	case "csv":
+		fmt.Println("field,weight,top_document_rank,kendall_tau,common_documents")
+		for _, step := range sweep.Steps {
+			tau := ""
+			if step.KendallTau != nil {
+				tau = fmt.Sprintf("%.4f", *step.KendallTau)
+			}
+			fmt.Printf("%s,%g,%d,%s,%d\n", sweep.Field, step.Weight, step.TopDocumentRank, tau, step.CommonDocuments)
+		}
+		return nil
+	}
+
+	fmt.Printf("Column Weight Sweep for: \"%s\"\n", sweep.Query)
+	fmt.Printf("=====================================\n\n")
+
+	if len(sweep.BaselineWeights) > 0 {
+		fmt.Printf("Baseline weights: %v\n", sweep.BaselineWeights)
+	}
+	if mode, match := matchMode(sweep.Query, sweep.Mode, sweep.Near); match != "" {
+		fmt.Printf("Mode: %s (MATCH %s)\n", mode, match)
+	}
+	fmt.Printf("Sweeping the %s weight; other columns keep their baseline weights\n", sweep.Field)
+	fmt.Printf("Baseline top document: %d (%s)\n", sweep.TopDocumentID, sweep.TopTitle)
+	fmt.Printf("Rankings compared over the top %d results\n\n", sweep.MaxResults)
+
+	values := make([]float64, len(sweep.Steps))
+	ranks := make([]float64, len(sweep.Steps))
+	taus := make([]float64, len(sweep.Steps))
+	for i, step := range sweep.Steps {
+		values[i] = step.Weight
+		ranks[i] = float64(step.TopDocumentRank)
+		taus[i] = math.NaN()
+		if step.KendallTau != nil {
+			taus[i] = *step.KendallTau
+		}
+	}
+
+	if len(sweep.Steps) > 1 {
+		fmt.Printf("Rank of document %d vs %s weight (lower is better):\n", sweep.TopDocumentID, sweep.Field)
+		graph := asciigraph.Plot(ranks, asciigraph.Height(10), asciigraph.Width(sweepGraphWidth), asciigraph.Precision(0))
+		fmt.Println(graph)
+		fmt.Println(sweepAxis(graph, sweep.Field, values))
+		fmt.Printf("\n")
+
+		if slices.ContainsFunc(taus, func(tau float64) bool { return !math.IsNaN(tau) }) {
+			fmt.Printf("Kendall tau vs baseline ranking:\n")
+			graph = asciigraph.Plot(taus, asciigraph.Height(10), asciigraph.Width(sweepGraphWidth), asciigraph.Precision(2))
+			fmt.Println(graph)
+			fmt.Println(sweepAxis(graph, sweep.Field, values))
+			fmt.Printf("\n")
+		}
+	}
+
+	fmt.Printf("%8s │ %8s │ %11s │ %6s\n", "Weight", "Top rank", "Kendall tau", "Common")
+	fmt.Printf("%s\n", strings.Repeat("-", 44))
+	for _, step := range sweep.Steps {
+		rank := "-"
+		if step.TopDocumentRank > 0 {
+			rank = fmt.Sprintf("%d", step.TopDocumentRank)
+		}
+		tau := "-"
+		if step.KendallTau != nil {
+			tau = fmt.Sprintf("%.4f", *step.KendallTau)
+		}
+		fmt.Printf("%8.2f │ %8s │ %11s │ %6d\n", step.Weight, rank, tau, step.CommonDocuments)
+	}
+	fmt.Printf("\n")
+
+	if sweep.StableFrom != nil {
+		fmt.Printf("The top %d ranking stops changing once the %s weight reaches %g.\n", sweep.MaxResults, sweep.Field, *sweep.StableFrom)
+	} else {
+		fmt.Printf("The top %d ranking is still changing at the end of the range.\n", sweep.MaxResults)
+	}
+
+	return nil
+}
+
+// generateHeatmap computes the mean score per category for each query's
+// results. Categories are ordered by their total result count across all
+// queries, ties in display order, so the columns are the same on every run.
+func (h *VisualizeHandler) generateHeatmap(queries []string, resultSets [][]*models.SearchResult) *models.CategoryHeatmap {
+	totals := make(models.CategoryCounts)
+	for _, results := range resultSets {
+		for _, result := range results {
+			totals[result.Category]++
+		}
+	}
+
+	heatmap := &models.CategoryHeatmap{
+		Categories: totals.SortedBy(config.App.CategoryOrder().Less),
+		Rows:       make([]models.HeatmapRow, len(queries)),
+	}
+
+	for i, results := range resultSets {
+		comparison := h.generateCategoryComparison(results, nil)
+
+		row := models.HeatmapRow{
+			Query:   queries[i],
+			Results: len(results),
+			Cells:   make([]models.HeatmapCell, len(heatmap.Categories)),
+		}
+		for c, category := range heatmap.Categories {
+			if data, ok := comparison[category]; ok {
+				mean := data.AvgScore
+				row.Cells[c] = models.HeatmapCell{Count: data.Count, MeanScore: &mean}
+			}
+		}
+		heatmap.Rows[i] = row
+	}
+
+	return heatmap
+}
+
+// heatmapShades run from the weakest mean score to the strongest
+var heatmapShades = []rune{'░', '▒', '▓', '█'}
+
+// heatmapEmpty marks a category with no results for a query
+const heatmapEmpty = '·'
+
+// shade returns the heatmap character for score within [strongest, weakest].
+// FTS5 scores are negative and more negative is a better match, so the most
+// negative mean gets the darkest shade, as do all cells when every mean is equal.
+func shade(score, strongest, weakest float64) rune {
+	position := stats.Normalize(score, strongest, weakest)
+	level := int(position * float64(len(heatmapShades)))
+	return heatmapShades[min(max(level, 0), len(heatmapShades)-1)]
+}
+
+// heatmapLabelWidth caps the width of the query column
+const heatmapLabelWidth = 30
+
+// displayHeatmap shows the heatmap as a shaded grid followed by the mean scores
+func (h *VisualizeHandler) displayHeatmap(heatmap *models.CategoryHeatmap) error {
+	switch config.App.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(heatmap)
+
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write(append([]string{"query", "results"}, heatmap.Categories...))
+		for _, row := range heatmap.Rows {
+			record := []string{row.Query, strconv.Itoa(row.Results)}
+			for _, cell := range row.Cells {
+				value := ""
+				if cell.MeanScore != nil {
+					value = strconv.FormatFloat(*cell.MeanScore, 'f', 4, 64)
+				}
+				record = append(record, value)
+			}
+			writer.Write(record)
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	fmt.Printf("Category Score Heatmap (%d queries)\n", len(heatmap.Rows))
+	fmt.Printf("=====================================\n\n")
+
+	if len(heatmap.Categories) == 0 {
+		fmt.Println("No results found for any query.")
+		return nil
+	}
+
+	strongest, weakest := math.Inf(1), math.Inf(-1)
+	for _, row := range heatmap.Rows {
+		for _, cell := range row.Cells {
+			if cell.MeanScore != nil {
+				strongest = math.Min(strongest, *cell.MeanScore)
+				weakest = math.Max(weakest, *cell.MeanScore)
+			}
+		}
+	}
+
+	labels := make([]string, len(heatmap.Rows))
+	labelWidth := len("Query")
+	for i, row := range heatmap.Rows {
+		labels[i] = truncateLabel(row.Query, heatmapLabelWidth)
+		labelWidth = max(labelWidth, utf8.RuneCountInString(labels[i]))
+	}
+
+	// Every column is as wide as its category name, and at least wide enough for a score
+	widths := make([]int, len(heatmap.Categories))
+	for c, category := range heatmap.Categories {
+		widths[c] = max(utf8.RuneCountInString(category), len("-00.0000"))
+	}
+
+	printHeader := func() {
+		fmt.Printf("%s", padLabel("Query", labelWidth))
+		for c, category := range heatmap.Categories {
+			fmt.Printf(" │ %s", padLabel(category, widths[c]))
+		}
+		fmt.Printf("\n")
+	}
+
+	printHeader()
+	for i, row := range heatmap.Rows {
+		fmt.Printf("%s", padLabel(labels[i], labelWidth))
+		for c, cell := range row.Cells {
+			char := heatmapEmpty
+			if cell.MeanScore != nil {
+				char = shade(*cell.MeanScore, strongest, weakest)
+			}
+			fmt.Printf(" │ %s", strings.Repeat(string(char), widths[c]))
+		}
+		fmt.Printf("\n")
+	}
+
+	if strongest == weakest {
+		fmt.Printf("\nShading: %c every mean score is %.4f; %c no results\n\n",
+			heatmapShades[len(heatmapShades)-1], strongest, heatmapEmpty)
+	} else {
+		fmt.Printf("\nShading: %c weakest mean score (%.4f) to %c strongest (%.4f); %c no results\n\n",
+			heatmapShades[0], weakest, heatmapShades[len(heatmapShades)-1], strongest, heatmapEmpty)
+	}
+
+	fmt.Printf("Mean scores:\n")
+	printHeader()
+	for i, row := range heatmap.Rows {
+		fmt.Printf("%s", padLabel(labels[i], labelWidth))
+		for c, cell := range row.Cells {
+			value := "-"
+			if cell.MeanScore != nil {
+				value = fmt.Sprintf("%.4f", *cell.MeanScore)
+			}
+			fmt.Printf(" │ %*s", widths[c], value)
+		}
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("\nNote: Lower scores indicate better relevance (SQLite FTS5 uses negative BM25)\n")
+	return nil
+}
+
+// truncateLabel shortens label to at most width runes, marking the cut with an ellipsis
+func truncateLabel(label string, width int) string {
+	runes := []rune(label)
+	if len(runes) <= width {
+		return label
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// padLabel pads label with spaces to width runes
+func padLabel(label string, width int) string {
+	return label + strings.Repeat(" ", max(width-utf8.RuneCountInString(label), 0))
+}
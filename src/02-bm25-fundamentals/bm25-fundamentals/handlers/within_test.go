@@ -0,0 +1,147 @@
+//go:build fts5
+
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/config"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/database"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/flagutil"
+	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/models"
+)
+
+// TestSearchWithinMatchesFilteredRanking refines a search with result files
+// in each format --within reads. The files list more documents than one
+// statement can bind, so the search runs in chunks, and the merged ranking
+// must equal the unrestricted ranking filtered to the listed documents.
+func TestSearchWithinMatchesFilteredRanking(t *testing.T) {
+	db := useTestDatabase(t)
+	search := NewSearchHandler(db)
+	manifest := generateTestCorpus(t, db, 3000, 5, func(options *models.CorpusOptions) {
+		options.Categories = []string{"technology"}
+	})
+	query := commonWord(readContents(t, db))
+	if query == "" {
+		t.Fatal("no word occurs in most documents")
+	}
+
+	options := models.DefaultSearchOptions()
+	options.Query = query
+	options.MaxResults = 0
+	options.IncludeSnippet = false
+	ranking, err := search.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	// A search result file listing the even-numbered matches
+	var even []*models.SearchResult
+	for _, result := range ranking {
+		if result.ID%2 == 0 {
+			even = append(even, result)
+		}
+	}
+	chunkSize := database.MaxParameters - reservedParameters(options)
+	if len(even) <= chunkSize {
+		t.Fatalf("%d listed documents fit one chunk of %d; the test needs several", len(even), chunkSize)
+	}
+
+	dir := t.TempDir()
+	searchOutput := func(format string) string {
+		previous := config.App.Format
+		config.App.Format = format
+		defer func() { config.App.Format = previous }()
+
+		output := captureStdout(t, func() error {
+			return search.displaySearchResults(even, options, time.Millisecond, nil, 0, nil)
+		})
+		path := filepath.Join(dir, "results."+format)
+		if err := os.WriteFile(path, output, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	exportPath := filepath.Join(dir, "corpus.jsonl")
+	file, err := os.Create(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewCorpusHandler(db).ExportDocuments(context.Background(), file, "jsonl")
+	file.Close()
+	if err != nil {
+		t.Fatalf("ExportDocuments: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		count int // Documents the file lists
+	}{
+		{"search json", searchOutput("json"), len(even)},
+		{"search csv", searchOutput("csv"), len(even)},
+		{"corpus export", exportPath, manifest.DocumentCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newSearchCommand(t, "query", query, "within", tt.path, "max-results", "60")
+			refined, err := flagutil.ExtractSearchOptions(cmd)
+			if err != nil {
+				t.Fatalf("ExtractSearchOptions: %v", err)
+			}
+			if len(refined.WithinIDs) != tt.count {
+				t.Fatalf("read %d IDs from %s, want %d", len(refined.WithinIDs), tt.path, tt.count)
+			}
+
+			listed := make(map[int64]bool, len(refined.WithinIDs))
+			for _, id := range refined.WithinIDs {
+				listed[id] = true
+			}
+			var filtered []*models.SearchResult
+			for _, result := range ranking {
+				if listed[result.ID] {
+					filtered = append(filtered, result)
+				}
+			}
+			// SQLite leaves the order of tied scores open; the merge breaks ties by id
+			sort.SliceStable(filtered, func(i, j int) bool {
+				if filtered[i].Score != filtered[j].Score {
+					return filtered[i].Score < filtered[j].Score
+				}
+				return filtered[i].ID < filtered[j].ID
+			})
+
+			for _, offset := range []int{0, 45} {
+				refined.Offset = offset
+				results, err := search.Search(context.Background(), refined)
+				if err != nil {
+					t.Fatalf("Search within %s: %v", tt.path, err)
+				}
+
+				want := filtered[offset:min(offset+refined.MaxResults, len(filtered))]
+				if !slices.EqualFunc(results, want, func(a, b *models.SearchResult) bool {
+					return a.ID == b.ID && a.Score == b.Score
+				}) {
+					t.Errorf("offset %d: refined ranking %v, want the filtered ranking %v", offset, resultIDs(results), resultIDs(want))
+				}
+			}
+		})
+	}
+}
+
+// resultIDs returns the document ID of each result, in order
+func resultIDs(results []*models.SearchResult) []int64 {
+	ids := make([]int64, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
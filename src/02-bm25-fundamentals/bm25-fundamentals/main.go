@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/commands"
+	apperrors "github.com/jaime/go-sqlite/02-bm25-fundamentals/bm25-fundamentals/errors"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -13,6 +15,9 @@ func main() {
 	commands.Root.Init()
 
 	err := commands.Root.Command.Execute()
+	if errors.Is(err, apperrors.ErrCancelled) {
+		os.Exit(130) // Conventional exit status for termination by SIGINT
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -1,13 +1,19 @@
 package models
 
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
 // ScoreAnalysis contains detailed BM25 score analysis results
 type ScoreAnalysis struct {
 	Query           string        `json:"query"`
 	TotalResults    int           `json:"total_results"`
 	ScoreRange      ScoreRange    `json:"score_range"`
-	Distribution    []ScoreBucket `json:"distribution"`
-	TopTerms        []TermFreq    `json:"top_terms"`
-	CategoryBreakdown map[string]CategoryStats `json:"category_breakdown,omitempty"`
+	Distribution    []ScoreBucket `json:"distribution,omitempty"`
+	TopTerms        []TermFreq    `json:"top_terms,omitempty"`
+	CategoryBreakdown CategoryScores `json:"category_breakdown,omitempty"`
 }
 
 // ScoreRange provides statistical information about score distribution
@@ -19,7 +25,9 @@ type ScoreRange struct {
 	StdDev float64 `json:"std_dev"`
 }
 
-// ScoreBucket represents a histogram bucket for score distribution
+// ScoreBucket represents a histogram bucket for score distribution. It covers
+// [Min, Max), except the last bucket, which also holds Max; Label spells out
+// which.
 type ScoreBucket struct {
 	Min   float64 `json:"min"`
 	Max   float64 `json:"max"`
@@ -35,6 +43,40 @@ type TermFreq struct {
 	IDF       float64 `json:"idf"`       // Inverse document frequency
 }
 
+// TermAnalysis lists the terms found in the most documents of the corpus, or
+// of the documents in the filtered categories
+type TermAnalysis struct {
+	IndexDocuments int        `json:"index_documents"` // Indexed documents (N in the IDF)
+	Documents      int        `json:"documents"`       // Documents the terms were counted in
+	Categories     []string   `json:"categories,omitempty"`
+	MinDocCount    int        `json:"min_doc_count"`
+	Limit          int        `json:"limit"`
+	Terms          []TermFreq `json:"terms"`
+}
+
+// LengthImpact relates the scores of a query's results to the lengths of the
+// documents, isolating the effect of BM25 length normalization
+type LengthImpact struct {
+	Query        string         `json:"query"`
+	Results      int            `json:"results"`       // Results analyzed
+	TotalMatches int            `json:"total_matches"` // Every document the query matches
+	Truncated    bool           `json:"truncated"`     // Fewer results were analyzed than matched
+	Pearson      *float64       `json:"pearson"`       // Correlation of length with score; nil below two results or when either is constant
+	Buckets      []LengthBucket `json:"buckets"`       // Shortest documents first
+}
+
+// LengthBucket summarizes the scores of the results whose document length
+// falls between MinLength and MaxLength, inclusive
+type LengthBucket struct {
+	MinLength   int     `json:"min_length"`
+	MaxLength   int     `json:"max_length"`
+	Count       int     `json:"count"`
+	MeanLength  float64 `json:"mean_length"`
+	MeanScore   float64 `json:"mean_score"`
+	MedianScore float64 `json:"median_score"`
+	StdDev      float64 `json:"std_dev"`
+}
+
 // CategoryStats provides category-specific scoring statistics
 type CategoryStats struct {
 	DocumentCount int        `json:"document_count"`
@@ -42,12 +84,105 @@ type CategoryStats struct {
 	ScoreRange    ScoreRange `json:"score_range"`
 }
 
+// CategoryScores maps a category to its scoring statistics. It encodes to JSON
+// as a list ordered by document count, largest first, then by name.
+type CategoryScores map[string]CategoryStats
+
+// categoryScore is the JSON form of one CategoryScores entry
+type categoryScore struct {
+	Category string `json:"category"`
+	CategoryStats
+}
+
+// MarshalJSON encodes the statistics as an ordered list
+func (c CategoryScores) MarshalJSON() ([]byte, error) {
+	counts := make(CategoryCounts, len(c))
+	for category, stats := range c {
+		counts[category] = stats.DocumentCount
+	}
+
+	entries := make([]categoryScore, 0, len(c))
+	for _, category := range counts.Sorted() {
+		entries = append(entries, categoryScore{Category: category, CategoryStats: c[category]})
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON decodes the list written by MarshalJSON
+func (c *CategoryScores) UnmarshalJSON(data []byte) error {
+	var entries []categoryScore
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	*c = make(CategoryScores, len(entries))
+	for _, entry := range entries {
+		(*c)[entry.Category] = entry.CategoryStats
+	}
+	return nil
+}
+
 // SearchComparison compares results from different search strategies
 type SearchComparison struct {
 	Query       string                    `json:"query"`
+	QueryB      string                    `json:"query_b,omitempty"` // Comparison query, when it differs from Query
 	Strategies  map[string]SearchStrategy `json:"strategies"`
+	Order       []string                  `json:"order"`        // Strategy names, baseline first
 	CommonDocs  []SearchResult         `json:"common_docs"`  // Documents in all result sets
+	CommonDocDeltas []ScoreDelta          `json:"common_doc_deltas"` // Score changes against the baseline, largest first within each strategy
 	UniqueDocs  map[string][]SearchResult `json:"unique_docs"` // Documents unique to each strategy
+	Overlaps    []StrategyOverlap         `json:"overlaps"`    // Every pair of strategies, in Order
+	Explanation *ExplanationComparison    `json:"explanation,omitempty"` // Set when a document was chosen for explanation
+}
+
+// ScoreDelta records how a document returned by the baseline and the named comparison
+// strategy scored under each. Delta is ComparisonScore - BaselineScore, so a negative
+// delta means the document scored better (more negative) under the comparison
+// strategy. RankChange is BaselineRank - ComparisonRank, positive when the document
+// moved up.
+type ScoreDelta struct {
+	Strategy        string  `json:"strategy"`
+	DocumentID      int64   `json:"document_id"`
+	Title           string  `json:"title"`
+	BaselineScore   float64 `json:"baseline_score"`
+	ComparisonScore float64 `json:"comparison_score"`
+	Delta           float64 `json:"delta"`
+	BaselineRank    int     `json:"baseline_rank"`
+	ComparisonRank  int     `json:"comparison_rank"`
+	RankChange      int     `json:"rank_change"`
+}
+
+// StrategyOverlap compares the result sets of two strategies by document ID
+type StrategyOverlap struct {
+	First           string  `json:"first"`
+	Second          string  `json:"second"`
+	CommonDocuments int     `json:"common_documents"`
+	FirstOnly       int     `json:"first_only"`
+	SecondOnly      int     `json:"second_only"`
+	Jaccard         float64 `json:"jaccard"` // Common documents over the union of both result sets
+
+	Correlation RankCorrelation `json:"rank_correlation"`
+}
+
+// RankCorrelation measures how far two strategies agree on the order of the documents
+// both returned. The correlations compare the documents' scores, so documents tied on
+// score under a strategy count as tied rather than in whatever order the search listed
+// them; documents only one strategy returned are left out of the correlations and
+// count against the top-k overlap instead.
+type RankCorrelation struct {
+	KendallTau *float64 `json:"kendall_tau"` // Kendall's tau-b; nil below two common documents or when one side is all ties
+	Spearman   *float64 `json:"spearman"`    // Spearman's rho over tie-averaged ranks; nil like KendallTau
+
+	// TopKJaccard is the Jaccard similarity of the first TopK results of each strategy
+	TopK        int     `json:"top_k"`
+	TopKJaccard float64 `json:"top_k_jaccard"`
+}
+
+// ExplanationComparison explains one document's score under both compared strategies
+type ExplanationComparison struct {
+	DocumentID    int64             `json:"document_id"`
+	Baseline      *ScoreExplanation `json:"baseline"`       // nil when the baseline does not match the document
+	Comparison    *ScoreExplanation `json:"comparison"`     // nil when the comparison does not match the document
+	ChangedFields []string          `json:"changed_fields"` // Fields whose contribution differs between strategies
 }
 
 // SearchStrategy represents a specific search configuration and its results
@@ -56,23 +191,56 @@ type SearchStrategy struct {
 	Description string           `json:"description"`
 	Config      StrategyConfig   `json:"config"`
 	Results     []SearchResult `json:"results"`
-	Analysis    ScoreAnalysis    `json:"analysis"`
+	Analysis    *ScoreAnalysis   `json:"analysis,omitempty"` // Set when the comparison is run with --analyze
 }
 
 // StrategyConfig holds configuration for a search strategy
 type StrategyConfig struct {
-	ColumnWeights map[string]float64 `json:"column_weights,omitempty"`
-	MaxResults    int                `json:"max_results"`
-	FieldFilter   string             `json:"field_filter,omitempty"`
+	Query          string             `json:"query"`
+	Mode           string             `json:"mode,omitempty"`
+	Near           int                `json:"near,omitempty"`
+	ColumnWeights  map[string]float64 `json:"column_weights,omitempty"`
+	MaxResults     int                `json:"max_results"`
+	FieldFilter    string             `json:"field_filter,omitempty"`
+	CategoryFilters []string          `json:"category_filters,omitempty"`
+	IncludeSnippet bool               `json:"include_snippet"`
+	SnippetLength  int                `json:"snippet_length,omitempty"`
+	SnippetTokens  int                `json:"snippet_tokens,omitempty"`
+	HighlightStart string             `json:"highlight_start,omitempty"`
+	HighlightEnd   string             `json:"highlight_end,omitempty"`
 }
 
 // ScoreExplanation provides detailed BM25 score breakdown
 type ScoreExplanation struct {
+	Rank          int                      `json:"rank"` // 1-based position in the search results
 	DocumentID    int64                    `json:"document_id"`
 	TotalScore    float64                  `json:"total_score"`
 	FieldScores   map[string]FieldScore    `json:"field_scores"`
 	QueryTerms    []TermScore              `json:"query_terms"`
 	DocumentStats DocumentStats            `json:"document_stats"`
+
+	// RecomputedScore is the score rebuilt from the index statistics, negated
+	// like TotalScore; the two agree for queries made only of plain terms
+	RecomputedScore float64 `json:"recomputed_score"`
+}
+
+// ExplainRecord is a saved search explain run: the explanations together
+// with the options and corpus statistics that produced them. It is written by
+// --save-explain and rendered again by --replay without the database.
+type ExplainRecord struct {
+	Saved        time.Time           `json:"saved"`
+	Options      SearchOptions       `json:"options"`
+	Corpus       CorpusSnapshot      `json:"corpus"`
+	Explanations []*ScoreExplanation `json:"explanations"`
+}
+
+// CorpusSnapshot records the index statistics scores were computed against
+type CorpusSnapshot struct {
+	Documents       int                `json:"documents"`         // Indexed documents (N in the IDF)
+	AvgLength       float64            `json:"avg_length"`        // Average document length in tokens (avgdl)
+	FieldAvgLengths map[string]float64 `json:"field_avg_lengths"` // Average length of each indexed column
+	Tokenizer       string             `json:"tokenizer"`         // FTS5 tokenize option of the index
+	BM25            BM25Params         `json:"bm25"`
 }
 
 // FieldScore breaks down scoring by field (title, content, category)
@@ -85,10 +253,12 @@ type FieldScore struct {
 // TermScore provides per-term scoring details
 type TermScore struct {
 	Term       string  `json:"term"`
-	TF         float64 `json:"tf"`          // Term frequency in document
-	IDF        float64 `json:"idf"`         // Inverse document frequency
-	FieldTF    float64 `json:"field_tf"`    // Term frequency in specific field
-	Score      float64 `json:"score"`       // Final BM25 score for this term
+	Indexed    string  `json:"indexed"`     // Term as the tokenizer indexed it (case-folded and stemmed)
+	Documents  int     `json:"documents"`   // Indexed documents containing the term (n in the IDF)
+	TF         float64 `json:"tf"`          // Weighted term frequency in document (or field)
+	IDF        float64 `json:"idf"`         // Inverse document frequency, as FTS5 computes it
+	FieldTF    float64 `json:"field_tf"`    // Unweighted occurrences in the document (or field)
+	Score      float64 `json:"score"`       // Term's (positive) contribution to the BM25 sum
 }
 
 // DocumentStats provides document-level statistics affecting BM25
@@ -97,6 +267,7 @@ type DocumentStats struct {
 	AvgLength     float64 `json:"avg_length"`     // Average document length in corpus
 	LengthNorm    float64 `json:"length_norm"`    // BM25 length normalization factor
 	FieldLengths  map[string]int `json:"field_lengths"` // Length of each field
+	FieldAvgLengths map[string]float64 `json:"field_avg_lengths"` // Corpus average length of each field
 }
 
 // ScoreDistribution provides percentile analysis
@@ -104,6 +275,105 @@ type ScoreDistribution struct {
 	Mean       float64            `json:"mean"`
 	Median     float64            `json:"median"`
 	StdDev     float64            `json:"std_dev"`
-	Percentiles map[int]float64   `json:"percentiles"` // 25th, 50th, 75th, 90th, 95th, 99th
-	Buckets    []ScoreBucket     `json:"buckets"`
-}
\ No newline at end of file
+	Percentiles Percentiles       `json:"percentiles,omitempty"` // 25th, 50th, 75th, 90th, 95th, 99th
+	Buckets    []ScoreBucket     `json:"buckets,omitempty"`
+}
+
+// Percentiles maps a percentile (25 for the 25th) to its score. It encodes to
+// JSON as a list in ascending percentile order.
+type Percentiles map[int]float64
+
+// percentileScore is the JSON form of one Percentiles entry
+type percentileScore struct {
+	Percentile int     `json:"percentile"`
+	Score      float64 `json:"score"`
+}
+
+// MarshalJSON encodes the percentiles as an ordered list
+func (p Percentiles) MarshalJSON() ([]byte, error) {
+	keys := make([]int, 0, len(p))
+	for percentile := range p {
+		keys = append(keys, percentile)
+	}
+	sort.Ints(keys)
+
+	entries := make([]percentileScore, 0, len(p))
+	for _, percentile := range keys {
+		entries = append(entries, percentileScore{Percentile: percentile, Score: p[percentile]})
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON decodes the list written by MarshalJSON
+func (p *Percentiles) UnmarshalJSON(data []byte) error {
+	var entries []percentileScore
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	*p = make(Percentiles, len(entries))
+	for _, entry := range entries {
+		(*p)[entry.Percentile] = entry.Score
+	}
+	return nil
+}
+// BM25Params are the free parameters of BM25. SQLite's bm25() always uses
+// DefaultBM25Params; other values can only be scored by recomputing BM25 from
+// the index statistics.
+type BM25Params struct {
+	K1 float64 `json:"k1"` // Term frequency saturation
+	B  float64 `json:"b"`  // Document length normalization
+}
+
+// DefaultBM25Params are the parameters compiled into FTS5's bm25()
+var DefaultBM25Params = BM25Params{K1: 1.2, B: 0.75}
+
+// ParameterSweep records how a score changes as one BM25 parameter varies and
+// the other stays at its default
+type ParameterSweep struct {
+	Parameter string     `json:"parameter"` // "k1" or "b"
+	Fixed     BM25Params `json:"fixed"`     // Parameters in effect apart from the swept one
+	Values    []float64  `json:"values"`
+	Scores    []float64  `json:"scores"`
+}
+
+// WeightSweep records how a ranking changes as one column's weight varies
+type WeightSweep struct {
+	Query           string             `json:"query"`
+	Mode            string             `json:"mode,omitempty"`
+	Near            int                `json:"near,omitempty"`
+	Field           string             `json:"field"`
+	BaselineWeights map[string]float64 `json:"baseline_weights,omitempty"`
+	MaxResults      int                `json:"max_results"`     // Ranking depth compared at each step
+	TopDocumentID   int64              `json:"top_document_id"` // Baseline's first result
+	TopTitle        string             `json:"top_title"`
+	Steps           []WeightStep       `json:"steps"`
+	StableFrom      *float64           `json:"stable_from,omitempty"` // Weight after which the ranking no longer changes; nil if it changes at the last step
+}
+
+// WeightStep is the ranking at one weight of a WeightSweep
+type WeightStep struct {
+	Weight          float64  `json:"weight"`
+	TopDocumentRank int      `json:"top_document_rank"` // Rank of the baseline's first result among all matches; 0 when unmatched
+	CommonDocuments int      `json:"common_documents"`
+	KendallTau      *float64 `json:"kendall_tau,omitempty"` // Against the baseline over common documents; nil below two
+	DocumentIDs     []int64  `json:"document_ids"`
+}
+
+// CategoryHeatmap holds the mean score of each category for each query of a workload
+type CategoryHeatmap struct {
+	Categories []string     `json:"categories"` // Column order, by total result count
+	Rows       []HeatmapRow `json:"rows"`       // One per query, in the order given
+}
+
+// HeatmapRow is one query's row of a CategoryHeatmap
+type HeatmapRow struct {
+	Query   string        `json:"query"`
+	Results int           `json:"results"`
+	Cells   []HeatmapCell `json:"cells"` // One per category, in Categories order
+}
+
+// HeatmapCell is the score summary of one category's results for one query
+type HeatmapCell struct {
+	Count     int      `json:"count"`
+	MeanScore *float64 `json:"mean_score"` // nil when no result falls in the category
+}
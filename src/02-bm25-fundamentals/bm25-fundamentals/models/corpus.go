@@ -12,13 +12,38 @@ type CorpusStats struct {
 	MedianDocLength   float64   `json:"median_doc_length"`
 	MinDocLength      int       `json:"min_doc_length"`
 	MaxDocLength      int       `json:"max_doc_length"`
-	UniqueTerms       int       `json:"unique_terms"`
+	UniqueTerms       int       `json:"unique_terms"`   // Distinct indexed terms, after stemming
+	TotalPostings     int64     `json:"total_postings"` // (term, document) pairs in the index
 	Categories        []string  `json:"categories"`
 	CategoryCounts    map[string]int `json:"category_counts"`
+	PerColumn         map[string]ColumnStats `json:"per_column,omitempty"` // Keyed by FTS5 column name
 	CreatedRange      TimeRange `json:"created_range"`
 	LastUpdated       time.Time `json:"last_updated"`
 }
 
+// ColumnStats describes one indexed column, counted from the FTS5 docsize table
+type ColumnStats struct {
+	Documents   int     `json:"documents"`    // Documents with at least one token in the column
+	TotalTokens int64   `json:"total_tokens"`
+	AvgLength   float64 `json:"avg_length"`   // Mean tokens per indexed document
+}
+
+// CorpusFingerprint identifies the exact corpus state results were produced
+// from: a SHA-256 over the schema and every document's id, title, content,
+// and category
+type CorpusFingerprint struct {
+	SHA256    string `json:"sha256"`
+	Documents int    `json:"documents"`
+}
+
+// CategorySummary describes the documents in one category
+type CategorySummary struct {
+	Name          string    `json:"name"`
+	Documents     int       `json:"documents"`
+	AverageLength float64   `json:"average_length"` // Mean document length in tokens
+	CreatedRange  TimeRange `json:"created_range"`  // Zero when no document has a readable timestamp
+}
+
 // TimeRange represents a time span
 type TimeRange struct {
 	Start time.Time `json:"start"`
@@ -34,6 +59,62 @@ type CorpusOptions struct {
 	TitleMinTokens int      `json:"title_min_tokens"`
 	TitleMaxTokens int      `json:"title_max_tokens"`
 	Seed           int64    `json:"seed,omitempty"` // For reproducible generation
+	Style          string   `json:"style"`          // "words" (word salad) or "sentences" (template sentences)
+
+	// Term injection: fraction of documents that receive each term (0 = disabled)
+	TermRates map[string]float64 `json:"term_rates,omitempty"`
+
+	// Phrase injection: each phrase is placed in exactly Count documents
+	Injections []PhraseInjection `json:"injections,omitempty"`
+
+	// Duplicate injection (fractions of Size, 0 = disabled)
+	DuplicateRate     float64 `json:"duplicate_rate,omitempty"`      // Exact copies of earlier documents
+	NearDuplicateRate float64 `json:"near_duplicate_rate,omitempty"` // Copies with a few substituted words
+
+	// Workers is the number of generator goroutines (0 = one per CPU); it never
+	// changes the generated corpus, so it is not recorded in manifests
+	Workers int `json:"-"`
+
+	// DeferIndex indexes the documents in one pass after they are all
+	// inserted instead of row by row; like Workers it is not recorded
+	DeferIndex bool `json:"-"`
+}
+
+// CorpusManifest records how a corpus was generated so experiments can be reproduced
+type CorpusManifest struct {
+	Options       CorpusOptions     `json:"options"`
+	Generated     time.Time         `json:"generated"`
+	BaseTime      time.Time         `json:"base_time"` // Seed-derived clock that created timestamps count back from
+	DocumentCount int               `json:"document_count"`
+	Duplicates    []DuplicateRecord `json:"duplicates,omitempty"`
+	Injections    []InjectionRecord `json:"injections,omitempty"`
+}
+
+// PhraseInjection requests that a phrase appear in exactly Count generated documents
+type PhraseInjection struct {
+	Phrase string `json:"phrase"`
+	Count  int    `json:"count"`
+}
+
+// InjectionRecord lists the documents that received an injected phrase (ground truth for evaluation)
+type InjectionRecord struct {
+	Phrase string  `json:"phrase"`
+	IDs    []int64 `json:"ids"`
+}
+
+// DuplicateRecord identifies an injected duplicate and the document it was copied from
+type DuplicateRecord struct {
+	ID       int64  `json:"id"`
+	SourceID int64  `json:"source_id"`
+	Kind     string `json:"kind"` // "exact" or "near"
+}
+
+// ImportSummary reports the outcome of importing documents from a file
+type ImportSummary struct {
+	Format   string  `json:"format"`   // "json" for an array, "jsonl" for one document per line
+	Valid    int     `json:"valid"`    // Records that passed validation
+	Inserted int     `json:"inserted"` // Documents written to the corpus (0 on a dry run)
+	Skipped  []error `json:"-"`        // Validation failure of each skipped record, in file order
 }
 
 // DefaultCorpusOptions returns sensible default options
@@ -46,5 +127,18 @@ func DefaultCorpusOptions() CorpusOptions {
 		TitleMinTokens: 2,
 		TitleMaxTokens: 8,
 		Seed:           0, // 0 means use current time
+		Style:          "words",
+	}
+}
+
+// DuplicateCounts returns the number of exact and near duplicates recorded in the manifest
+func (m *CorpusManifest) DuplicateCounts() (exact, near int) {
+	for _, dup := range m.Duplicates {
+		if dup.Kind == "near" {
+			near++
+		} else {
+			exact++
+		}
 	}
-}
\ No newline at end of file
+	return exact, near
+}
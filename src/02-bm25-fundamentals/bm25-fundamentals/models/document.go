@@ -4,16 +4,31 @@ import (
 	"time"
 )
 
+// DefaultCategory is the category of a document stored without one
+const DefaultCategory = "general"
+
 // Document represents a document in the corpus
 type Document struct {
 	ID       int64     `json:"id" db:"id"`
 	Title    string    `json:"title" db:"title"`
-	Content  string    `json:"content" db:"content"`
+	Content  string    `json:"content,omitempty" db:"content"`
 	Category string    `json:"category" db:"category"`
 	Length   int       `json:"length" db:"length"`         // Document length in tokens
 	Created  time.Time `json:"created" db:"created"`
 }
 
+// ApplyDefaults fills the fields a document may arrive without, as in an
+// imported file: an empty Category becomes DefaultCategory, and a zero Created
+// becomes now, the time the document is stored.
+func (d *Document) ApplyDefaults(now time.Time) {
+	if d.Category == "" {
+		d.Category = DefaultCategory
+	}
+	if d.Created.IsZero() {
+		d.Created = now
+	}
+}
+
 // DocumentInfo provides metadata about a document for analysis
 type DocumentInfo struct {
 	ID            int64     `json:"id"`
@@ -23,4 +38,23 @@ type DocumentInfo struct {
 	UniqueTerms   int       `json:"unique_terms"`
 	AvgTermLength float64   `json:"avg_term_length"`
 	Created       time.Time `json:"created"`
-}
\ No newline at end of file
+}
+// DocumentAnalysis breaks down the length statistics bm25 normalizes a
+// document's scores by
+type DocumentAnalysis struct {
+	Info            DocumentInfo  `json:"info"`
+	Fields          []FieldLength `json:"fields"`            // One per indexed column, in schema order
+	StoredLength    int           `json:"stored_length"`     // The documents.length column, which corpus recount keeps in step with the index
+	CorpusAvgLength float64       `json:"corpus_avg_length"` // Average document length in tokens (avgdl)
+	LengthRatio     float64       `json:"length_ratio"`      // TokenCount / avgdl
+	LengthNorm      float64       `json:"length_norm"`       // k1 × ((1 - b) + b × |d| / avgdl)
+	BM25            BM25Params    `json:"bm25"`
+}
+
+// FieldLength is one indexed column's share of a document's length
+type FieldLength struct {
+	Field           string  `json:"field"`
+	Tokens          int     `json:"tokens"`
+	UniqueTerms     int     `json:"unique_terms"`
+	CorpusAvgLength float64 `json:"corpus_avg_length"` // Average length of the column across the corpus
+}
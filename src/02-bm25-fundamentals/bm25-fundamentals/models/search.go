@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"sort"
 	"time"
 )
 
@@ -9,26 +11,121 @@ type SearchResult struct {
 	Document
 	Score     float64 `json:"score"`
 	Snippet   string  `json:"snippet,omitempty"`
+
+	// Highlighted is the content with matched terms marked, set by search query --highlight
+	Highlighted string `json:"highlighted,omitempty"`
+
+	// Sample places the result in the full ranking, set by search query --sample
+	Sample *SamplePosition `json:"sample,omitempty"`
 	Relevance string  `json:"relevance,omitempty"` // "excellent", "good", "fair", "poor"
+
+	// FieldShares is each column's share of the score, set by search query --explain-inline
+	FieldShares map[string]float64 `json:"field_shares,omitempty"`
+}
+
+// SamplePosition is where a sampled result ranks among every match
+type SamplePosition struct {
+	Rank       int     `json:"rank"`       // 1-based rank in the full ranking
+	Matches    int     `json:"matches"`    // Number of documents the query matched
+	Percentile float64 `json:"percentile"` // Share of the other matches ranked below: 100 for the best, 0 for the worst
 }
 
 // SearchOptions holds parameters for search queries
 type SearchOptions struct {
 	Query          string            `json:"query"`
+	Mode           string            `json:"mode,omitempty"` // How Query becomes a MATCH expression; empty uses FTS5 query syntax
+	Near           int               `json:"near,omitempty"` // Match the query terms within this many tokens of each other as one NEAR group; 0 = off
 	MaxResults     int               `json:"max_results"`
 	ColumnWeights  map[string]float64 `json:"column_weights,omitempty"`
-	CategoryFilter string            `json:"category_filter,omitempty"`
+	CategoryFilters []string         `json:"category_filters,omitempty"` // Match documents in any of these categories; empty matches every category
+	CreatedAfter   time.Time         `json:"created_after,omitzero"`  // Match documents created at or after this time; zero = no lower bound
+	CreatedBefore  time.Time         `json:"created_before,omitzero"` // Match documents created at or before this time; zero = no upper bound
+	MinScore       *float64          `json:"min_score,omitempty"` // Drop matches scoring worse (closer to zero) than this; nil keeps every match
+	SortBy         string            `json:"sort_by,omitempty"`   // Result order: one of SortKeys; empty sorts by score
+	SortDesc       bool              `json:"sort_desc,omitempty"` // Reverse SortBy: worst score, newest, longest, or Z to A first
 	IncludeSnippet bool              `json:"include_snippet"`
+	IncludeContent bool              `json:"include_content"` // false leaves Content empty
 	SnippetLength  int               `json:"snippet_length"`
+	SnippetTokens  int               `json:"snippet_tokens,omitempty"` // Overrides SnippetLength; 0 uses display.snippet_tokens
 	ExplainScores  bool              `json:"explain_scores"`
+	ExplainInline  bool              `json:"-"` // Display only: add field shares to text results
+	Highlight      bool              `json:"-"` // Select the content with matched terms marked by FTS5 highlight()
+	Sample         int               `json:"-"` // Return this many results spread across the ranking instead of the top MaxResults
+	Offset         int               `json:"-"` // Skip this many of the best matches before the MaxResults returned, for paging
+	Truncate       int               `json:"-"` // Display only: cut text fields to this many characters; 0 uses display.max_field_chars, negative never cuts
+
+	// SkipCategoryCheck searches CategoryFilters even when the corpus lacks
+	// one of them, instead of failing with the closest names
+	SkipCategoryCheck bool `json:"-"`
+
+	// HighlightStart and HighlightEnd surround matched terms in snippets;
+	// empty values use display.highlight_start and display.highlight_end
+	HighlightStart string `json:"highlight_start,omitempty"`
+	HighlightEnd   string `json:"highlight_end,omitempty"`
+
+	// Within names a result file from an earlier search; the search is then
+	// restricted to the documents it lists, whose IDs are read into WithinIDs.
+	// A nil WithinIDs searches every document.
+	Within    string  `json:"within,omitempty"`
+	WithinIDs []int64 `json:"-"`
 }
 
+// Query modes select how SearchOptions.Query is turned into an FTS5 MATCH
+// expression; an empty mode passes the query through as FTS5 query syntax
+const (
+	QueryModeAny    = "any"    // Documents with any of the terms
+	QueryModeAll    = "all"    // Documents with every term
+	QueryModePhrase = "phrase" // Documents with the terms in order as one phrase
+	QueryModePrefix = "prefix" // Documents with a term starting with each query term
+)
+
+// QueryModes lists the query modes in the order help text shows them
+var QueryModes = []string{QueryModeAny, QueryModeAll, QueryModePhrase, QueryModePrefix}
+
+// Sort keys select the order of search results. Each sorts ascending unless
+// SearchOptions.SortDesc is set; BM25 scores are negative, so ascending score
+// puts the best match first.
+const (
+	SortScore   = "score"   // BM25 score, best first
+	SortCreated = "created" // Creation time, oldest first
+	SortLength  = "length"  // Document length in tokens, shortest first
+	SortTitle   = "title"   // Title, in byte order
+)
+
+// SortKeys lists the sort keys in the order help text shows them
+var SortKeys = []string{SortScore, SortCreated, SortLength, SortTitle}
+
+// ResultPage places a page of search results within all matches
+type ResultPage struct {
+	Number int `json:"page"`      // 1-based page number
+	Size   int `json:"page_size"` // Results per page
+	Total  int `json:"total"`     // Documents matching the query
+}
+
+// First returns the 1-based rank of the first result on the page
+func (p *ResultPage) First() int {
+	return (p.Number-1)*p.Size + 1
+}
+
+// Pages returns the number of pages the matches fill
+func (p *ResultPage) Pages() int {
+	if p.Size <= 0 {
+		return 0
+	}
+	return (p.Total + p.Size - 1) / p.Size
+}
+
+// MaxSnippetTokens is the largest snippet, in tokens, the FTS5 snippet()
+// function builds
+const MaxSnippetTokens = 64
+
 // DefaultSearchOptions returns sensible defaults for search
 func DefaultSearchOptions() SearchOptions {
 	return SearchOptions{
 		MaxResults:     20,
 		ColumnWeights:  nil, // Use FTS5 defaults
 		IncludeSnippet: true,
+		IncludeContent: true,
 		SnippetLength:  200,
 		ExplainScores:  false,
 	}
@@ -37,11 +134,83 @@ func DefaultSearchOptions() SearchOptions {
 // SearchStats provides statistics about search results
 type SearchStats struct {
 	Query           string        `json:"query"`
+	Mode            string        `json:"mode,omitempty"`
+	Near            int           `json:"near,omitempty"`
+	CreatedAfter    time.Time     `json:"created_after,omitzero"`
+	CreatedBefore   time.Time     `json:"created_before,omitzero"`
 	TotalResults    int           `json:"total_results"`
+	MinScore        *float64      `json:"min_score,omitempty"`
+	ExcludedByThreshold int       `json:"excluded_by_threshold"` // Matches MinScore dropped
+	TotalMatches    int           `json:"total_matches"` // Documents the query matched; above TotalResults when Truncated
+	Truncated       bool          `json:"truncated"`     // Only the best TotalResults matches were analyzed
 	ExecutionTime   time.Duration `json:"execution_time"`
 	ScoreRange      ScoreRange    `json:"score_range"`
 	ScoreDistrib    ScoreDistribution `json:"score_distribution"`
-	CategoryBreakdown map[string]int `json:"category_breakdown"`
+	CategoryBreakdown CategoryCounts `json:"category_breakdown,omitempty"`
+}
+
+// CategoryCounts maps a category to a number of documents. It encodes to JSON
+// as a list in Sorted order, so the largest categories come first and repeated
+// runs serialize identically.
+type CategoryCounts map[string]int
+
+// categoryCount is the JSON form of one CategoryCounts entry
+type categoryCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// Sorted returns the categories by count, largest first, then by name
+func (c CategoryCounts) Sorted() []string {
+	return c.SortedBy(func(a, b string) bool { return a < b })
+}
+
+// SortedBy returns the categories by count, largest first, then by name in
+// the order given by less
+func (c CategoryCounts) SortedBy(less func(a, b string) bool) []string {
+	categories := make([]string, 0, len(c))
+	for category := range c {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if c[categories[i]] != c[categories[j]] {
+			return c[categories[i]] > c[categories[j]]
+		}
+		return less(categories[i], categories[j])
+	})
+	return categories
 }
 
+// MarshalJSON encodes the counts as an ordered list
+func (c CategoryCounts) MarshalJSON() ([]byte, error) {
+	entries := make([]categoryCount, 0, len(c))
+	for _, category := range c.Sorted() {
+		entries = append(entries, categoryCount{Category: category, Count: c[category]})
+	}
+	return json.Marshal(entries)
+}
 
+// UnmarshalJSON decodes the list written by MarshalJSON
+func (c *CategoryCounts) UnmarshalJSON(data []byte) error {
+	var entries []categoryCount
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	*c = make(CategoryCounts, len(entries))
+	for _, entry := range entries {
+		(*c)[entry.Category] = entry.Count
+	}
+	return nil
+}
+
+// QueryEvaluation measures how well a query retrieves a known set of relevant documents
+type QueryEvaluation struct {
+	Query             string  `json:"query"`
+	Relevant          int     `json:"relevant"`           // Size of the ground-truth set
+	Retrieved         int     `json:"retrieved"`          // Number of results returned
+	RelevantRetrieved int     `json:"relevant_retrieved"` // Results that are in the ground-truth set
+	Precision         float64 `json:"precision"`
+	Recall            float64 `json:"recall"`
+	RPrecision        float64 `json:"r_precision"`       // Precision at rank R (R = relevant count)
+	AveragePrecision  float64 `json:"average_precision"` // Mean precision at each relevant hit
+}
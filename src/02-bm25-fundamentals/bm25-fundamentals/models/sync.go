@@ -0,0 +1,18 @@
+package models
+
+// SyncReport describes whether the FTS5 index matches the documents table
+type SyncReport struct {
+	DocumentCount  int     `json:"document_count"`            // Rows in the documents table
+	IndexedCount   int     `json:"indexed_count"`             // Rows present in the FTS5 index
+	MissingCount   int     `json:"missing_count"`             // Documents that are not indexed
+	OrphanedCount  int     `json:"orphaned_count"`            // Indexed rows with no document
+	MissingIDs     []int64 `json:"missing_ids,omitempty"`     // Bounded sample of missing rowids
+	OrphanedIDs    []int64 `json:"orphaned_ids,omitempty"`    // Bounded sample of orphaned rowids
+	IntegrityOK    bool    `json:"integrity_ok"`              // Result of the FTS5 integrity-check
+	IntegrityError string  `json:"integrity_error,omitempty"` // Message reported by a failed integrity-check
+}
+
+// InSync reports whether the index and documents table agree
+func (r *SyncReport) InSync() bool {
+	return r.MissingCount == 0 && r.OrphanedCount == 0 && r.IntegrityOK
+}
@@ -0,0 +1,61 @@
+// Package numfmt formats counts and scores for reports read by people. Text,
+// markdown, and HTML output group digits and place the decimal separator the
+// way a locale expects ("4,812.5" in English, "4.812,5" in German); JSON and
+// CSV keep plain numbers so scripts can parse them.
+package numfmt
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Format formats numbers for a locale. The zero Format (no locale) writes
+// plain numbers as fmt does, with no digit grouping.
+type Format struct {
+	printer *message.Printer
+}
+
+// New returns the format for locale, a BCP 47 tag such as "en" or "de".
+// An empty locale gives plain numbers.
+func New(locale string) (*Format, error) {
+	if locale == "" {
+		return &Format{}, nil
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, err
+	}
+	return &Format{printer: message.NewPrinter(tag)}, nil
+}
+
+// Int formats a count, e.g. "4,812"
+func (f *Format) Int(n int) string {
+	return f.Int64(int64(n))
+}
+
+// Int64 formats a count that may exceed an int, such as a token total
+func (f *Format) Int64(n int64) string {
+	if f == nil || f.printer == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	return f.printer.Sprintf("%d", n)
+}
+
+// Float formats a value with precision digits after the decimal separator,
+// e.g. "-1,234.5679" for a precision of 4
+func (f *Format) Float(value float64, precision int) string {
+	if f == nil || f.printer == nil {
+		return strconv.FormatFloat(value, 'f', precision, 64)
+	}
+	// The printer does not take the precision as an argument (%.*f)
+	return f.printer.Sprintf(fmt.Sprintf("%%.%df", precision), value)
+}
+
+// Percent formats a share out of 100 with one decimal, e.g. "12.5%"
+func (f *Format) Percent(value float64) string {
+	return f.Float(value, 1) + "%"
+}
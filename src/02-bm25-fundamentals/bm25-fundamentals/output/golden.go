@@ -0,0 +1,139 @@
+// Package output scrubs command output for golden files. With --format golden
+// a command renders its text output, and everything it writes to stdout passes
+// through a Scrubber that replaces the values that change between runs, so the
+// output of a seeded corpus can be embedded in documentation and diffed.
+package output
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholders written in place of run-specific values
+const (
+	TimePlaceholder     = "<time>"
+	DatePlaceholder     = "<date>"
+	DatabasePlaceholder = "<database>"
+	TempPlaceholder     = "<tmp>"
+	HomePlaceholder     = "~"
+)
+
+var (
+	// timestampPattern matches dates with a time of day, in the layouts the
+	// commands print and go-sqlite3 stores: "2006-01-02 15:04",
+	// "2006-01-02T15:04:05Z", "2006-01-02 15:04:05.999 -0700 MST", and so on
+	timestampPattern = regexp.MustCompile(
+		`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?(?: [+-]\d{4}(?: [A-Z]{2,5})?)?`)
+
+	// datePattern matches a bare date such as a created range in corpus stats
+	datePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+
+	// durationPattern matches time.Duration strings. Whole seconds without a
+	// fraction ("3s") are left alone so text such as "1990s" survives; measured
+	// durations carry a fraction or a sub-second unit.
+	durationPattern = regexp.MustCompile(
+		`\b(?:\d+h)?(?:\d+m)?\d+(?:\.\d+)?(?:ns|µs|us|ms)\b|\b(?:\d+h)?(?:\d+m)?\d+\.\d+s\b|\b(?:\d+h)?\d+m\d+(?:\.\d+)?s\b`)
+)
+
+// Scrubber replaces run-specific values in output lines
+type Scrubber struct {
+	// literals are replaced longest first, so a database path inside the
+	// temporary directory becomes <database> rather than <tmp>/...
+	literals []literal
+}
+
+// literal is one fixed string and its placeholder
+type literal struct {
+	value       string
+	placeholder string
+}
+
+// NewScrubber returns a Scrubber that replaces each key of literals (such as
+// a database path) with its value, and timestamps and durations with
+// DatePlaceholder and TimePlaceholder. Empty keys are ignored.
+func NewScrubber(literals map[string]string) *Scrubber {
+	s := &Scrubber{}
+	for value, placeholder := range literals {
+		if value != "" {
+			s.literals = append(s.literals, literal{value: value, placeholder: placeholder})
+		}
+	}
+	sort.Slice(s.literals, func(i, j int) bool {
+		if len(s.literals[i].value) != len(s.literals[j].value) {
+			return len(s.literals[i].value) > len(s.literals[j].value)
+		}
+		return s.literals[i].value < s.literals[j].value
+	})
+	return s
+}
+
+// Line scrubs one line of output
+func (s *Scrubber) Line(line string) string {
+	for _, l := range s.literals {
+		line = strings.ReplaceAll(line, l.value, l.placeholder)
+	}
+	line = timestampPattern.ReplaceAllString(line, DatePlaceholder)
+	line = datePattern.ReplaceAllString(line, DatePlaceholder)
+	return durationPattern.ReplaceAllString(line, TimePlaceholder)
+}
+
+// capture is a stdout redirection installed by Capture
+type capture struct {
+	stdout *os.File      // The real stdout, restored by Close
+	writer *os.File      // Write end of the pipe standing in for stdout
+	done   chan struct{} // Closed once every line has been scrubbed and written
+}
+
+// active is the redirection in place, if any
+var active *capture
+
+// Capture redirects os.Stdout through s until Close. Output is scrubbed line
+// by line as it is written, so long-running commands still stream.
+func Capture(s *Scrubber) error {
+	if active != nil {
+		return nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	c := &capture{stdout: os.Stdout, writer: writer, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		defer reader.Close()
+
+		buffered := bufio.NewReader(reader)
+		for {
+			line, err := buffered.ReadString('\n')
+			if line != "" {
+				io.WriteString(c.stdout, s.Line(line))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	os.Stdout = writer
+	active = c
+	return nil
+}
+
+// Close restores os.Stdout and waits for the captured output to be written.
+// It does nothing when no capture is active.
+func Close() {
+	if active == nil {
+		return
+	}
+
+	os.Stdout = active.stdout
+	active.writer.Close()
+	<-active.done
+	active = nil
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"cmp"
+	"math"
+	"sort"
+)
+
+// Ranks returns the 1-based ascending rank of each value. Tied values share
+// the average of the ranks they span, so two scores tied for second both get
+// rank 2.5.
+func Ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] < values[order[b]]
+	})
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		// Positions start..end-1 hold ranks start+1..end
+		average := float64(start+1+end) / 2
+		for _, index := range order[start:end] {
+			ranks[index] = average
+		}
+		start = end
+	}
+	return ranks
+}
+
+// Pearson computes the Pearson correlation of paired values: their covariance
+// over the product of their standard deviations. It is nil for fewer than two
+// pairs, or when every value on one side is equal and the correlation is
+// undefined.
+func Pearson(x, y []float64) *float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return nil
+	}
+
+	meanX, meanY := Mean(x), Mean(y)
+
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return nil
+	}
+
+	r := covariance / math.Sqrt(varianceX*varianceY)
+	return &r
+}
+
+// Spearman computes Spearman's rho of paired values: the Pearson correlation
+// of their ranks, with tied values sharing their average rank. It is nil for
+// fewer than two pairs, or when every value on one side ties and the
+// correlation is undefined.
+func Spearman(x, y []float64) *float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return nil
+	}
+	return Pearson(Ranks(x), Ranks(y))
+}
+
+// KendallTau computes Kendall's tau-b of paired values: concordant minus
+// discordant pairs over the pairs that are not tied. A pair tied on either
+// side counts as neither, and the denominator discounts the ties on each side
+// separately, so a ranking compared with itself is 1 even when it has ties.
+// It is nil for fewer than two pairs, or when every value on one side ties.
+func KendallTau(x, y []float64) *float64 {
+	n := len(x)
+	if n != len(y) || n < 2 {
+		return nil
+	}
+
+	balance, untiedX, untiedY := 0, 0, 0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			dx := cmp.Compare(x[i], x[j])
+			dy := cmp.Compare(y[i], y[j])
+			if dx != 0 {
+				untiedX++
+			}
+			if dy != 0 {
+				untiedY++
+			}
+			balance += dx * dy
+		}
+	}
+	if untiedX == 0 || untiedY == 0 {
+		return nil
+	}
+
+	tau := float64(balance) / math.Sqrt(float64(untiedX)*float64(untiedY))
+	return &tau
+}
@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// tolerance is how far a computed statistic may drift from its expected value
+const tolerance = 1e-9
+
+// checkCoefficient compares a coefficient that may be undefined (nil) with want
+func checkCoefficient(t *testing.T, name string, got *float64, want *float64) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil:
+		t.Errorf("%s = nil, want %.10f", name, *want)
+	case want == nil:
+		t.Errorf("%s = %.10f, want nil", name, *got)
+	case math.Abs(*got-*want) > tolerance:
+		t.Errorf("%s = %.10f, want %.10f", name, *got, *want)
+	}
+}
+
+func value(v float64) *float64 { return &v }
+
+func TestRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{"empty", nil, []float64{}},
+		{"distinct", []float64{30, 10, 20}, []float64{3, 1, 2}},
+		{"pair tied for second", []float64{1, 5, 5, 9}, []float64{1, 2.5, 2.5, 4}},
+		{"three tied", []float64{-2, -2, -2, -7}, []float64{3, 3, 3, 1}},
+		{"all tied", []float64{4, 4}, []float64{1.5, 1.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Ranks(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ranks(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPearson(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want *float64
+	}{
+		// 6 / sqrt(10 × 6)
+		{"textbook", []float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5}, value(0.7745966692414834)},
+		{"perfect positive", []float64{1, 2, 3}, []float64{10, 20, 30}, value(1)},
+		{"perfect negative", []float64{1, 2, 3}, []float64{6, 4, 2}, value(-1)},
+		{"zero variance", []float64{1, 2, 3}, []float64{5, 5, 5}, nil},
+		{"one point", []float64{1}, []float64{2}, nil},
+		{"no points", nil, nil, nil},
+		{"unequal lengths", []float64{1, 2, 3}, []float64{1, 2}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkCoefficient(t, "Pearson", Pearson(tt.x, tt.y), tt.want)
+		})
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want *float64
+	}{
+		{"monotonic but not linear", []float64{1, 2, 3, 4}, []float64{1, 8, 27, 64}, value(1)},
+		{"reversed", []float64{1, 2, 3, 4}, []float64{-1, -4, -9, -16}, value(-1)},
+		// y ranks 1, 2, 3.5, 5, 3.5 with the tied 7s sharing ranks 3 and 4
+		{"tied ranks", []float64{1, 2, 3, 4, 5}, []float64{5, 6, 7, 8, 7}, value(0.8207826816681233)},
+		{"all tied", []float64{1, 2, 3}, []float64{4, 4, 4}, nil},
+		{"one point", []float64{1}, []float64{1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkCoefficient(t, "Spearman", Spearman(tt.x, tt.y), tt.want)
+		})
+	}
+}
+
+func TestKendallTau(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want *float64
+	}{
+		// 7 concordant and 3 discordant of 10 pairs
+		{"textbook", []float64{1, 2, 3, 4, 5}, []float64{3, 1, 2, 5, 4}, value(0.4)},
+		{"reversed", []float64{1, 2, 3}, []float64{3, 2, 1}, value(-1)},
+		// 5 concordant pairs; x ties one pair, so tau-b = 5 / sqrt(5 × 6)
+		{"tie correction", []float64{1, 2, 2, 3}, []float64{1, 3, 2, 4}, value(0.9128709291752769)},
+		{"ties compared with themselves", []float64{1, 1, 2, 3}, []float64{1, 1, 2, 3}, value(1)},
+		{"all tied", []float64{7, 7, 7}, []float64{1, 2, 3}, nil},
+		{"one point", []float64{1}, []float64{1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkCoefficient(t, "KendallTau", KendallTau(tt.x, tt.y), tt.want)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package stats provides the descriptive statistics and histogram binning that
+// search stats and visualize report for a set of BM25 scores, so both commands
+// describe the same result set with the same numbers.
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// ReportedPercentiles are the percentiles the score summaries report
+var ReportedPercentiles = []int{25, 50, 75, 90, 95, 99}
+
+// Mean returns the arithmetic mean of values, or 0 for an empty slice. It sums
+// offsets from the first value, so the mean of identical values (a one-term
+// query on a small corpus often scores every match the same) is exactly that
+// value rather than one rounded from their sum.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value - values[0]
+	}
+	return values[0] + sum/float64(len(values))
+}
+
+// StdDev returns the population standard deviation of values: 0 for a single
+// value or identical values, and for an empty slice
+func StdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := Mean(values)
+	sumSquareDiff := 0.0
+	for _, value := range values {
+		diff := value - mean
+		sumSquareDiff += diff * diff
+	}
+	return math.Sqrt(sumSquareDiff / float64(len(values)))
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted, which must be in
+// ascending order. It uses linear interpolation between closest ranks (the
+// R-7 method, as in numpy and spreadsheet PERCENTILE): the value sits at
+// position p/100 × (n-1) and falls between the two neighbouring scores. When
+// the neighbours are equal the result is that score exactly, so every
+// percentile of identical scores is the score itself.
+func Percentile(sorted []float64, p float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+
+	index := p / 100 * float64(n-1)
+	lower := int(math.Floor(index))
+	upper := int(math.Ceil(index))
+	if lower == upper || sorted[lower] == sorted[upper] {
+		return sorted[lower]
+	}
+
+	weight := index - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
+
+// Median returns the 50th percentile of sorted. Under R-7 this is the middle
+// score, or the mean of the two middle scores for an even count.
+func Median(sorted []float64) float64 {
+	return Percentile(sorted, 50)
+}
+
+// Percentiles returns each of ReportedPercentiles of sorted, keyed by percentile
+func Percentiles(sorted []float64) map[int]float64 {
+	percentiles := make(map[int]float64, len(ReportedPercentiles))
+	if len(sorted) == 0 {
+		return percentiles
+	}
+	for _, p := range ReportedPercentiles {
+		percentiles[p] = Percentile(sorted, float64(p))
+	}
+	return percentiles
+}
+
+// Normalize maps score onto [0, 1] between the weakest score (0) and the
+// strongest (1). FTS5 scores are negative and more negative is a better match,
+// so strongest <= weakest. When every score is equal there is no range to
+// place score in and all scores normalize to 1.
+func Normalize(score, strongest, weakest float64) float64 {
+	if weakest <= strongest {
+		return 1
+	}
+	return (weakest - score) / (weakest - strongest)
+}
+
+// Bin is one interval of a Histogram. Every bin covers [Min, Max) except the
+// last, which is closed ([Min, Max]) so that the largest value is counted.
+type Bin struct {
+	Min   float64
+	Max   float64
+	Count int
+
+	// Closed reports whether Max itself belongs to the bin
+	Closed bool
+}
+
+// Label formats the bin in interval notation with the given number of
+// decimal places, e.g. "[-3.14, -2.72)" or "[-1.20, -0.94]" for the last bin
+func (b Bin) Label(precision int) string {
+	closing := ")"
+	if b.Closed {
+		closing = "]"
+	}
+	return fmt.Sprintf("[%.*f, %.*f%s", precision, b.Min, precision, b.Max, closing)
+}
+
+// Histogram divides the range of sorted (ascending) into n bins of equal
+// width and counts the values in each. A value equal to a boundary belongs to
+// the bin it opens, and the maximum belongs to the last bin. When every value
+// is equal there is a single closed bin holding all of them.
+func Histogram(sorted []float64, n int) []Bin {
+	if len(sorted) == 0 {
+		return nil
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	min := sorted[0]
+	max := sorted[len(sorted)-1]
+	if min == max {
+		return []Bin{{Min: min, Max: max, Count: len(sorted), Closed: true}}
+	}
+
+	bins := make([]Bin, n)
+	width := (max - min) / float64(n)
+	for i := range bins {
+		bins[i].Min = min + float64(i)*width
+		bins[i].Max = min + float64(i+1)*width
+	}
+	// Pin the top edge so rounding in the width cannot leave max outside
+	bins[n-1].Max = max
+	bins[n-1].Closed = true
+
+	for _, value := range sorted {
+		i := int((value - min) / width)
+		if i >= n {
+			i = n - 1
+		}
+		// The division can land one bin off at a boundary; settle it against
+		// the stored edges so membership always agrees with the labels
+		for i > 0 && value < bins[i].Min {
+			i--
+		}
+		for i < n-1 && value >= bins[i+1].Min {
+			i++
+		}
+		bins[i].Count++
+	}
+
+	return bins
+}
@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 50, 0},
+		{"single value at p0", []float64{-2.5}, 0, -2.5},
+		{"single value at p99", []float64{-2.5}, 99, -2.5},
+		{"p0 is the minimum", []float64{1, 2, 3, 4}, 0, 1},
+		{"p100 is the maximum", []float64{1, 2, 3, 4}, 100, 4},
+		// Position 0.25 × 3 = 0.75, three quarters of the way from 1 to 2
+		{"p25 interpolates", []float64{1, 2, 3, 4}, 25, 1.75},
+		// Position 0.9 × 3 = 2.7
+		{"p90 interpolates", []float64{1, 2, 3, 4}, 90, 3.7},
+		{"exact position", []float64{10, 20, 30, 40, 50}, 75, 40},
+		{"negative scores", []float64{-4, -3, -2, -1}, 50, -2.5},
+		{"identical neighbours", []float64{0.1, 0.1, 0.1}, 37, 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.sorted, tt.p); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Percentile(%v, %g) = %g, want %g", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{-3}, -3},
+		{[]float64{1, 3, 9}, 3},
+		{[]float64{1, 2, 3, 4}, 2.5},
+	}
+
+	for _, tt := range tests {
+		if got := Median(tt.sorted); got != tt.want {
+			t.Errorf("Median(%v) = %g, want %g", tt.sorted, got, tt.want)
+		}
+	}
+}
+
+func TestMeanAndStdDev(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		mean   float64
+		stdDev float64
+	}{
+		{"empty", nil, 0, 0},
+		{"single value", []float64{-1.7}, -1.7, 0},
+		// The population standard deviation of this textbook set is exactly 2
+		{"textbook", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 2},
+		// Identical values keep their exact mean rather than a rounded sum
+		{"identical values", []float64{0.1, 0.1, 0.1}, 0.1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mean(tt.values); got != tt.mean {
+				t.Errorf("Mean(%v) = %g, want %g", tt.values, got, tt.mean)
+			}
+			if got := StdDev(tt.values); math.Abs(got-tt.stdDev) > tolerance {
+				t.Errorf("StdDev(%v) = %g, want %g", tt.values, got, tt.stdDev)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name                      string
+		score, strongest, weakest float64
+		want                      float64
+	}{
+		{"strongest", -4, -4, -1, 1},
+		{"weakest", -1, -4, -1, 0},
+		{"midpoint", -2.5, -4, -1, 0.5},
+		{"constant scores", -2, -2, -2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.score, tt.strongest, tt.weakest); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Normalize(%g, %g, %g) = %g, want %g", tt.score, tt.strongest, tt.weakest, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		n      int
+		counts []int
+	}{
+		{"empty", nil, 4, nil},
+		// Edges at 0, 1, 2, 3, 4: a value on an interior edge opens the next
+		// bin, and the maximum lands in the closed last bin
+		{"interior edge and maximum", []float64{0, 1, 2, 3, 4}, 4, []int{1, 1, 1, 2}},
+		{"maximum only in last bin", []float64{0, 0.5, 10}, 2, []int{2, 1}},
+		{"all values equal", []float64{-2, -2, -2}, 5, []int{3}},
+		{"zero bins", []float64{-3, -2, -1}, 0, []int{3}},
+		{"negative bins", []float64{-3, -2, -1}, -2, []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bins := Histogram(tt.sorted, tt.n)
+			if len(bins) != len(tt.counts) {
+				t.Fatalf("Histogram(%v, %d) made %d bins, want %d", tt.sorted, tt.n, len(bins), len(tt.counts))
+			}
+			for i, bin := range bins {
+				if bin.Count != tt.counts[i] {
+					t.Errorf("bin %d %s holds %d values, want %d", i, bin.Label(2), bin.Count, tt.counts[i])
+				}
+				if last := i == len(bins)-1; bin.Closed != last {
+					t.Errorf("bin %d %s Closed = %v, want %v", i, bin.Label(2), bin.Closed, last)
+				}
+			}
+			if len(bins) > 0 && bins[len(bins)-1].Max != tt.sorted[len(tt.sorted)-1] {
+				t.Errorf("last bin ends at %g, want the maximum %g", bins[len(bins)-1].Max, tt.sorted[len(tt.sorted)-1])
+			}
+		})
+	}
+}
+
+func TestHistogramMembershipMatchesEdges(t *testing.T) {
+	// Widths such as 2.2 / 7 are not exact in binary; values computed the same
+	// way as the edges sit right on them and must fall in the bin they open
+	const min, max = -3.14, -0.94
+	for n := 1; n <= 12; n++ {
+		width := (max - min) / float64(n)
+		sorted := make([]float64, 0, n+1)
+		for i := 0; i < n; i++ {
+			sorted = append(sorted, min+float64(i)*width)
+		}
+		sorted = append(sorted, max)
+
+		bins := Histogram(sorted, n)
+		for i, bin := range bins {
+			count := 0
+			for _, value := range sorted {
+				if value >= bin.Min && (value < bin.Max || bin.Closed && value == bin.Max) {
+					count++
+				}
+			}
+			if count != bin.Count {
+				t.Errorf("%d bins: bin %d %s counts %d values, its edges hold %d", n, i, bin.Label(4), bin.Count, count)
+			}
+			want := 1
+			if i == n-1 {
+				want = 2
+			}
+			if bin.Count != want {
+				t.Errorf("%d bins: bin %d %s counts %d values, want %d", n, i, bin.Label(4), bin.Count, want)
+			}
+		}
+	}
+}
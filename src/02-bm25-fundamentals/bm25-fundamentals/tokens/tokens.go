@@ -0,0 +1,85 @@
+// Package tokens splits text the way the documents_fts unicode61 tokenizer
+// does, so document lengths, snippet boundaries, and query terms all agree
+// with what FTS5 indexes and BM25 normalizes by.
+//
+// The porter stemmer never adds or removes tokens, so token boundaries follow
+// unicode61: a token is a run of letters, numbers, and private-use characters,
+// which may continue (but not start) with a combining diacritic. Everything
+// else, including punctuation such as hyphens and apostrophes, separates
+// tokens: "real-time" and "don't" are two tokens each.
+package tokens
+
+import "unicode"
+
+// Span is the byte range [Start, End) of one token within its text
+type Span struct {
+	Start int
+	End   int
+}
+
+// CountTokens returns the number of tokens in text
+func CountTokens(text string) int {
+	count := 0
+	scan(text, func(start, end int) { count++ })
+	return count
+}
+
+// TokenSpans returns the byte range of each token in text, in order. Spans
+// always start and end on rune boundaries.
+func TokenSpans(text string) []Span {
+	var spans []Span
+	scan(text, func(start, end int) {
+		spans = append(spans, Span{Start: start, End: end})
+	})
+	return spans
+}
+
+// Split returns the tokens of text, before case folding and stemming
+func Split(text string) []string {
+	var words []string
+	scan(text, func(start, end int) {
+		words = append(words, text[start:end])
+	})
+	return words
+}
+
+// scan calls fn with the byte range of each token in text
+func scan(text string, fn func(start, end int)) {
+	start := -1
+	for i, r := range text {
+		switch {
+		case isTokenChar(r):
+			if start < 0 {
+				start = i
+			}
+		case start >= 0 && isDiacritic(r):
+			// Combining marks stay part of the current token
+		default:
+			if start >= 0 {
+				fn(start, i)
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		fn(start, len(text))
+	}
+}
+
+// isTokenChar reports whether unicode61 treats r as a token character by default (L* N* Co)
+func isTokenChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Co, r)
+}
+
+// isDiacritic mirrors sqlite3Fts5UnicodeIsdiacritic: the combining marks
+// between U+0300 and U+0331 that unicode61 folds into the preceding token
+func isDiacritic(r rune) bool {
+	const mask0, mask1 = 0x08029FDF, 0x000361F8
+	if r < 0x300 || r > 0x331 {
+		return false
+	}
+	if r < 0x320 {
+		return mask0&(1<<(r-0x300)) != 0
+	}
+	return mask1&(1<<(r-0x320)) != 0
+}
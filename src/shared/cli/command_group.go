@@ -1,4 +1,4 @@
-package commands
+package cli
 
 import "github.com/spf13/cobra"
 
@@ -6,45 +6,46 @@ import "github.com/spf13/cobra"
 type CommandGroup struct {
 	// The Cobra command this group represents
 	Command *cobra.Command
-	
-	// Child command groups (base commands like "document", "user", etc.)
+
+	// Child command groups (base commands like "document", "corpus", etc.)
 	ChildGroups []*CommandGroup
-	
+
 	// Direct sub-commands attached to this group
 	SubCommands []*cobra.Command
-	
+
 	// Flag registration function for this group and its commands
 	FlagSetup func()
 }
 
-
-// Init initializes the command group by registering all commands and flags
+// Init initializes the command group in two passes. The first pass attaches
+// every sub-command and child group so the whole tree exists; the second runs
+// each FlagSetup, parent before children. A FlagSetup may therefore look up
+// its parent's persistent flags, and may call MarkFlagRequired or
+// MarkFlagsMutuallyExclusive for any flag it defines itself.
 func (cg *CommandGroup) Init() {
 	cg.RegisterCommands()
 	cg.RegisterFlags()
 }
 
-// RegisterFlags recursively calls flag setup for this group and all children
-func (cg *CommandGroup) RegisterFlags() {
-	if cg.FlagSetup != nil {
-		cg.FlagSetup()
-	}
-	
-	for _, childGroup := range cg.ChildGroups {
-		childGroup.RegisterFlags()
-	}
-}
-
-// RegisterCommands recursively registers all commands in the hierarchy
+// RegisterCommands recursively attaches sub-commands and child groups
 func (cg *CommandGroup) RegisterCommands() {
-	// Add direct sub-commands to this group's command
 	for _, subCmd := range cg.SubCommands {
 		cg.Command.AddCommand(subCmd)
 	}
-	
-	// Add child groups to this group's command and recursively register their commands
+
 	for _, childGroup := range cg.ChildGroups {
 		cg.Command.AddCommand(childGroup.Command)
 		childGroup.RegisterCommands()
 	}
-}
\ No newline at end of file
+}
+
+// RegisterFlags recursively runs flag setup, this group before its children
+func (cg *CommandGroup) RegisterFlags() {
+	if cg.FlagSetup != nil {
+		cg.FlagSetup()
+	}
+
+	for _, childGroup := range cg.ChildGroups {
+		childGroup.RegisterFlags()
+	}
+}
@@ -0,0 +1,136 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Display writes errors in a phase's house style. The simple form prints the
+// category label and message plus an optional hint; the verbose form is
+// delegated to Verbose.
+type Display struct {
+	// Registry classifies errors into labelled categories
+	Registry *Registry
+
+	// Out receives the output; nil means os.Stderr
+	Out io.Writer
+
+	// Prefix is written before each label, e.g. "❌ "
+	Prefix string
+
+	// HintPrefix is written before each hint, e.g. "Hint: "
+	HintPrefix string
+
+	// Message extracts the text shown after a category label; nil shows the full error
+	Message func(error) string
+
+	// Verbose prints the detailed form; nil falls back to the simple form
+	Verbose func(d *Display, err error)
+}
+
+// Show displays err in the verbose form when verbose is set and the simple form otherwise
+func (d *Display) Show(err error, verbose bool) {
+	if err == nil {
+		return
+	}
+
+	if verbose && d.Verbose != nil {
+		d.Verbose(d, err)
+	} else {
+		d.Simple(err)
+	}
+}
+
+// Simple prints the category label, message, and hint for err
+func (d *Display) Simple(err error) {
+	w := d.out()
+
+	c, ok := d.Registry.Classify(err)
+	if !ok {
+		fmt.Fprintf(w, "%sError: %v\n", d.Prefix, err)
+		return
+	}
+
+	message := err.Error()
+	if d.Message != nil {
+		message = d.Message(err)
+	}
+	fmt.Fprintf(w, "%s%s: %s\n", d.Prefix, c.Label, message)
+	if c.Hint != "" {
+		fmt.Fprintf(w, "%s%s\n", d.HintPrefix, c.Hint)
+	}
+}
+
+func (d *Display) out() io.Writer {
+	if d.Out != nil {
+		return d.Out
+	}
+	return os.Stderr
+}
+
+// StripSentinel returns the error message without its leading sentinel text,
+// i.e. everything after the first ": "
+func StripSentinel(err error) string {
+	parts := strings.SplitN(err.Error(), ": ", 2)
+	if len(parts) > 1 {
+		return parts[1]
+	}
+	return err.Error()
+}
+
+// LastSegment returns the innermost part of the error message, i.e.
+// everything after the last ": "
+func LastSegment(err error) string {
+	msg := err.Error()
+	if idx := strings.LastIndex(msg, ": "); idx != -1 {
+		return msg[idx+2:]
+	}
+	return msg
+}
+
+// PrintChainTree writes err and each error it wraps as an indented tree
+func PrintChainTree(w io.Writer, err error) {
+	for depth, current := range Chain(err) {
+		fmt.Fprintf(w, "%s└─ %v\n", strings.Repeat("  ", depth), current)
+	}
+}
+
+// VerboseCauses prints the error, its type, and each wrapped cause on a numbered line
+func VerboseCauses(d *Display, err error) {
+	w := d.out()
+	fmt.Fprintf(w, "%sDetailed Error Information:\n", d.Prefix)
+	fmt.Fprintf(w, "   Error: %v\n", err)
+	fmt.Fprintf(w, "   Type: %T\n", err)
+
+	for depth, cause := range Chain(err)[1:] {
+		fmt.Fprintf(w, "   Caused by [%d]: %v\n", depth+1, cause)
+	}
+}
+
+// VerboseFullChain prints the simple form followed by the full error text
+func VerboseFullChain(d *Display, err error) {
+	d.Simple(err)
+	fmt.Fprintf(d.out(), "\nFull error chain: %+v\n", err)
+}
+
+// VerboseReport prints the error type, the wrapped chain as a tree, and the
+// description of the error's category
+func VerboseReport(d *Display, err error) {
+	w := d.out()
+	fmt.Fprintln(w, "=== VERBOSE ERROR OUTPUT ===")
+	fmt.Fprintf(w, "Error Type: %T\n", err)
+
+	fmt.Fprintln(w, "\nError Chain:")
+	PrintChainTree(w, err)
+
+	fmt.Fprintln(w, "\nError Category:")
+	if c, ok := d.Registry.Classify(err); ok {
+		fmt.Fprintf(w, "  %s\n", c.Description)
+	} else {
+		fmt.Fprintln(w, "  Uncategorized Error")
+	}
+
+	fmt.Fprintln(w, "\n=== END VERBOSE OUTPUT ===")
+}
@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors shared by every phase. Phases wrap them with the helpers below
+// and may register additional sentinels of their own with a Registry.
+var (
+	// ErrValidation indicates input validation failed
+	ErrValidation = errors.New("validation failed")
+
+	// ErrDatabase indicates a database operation failed
+	ErrDatabase = errors.New("database operation failed")
+
+	// ErrFTS5 indicates an FTS5-specific operation failed
+	ErrFTS5 = errors.New("FTS5 operation failed")
+
+	// ErrConnection indicates the database connection could not be established
+	ErrConnection = errors.New("connection failed")
+
+	// ErrNotFound indicates a requested resource was not found
+	ErrNotFound = errors.New("not found")
+
+	// ErrTransaction indicates a transaction failed
+	ErrTransaction = errors.New("transaction failed")
+
+	// ErrCancelled indicates an operation was interrupted before completion
+	ErrCancelled = errors.New("operation cancelled")
+)
+
+// Wrapf creates a formatted error that wraps sentinel, so errors.Is matches it
+// through any further wrapping. The message reads "<sentinel>: <details>".
+func Wrapf(sentinel error, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{sentinel}, args...)...)
+}
+
+// Validationf creates a formatted validation error
+func Validationf(format string, args ...interface{}) error {
+	return Wrapf(ErrValidation, format, args...)
+}
+
+// Databasef creates a formatted database error
+func Databasef(format string, args ...interface{}) error {
+	return Wrapf(ErrDatabase, format, args...)
+}
+
+// FTS5f creates a formatted FTS5 error
+func FTS5f(format string, args ...interface{}) error {
+	return Wrapf(ErrFTS5, format, args...)
+}
+
+// Connectionf creates a formatted connection error
+func Connectionf(format string, args ...interface{}) error {
+	return Wrapf(ErrConnection, format, args...)
+}
+
+// NotFoundf creates a formatted not found error
+func NotFoundf(format string, args ...interface{}) error {
+	return Wrapf(ErrNotFound, format, args...)
+}
+
+// Transactionf creates a formatted transaction error
+func Transactionf(format string, args ...interface{}) error {
+	return Wrapf(ErrTransaction, format, args...)
+}
+
+// Cancelledf creates a formatted cancellation error
+func Cancelledf(format string, args ...interface{}) error {
+	return Wrapf(ErrCancelled, format, args...)
+}
+
+// Chain returns err followed by each error it wraps, outermost first
+func Chain(err error) []error {
+	var chain []error
+	for current := err; current != nil; current = errors.Unwrap(current) {
+		chain = append(chain, current)
+	}
+	return chain
+}
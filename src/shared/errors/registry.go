@@ -0,0 +1,52 @@
+package errors
+
+import "errors"
+
+// Category describes how errors wrapping one sentinel are reported
+type Category struct {
+	// Sentinel identifies the category; an error belongs to it when errors.Is matches
+	Sentinel error
+
+	// Label prefixes the simple display, e.g. "Validation Error"
+	Label string
+
+	// Hint is an optional follow-up line suggesting how to resolve the error
+	Hint string
+
+	// Description summarizes the category in verbose output
+	Description string
+}
+
+// Registry classifies errors into categories. Categories are checked in
+// registration order, so an error wrapping several sentinels takes the first
+// matching category.
+type Registry struct {
+	categories []Category
+}
+
+// NewRegistry creates a registry with the given categories
+func NewRegistry(categories ...Category) *Registry {
+	r := &Registry{}
+	for _, c := range categories {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register appends a category, letting phases add sentinels of their own
+func (r *Registry) Register(c Category) {
+	r.categories = append(r.categories, c)
+}
+
+// Classify returns the first registered category whose sentinel err wraps
+func (r *Registry) Classify(err error) (Category, bool) {
+	if r == nil || err == nil {
+		return Category{}, false
+	}
+	for _, c := range r.categories {
+		if errors.Is(err, c.Sentinel) {
+			return c, true
+		}
+	}
+	return Category{}, false
+}
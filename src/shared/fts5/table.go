@@ -0,0 +1,201 @@
+// Package fts5 builds and runs the CREATE statements for FTS5 virtual tables,
+// so phases describe a table with a TableSpec instead of assembling SQL by hand.
+package fts5
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jaime/go-sqlite/shared/errors"
+)
+
+// Execer runs a statement; *sql.DB, *sql.Tx, and *sql.Conn all satisfy it
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// TableSpec describes an FTS5 virtual table
+type TableSpec struct {
+	// Columns are the indexed columns, in order
+	Columns []string
+
+	// Tokenizer is the tokenize option, e.g. "porter unicode61 remove_diacritics 1";
+	// empty uses the FTS5 default (unicode61)
+	Tokenizer string
+
+	// Prefix lists the prefix lengths to index, e.g. []int{2, 3}
+	Prefix []int
+
+	// Content names an external content table in the same schema as the FTS5
+	// table; empty stores content in the FTS5 table itself
+	Content string
+
+	// ContentRowID is the integer key column of the Content table; empty uses rowid
+	ContentRowID string
+
+	// IfNotExists leaves an existing table (and triggers) in place
+	IfNotExists bool
+}
+
+// CreateTableSQL returns the CREATE VIRTUAL TABLE statement for name. A name of
+// the form "schema.table" (e.g. "temp.scratch") creates the table in that schema.
+// Identifiers are double-quoted and option values single-quoted.
+func CreateTableSQL(name string, spec TableSpec) (string, error) {
+	if name == "" {
+		return "", errors.Validationf("FTS5 table name is empty")
+	}
+	if len(spec.Columns) == 0 {
+		return "", errors.Validationf("FTS5 table %s has no columns", name)
+	}
+	if spec.ContentRowID != "" && spec.Content == "" {
+		return "", errors.Validationf("FTS5 table %s sets a content rowid without a content table", name)
+	}
+
+	args := make([]string, 0, len(spec.Columns)+4)
+	for _, column := range spec.Columns {
+		args = append(args, quoteIdent(column))
+	}
+
+	if spec.Content != "" {
+		// FTS5 reads the content table from its own schema
+		args = append(args, "content="+quoteString(tableName(spec.Content)))
+	}
+	if spec.ContentRowID != "" {
+		args = append(args, "content_rowid="+quoteString(spec.ContentRowID))
+	}
+	if spec.Tokenizer != "" {
+		args = append(args, "tokenize="+quoteString(spec.Tokenizer))
+	}
+	if len(spec.Prefix) > 0 {
+		lengths := make([]string, len(spec.Prefix))
+		for i, length := range spec.Prefix {
+			if length < 1 {
+				return "", errors.Validationf("FTS5 table %s has invalid prefix length %d", name, length)
+			}
+			lengths[i] = strconv.Itoa(length)
+		}
+		args = append(args, "prefix="+quoteString(strings.Join(lengths, " ")))
+	}
+
+	return fmt.Sprintf("CREATE VIRTUAL TABLE %s%s USING fts5(%s)",
+		ifNotExists(spec.IfNotExists), quoteName(name), strings.Join(args, ", ")), nil
+}
+
+// CreateFTS5Table creates the FTS5 table name described by spec
+func CreateFTS5Table(ctx context.Context, db Execer, name string, spec TableSpec) error {
+	statement, err := CreateTableSQL(name, spec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, statement); err != nil {
+		return errors.FTS5f("failed to create FTS5 table %s: %w", name, err)
+	}
+	return nil
+}
+
+// TriggerSQL returns the statements creating the triggers that keep an
+// external-content table's index in step with spec.Content. The triggers are
+// named <content>_after_insert, <content>_after_update, and
+// <content>_after_delete.
+func TriggerSQL(name string, spec TableSpec) ([]string, error) {
+	if spec.Content == "" {
+		return nil, errors.Validationf("FTS5 table %s has no content table to keep in sync", name)
+	}
+	if len(spec.Columns) == 0 {
+		return nil, errors.Validationf("FTS5 table %s has no columns", name)
+	}
+
+	rowID := spec.ContentRowID
+	if rowID == "" {
+		rowID = "rowid"
+	}
+
+	// Statements inside a trigger may not name a schema; the trigger itself is
+	// created in the content table's schema
+	table := quoteIdent(tableName(name))
+	columns := make([]string, len(spec.Columns))
+	newValues := make([]string, len(spec.Columns))
+	oldValues := make([]string, len(spec.Columns))
+	for i, column := range spec.Columns {
+		columns[i] = quoteIdent(column)
+		newValues[i] = "new." + quoteIdent(column)
+		oldValues[i] = "old." + quoteIdent(column)
+	}
+	columnList := strings.Join(columns, ", ")
+
+	insert := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.%s, %s);",
+		table, columnList, quoteIdent(rowID), strings.Join(newValues, ", "))
+	remove := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s);",
+		table, table, columnList, quoteIdent(rowID), strings.Join(oldValues, ", "))
+
+	trigger := func(event, body string) string {
+		return fmt.Sprintf("CREATE TRIGGER %s%s AFTER %s ON %s BEGIN %s END",
+			ifNotExists(spec.IfNotExists), quoteName(spec.Content+"_after_"+strings.ToLower(event)),
+			event, quoteIdent(tableName(spec.Content)), body)
+	}
+
+	return []string{
+		trigger("INSERT", insert),
+		trigger("UPDATE", remove+" "+insert),
+		trigger("DELETE", remove),
+	}, nil
+}
+
+// CreateFTS5TableWithTriggers creates an external-content FTS5 table and the
+// triggers that index rows as they are inserted into, updated in, and deleted
+// from spec.Content. spec.Content must name an existing table.
+func CreateFTS5TableWithTriggers(ctx context.Context, db Execer, name string, spec TableSpec) error {
+	triggers, err := TriggerSQL(name, spec)
+	if err != nil {
+		return err
+	}
+
+	if err := CreateFTS5Table(ctx, db, name, spec); err != nil {
+		return err
+	}
+
+	for _, statement := range triggers {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return errors.FTS5f("failed to create sync trigger for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ifNotExists returns the IF NOT EXISTS clause when set
+func ifNotExists(set bool) string {
+	if set {
+		return "IF NOT EXISTS "
+	}
+	return ""
+}
+
+// quoteName quotes a table name, keeping a "schema." prefix as a separate identifier
+func quoteName(name string) string {
+	if schema, table, ok := strings.Cut(name, "."); ok {
+		return quoteIdent(schema) + "." + quoteIdent(table)
+	}
+	return quoteIdent(name)
+}
+
+// tableName returns name without its schema prefix
+func tableName(name string) string {
+	if _, table, ok := strings.Cut(name, "."); ok {
+		return table
+	}
+	return name
+}
+
+// quoteIdent quotes an SQL identifier
+func quoteIdent(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+// quoteString quotes an SQL string literal
+func quoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
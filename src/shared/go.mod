@@ -0,0 +1,13 @@
+module github.com/jaime/go-sqlite/shared
+
+go 1.24
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.17
+	github.com/spf13/cobra v1.8.0
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+)
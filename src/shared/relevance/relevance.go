@@ -0,0 +1,47 @@
+// Package relevance labels SQLite FTS5 BM25 scores so that every phase describes the
+// same score with the same word.
+package relevance
+
+import "fmt"
+
+// Thresholds holds the BM25 score bounds of the relevance labels. FTS5 scores are
+// negative and more negative is a better match, so a score is "excellent" at or below
+// Excellent, "good" at or below Good, "fair" at or below Fair, and "poor" otherwise.
+type Thresholds struct {
+	Excellent float64
+	Good      float64
+	Fair      float64
+}
+
+// DefaultThresholds returns the thresholds used when none are configured
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		Excellent: -4.0,
+		Good:      -2.0,
+		Fair:      -1.0,
+	}
+}
+
+// Validate checks that the thresholds are ordered excellent < good < fair <= 0
+func (t Thresholds) Validate() error {
+	if !(t.Excellent < t.Good && t.Good < t.Fair && t.Fair <= 0) {
+		return fmt.Errorf("relevance thresholds must satisfy excellent < good < fair <= 0 (scores are negative, lower is better), got %g, %g, %g",
+			t.Excellent, t.Good, t.Fair)
+	}
+	return nil
+}
+
+// Classify labels a BM25 score "excellent", "good", "fair", or "poor". The label
+// depends on the score alone, not on the other results of the same search.
+func (t Thresholds) Classify(score float64) string {
+	switch {
+	case score <= t.Excellent:
+		return "excellent"
+	case score <= t.Good:
+		return "good"
+	case score <= t.Fair:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
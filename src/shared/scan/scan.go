@@ -0,0 +1,153 @@
+// Package scan reads query results row by row: it checks that a query returns
+// the columns a scanner expects, reports which row a failure happened at, and
+// tolerates NULL in text columns.
+package scan
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jaime/go-sqlite/shared/errors"
+)
+
+// Rows is the part of *sql.Rows the helpers use. Wrappers that classify
+// iteration errors can be passed in place of *sql.Rows.
+type Rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// Columns checks that rows returns exactly want, in order. Names compare
+// case-insensitively and without a table prefix, so "d.id" and "ID" both
+// match "id"; callers alias computed columns to the expected name.
+func Columns(rows Rows, want ...string) error {
+	got, err := rows.Columns()
+	if err != nil {
+		return errors.Databasef("failed to read result columns: %w", err)
+	}
+
+	matches := len(got) == len(want)
+	for i := 0; matches && i < len(got); i++ {
+		name := got[i]
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+		matches = strings.EqualFold(name, want[i])
+	}
+	if !matches {
+		return errors.Databasef("query returned columns (%s), expected (%s)",
+			strings.Join(got, ", "), strings.Join(want, ", "))
+	}
+	return nil
+}
+
+// Each calls fn for every row and returns the number of rows read. what names
+// a row in error messages, e.g. "search result"; failures report the 1-based
+// row they happened at.
+func Each(rows Rows, what string, fn func(row Rows) error) (int, error) {
+	count := 0
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return count, errors.Databasef("failed to scan %s at row %d: %w", what, count+1, err)
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, errors.Databasef("error iterating %s rows after %d rows: %w", what, count, err)
+	}
+	return count, nil
+}
+
+// All scans every row with fn and collects the values
+func All[T any](rows Rows, what string, fn func(row Rows) (T, error)) ([]T, error) {
+	var values []T
+	_, err := Each(rows, what, func(row Rows) error {
+		value, err := fn(row)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Text returns a Scan destination for a text column that stores NULL as the
+// empty string. FTS5 columns are untyped and imported rows may leave them
+// NULL, which a plain *string cannot hold.
+func Text(dest *string) sql.Scanner {
+	return text{dest: dest}
+}
+
+// TextOr is Text with fallback stored for NULL instead of the empty string
+func TextOr(dest *string, fallback string) sql.Scanner {
+	return text{dest: dest, fallback: fallback}
+}
+
+// text is the Scanner returned by Text and TextOr
+type text struct {
+	dest     *string
+	fallback string
+}
+
+func (t text) Scan(src any) error {
+	var value sql.NullString
+	if err := value.Scan(src); err != nil {
+		return err
+	}
+	if !value.Valid {
+		*t.dest = t.fallback
+		return nil
+	}
+	*t.dest = value.String
+	return nil
+}
+
+// Time returns a Scan destination for a timestamp column that stores NULL as
+// the zero time. Drivers hand back text they could not parse as a string;
+// Time tries each of layouts on it.
+func Time(dest *time.Time, layouts ...string) sql.Scanner {
+	return timestamp{dest: dest, layouts: layouts}
+}
+
+// timestamp is the Scanner returned by Time
+type timestamp struct {
+	dest    *time.Time
+	layouts []string
+}
+
+func (t timestamp) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*t.dest = time.Time{}
+		return nil
+	case time.Time:
+		*t.dest = value
+		return nil
+	case []byte:
+		return t.parse(string(value))
+	case string:
+		return t.parse(value)
+	default:
+		return fmt.Errorf("cannot scan %T into a timestamp", src)
+	}
+}
+
+// parse stores the first successful parse of value against the layouts
+func (t timestamp) parse(value string) error {
+	for _, layout := range t.layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			*t.dest = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized timestamp %q", value)
+}
@@ -0,0 +1,92 @@
+package txn
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"time"
+
+	"github.com/jaime/go-sqlite/shared/errors"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Options controls how Run retries a transaction that hit a locked database
+type Options struct {
+	// BusyRetries is how many times to rerun the transaction after SQLITE_BUSY
+	// or SQLITE_LOCKED; zero disables retrying
+	BusyRetries int
+
+	// Backoff is the wait before the first retry, doubled for each further
+	// attempt; zero uses DefaultBackoff
+	Backoff time.Duration
+}
+
+// DefaultBackoff is the initial retry wait when Options.Backoff is unset
+const DefaultBackoff = 50 * time.Millisecond
+
+// Run executes fn inside a transaction on db. The transaction commits when fn
+// returns nil and rolls back when fn returns an error or panics; a panic is
+// re-raised after the rollback. Errors from fn are returned unchanged.
+//
+// When the transaction fails because the database is busy, Run retries it up
+// to opts.BusyRetries times, calling fn again from the start, so fn must not
+// keep state from an earlier attempt.
+func Run(ctx context.Context, db *sql.DB, opts Options, fn func(tx *sql.Tx) error) error {
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runOnce(ctx, db, fn)
+		if err == nil || attempt >= opts.BusyRetries || !IsBusy(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
+
+// runOnce runs a single attempt of the transaction
+func runOnce(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Transactionf("failed to begin transaction: %w", err)
+	}
+
+	// err is the named result, so the rollback sees the final outcome,
+	// including a failed commit
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Transactionf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// IsBusy reports whether err was caused by another connection holding a lock,
+// which SQLite reports as SQLITE_BUSY or SQLITE_LOCKED
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !stderrors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
@@ -0,0 +1,183 @@
+package txn
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// openTestDB opens a file database with busy waiting disabled, so a held lock
+// fails immediately with SQLITE_BUSY instead of blocking
+func openTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=0")
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func setupNotes(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "txn.db")
+	db := openTestDB(t, path)
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	return path, db
+}
+
+func countNotes(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notes").Scan(&n); err != nil {
+		t.Fatalf("count notes: %v", err)
+	}
+	return n
+}
+
+func TestRunCommitsOnSuccess(t *testing.T) {
+	_, db := setupNotes(t)
+
+	err := Run(context.Background(), db, Options{}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO notes (body) VALUES ('kept')")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if n := countNotes(t, db); n != 1 {
+		t.Errorf("notes after commit = %d, want 1", n)
+	}
+}
+
+func TestRunRollsBackOnError(t *testing.T) {
+	_, db := setupNotes(t)
+	failure := fmt.Errorf("stop")
+
+	err := Run(context.Background(), db, Options{}, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO notes (body) VALUES ('discarded')"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("Run error = %v, want the error returned by fn unchanged", err)
+	}
+	if n := countNotes(t, db); n != 0 {
+		t.Errorf("notes after rollback = %d, want 0", n)
+	}
+}
+
+func TestRunRollsBackOnPanic(t *testing.T) {
+	_, db := setupNotes(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic re-raised", r)
+			}
+		}()
+		Run(context.Background(), db, Options{}, func(tx *sql.Tx) error {
+			if _, err := tx.Exec("INSERT INTO notes (body) VALUES ('discarded')"); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+
+	if n := countNotes(t, db); n != 0 {
+		t.Errorf("notes after panic = %d, want 0", n)
+	}
+}
+
+func TestRunRetriesWhenBusy(t *testing.T) {
+	path, db := setupNotes(t)
+
+	// A second connection holds the write lock until the first attempt has failed
+	locker := openTestDB(t, path)
+	lock, err := locker.Begin()
+	if err != nil {
+		t.Fatalf("begin locker: %v", err)
+	}
+	if _, err := lock.Exec("INSERT INTO notes (body) VALUES ('locker')"); err != nil {
+		t.Fatalf("take write lock: %v", err)
+	}
+
+	attempts := 0
+	err = Run(context.Background(), db, Options{BusyRetries: 3, Backoff: 10 * time.Millisecond}, func(tx *sql.Tx) error {
+		attempts++
+		if attempts == 2 {
+			lock.Rollback()
+		}
+		_, err := tx.Exec("INSERT INTO notes (body) VALUES ('retried')")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if n := countNotes(t, db); n != 1 {
+		t.Errorf("notes after retry = %d, want 1", n)
+	}
+}
+
+func TestRunGivesUpAfterBusyRetries(t *testing.T) {
+	path, db := setupNotes(t)
+
+	locker := openTestDB(t, path)
+	lock, err := locker.Begin()
+	if err != nil {
+		t.Fatalf("begin locker: %v", err)
+	}
+	defer lock.Rollback()
+	if _, err := lock.Exec("INSERT INTO notes (body) VALUES ('locker')"); err != nil {
+		t.Fatalf("take write lock: %v", err)
+	}
+
+	attempts := 0
+	err = Run(context.Background(), db, Options{BusyRetries: 2, Backoff: time.Millisecond}, func(tx *sql.Tx) error {
+		attempts++
+		_, err := tx.Exec("INSERT INTO notes (body) VALUES ('blocked')")
+		return err
+	})
+	if !IsBusy(err) {
+		t.Fatalf("Run error = %v, want a busy error", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3 (one try and two retries)", attempts)
+	}
+}
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"busy", sqlite3.Error{Code: sqlite3.ErrBusy}, true},
+		{"locked", sqlite3.Error{Code: sqlite3.ErrLocked}, true},
+		{"wrapped busy", fmt.Errorf("insert: %w", sqlite3.Error{Code: sqlite3.ErrBusy}), true},
+		{"other sqlite error", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"message only", stderrors.New("database is locked"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBusy(tt.err); got != tt.want {
+				t.Errorf("IsBusy(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}